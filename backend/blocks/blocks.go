@@ -0,0 +1,359 @@
+// Package blocks implements structured post content: a document is a list
+// of typed blocks such as paragraphs, headings and images, stored as JSON
+// and rendered to HTML or plain text.
+//
+// A block is encoded as {"id": "...", "type": "heading", "data": {...}}
+// where data's fields depend on the type. Parse validates a document and
+// sanitizes the markup in its text fields, so stored documents are safe
+// for frontends to use directly.
+package blocks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sasanzare/go-cms/models"
+	xhtml "golang.org/x/net/html"
+)
+
+// Block types
+const (
+	TypeParagraph = "paragraph"
+	TypeHeading   = "heading"
+	TypeImage     = "image"
+	TypeQuote     = "quote"
+	TypeCode      = "code"
+	TypeEmbed     = "embed"
+	TypeGallery   = "gallery"
+)
+
+// Document limits
+const (
+	MaxBlocks   = 500
+	maxIDLength = 64
+)
+
+// data is a block type's payload.
+type data interface {
+	// normalize validates the data and sanitizes its text in place
+	normalize() error
+	// renderHTML writes the block as HTML; media holds the referenced items
+	renderHTML(b *strings.Builder, media map[uint]models.Media)
+	// renderText writes the block's text
+	renderText(b *strings.Builder)
+}
+
+// mediaRef is data referring to media items.
+type mediaRef interface {
+	mediaIDs() []uint
+}
+
+var dataTypes = map[string]func() data{
+	TypeParagraph: func() data { return &Paragraph{} },
+	TypeHeading:   func() data { return &Heading{} },
+	TypeImage:     func() data { return &Image{} },
+	TypeQuote:     func() data { return &Quote{} },
+	TypeCode:      func() data { return &Code{} },
+	TypeEmbed:     func() data { return &Embed{} },
+	TypeGallery:   func() data { return &Gallery{} },
+}
+
+// Block is one typed piece of a document. Data holds a pointer to the
+// type's struct, such as *Heading.
+type Block struct {
+	// ID is an optional key frontends can keep stable across edits
+	ID   string `json:"id,omitempty"`
+	Type string `json:"type"`
+	Data data   `json:"data"`
+}
+
+// UnmarshalJSON decodes data into the struct for the block's type,
+// rejecting unknown types and fields.
+func (b *Block) UnmarshalJSON(raw []byte) error {
+	var envelope struct {
+		ID   string          `json:"id"`
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return err
+	}
+	newData, ok := dataTypes[envelope.Type]
+	if !ok {
+		return fmt.Errorf("unknown block type %q", envelope.Type)
+	}
+
+	d := newData()
+	if len(envelope.Data) > 0 && string(envelope.Data) != "null" {
+		dec := json.NewDecoder(bytes.NewReader(envelope.Data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(d); err != nil {
+			return fmt.Errorf("invalid %s data: %w", envelope.Type, err)
+		}
+	}
+	b.ID, b.Type, b.Data = envelope.ID, envelope.Type, d
+	return nil
+}
+
+// Document is a post's content as blocks.
+type Document []Block
+
+// Parse decodes and validates a document. Errors name the offending
+// block by its position, counting from 1.
+func Parse(raw []byte) (Document, error) {
+	var doc Document
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field == "" {
+			return nil, errors.New("blocks must be a list")
+		}
+		return nil, err
+	}
+	if len(doc) == 0 {
+		return nil, errors.New("at least one block is required")
+	}
+	if len(doc) > MaxBlocks {
+		return nil, fmt.Errorf("at most %d blocks are allowed", MaxBlocks)
+	}
+
+	ids := map[string]bool{}
+	for i := range doc {
+		block := &doc[i]
+		if len(block.ID) > maxIDLength {
+			return nil, fmt.Errorf("block %d: id exceeds %d characters", i+1, maxIDLength)
+		}
+		if block.ID != "" {
+			if ids[block.ID] {
+				return nil, fmt.Errorf("block %d: duplicate id %q", i+1, block.ID)
+			}
+			ids[block.ID] = true
+		}
+		if err := block.Data.normalize(); err != nil {
+			return nil, fmt.Errorf("block %d (%s): %w", i+1, block.Type, err)
+		}
+	}
+	return doc, nil
+}
+
+// JSON encodes the document for storage.
+func (d Document) JSON() ([]byte, error) {
+	return json.Marshal(d)
+}
+
+// MediaIDs lists the media items the document refers to, each once.
+func (d Document) MediaIDs() []uint {
+	seen := map[uint]bool{}
+	var ids []uint
+	for _, block := range d {
+		ref, ok := block.Data.(mediaRef)
+		if !ok {
+			continue
+		}
+		for _, id := range ref.mediaIDs() {
+			if id != 0 && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// HTML renders the document. media must hold the items MediaIDs lists;
+// images whose item is missing are left out.
+func (d Document) HTML(media map[uint]models.Media) string {
+	var b strings.Builder
+	for _, block := range d {
+		block.Data.renderHTML(&b, media)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Text renders the document as plain text, one block per paragraph.
+func (d Document) Text() string {
+	parts := make([]string, 0, len(d))
+	for _, block := range d {
+		var b strings.Builder
+		block.Data.renderText(&b)
+		if text := strings.TrimSpace(b.String()); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// FromHTML converts existing HTML content to sanitized paragraph blocks.
+// Content over the paragraph text limit is split between top-level
+// elements, so it renders as before; an element over the limit is split
+// between its children, each part wrapped in a copy of its tags, and
+// long text between words. It fails for content without text.
+func FromHTML(html string) (Document, error) {
+	html = strings.TrimSpace(richPolicy.Sanitize(html))
+	var (
+		doc   Document
+		chunk strings.Builder
+		size  int
+	)
+	flush := func() error {
+		if chunk.Len() == 0 {
+			return nil
+		}
+		p := &Paragraph{Text: chunk.String()}
+		chunk.Reset()
+		size = 0
+		if err := p.normalize(); err != nil {
+			return err
+		}
+		doc = append(doc, Block{Type: TypeParagraph, Data: p})
+		return nil
+	}
+	for _, piece := range fitPieces(html, maxTextLength) {
+		n := utf8.RuneCountInString(piece)
+		if size+n > maxTextLength {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		chunk.WriteString(piece)
+		size += n
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if len(doc) == 0 {
+		return nil, errors.New("text is required")
+	}
+	if len(doc) > MaxBlocks {
+		return nil, fmt.Errorf("at most %d blocks are allowed", MaxBlocks)
+	}
+	return doc, nil
+}
+
+// fitPieces splits sanitized HTML into top-level pieces of at most limit
+// characters. Elements over the limit are split between their children,
+// each group wrapped in the element's tags, and text between words.
+func fitPieces(html string, limit int) []string {
+	var pieces []string
+	for _, piece := range topLevelPieces(html) {
+		if utf8.RuneCountInString(piece) <= limit {
+			pieces = append(pieces, piece)
+			continue
+		}
+		open, inner, close, ok := splitElement(piece)
+		if !ok {
+			pieces = append(pieces, splitText(piece, limit)...)
+			continue
+		}
+		budget := limit - utf8.RuneCountInString(open) - utf8.RuneCountInString(close)
+		if budget < limit/2 {
+			// Tags this long leave no room; drop them rather than the text
+			pieces = append(pieces, fitPieces(inner, limit)...)
+			continue
+		}
+		var (
+			group strings.Builder
+			size  int
+		)
+		for _, child := range fitPieces(inner, budget) {
+			n := utf8.RuneCountInString(child)
+			if size+n > budget && group.Len() > 0 {
+				pieces = append(pieces, open+group.String()+close)
+				group.Reset()
+				size = 0
+			}
+			group.WriteString(child)
+			size += n
+		}
+		if group.Len() > 0 {
+			pieces = append(pieces, open+group.String()+close)
+		}
+	}
+	return pieces
+}
+
+// splitElement splits a top-level element into its start tag, content and
+// end tag. It fails for text.
+func splitElement(piece string) (open, inner, close string, ok bool) {
+	z := xhtml.NewTokenizer(strings.NewReader(piece))
+	if z.Next() != xhtml.StartTagToken {
+		return "", "", "", false
+	}
+	open = string(z.Raw())
+	name, _ := z.TagName()
+	inner = piece[len(open):]
+	if end := "</" + string(name) + ">"; strings.HasSuffix(inner, end) {
+		inner, close = strings.TrimSuffix(inner, end), end
+	}
+	return open, inner, close, true
+}
+
+// splitText splits text into runs of at most limit characters, between
+// words where it can and never inside a character reference.
+func splitText(text string, limit int) []string {
+	var runs []string
+	for utf8.RuneCountInString(text) > limit {
+		cut, n := 0, 0
+		for i := range text {
+			if n == limit {
+				cut = i
+				break
+			}
+			n++
+		}
+		if space := strings.LastIndexAny(text[:cut], " \t\n"); space > 0 {
+			cut = space + 1
+		} else if amp := strings.LastIndexByte(text[:cut], '&'); amp > 0 && !strings.Contains(text[amp:cut], ";") {
+			cut = amp
+		}
+		runs = append(runs, text[:cut])
+		text = text[cut:]
+	}
+	return append(runs, text)
+}
+
+// voidElements have no end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// topLevelPieces splits sanitized HTML into its top-level elements and
+// text runs, keeping their markup as written.
+func topLevelPieces(html string) []string {
+	var (
+		pieces []string
+		piece  strings.Builder
+		depth  int
+	)
+	z := xhtml.NewTokenizer(strings.NewReader(html))
+	for {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			break
+		}
+		piece.Write(z.Raw())
+		switch tt {
+		case xhtml.StartTagToken:
+			if name, _ := z.TagName(); !voidElements[string(name)] {
+				depth++
+			}
+		case xhtml.EndTagToken:
+			if depth > 0 {
+				depth--
+			}
+		}
+		if depth == 0 {
+			pieces = append(pieces, piece.String())
+			piece.Reset()
+		}
+	}
+	if piece.Len() > 0 {
+		pieces = append(pieces, piece.String())
+	}
+	return pieces
+}
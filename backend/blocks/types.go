@@ -0,0 +1,353 @@
+package blocks
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/sanitize"
+	"github.com/sasanzare/go-cms/utils"
+	xhtml "golang.org/x/net/html"
+)
+
+var (
+	// inlinePolicy is the markup headings, quotes and captions may hold
+	inlinePolicy = sanitize.NewPolicy().
+			AllowElements("strong", "b", "em", "i", "u", "s", "code", "mark", "sub", "sup", "small", "br").
+			AllowAttrs("a", "href", "title")
+	// richPolicy is the markup paragraphs may hold. Besides inline markup
+	// it allows the block elements of content migrated from HTML.
+	richPolicy = sanitize.Post()
+)
+
+var languagePattern = regexp.MustCompile(`^[a-z0-9_+#-]{0,30}$`)
+
+// Text field limits
+const (
+	maxTextLength    = 20000
+	maxCaptionLength = 1000
+	maxCodeLength    = 50000
+	maxGalleryImages = 50
+)
+
+// blockElements start a block of their own; paragraphs holding them are
+// not wrapped in <p>.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "dl": true, "pre": true, "blockquote": true, "table": true,
+	"figure": true, "hr": true,
+}
+
+func cleanText(policy *sanitize.Policy, text string, max int, required bool) (string, error) {
+	text = strings.TrimSpace(policy.Sanitize(text))
+	if required && utils.SanitizeText(text) == "" {
+		return "", errors.New("text is required")
+	}
+	if utf8.RuneCountInString(text) > max {
+		return "", fmt.Errorf("text exceeds %d characters", max)
+	}
+	return text, nil
+}
+
+// hasBlockMarkup reports whether sanitized HTML contains block elements.
+func hasBlockMarkup(text string) bool {
+	z := xhtml.NewTokenizer(strings.NewReader(text))
+	for {
+		switch z.Next() {
+		case xhtml.ErrorToken:
+			return false
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			if name, _ := z.TagName(); blockElements[string(name)] {
+				return true
+			}
+		}
+	}
+}
+
+// checkURL accepts absolute http(s) URLs, and relative ones when allowed.
+func checkURL(raw string, relative bool) (string, error) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return "", errors.New("a valid url is required")
+	}
+	switch {
+	case u.Scheme == "https" || u.Scheme == "http":
+		if u.Host == "" {
+			return "", errors.New("a valid url is required")
+		}
+	case u.Scheme == "" && relative && strings.HasPrefix(raw, "/") && !strings.HasPrefix(raw, "//"):
+	default:
+		return "", errors.New("url must use http or https")
+	}
+	return raw, nil
+}
+
+func writeCaption(b *strings.Builder, caption string) {
+	if caption != "" {
+		b.WriteString("<figcaption>" + caption + "</figcaption>")
+	}
+}
+
+// Paragraph is a run of text with inline markup.
+type Paragraph struct {
+	Text string `json:"text"`
+}
+
+func (p *Paragraph) normalize() (err error) {
+	p.Text, err = cleanText(richPolicy, p.Text, maxTextLength, true)
+	return err
+}
+
+func (p *Paragraph) renderHTML(b *strings.Builder, _ map[uint]models.Media) {
+	if hasBlockMarkup(p.Text) {
+		b.WriteString(p.Text)
+		return
+	}
+	b.WriteString("<p>" + p.Text + "</p>")
+}
+
+func (p *Paragraph) renderText(b *strings.Builder) {
+	b.WriteString(utils.SanitizeText(p.Text))
+}
+
+// Heading is a section title of Level 1 to 6.
+type Heading struct {
+	Text  string `json:"text"`
+	Level int    `json:"level"`
+}
+
+func (h *Heading) normalize() (err error) {
+	if h.Level == 0 {
+		h.Level = 2
+	}
+	if h.Level < 1 || h.Level > 6 {
+		return errors.New("level must be between 1 and 6")
+	}
+	h.Text, err = cleanText(inlinePolicy, h.Text, maxCaptionLength, true)
+	return err
+}
+
+func (h *Heading) renderHTML(b *strings.Builder, _ map[uint]models.Media) {
+	tag := "h" + strconv.Itoa(h.Level)
+	b.WriteString("<" + tag + ">" + h.Text + "</" + tag + ">")
+}
+
+func (h *Heading) renderText(b *strings.Builder) {
+	b.WriteString(utils.SanitizeText(h.Text))
+}
+
+// Image shows a media item, or an external image by URL.
+type Image struct {
+	MediaID uint   `json:"media_id,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Alt     string `json:"alt,omitempty"`
+	Caption string `json:"caption,omitempty"`
+}
+
+func (i *Image) normalize() (err error) {
+	switch {
+	case i.MediaID != 0 && i.URL != "":
+		return errors.New("give either media_id or url, not both")
+	case i.MediaID == 0 && i.URL == "":
+		return errors.New("media_id or url is required")
+	case i.URL != "":
+		if i.URL, err = checkURL(i.URL, true); err != nil {
+			return err
+		}
+	}
+	i.Alt = strings.TrimSpace(utils.SanitizeText(i.Alt))
+	if utf8.RuneCountInString(i.Alt) > maxCaptionLength {
+		return fmt.Errorf("alt exceeds %d characters", maxCaptionLength)
+	}
+	i.Caption, err = cleanText(inlinePolicy, i.Caption, maxCaptionLength, false)
+	return err
+}
+
+func (i *Image) mediaIDs() []uint {
+	return []uint{i.MediaID}
+}
+
+// img renders the image tag, or nothing when its media item is missing.
+func (i *Image) img(media map[uint]models.Media) string {
+	src, alt := i.URL, i.Alt
+	var width, height int
+	if i.MediaID != 0 {
+		item, ok := media[i.MediaID]
+		if !ok {
+			return ""
+		}
+		src, width, height = item.URL, item.Width, item.Height
+		if alt == "" {
+			alt = item.Alt
+		}
+	}
+	tag := `<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(alt) + `"`
+	if width > 0 && height > 0 {
+		tag += fmt.Sprintf(` width="%d" height="%d"`, width, height)
+	}
+	return tag + ">"
+}
+
+func (i *Image) renderHTML(b *strings.Builder, media map[uint]models.Media) {
+	img := i.img(media)
+	if img == "" {
+		return
+	}
+	b.WriteString("<figure>" + img)
+	writeCaption(b, i.Caption)
+	b.WriteString("</figure>")
+}
+
+func (i *Image) renderText(b *strings.Builder) {
+	b.WriteString(utils.SanitizeText(i.Caption))
+}
+
+// Quote is a quotation with an optional attribution.
+type Quote struct {
+	Text     string `json:"text"`
+	Citation string `json:"citation,omitempty"`
+}
+
+func (q *Quote) normalize() (err error) {
+	if q.Text, err = cleanText(inlinePolicy, q.Text, maxTextLength, true); err != nil {
+		return err
+	}
+	q.Citation, err = cleanText(inlinePolicy, q.Citation, maxCaptionLength, false)
+	return err
+}
+
+func (q *Quote) renderHTML(b *strings.Builder, _ map[uint]models.Media) {
+	b.WriteString("<blockquote><p>" + q.Text + "</p>")
+	if q.Citation != "" {
+		b.WriteString("<cite>" + q.Citation + "</cite>")
+	}
+	b.WriteString("</blockquote>")
+}
+
+func (q *Quote) renderText(b *strings.Builder) {
+	b.WriteString(utils.SanitizeText(q.Text))
+	if q.Citation != "" {
+		b.WriteString("\n— " + utils.SanitizeText(q.Citation))
+	}
+}
+
+// Code is a code listing, kept verbatim.
+type Code struct {
+	Code     string `json:"code"`
+	Language string `json:"language,omitempty"`
+}
+
+func (c *Code) normalize() error {
+	if strings.TrimSpace(c.Code) == "" {
+		return errors.New("code is required")
+	}
+	if len(c.Code) > maxCodeLength {
+		return fmt.Errorf("code exceeds %d bytes", maxCodeLength)
+	}
+	c.Language = strings.ToLower(strings.TrimSpace(c.Language))
+	if !languagePattern.MatchString(c.Language) {
+		return errors.New("invalid language")
+	}
+	return nil
+}
+
+func (c *Code) renderHTML(b *strings.Builder, _ map[uint]models.Media) {
+	b.WriteString("<pre><code")
+	if c.Language != "" {
+		b.WriteString(` class="language-` + c.Language + `"`)
+	}
+	b.WriteString(">" + html.EscapeString(c.Code) + "</code></pre>")
+}
+
+func (c *Code) renderText(b *strings.Builder) {
+	b.WriteString(c.Code)
+}
+
+// Embed is third-party content, such as a video, that frontends embed by
+// URL. Rendered HTML links to it, since iframes are not allowed.
+type Embed struct {
+	URL     string `json:"url"`
+	Caption string `json:"caption,omitempty"`
+}
+
+func (e *Embed) normalize() (err error) {
+	if e.URL, err = checkURL(e.URL, false); err != nil {
+		return err
+	}
+	e.Caption, err = cleanText(inlinePolicy, e.Caption, maxCaptionLength, false)
+	return err
+}
+
+func (e *Embed) renderHTML(b *strings.Builder, _ map[uint]models.Media) {
+	link := html.EscapeString(e.URL)
+	b.WriteString(`<figure><a href="` + link + `">` + link + "</a>")
+	writeCaption(b, e.Caption)
+	b.WriteString("</figure>")
+}
+
+func (e *Embed) renderText(b *strings.Builder) {
+	b.WriteString(utils.SanitizeText(e.Caption))
+}
+
+// Gallery is a set of images shown together.
+type Gallery struct {
+	Images  []Image `json:"images"`
+	Caption string  `json:"caption,omitempty"`
+}
+
+func (g *Gallery) normalize() (err error) {
+	if len(g.Images) == 0 {
+		return errors.New("at least one image is required")
+	}
+	if len(g.Images) > maxGalleryImages {
+		return fmt.Errorf("at most %d images are allowed", maxGalleryImages)
+	}
+	for i := range g.Images {
+		if err := g.Images[i].normalize(); err != nil {
+			return fmt.Errorf("image %d: %w", i+1, err)
+		}
+	}
+	g.Caption, err = cleanText(inlinePolicy, g.Caption, maxCaptionLength, false)
+	return err
+}
+
+func (g *Gallery) mediaIDs() []uint {
+	ids := make([]uint, len(g.Images))
+	for i, img := range g.Images {
+		ids[i] = img.MediaID
+	}
+	return ids
+}
+
+func (g *Gallery) renderHTML(b *strings.Builder, media map[uint]models.Media) {
+	var items strings.Builder
+	for i := range g.Images {
+		g.Images[i].renderHTML(&items, media)
+	}
+	if items.Len() == 0 {
+		return
+	}
+	b.WriteString("<figure><div>" + items.String() + "</div>")
+	writeCaption(b, g.Caption)
+	b.WriteString("</figure>")
+}
+
+func (g *Gallery) renderText(b *strings.Builder) {
+	var parts []string
+	for i := range g.Images {
+		if caption := utils.SanitizeText(g.Images[i].Caption); caption != "" {
+			parts = append(parts, caption)
+		}
+	}
+	if caption := utils.SanitizeText(g.Caption); caption != "" {
+		parts = append(parts, caption)
+	}
+	b.WriteString(strings.Join(parts, "\n"))
+}
@@ -1,16 +1,286 @@
 package config
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand/v2"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/sasanzare/go-cms/logging"
+	"github.com/sasanzare/go-cms/tracing"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
+// LogConfig holds application logging settings.
+type LogConfig struct {
+	Level  string
+	Format string
+}
+
+func LoadLogConfig() *LogConfig {
+	return &LogConfig{
+		Level:  getEnv("LOG_LEVEL", "info"),
+		Format: getEnv("LOG_FORMAT", "json"),
+	}
+}
+
+// TracingConfig holds OpenTelemetry tracing settings.
+type TracingConfig struct {
+	ServiceName  string
+	Exporter     string
+	OTLPEndpoint string
+	FilePath     string
+	SampleRatio  float64
+}
+
+func LoadTracingConfig() *TracingConfig {
+	return &TracingConfig{
+		ServiceName:  getEnv("OTEL_SERVICE_NAME", "go-cms"),
+		Exporter:     getEnv("OTEL_TRACES_EXPORTER", ""),
+		OTLPEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		FilePath:     getEnv("OTEL_TRACES_FILE", "traces.json"),
+		SampleRatio:  getEnvFloat("OTEL_TRACES_SAMPLE_RATIO", 1.0),
+	}
+}
+
+// SearchConfig selects the search backend: "postgres" uses the database's
+// full-text search, "memory" an embedded index rebuilt at startup.
+type SearchConfig struct {
+	Backend string
+}
+
+func LoadSearchConfig() *SearchConfig {
+	return &SearchConfig{
+		Backend: getEnv("SEARCH_BACKEND", "postgres"),
+	}
+}
+
+// SiteConfig describes the public site; emails use it for branding and
+// links.
+type SiteConfig struct {
+	Name          string
+	URL           string
+	LogoURL       string
+	SupportEmail  string
+	DefaultLocale string
+	// Locales lists the locales content may be written in besides
+	// DefaultLocale
+	Locales []string
+	// LocaleFallbacks maps a locale to those tried before DefaultLocale when
+	// content is missing in it
+	LocaleFallbacks map[string][]string
+	// TimeZone is the IANA zone dates are shown and archived in
+	TimeZone string
+}
+
+func LoadSiteConfig() *SiteConfig {
+	locales := getEnvList("SITE_LOCALES")
+	if _, set := os.LookupEnv("SITE_LOCALES"); !set {
+		locales = []string{"en", "fa"}
+	}
+	return &SiteConfig{
+		Name:            getEnv("SITE_NAME", "Go CMS"),
+		URL:             getEnv("SITE_URL", "http://localhost:8000"),
+		LogoURL:         getEnv("SITE_LOGO_URL", ""),
+		SupportEmail:    getEnv("SITE_SUPPORT_EMAIL", ""),
+		DefaultLocale:   getEnv("SITE_DEFAULT_LOCALE", "en"),
+		Locales:         locales,
+		LocaleFallbacks: getEnvFallbacks("SITE_LOCALE_FALLBACKS"),
+		TimeZone:        getEnv("SITE_TIMEZONE", getEnv("DB_TIMEZONE", "Asia/Tehran")),
+	}
+}
+
+// getEnvFallbacks parses fallback chains written as "ps=fa,en;ku=fa".
+func getEnvFallbacks(key string) map[string][]string {
+	fallbacks := map[string][]string{}
+	for _, entry := range strings.Split(os.Getenv(key), ";") {
+		locale, chain, ok := strings.Cut(entry, "=")
+		if locale = strings.TrimSpace(locale); !ok || locale == "" {
+			continue
+		}
+		for _, fallback := range strings.Split(chain, ",") {
+			if fallback = strings.TrimSpace(fallback); fallback != "" {
+				fallbacks[locale] = append(fallbacks[locale], fallback)
+			}
+		}
+	}
+	return fallbacks
+}
+
+// MailConfig selects and configures the email transport: "smtp" or "file",
+// which writes .eml files to FileDir for development.
+type MailConfig struct {
+	Transport    string
+	From         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUser     string
+	SMTPPass     string
+	SMTPSecurity string
+	SMTPTimeout  time.Duration
+	FileDir      string
+}
+
+func LoadMailConfig() *MailConfig {
+	return &MailConfig{
+		Transport:    getEnv("MAIL_TRANSPORT", "smtp"),
+		From:         getEnv("EMAIL_SENDER", ""),
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUser:     getEnv("SMTP_USER", ""),
+		SMTPPass:     getEnv("SMTP_PASS", ""),
+		SMTPSecurity: getEnv("SMTP_SECURITY", "starttls"),
+		SMTPTimeout:  getEnvDuration("SMTP_TIMEOUT", 30*time.Second),
+		FileDir:      getEnv("MAIL_FILE_DIR", "mail-out"),
+	}
+}
+
+// PostConfig holds content settings for posts.
+type PostConfig struct {
+	// RevisionLimit is the number of revisions kept per post; older ones
+	// are pruned on save. Zero keeps every revision.
+	RevisionLimit int
+}
+
+func LoadPostConfig() *PostConfig {
+	return &PostConfig{
+		RevisionLimit: getEnvInt("POST_REVISION_LIMIT", 50),
+	}
+}
+
+// FeedConfig controls the RSS, Atom and JSON feeds of published posts.
+type FeedConfig struct {
+	// Items is the number of posts per feed
+	Items int
+	// Content is "full" to include post content in feeds or "excerpt" to
+	// only summarize posts; readers may ask for the other with ?content=
+	Content string
+}
+
+func LoadFeedConfig() *FeedConfig {
+	return &FeedConfig{
+		Items:   getEnvInt("FEED_ITEMS", 20),
+		Content: getEnv("FEED_CONTENT", "full"),
+	}
+}
+
+// NotificationConfig controls notification delivery.
+type NotificationConfig struct {
+	// DigestHour is the local hour (0-23) daily email digests are sent at;
+	// negative disables digests
+	DigestHour int
+}
+
+func LoadNotificationConfig() (*NotificationConfig, error) {
+	hour := getEnvInt("NOTIFICATION_DIGEST_HOUR", 8)
+	if hour > 23 {
+		return nil, fmt.Errorf("NOTIFICATION_DIGEST_HOUR must be between 0 and 23, got %d", hour)
+	}
+	return &NotificationConfig{DigestHour: hour}, nil
+}
+
+// SpamConfig tunes spam screening of comments and registrations.
+type SpamConfig struct {
+	// Threshold is the total rule score at which a submission is spam
+	Threshold float64
+	// MaxLinks is how many links a submission may contain unpenalized
+	MaxLinks int
+	// Blocklist holds words and phrases typical of spam
+	Blocklist []string
+	// MinSubmitTime is the least time a person needs to fill in a form
+	MinSubmitTime time.Duration
+	// FormSecret signs form tokens; defaults to JWT_SECRET
+	FormSecret string
+}
+
+// LoadSpamConfig fails when neither SPAM_FORM_SECRET nor JWT_SECRET is
+// set, since form tokens signed with an empty key could be forged.
+func LoadSpamConfig() (*SpamConfig, error) {
+	secret := os.Getenv("SPAM_FORM_SECRET")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("SPAM_FORM_SECRET or JWT_SECRET must be set")
+	}
+	return &SpamConfig{
+		Threshold:     getEnvFloat("SPAM_THRESHOLD", 1.0),
+		MaxLinks:      getEnvInt("SPAM_MAX_LINKS", 2),
+		Blocklist:     getEnvList("SPAM_BLOCKLIST"),
+		MinSubmitTime: getEnvDuration("SPAM_MIN_SUBMIT_TIME", 3*time.Second),
+		FormSecret:    secret,
+	}, nil
+}
+
+// SchedulerConfig controls the background post scheduler.
+type SchedulerConfig struct {
+	// Interval between checks for due posts; zero disables the scheduler
+	Interval time.Duration
+}
+
+func LoadSchedulerConfig() *SchedulerConfig {
+	return &SchedulerConfig{
+		Interval: getEnvDuration("SCHEDULER_INTERVAL", 30*time.Second),
+	}
+}
+
+// JobsConfig configures the background job queue.
+type JobsConfig struct {
+	// Concurrency is the number of workers per queue, from a list like
+	// "default:2,email:4,search:1,webhooks:2"
+	Concurrency  map[string]int
+	PollInterval time.Duration
+	RescueAfter  time.Duration
+	MaxAttempts  int
+}
+
+func LoadJobsConfig() (*JobsConfig, error) {
+	concurrency := map[string]int{}
+	for _, entry := range getEnvList("JOBS_CONCURRENCY") {
+		name, raw, ok := strings.Cut(entry, ":")
+		n, err := strconv.Atoi(raw)
+		if !ok || err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid JOBS_CONCURRENCY entry %q, want queue:workers", entry)
+		}
+		concurrency[strings.TrimSpace(name)] = n
+	}
+	if len(concurrency) == 0 {
+		concurrency = map[string]int{"default": 2, "email": 4, "search": 1, "webhooks": 2}
+	}
+
+	return &JobsConfig{
+		Concurrency:  concurrency,
+		PollInterval: getEnvDuration("JOBS_POLL_INTERVAL", time.Second),
+		RescueAfter:  getEnvDuration("JOBS_RESCUE_AFTER", 15*time.Minute),
+		MaxAttempts:  getEnvInt("JOBS_MAX_ATTEMPTS", 5),
+	}, nil
+}
+
+// WebhookConfig lists the endpoints domain events are posted to.
+type WebhookConfig struct {
+	// URLs receive every event, from a comma-separated list
+	URLs []string
+	// Secret signs request bodies in X-Webhook-Signature; empty sends
+	// them unsigned
+	Secret  string
+	Timeout time.Duration
+}
+
+func LoadWebhookConfig() *WebhookConfig {
+	return &WebhookConfig{
+		URLs:    getEnvList("WEBHOOK_URLS"),
+		Secret:  os.Getenv("WEBHOOK_SECRET"),
+		Timeout: getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+	}
+}
+
 type DBConfig struct {
 	Host     string
 	Port     string
@@ -19,49 +289,114 @@ type DBConfig struct {
 	Name     string
 	SSLMode  string
 	TimeZone string
+
+	// Connection retry settings. The delay between attempts doubles from
+	// RetryBaseDelay up to RetryMaxDelay, with random jitter applied.
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+
+	// GORM logging settings. LogLevel is one of silent, error, warn or info.
+	LogLevel      string
+	SlowThreshold time.Duration
+
+	// ReplicaDSNs are optional read replicas. Read queries are routed to
+	// them while writes and transactions stay on the primary.
+	ReplicaDSNs []string
 }
 
 var DB *gorm.DB
 
 func LoadDBConfig() *DBConfig {
 	return &DBConfig{
-		Host:     getEnv("DB_HOST", "localhost"),
-		Port:     getEnv("DB_PORT", "5432"),
-		User:     getEnv("DB_USER", "admin"),
-		Password: getEnv("DB_PASSWORD", "admin"),
-		Name:     getEnv("DB_NAME", "cms_db"),
-		SSLMode:  getEnv("DB_SSLMODE", "disable"),
-		TimeZone: getEnv("DB_TIMEZONE", "Asia/Tehran"),
+		Host:           getEnv("DB_HOST", "localhost"),
+		Port:           getEnv("DB_PORT", "5432"),
+		User:           getEnv("DB_USER", "admin"),
+		Password:       getEnv("DB_PASSWORD", "admin"),
+		Name:           getEnv("DB_NAME", "cms_db"),
+		SSLMode:        getEnv("DB_SSLMODE", "disable"),
+		TimeZone:       getEnv("DB_TIMEZONE", "Asia/Tehran"),
+		MaxRetries:     getEnvInt("DB_MAX_RETRIES", 5),
+		RetryBaseDelay: getEnvDuration("DB_RETRY_BASE_DELAY", 500*time.Millisecond),
+		RetryMaxDelay:  getEnvDuration("DB_RETRY_MAX_DELAY", 30*time.Second),
+		LogLevel:       getEnv("DB_LOG_LEVEL", "warn"),
+		SlowThreshold:  getEnvDuration("DB_SLOW_THRESHOLD", 200*time.Millisecond),
+		ReplicaDSNs:    getEnvList("DB_REPLICA_DSN"),
 	}
 }
 
-func ConnectDB(config *DBConfig) (*gorm.DB, error) {
-	dsn := fmt.Sprintf(
+// DSN builds the PostgreSQL connection string for the primary database.
+func (c *DBConfig) DSN() string {
+	return fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=%s",
-		config.Host, config.User, config.Password, config.Name, config.Port, config.SSLMode, config.TimeZone,
+		c.Host, c.User, c.Password, c.Name, c.Port, c.SSLMode, c.TimeZone,
 	)
+}
+
+// ConnectDB opens the primary connection, retrying with exponential backoff
+// until it succeeds, the retries are exhausted or ctx is cancelled.
+func ConnectDB(ctx context.Context, config *DBConfig) (*gorm.DB, error) {
+	gormConfig := &gorm.Config{
+		Logger: logging.NewGormLogger(parseLogLevel(config.LogLevel), config.SlowThreshold),
+		// Pinging is done below with ctx so that cancellation interrupts it
+		DisableAutomaticPing: true,
+	}
+
+	maxRetries := config.MaxRetries
+	if maxRetries < 1 {
+		maxRetries = 1
+	}
 
 	var db *gorm.DB
 	var err error
 
-	maxRetries := 5
-	for i := 0; i < maxRetries; i++ {
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Info),
-		})
-
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		db, err = openAndPing(ctx, config.DSN(), gormConfig)
 		if err == nil {
 			break
 		}
+		if ctx.Err() != nil || attempt == maxRetries-1 {
+			break
+		}
 
-		log.Printf("Attempt %d: Failed to connect to database. Retrying in 5 seconds...", i+1)
-		time.Sleep(5 * time.Second)
+		delay := retryDelay(attempt, config.RetryBaseDelay, config.RetryMaxDelay)
+		slog.WarnContext(ctx, "failed to connect to database, retrying",
+			slog.Int("attempt", attempt+1),
+			slog.String("error", err.Error()),
+			slog.Duration("retry_in", delay.Round(time.Millisecond)),
+		)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
 	}
 
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, fmt.Errorf("database connection cancelled: %w", ctxErr)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database after %d attempts: %w", maxRetries, err)
 	}
 
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		return nil, fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+
+	if len(config.ReplicaDSNs) > 0 {
+		replicas := make([]gorm.Dialector, 0, len(config.ReplicaDSNs))
+		for _, dsn := range config.ReplicaDSNs {
+			replicas = append(replicas, postgres.Open(dsn))
+		}
+		if err := db.Use(dbresolver.Register(dbresolver.Config{
+			Replicas: replicas,
+			Policy:   dbresolver.RandomPolicy{},
+		})); err != nil {
+			return nil, fmt.Errorf("failed to register read replicas: %w", err)
+		}
+		slog.InfoContext(ctx, "registered read replicas", slog.Int("count", len(replicas)))
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sql.DB: %w", err)
@@ -72,13 +407,119 @@ func ConnectDB(config *DBConfig) (*gorm.DB, error) {
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
 	DB = db
-	log.Println("Database connection established")
+	slog.InfoContext(ctx, "database connection established")
 	return db, nil
 }
 
+// openAndPing opens a connection and verifies it is usable. The underlying
+// pool is closed again if the ping fails so retries don't leak connections.
+func openAndPing(ctx context.Context, dsn string, gormConfig *gorm.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(dsn), gormConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := sqlDB.PingContext(pingCtx); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// retryDelay returns the backoff before the next attempt: base * 2^attempt
+// capped at max, with "equal jitter" so the wait lies in [d/2, d).
+func retryDelay(attempt int, base, max time.Duration) time.Duration {
+	if base <= 0 {
+		base = 500 * time.Millisecond
+	}
+	if max < base {
+		max = base
+	}
+
+	delay := max
+	if attempt < 32 {
+		if d := base << attempt; d > 0 && d < max {
+			delay = d
+		}
+	}
+
+	half := delay / 2
+	return half + rand.N(delay-half)
+}
+
+func parseLogLevel(level string) logger.LogLevel {
+	switch strings.ToLower(level) {
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "info":
+		return logger.Info
+	default:
+		return logger.Warn
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("invalid integer in environment, using default", slog.String("key", key), slog.String("value", value), slog.Int("default", defaultValue))
+		return defaultValue
+	}
+	return n
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("invalid number in environment, using default", slog.String("key", key), slog.String("value", value), slog.Float64("default", defaultValue))
+		return defaultValue
+	}
+	return f
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Warn("invalid duration in environment, using default", slog.String("key", key), slog.String("value", value), slog.Duration("default", defaultValue))
+		return defaultValue
+	}
+	return d
+}
+
+func getEnvList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,139 @@
+package content
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Filter operators
+const (
+	OpEq  = "eq"
+	OpNe  = "ne"
+	OpGt  = "gt"
+	OpGte = "gte"
+	OpLt  = "lt"
+	OpLte = "lte"
+	OpIn  = "in"
+)
+
+// maxInValues caps the values of one "in" filter.
+const maxInValues = 100
+
+var rangeOps = map[string]string{OpGt: ">", OpGte: ">=", OpLt: "<", OpLte: "<="}
+
+// Filter restricts entries by one field's value.
+type Filter struct {
+	Field  Field
+	Op     string
+	Values []interface{}
+}
+
+// ParseFilter parses a filter on the field called name. raw is a value,
+// for equality, or "op:value" with op one of the Op constants; "in" takes
+// a comma-separated list. Ranges apply to number and date fields only.
+func (s Schema) ParseFilter(name, raw string) (Filter, error) {
+	f, ok := s.Field(name)
+	if !ok {
+		return Filter{}, fmt.Errorf("unknown field %q", name)
+	}
+	filter := Filter{Field: f, Op: OpEq}
+	if op, value, found := strings.Cut(raw, ":"); found && (rangeOps[op] != "" || op == OpEq || op == OpNe || op == OpIn) {
+		filter.Op, raw = op, value
+	}
+	if _, isRange := rangeOps[filter.Op]; isRange && f.Type != FieldNumber && f.Type != FieldDate {
+		return Filter{}, fmt.Errorf("%s: %s only applies to number and date fields", name, filter.Op)
+	}
+
+	values := []string{raw}
+	if filter.Op == OpIn {
+		values = strings.Split(raw, ",")
+		if len(values) > maxInValues {
+			return Filter{}, fmt.Errorf("%s: at most %d values are allowed", name, maxInValues)
+		}
+	}
+	for _, v := range values {
+		value, err := f.filterValue(strings.TrimSpace(v))
+		if err != nil {
+			return Filter{}, fmt.Errorf("%s: %w", name, err)
+		}
+		filter.Values = append(filter.Values, value)
+	}
+	return filter, nil
+}
+
+// filterValue parses a filter value the way Validate normalizes stored
+// ones, so the two compare equal.
+func (f Field) filterValue(raw string) (interface{}, error) {
+	switch f.Type {
+	case FieldNumber:
+		return ParseNumber(raw)
+	case FieldDate:
+		return ParseDate(raw)
+	case FieldReference, FieldMedia:
+		return ParseID(raw)
+	case FieldEnum:
+		return f.option(raw)
+	}
+	if raw == "" {
+		return nil, errors.New("a value is required")
+	}
+	return raw, nil
+}
+
+// SQL returns the filter as a condition on column, the JSONB column
+// holding entry data. Equality uses containment so a GIN index on the
+// column applies.
+func (f Filter) SQL(column string) (string, []interface{}) {
+	contains := func(value interface{}) (string, interface{}) {
+		doc, _ := json.Marshal(map[string]interface{}{f.Field.Name: value})
+		return column + " @> ?::jsonb", string(doc)
+	}
+
+	switch f.Op {
+	case OpEq, OpNe:
+		cond, arg := contains(f.Values[0])
+		if f.Op == OpNe {
+			cond = "NOT (" + cond + ")"
+		}
+		return cond, []interface{}{arg}
+	case OpIn:
+		conds := make([]string, len(f.Values))
+		args := make([]interface{}, len(f.Values))
+		for i, value := range f.Values {
+			conds[i], args[i] = contains(value)
+		}
+		return "(" + strings.Join(conds, " OR ") + ")", args
+	}
+	return f.Field.expr(column) + " " + rangeOps[f.Op] + " ?", f.Values
+}
+
+// expr is the SQL expression reading the field from column, typed so it
+// orders correctly. Field names are validated, so quoting them is safe.
+func (f Field) expr(column string) string {
+	value := column + "->>'" + f.Name + "'"
+	switch f.Type {
+	case FieldNumber:
+		return "(" + value + ")::numeric"
+	case FieldReference, FieldMedia:
+		return "(" + value + ")::bigint"
+	}
+	return "(" + value + ")"
+}
+
+// Order returns the ORDER BY expression for sort, a field name optionally
+// prefixed with "-" for descending order, reading fields from column.
+// Entries without a value come last either way.
+func (s Schema) Order(column, sort string) (string, error) {
+	name, desc := strings.CutPrefix(sort, "-")
+	f, ok := s.Field(name)
+	if !ok {
+		return "", fmt.Errorf("unknown sort field %q", name)
+	}
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	return f.expr(column) + " " + direction + " NULLS LAST", nil
+}
@@ -0,0 +1,203 @@
+// Package content implements content types defined at runtime: a schema
+// is a list of typed fields, and entries of the type hold their values as
+// a JSON object validated against it.
+//
+// Values are normalized on validation so they compare and sort correctly
+// inside the database: text is stripped of markup, numbers are JSON
+// numbers, dates are RFC 3339 timestamps in UTC, and references and media
+// are IDs.
+package content
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Field types
+const (
+	FieldText      = "text"
+	FieldNumber    = "number"
+	FieldDate      = "date"
+	FieldReference = "reference"
+	FieldMedia     = "media"
+	FieldEnum      = "enum"
+)
+
+// Schema limits
+const (
+	MaxFields        = 100
+	maxEnumOptions   = 200
+	defaultMaxLength = 10000
+)
+
+var (
+	fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+	slugPattern      = regexp.MustCompile(`^[a-z][a-z0-9-]{0,62}$`)
+)
+
+var fieldTypes = map[string]bool{
+	FieldText: true, FieldNumber: true, FieldDate: true,
+	FieldReference: true, FieldMedia: true, FieldEnum: true,
+}
+
+// Field describes one value entries of a type hold.
+type Field struct {
+	// Name is the value's key in entry data
+	Name  string `json:"name"`
+	Label string `json:"label,omitempty"`
+	Type  string `json:"type"`
+	// Required fields must be present and not empty
+	Required bool `json:"required,omitempty"`
+	// Options lists the values an enum field accepts
+	Options []string `json:"options,omitempty"`
+	// Target is the slug of the content type a reference points to
+	Target string `json:"target,omitempty"`
+	// MaxLength caps text fields, in characters
+	MaxLength int `json:"max_length,omitempty"`
+	// Searchable text fields feed full-text search. When no field is
+	// marked, every text field does.
+	Searchable bool `json:"searchable,omitempty"`
+}
+
+// Schema is the ordered list of a content type's fields.
+type Schema []Field
+
+// IsValidSlug reports whether slug can name a content type.
+func IsValidSlug(slug string) bool {
+	return slugPattern.MatchString(slug)
+}
+
+// ParseSchema decodes and validates a schema. Errors name the offending
+// field.
+func ParseSchema(raw []byte) (Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field == "" {
+			return nil, errors.New("fields must be a list")
+		}
+		return nil, err
+	}
+	if len(schema) == 0 {
+		return nil, errors.New("at least one field is required")
+	}
+	if len(schema) > MaxFields {
+		return nil, fmt.Errorf("at most %d fields are allowed", MaxFields)
+	}
+
+	names := map[string]bool{}
+	for i := range schema {
+		f := &schema[i]
+		if !fieldNamePattern.MatchString(f.Name) {
+			return nil, fmt.Errorf("field %d: name must be lowercase letters, digits and underscores, starting with a letter", i+1)
+		}
+		if names[f.Name] {
+			return nil, fmt.Errorf("field %q: duplicate name", f.Name)
+		}
+		names[f.Name] = true
+		if err := f.normalize(); err != nil {
+			return nil, fmt.Errorf("field %q: %w", f.Name, err)
+		}
+	}
+	return schema, nil
+}
+
+func (f *Field) normalize() error {
+	if !fieldTypes[f.Type] {
+		return fmt.Errorf("unknown field type %q", f.Type)
+	}
+	f.Label = strings.TrimSpace(f.Label)
+	if f.Label == "" {
+		f.Label = f.Name
+	}
+	if f.MaxLength < 0 || f.MaxLength > defaultMaxLength {
+		return fmt.Errorf("max_length must be between 0 and %d", defaultMaxLength)
+	}
+	if f.Type != FieldText && (f.MaxLength != 0 || f.Searchable) {
+		return errors.New("max_length and searchable only apply to text fields")
+	}
+
+	if f.Type == FieldEnum {
+		if len(f.Options) == 0 || len(f.Options) > maxEnumOptions {
+			return fmt.Errorf("enum fields need between 1 and %d options", maxEnumOptions)
+		}
+		seen := map[string]bool{}
+		for _, option := range f.Options {
+			if option == "" || seen[option] {
+				return errors.New("options must be unique and not empty")
+			}
+			seen[option] = true
+		}
+	} else if len(f.Options) > 0 {
+		return errors.New("options only apply to enum fields")
+	}
+
+	if f.Type == FieldReference {
+		if !IsValidSlug(f.Target) {
+			return errors.New("reference fields need the target content type's slug")
+		}
+	} else if f.Target != "" {
+		return errors.New("target only applies to reference fields")
+	}
+	return nil
+}
+
+// Field returns the field called name.
+func (s Schema) Field(name string) (Field, bool) {
+	for _, f := range s {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// Targets lists the content types the schema's reference fields point to.
+func (s Schema) Targets() []string {
+	var targets []string
+	for _, f := range s {
+		if f.Type == FieldReference {
+			targets = append(targets, f.Target)
+		}
+	}
+	return targets
+}
+
+// CheckChange reports why s can't replace old for a type that already has
+// entries: existing fields keep their type and reference target, and new
+// fields can't be required.
+func (s Schema) CheckChange(old Schema) error {
+	for _, f := range s {
+		prev, ok := old.Field(f.Name)
+		switch {
+		case !ok && f.Required:
+			return fmt.Errorf("field %q: new fields can't be required while entries exist", f.Name)
+		case ok && prev.Type != f.Type:
+			return fmt.Errorf("field %q: type can't change while entries exist", f.Name)
+		case ok && prev.Target != f.Target:
+			return fmt.Errorf("field %q: target can't change while entries exist", f.Name)
+		}
+	}
+	return nil
+}
+
+// TitleField returns the text field titling entries: name when set, or
+// the first text field.
+func (s Schema) TitleField(name string) (string, error) {
+	if name != "" {
+		f, ok := s.Field(name)
+		if !ok || f.Type != FieldText {
+			return "", fmt.Errorf("title field %q must be one of the text fields", name)
+		}
+		return name, nil
+	}
+	for _, f := range s {
+		if f.Type == FieldText {
+			return f.Name, nil
+		}
+	}
+	return "", nil
+}
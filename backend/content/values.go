@@ -0,0 +1,227 @@
+package content
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// Values are an entry's data keyed by field name. Fields without a value
+// are absent.
+type Values map[string]interface{}
+
+// Validate decodes entry data and checks it against the schema, returning
+// the normalized values. Keys that are not fields are rejected; null and
+// empty strings count as no value.
+func (s Schema) Validate(raw []byte) (Values, error) {
+	var input map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &input); err != nil || input == nil {
+		return nil, errors.New("data must be an object")
+	}
+	for name := range input {
+		if _, ok := s.Field(name); !ok {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+	}
+
+	values := Values{}
+	for _, f := range s {
+		value, err := f.value(input[f.Name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if value == nil {
+			if f.Required {
+				return nil, fmt.Errorf("%s: a value is required", f.Name)
+			}
+			continue
+		}
+		values[f.Name] = value
+	}
+	return values, nil
+}
+
+// Prune drops the keys of stored entry data that are no longer fields of
+// s, such as those of removed fields, reporting whether it dropped any.
+// The remaining values are kept as stored.
+func (s Schema) Prune(raw []byte) ([]byte, bool, error) {
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, false, err
+	}
+	pruned := false
+	for name := range data {
+		if _, ok := s.Field(name); !ok {
+			delete(data, name)
+			pruned = true
+		}
+	}
+	if !pruned {
+		return raw, false, nil
+	}
+	raw, err := json.Marshal(data)
+	return raw, true, err
+}
+
+// value decodes and normalizes one field's raw JSON value; nil means none.
+func (f Field) value(raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	switch f.Type {
+	case FieldText:
+		text, ok := v.(string)
+		if !ok {
+			return nil, errors.New("must be a string")
+		}
+		return f.text(text)
+	case FieldNumber:
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, errors.New("must be a number")
+		}
+		return ParseNumber(n.String())
+	case FieldDate:
+		text, ok := v.(string)
+		if !ok {
+			return nil, errors.New("must be a date string")
+		}
+		if text == "" {
+			return nil, nil
+		}
+		return ParseDate(text)
+	case FieldReference, FieldMedia:
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, errors.New("must be an ID")
+		}
+		return ParseID(n.String())
+	case FieldEnum:
+		text, ok := v.(string)
+		if !ok {
+			return nil, errors.New("must be a string")
+		}
+		if text == "" {
+			return nil, nil
+		}
+		return f.option(text)
+	}
+	return nil, fmt.Errorf("unknown field type %q", f.Type)
+}
+
+func (f Field) text(text string) (interface{}, error) {
+	text = strings.TrimSpace(utils.SanitizeText(text))
+	if text == "" {
+		return nil, nil
+	}
+	max := f.MaxLength
+	if max == 0 {
+		max = defaultMaxLength
+	}
+	if utf8.RuneCountInString(text) > max {
+		return nil, fmt.Errorf("exceeds %d characters", max)
+	}
+	return text, nil
+}
+
+func (f Field) option(text string) (string, error) {
+	for _, option := range f.Options {
+		if text == option {
+			return text, nil
+		}
+	}
+	return "", fmt.Errorf("must be one of %s", strings.Join(f.Options, ", "))
+}
+
+// ParseNumber parses a finite number value.
+func ParseNumber(raw string) (float64, error) {
+	n, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+		return 0, errors.New("must be a number")
+	}
+	return n, nil
+}
+
+// ParseDate parses a date value given as YYYY-MM-DD or an RFC 3339
+// timestamp, returning it as an RFC 3339 timestamp in UTC. Stored dates
+// share this format so they compare correctly as strings.
+func ParseDate(raw string) (string, error) {
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		if t, err = time.Parse("2006-01-02", raw); err != nil {
+			return "", errors.New("must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+		}
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+// ParseID parses a reference or media value.
+func ParseID(raw string) (uint, error) {
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || id == 0 {
+		return 0, errors.New("must be a positive integer ID")
+	}
+	return uint(id), nil
+}
+
+// References lists the entry IDs values refer to by target content type.
+func (v Values) References(s Schema) map[string][]uint {
+	refs := map[string][]uint{}
+	for _, f := range s {
+		if id, ok := v[f.Name].(uint); ok && f.Type == FieldReference {
+			refs[f.Target] = append(refs[f.Target], id)
+		}
+	}
+	return refs
+}
+
+// MediaIDs lists the media items values refer to, in ascending order.
+func (v Values) MediaIDs(s Schema) []uint {
+	var ids []uint
+	for _, f := range s {
+		if id, ok := v[f.Name].(uint); ok && f.Type == FieldMedia {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Text returns the value of the text field name, or "".
+func (v Values) Text(name string) string {
+	text, _ := v[name].(string)
+	return text
+}
+
+// SearchText joins the values of the schema's searchable text fields.
+func (v Values) SearchText(s Schema) string {
+	marked := false
+	for _, f := range s {
+		marked = marked || f.Searchable
+	}
+	var parts []string
+	for _, f := range s {
+		if f.Type == FieldText && (f.Searchable || !marked) {
+			if text := v.Text(f.Name); text != "" {
+				parts = append(parts, text)
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
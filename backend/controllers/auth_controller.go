@@ -1 +1,86 @@
-package controllers
\ No newline at end of file
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/spam"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+type AuthController struct {
+	service *services.AuthService
+}
+
+func NewAuthController(service *services.AuthService) *AuthController {
+	return &AuthController{service: service}
+}
+
+// RegisterRequest is a public signup.
+//
+// Website is a honeypot: forms hide it from people, so it must stay empty.
+// FormToken comes from GET /api/spam/form-token when the form is shown.
+type RegisterRequest struct {
+	Username  string `json:"username" binding:"required,alphanum,min=3,max=50"`
+	Email     string `json:"email" binding:"required,max=255"`
+	Password  string `json:"password" binding:"required"`
+	FirstName string `json:"first_name" binding:"required,min=3,max=100"`
+	LastName  string `json:"last_name" binding:"required,min=3,max=100"`
+	Locale    string `json:"locale" binding:"omitempty,max=10"`
+	Website   string `json:"website"`
+	FormToken string `json:"form_token"`
+}
+
+// Register handles POST /api/auth/register. New accounts get the user
+// role and a verification email; signups are screened for spam.
+func (ac *AuthController) Register(c *gin.Context) {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+
+	user, err := ac.service.RegisterUser(c.Request.Context(), &models.User{
+		Username:  req.Username,
+		Email:     req.Email,
+		Password:  req.Password,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Locale:    req.Locale,
+	}, models.UserRoleUser, &spam.Signals{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Honeypot:  req.Website,
+		FormToken: req.FormToken,
+	})
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.JSONResponse{
+		Success: true,
+		Message: "Account created; check your email to verify your address",
+		Data:    user,
+	})
+}
+
+// VerifyEmail handles GET /api/auth/verify-email?token=..., the link sent
+// in verification emails.
+func (ac *AuthController) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		utils.SendValidationError(c, gin.H{"token": "is required"})
+		return
+	}
+
+	user, err := ac.service.VerifyEmail(c.Request.Context(), token)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "Email address verified", user)
+}
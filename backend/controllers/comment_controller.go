@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/spam"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+type CommentController struct {
+	service *services.CommentService
+}
+
+func NewCommentController(service *services.CommentService) *CommentController {
+	return &CommentController{service: service}
+}
+
+// ListComments handles GET /api/posts/:id/comments, returning approved
+// threads with nested replies.
+//
+// Query parameters: page and page_size, counting top-level comments.
+func (cc *CommentController) ListComments(c *gin.Context) {
+	postID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	page, pageSize, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	comments, pagination, err := cc.service.ListComments(c.Request.Context(), postID, page, pageSize)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendPaginated(c, "", comments, pagination)
+}
+
+// CreateCommentRequest is a new comment or reply. Guests must give a name
+// and email.
+//
+// Website is a honeypot: forms hide it from people, so it must stay empty.
+// FormToken comes from GET /api/spam/form-token when the form is shown.
+type CreateCommentRequest struct {
+	Body      string `json:"body" binding:"required"`
+	ParentID  *uint  `json:"parent_id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Website   string `json:"website"`
+	FormToken string `json:"form_token"`
+}
+
+// CreateComment handles POST /api/posts/:id/comments for users and guests.
+// Comments awaiting moderation are answered with 202 Accepted.
+func (cc *CommentController) CreateComment(c *gin.Context) {
+	postID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+
+	comment, err := cc.service.CreateComment(c.Request.Context(), services.CommentInput{
+		PostID:     postID,
+		ParentID:   req.ParentID,
+		Body:       req.Body,
+		GuestName:  req.Name,
+		GuestEmail: req.Email,
+		Signals: spam.Signals{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Honeypot:  req.Website,
+			FormToken: req.FormToken,
+		},
+	})
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	if comment.Status != models.CommentStatusApproved {
+		c.JSON(http.StatusAccepted, utils.JSONResponse{
+			Success: true,
+			Message: "Comment submitted for moderation",
+			Data:    comment,
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, utils.JSONResponse{
+		Success: true,
+		Message: "Comment posted",
+		Data:    comment,
+	})
+}
+
+// DeleteComment handles DELETE /api/comments/:id, moving the comment to
+// the trash. Users may trash their own comments; staff any comment.
+func (cc *CommentController) DeleteComment(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	comment, err := cc.service.GetComment(c.Request.Context(), id)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+	userID, _, _ := middleware.CurrentUser(c)
+	if !middleware.IsStaff(c) && (comment.UserID == nil || *comment.UserID != userID) {
+		utils.SendError(c, http.StatusForbidden, "You can only delete your own comments")
+		return
+	}
+
+	if _, err := cc.service.ModerateComment(c.Request.Context(), id, models.CommentStatusTrashed); err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccessMessage(c, "Comment deleted")
+}
+
+// ModerationQueue handles GET /api/comments/moderation for staff.
+//
+// Query parameters: status (pending by default, approved, spam or
+// trashed), post_id, page and page_size.
+func (cc *CommentController) ModerationQueue(c *gin.Context) {
+	postID, err := queryUint(c, "post_id")
+	if err != nil {
+		utils.SendValidationError(c, gin.H{"post_id": err.Error()})
+		return
+	}
+	page, pageSize, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	comments, pagination, err := cc.service.ModerationQueue(c.Request.Context(), c.Query("status"), postID, page, pageSize)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendPaginated(c, "", comments, pagination)
+}
+
+// ModerationCounts handles GET /api/comments/moderation/counts for staff,
+// returning the number of comments in each state.
+func (cc *CommentController) ModerationCounts(c *gin.Context) {
+	counts, err := cc.service.ModerationCounts(c.Request.Context())
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "", counts)
+}
+
+// ModerateCommentRequest sets a comment's moderation state.
+type ModerateCommentRequest struct {
+	Status string `json:"status" binding:"required,oneof=pending approved spam trashed"`
+}
+
+// ModerateComment handles PUT /api/comments/:id/status for staff.
+func (cc *CommentController) ModerateComment(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req ModerateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+
+	comment, err := cc.service.ModerateComment(c.Request.Context(), id, req.Status)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "Comment updated", comment)
+}
@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// ContentController serves content types defined at runtime and the
+// generic CRUD, list and search endpoints for their entries. Readers only
+// see published entries; staff see drafts too.
+type ContentController struct {
+	service *services.ContentService
+}
+
+func NewContentController(service *services.ContentService) *ContentController {
+	return &ContentController{service: service}
+}
+
+// ContentTypeRequest defines a content type. Fields is a list of field
+// definitions such as {"name": "starts_at", "type": "date", "required": true}.
+type ContentTypeRequest struct {
+	Name        string          `json:"name" binding:"required,max=100"`
+	Slug        string          `json:"slug" binding:"max=63"`
+	Description string          `json:"description" binding:"max=1000"`
+	Fields      json.RawMessage `json:"fields" binding:"required"`
+	TitleField  string          `json:"title_field" binding:"max=63"`
+}
+
+func (r *ContentTypeRequest) contentType() *models.ContentType {
+	return &models.ContentType{
+		Name:        r.Name,
+		Slug:        r.Slug,
+		Description: r.Description,
+		Fields:      r.Fields,
+		TitleField:  r.TitleField,
+	}
+}
+
+// ListContentTypes handles GET /api/content-types.
+func (cc *ContentController) ListContentTypes(c *gin.Context) {
+	types, err := cc.service.ListContentTypes(c.Request.Context())
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "", types)
+}
+
+// GetContentType handles GET /api/content-types/:type.
+func (cc *ContentController) GetContentType(c *gin.Context) {
+	ct, err := cc.service.GetContentType(c.Request.Context(), c.Param("type"))
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "", ct)
+}
+
+// CreateContentType handles POST /api/content-types.
+func (cc *ContentController) CreateContentType(c *gin.Context) {
+	var req ContentTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+
+	ct := req.contentType()
+	if err := cc.service.CreateContentType(c.Request.Context(), ct); err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.JSONResponse{Success: true, Message: "Content type created", Data: ct})
+}
+
+// UpdateContentType handles PUT /api/content-types/:type, replacing the
+// type's definition. The slug can't change.
+func (cc *ContentController) UpdateContentType(c *gin.Context) {
+	var req ContentTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+
+	ct, err := cc.service.UpdateContentType(c.Request.Context(), c.Param("type"), req.contentType())
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "Content type updated", ct)
+}
+
+// DeleteContentType handles DELETE /api/content-types/:type.
+func (cc *ContentController) DeleteContentType(c *gin.Context) {
+	if err := cc.service.DeleteContentType(c.Request.Context(), c.Param("type")); err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccessMessage(c, "Content type deleted")
+}
+
+// entryQuery reads the list parameters shared by ListEntries and
+// SearchEntries.
+func entryQuery(c *gin.Context) (services.EntryQuery, bool) {
+	page, pageSize, ok := pageParams(c)
+	if !ok {
+		return services.EntryQuery{}, false
+	}
+	q := services.EntryQuery{
+		Filters:  queryMapList(c, "filter"),
+		Status:   c.Query("status"),
+		Sort:     c.Query("sort"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if !middleware.IsStaff(c) {
+		q.Status = models.EntryStatusPublished
+	}
+	return q, true
+}
+
+// ListEntries handles GET /api/content/:type.
+//
+// Query parameters:
+//   - filter[field]: a value, or op:value with op one of eq, ne, gt, gte,
+//     lt, lte and in (comma-separated values); repeat to combine
+//   - sort: a field, created_at, updated_at, published_at or title,
+//     prefixed with - for descending order
+//   - status (staff only), page and page_size
+func (cc *ContentController) ListEntries(c *gin.Context) {
+	q, ok := entryQuery(c)
+	if !ok {
+		return
+	}
+
+	entries, pagination, err := cc.service.ListEntries(c.Request.Context(), c.Param("type"), q)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendPaginated(c, "", entries, pagination)
+}
+
+// SearchEntries handles GET /api/content/:type/search?q=..., taking the
+// same filters as ListEntries. Results are ordered by relevance.
+func (cc *ContentController) SearchEntries(c *gin.Context) {
+	q, ok := entryQuery(c)
+	if !ok {
+		return
+	}
+
+	entries, pagination, err := cc.service.SearchEntries(c.Request.Context(), c.Param("type"), c.Query("q"), q)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendPaginated(c, "", entries, pagination)
+}
+
+// GetEntry handles GET /api/content/:type/:id.
+func (cc *ContentController) GetEntry(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	entry, err := cc.service.GetEntry(c.Request.Context(), c.Param("type"), id)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+	if !entry.IsPublished() && !middleware.IsStaff(c) {
+		utils.SendError(c, http.StatusNotFound, "entry not found")
+		return
+	}
+
+	utils.SendSuccess(c, "", entry)
+}
+
+// CreateEntryRequest is a new entry; Data holds its field values.
+type CreateEntryRequest struct {
+	Data   json.RawMessage `json:"data" binding:"required"`
+	Status string          `json:"status" binding:"omitempty,oneof=draft published"`
+}
+
+// CreateEntry handles POST /api/content/:type.
+func (cc *ContentController) CreateEntry(c *gin.Context) {
+	var req CreateEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+
+	userID, _, _ := middleware.CurrentUser(c)
+	entry := &models.ContentEntry{Data: req.Data, Status: req.Status, AuthorID: userID}
+	if err := cc.service.CreateEntry(c.Request.Context(), c.Param("type"), entry); err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.JSONResponse{Success: true, Message: "Entry created", Data: entry})
+}
+
+// UpdateEntryRequest replaces an entry's data and sets its status; omitted
+// fields are left unchanged.
+type UpdateEntryRequest struct {
+	Data   json.RawMessage `json:"data"`
+	Status *string         `json:"status" binding:"omitempty,oneof=draft published"`
+}
+
+// UpdateEntry handles PUT /api/content/:type/:id.
+func (cc *ContentController) UpdateEntry(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req UpdateEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+	if req.Data == nil && req.Status == nil {
+		utils.SendValidationError(c, gin.H{"body": "no fields to update"})
+		return
+	}
+
+	entry, err := cc.service.UpdateEntry(c.Request.Context(), c.Param("type"), id, req.Data, req.Status)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "Entry updated", entry)
+}
+
+// DeleteEntry handles DELETE /api/content/:type/:id.
+func (cc *ContentController) DeleteEntry(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := cc.service.DeleteEntry(c.Request.Context(), c.Param("type"), id); err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccessMessage(c, "Entry deleted")
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/jalali"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+)
+
+// Values for ?date_format=
+const (
+	dateFormatDate     = "date"
+	dateFormatDateTime = "datetime"
+	dateFormatLong     = "long"
+)
+
+// dateOptions are how a request reads and writes dates, in the site's
+// time zone. ?calendar=jalali takes filter dates in the Jalali calendar;
+// with ?calendar= set, posts also carry their dates formatted in it as
+// ?date_format= says: date (the default), datetime or long.
+type dateOptions struct {
+	calendar string
+	style    string
+	location *time.Location
+	// localize is set when the request asked for a calendar
+	localize bool
+}
+
+func parseDateOptions(c *gin.Context, loc *time.Location) (dateOptions, error) {
+	opts := dateOptions{
+		calendar: c.DefaultQuery("calendar", services.CalendarGregorian),
+		style:    c.DefaultQuery("date_format", dateFormatDate),
+		location: loc,
+		localize: c.Query("calendar") != "",
+	}
+	if !services.IsCalendar(opts.calendar) {
+		return opts, errors.New("calendar must be gregorian or jalali")
+	}
+	switch opts.style {
+	case dateFormatDate, dateFormatDateTime, dateFormatLong:
+	default:
+		return opts, errors.New("date_format must be date, datetime or long")
+	}
+	return opts, nil
+}
+
+// parse reads an RFC 3339 timestamp or a date of the request's calendar. A
+// date-only upper bound (endOfDay) covers the whole day.
+func (o dateOptions) parse(name, raw string, endOfDay bool) (*time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, nil
+	}
+
+	var t time.Time
+	if o.calendar == services.CalendarJalali {
+		date, err := jalali.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a Jalali date (YYYY/MM/DD) or RFC 3339 timestamp", name)
+		}
+		t = date.Time(o.location)
+	} else {
+		var err error
+		if t, err = time.ParseInLocation("2006-01-02", raw, o.location); err != nil {
+			return nil, fmt.Errorf("%s must be a date (YYYY-MM-DD) or RFC 3339 timestamp", name)
+		}
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return &t, nil
+}
+
+// format writes t in the request's calendar and date format. Long Jalali
+// dates use Persian script for Persian content.
+func (o dateOptions) format(t time.Time, locale string) string {
+	t = t.In(o.location)
+	if o.calendar == services.CalendarGregorian {
+		switch o.style {
+		case dateFormatDateTime:
+			return t.Format("2006-01-02 15:04")
+		case dateFormatLong:
+			return t.Format("2 January 2006")
+		}
+		return t.Format("2006-01-02")
+	}
+
+	date := jalali.FromTime(t)
+	switch o.style {
+	case dateFormatDateTime:
+		return date.String() + t.Format(" 15:04")
+	case dateFormatLong:
+		return date.Long(strings.HasPrefix(locale, "fa"))
+	}
+	return date.String()
+}
+
+// etag marks a resource's ETag with the calendar and format of its local
+// dates, which change the representation.
+func (o dateOptions) etag(etag string) string {
+	if !o.localize {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + "-" + o.calendar + "-" + o.style + `"`
+}
+
+// localizePost sets the LocalDates of post when the request asked for a
+// calendar.
+func (o dateOptions) localizePost(post *models.Post) {
+	if !o.localize {
+		return
+	}
+	post.LocalDates = &models.LocalDates{Calendar: o.calendar, CreatedAt: o.format(post.CreatedAt, post.Locale)}
+	if post.PublishedAt != nil {
+		post.LocalDates.PublishedAt = o.format(*post.PublishedAt, post.Locale)
+	}
+}
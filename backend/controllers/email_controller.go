@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// EmailController lets administrators preview email templates.
+type EmailController struct {
+	service *services.EmailService
+}
+
+func NewEmailController(service *services.EmailService) *EmailController {
+	return &EmailController{service: service}
+}
+
+// ListTemplates handles GET /api/admin/emails/templates.
+func (ec *EmailController) ListTemplates(c *gin.Context) {
+	templates, locales := ec.service.PreviewOptions()
+	utils.SendSuccess(c, "", gin.H{"templates": templates, "locales": locales})
+}
+
+// Preview handles GET /api/admin/emails/preview/:template, rendering the
+// template with sample data.
+//
+// Query parameters: locale (defaults to the site locale) and format: html
+// (default) returns the page for viewing in a browser, text the plain-text
+// alternative and json the subject with both bodies.
+func (ec *EmailController) Preview(c *gin.Context) {
+	msg, err := ec.service.Preview(c.Query("locale"), c.Param("template"))
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	switch c.DefaultQuery("format", "html") {
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(msg.HTML))
+	case "text":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(msg.Text))
+	case "json":
+		utils.SendSuccess(c, "", msg)
+	default:
+		utils.SendValidationError(c, gin.H{"format": "must be html, text or json"})
+	}
+}
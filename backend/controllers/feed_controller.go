@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/feed"
+	"github.com/sasanzare/go-cms/i18n"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// FeedController serves RSS, Atom and JSON feeds of published posts. The
+// format follows the path's extension: feed.rss, feed.atom or feed.json.
+//
+// Query parameters: content (full or excerpt, defaulting to the site's
+// setting) and lang. Feeds list posts in the negotiated locale and its
+// fallbacks, and support conditional GET with ETag and Last-Modified.
+type FeedController struct {
+	service *services.FeedService
+	locales *i18n.Locales
+}
+
+func NewFeedController(service *services.FeedService, locales *i18n.Locales) *FeedController {
+	return &FeedController{service: service, locales: locales}
+}
+
+// SiteFeed handles GET /feed.{rss,atom,json}.
+func (fc *FeedController) SiteFeed(c *gin.Context) {
+	fc.serve(c, services.FeedQuery{})
+}
+
+// CategoryFeed handles GET /categories/:slug/feed.{rss,atom,json}.
+func (fc *FeedController) CategoryFeed(c *gin.Context) {
+	fc.serve(c, services.FeedQuery{CategorySlug: c.Param("slug")})
+}
+
+// TagFeed handles GET /tags/:slug/feed.{rss,atom,json}.
+func (fc *FeedController) TagFeed(c *gin.Context) {
+	fc.serve(c, services.FeedQuery{TagSlug: c.Param("slug")})
+}
+
+// AuthorFeed handles GET /authors/:id/feed.{rss,atom,json}.
+func (fc *FeedController) AuthorFeed(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	fc.serve(c, services.FeedQuery{AuthorID: id})
+}
+
+func (fc *FeedController) serve(c *gin.Context, q services.FeedQuery) {
+	format := strings.TrimPrefix(path.Ext(c.Request.URL.Path), ".")
+	q.Path = c.Request.URL.RequestURI()
+	q.Locales = readerLocales(c, fc.locales)
+	q.Content = c.Query("content")
+
+	f, err := fc.service.Feed(c.Request.Context(), q)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+	var body bytes.Buffer
+	if err := feed.Write(&body, f, format); err != nil {
+		_ = c.Error(err)
+		utils.SendError(c, http.StatusInternalServerError, "Failed to generate feed")
+		return
+	}
+
+	sum := sha256.Sum256(body.Bytes())
+	etag := fmt.Sprintf(`"feed-%x"`, sum[:16])
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=300")
+	if !f.Updated.IsZero() {
+		c.Header("Last-Modified", f.Updated.UTC().Format(http.TimeFormat))
+	}
+	if notModified(c, etag, f.Updated) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, feed.ContentType(format), body.Bytes())
+}
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/i18n"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// parseIDParam reads a positive integer path parameter.
+func parseIDParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		utils.SendError(c, http.StatusBadRequest, fmt.Sprintf("Invalid %s", name))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// queryUint parses an optional unsigned integer query parameter.
+func queryUint(c *gin.Context, name string) (uint, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a positive integer", name)
+	}
+	return uint(n), nil
+}
+
+// queryInt parses an optional integer query parameter.
+func queryInt(c *gin.Context, name string) (int, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return n, nil
+}
+
+// pageParams reads the page and page_size query parameters, writing a
+// validation error and returning false when they are malformed.
+func pageParams(c *gin.Context) (int, int, bool) {
+	page, err := queryInt(c, "page")
+	if err != nil {
+		utils.SendValidationError(c, gin.H{"page": err.Error()})
+		return 0, 0, false
+	}
+	pageSize, err := queryInt(c, "page_size")
+	if err != nil {
+		utils.SendValidationError(c, gin.H{"page_size": err.Error()})
+		return 0, 0, false
+	}
+	return page, pageSize, true
+}
+
+// queryList splits a comma-separated query parameter, also accepting the
+// parameter repeated (?tag=a&tag=b).
+func queryList(c *gin.Context, name string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(name) {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// queryUintList parses a comma-separated list of unsigned integers.
+func queryUintList(c *gin.Context, name string) ([]uint, error) {
+	var ids []uint
+	for _, v := range queryList(c, name) {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a list of positive integers", name)
+		}
+		ids = append(ids, uint(n))
+	}
+	return ids, nil
+}
+
+// queryTime parses an optional RFC 3339 timestamp or date in the calendar
+// of dates. A date-only upper bound (endOfDay) covers the whole day.
+func queryTime(c *gin.Context, name string, endOfDay bool, dates dateOptions) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	return dates.parse(name, raw, endOfDay)
+}
+
+// sendServiceError maps service errors to HTTP responses.
+func sendServiceError(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case errors.Is(err, utils.ErrInvalidCursor), strings.HasPrefix(msg, utils.ValidationFailedMsg):
+		utils.SendError(c, http.StatusBadRequest, msg)
+	case strings.HasSuffix(msg, "not found"):
+		utils.SendError(c, http.StatusNotFound, msg)
+	default:
+		_ = c.Error(err)
+		utils.SendError(c, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// queryMapList collects the parameters named name[key], such as
+// filter[price]=gte:10, by key. Repeated parameters keep every value.
+func queryMapList(c *gin.Context, name string) map[string][]string {
+	values := map[string][]string{}
+	for param, v := range c.Request.URL.Query() {
+		key, ok := strings.CutPrefix(param, name+"[")
+		if !ok {
+			continue
+		}
+		if key, ok = strings.CutSuffix(key, "]"); ok && key != "" {
+			values[key] = append(values[key], v...)
+		}
+	}
+	return values
+}
+
+// readerLocales returns the locales content is shown to a reader in: the
+// fallback chain of the locale middleware.Locale negotiated, announced in
+// Content-Language. Staff see every locale, so it returns nil for them.
+func readerLocales(c *gin.Context, locales *i18n.Locales) []string {
+	locale, ok := middleware.RequestLocale(c)
+	if !ok || middleware.IsStaff(c) {
+		return nil
+	}
+	c.Header("Content-Language", locale)
+	return locales.Chain(locale)
+}
+
+// notModified reports whether the client's copy, named by If-None-Match
+// or else dated by If-Modified-Since, is current.
+func notModified(c *gin.Context, etag string, modified time.Time) bool {
+	if header := c.GetHeader("If-None-Match"); header != "" {
+		return utils.ETagListContains(header, etag)
+	}
+	since, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+	if err != nil || modified.IsZero() {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/jobs"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// JobController lets administrators inspect the background job queue and
+// retry failed jobs.
+type JobController struct {
+	queue *jobs.Queue
+}
+
+func NewJobController(queue *jobs.Queue) *JobController {
+	return &JobController{queue: queue}
+}
+
+// Stats handles GET /api/admin/jobs/stats.
+func (jc *JobController) Stats(c *gin.Context) {
+	stats, err := jc.queue.Stats(c.Request.Context())
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "", stats)
+}
+
+// ListJobs handles GET /api/admin/jobs.
+//
+// Query parameters: queue, status (pending or running), page and page_size.
+func (jc *JobController) ListJobs(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && status != models.JobStatusPending && status != models.JobStatusRunning {
+		utils.SendValidationError(c, gin.H{"status": "must be pending or running"})
+		return
+	}
+	page, pageSize, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	list, pagination, err := jc.queue.ListJobs(c.Request.Context(), c.Query("queue"), status, page, pageSize)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendPaginated(c, "", list, pagination)
+}
+
+// ListDeadJobs handles GET /api/admin/jobs/dead.
+//
+// Query parameters: queue, page and page_size.
+func (jc *JobController) ListDeadJobs(c *gin.Context) {
+	page, pageSize, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	list, pagination, err := jc.queue.ListDeadJobs(c.Request.Context(), c.Query("queue"), page, pageSize)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendPaginated(c, "", list, pagination)
+}
+
+// RetryDeadJob handles POST /api/admin/jobs/dead/:id/retry.
+func (jc *JobController) RetryDeadJob(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	job, err := jc.queue.RetryDeadJob(c.Request.Context(), id)
+	if errors.Is(err, jobs.ErrDuplicate) {
+		utils.SendError(c, http.StatusConflict, "An equivalent job is already queued")
+		return
+	}
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "Job queued for retry", job)
+}
+
+// DeleteDeadJob handles DELETE /api/admin/jobs/dead/:id.
+func (jc *JobController) DeleteDeadJob(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := jc.queue.DeleteDeadJob(c.Request.Context(), id); err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccessMessage(c, "Dead job deleted")
+}
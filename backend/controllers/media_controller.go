@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// MediaController manages the media library content blocks refer to.
+type MediaController struct {
+	service *services.MediaService
+}
+
+func NewMediaController(service *services.MediaService) *MediaController {
+	return &MediaController{service: service}
+}
+
+// CreateMediaRequest registers a file already stored at URL.
+type CreateMediaRequest struct {
+	URL      string `json:"url" binding:"required,max=1024"`
+	Filename string `json:"filename" binding:"max=255"`
+	MimeType string `json:"mime_type" binding:"required,max=100"`
+	Size     int64  `json:"size" binding:"min=0"`
+	Width    int    `json:"width" binding:"min=0"`
+	Height   int    `json:"height" binding:"min=0"`
+	Alt      string `json:"alt" binding:"max=500"`
+}
+
+// ListMedia handles GET /api/media.
+//
+// Query parameters: type (a MIME type prefix such as image/), page and
+// page_size.
+func (mc *MediaController) ListMedia(c *gin.Context) {
+	page, pageSize, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	media, pagination, err := mc.service.ListMedia(c.Request.Context(), c.Query("type"), page, pageSize)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendPaginated(c, "", media, pagination)
+}
+
+// GetMedia handles GET /api/media/:id.
+func (mc *MediaController) GetMedia(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	media, err := mc.service.GetMedia(c.Request.Context(), id)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "", media)
+}
+
+// CreateMedia handles POST /api/media.
+func (mc *MediaController) CreateMedia(c *gin.Context) {
+	var req CreateMediaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+
+	userID, _, _ := middleware.CurrentUser(c)
+	media := &models.Media{
+		URL:        req.URL,
+		Filename:   req.Filename,
+		MimeType:   req.MimeType,
+		Size:       req.Size,
+		Width:      req.Width,
+		Height:     req.Height,
+		Alt:        req.Alt,
+		UploaderID: userID,
+	}
+	if err := mc.service.CreateMedia(c.Request.Context(), media); err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.JSONResponse{Success: true, Message: "Media created", Data: media})
+}
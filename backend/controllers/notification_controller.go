@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// NotificationController serves the signed-in user's notification center.
+type NotificationController struct {
+	service *services.NotificationService
+}
+
+func NewNotificationController(service *services.NotificationService) *NotificationController {
+	return &NotificationController{service: service}
+}
+
+// ListNotifications handles GET /api/notifications.
+//
+// Query parameters: unread (true for unread only), page and page_size.
+func (nc *NotificationController) ListNotifications(c *gin.Context) {
+	userID, _, _ := middleware.CurrentUser(c)
+	page, pageSize, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	list, pagination, err := nc.service.ListNotifications(c.Request.Context(), userID, c.Query("unread") == "true", page, pageSize)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendPaginated(c, "", list, pagination)
+}
+
+// UnreadCount handles GET /api/notifications/unread-count.
+func (nc *NotificationController) UnreadCount(c *gin.Context) {
+	userID, _, _ := middleware.CurrentUser(c)
+
+	count, err := nc.service.UnreadCount(c.Request.Context(), userID)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "", gin.H{"unread": count})
+}
+
+// MarkRead handles POST /api/notifications/:id/read.
+func (nc *NotificationController) MarkRead(c *gin.Context) {
+	userID, _, _ := middleware.CurrentUser(c)
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	notification, err := nc.service.MarkRead(c.Request.Context(), userID, id)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "Notification marked as read", notification)
+}
+
+// MarkAllRead handles POST /api/notifications/read-all.
+func (nc *NotificationController) MarkAllRead(c *gin.Context) {
+	userID, _, _ := middleware.CurrentUser(c)
+
+	count, err := nc.service.MarkAllRead(c.Request.Context(), userID)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "Notifications marked as read", gin.H{"updated": count})
+}
+
+// GetPreferences handles GET /api/notifications/preferences.
+func (nc *NotificationController) GetPreferences(c *gin.Context) {
+	userID, _, _ := middleware.CurrentUser(c)
+
+	prefs, err := nc.service.Preferences(c.Request.Context(), userID)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "", prefs)
+}
+
+// UpdatePreferencesRequest sets the channels of one or more notification
+// types; types left out keep their current settings.
+type UpdatePreferencesRequest struct {
+	Preferences []struct {
+		Type  string `json:"type" binding:"required"`
+		InApp bool   `json:"in_app"`
+		Email string `json:"email" binding:"required,oneof=off instant daily"`
+	} `json:"preferences" binding:"required,min=1,dive"`
+}
+
+// UpdatePreferences handles PUT /api/notifications/preferences.
+func (nc *NotificationController) UpdatePreferences(c *gin.Context) {
+	userID, _, _ := middleware.CurrentUser(c)
+
+	var req UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+
+	prefs := make([]models.NotificationPreference, len(req.Preferences))
+	for i, p := range req.Preferences {
+		prefs[i] = models.NotificationPreference{Type: p.Type, InApp: p.InApp, Email: p.Email}
+	}
+
+	saved, err := nc.service.UpdatePreferences(c.Request.Context(), userID, prefs)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "Preferences updated", saved)
+}
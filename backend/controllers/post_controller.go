@@ -1,33 +1,533 @@
 package controllers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/markup"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/utils"
 )
 
-func ListPosts(c *gin.Context) {
-	c.JSON(200, gin.H{"message": "List posts"})
+type PostController struct {
+	service *services.PostService
+}
+
+func NewPostController(service *services.PostService) *PostController {
+	return &PostController{service: service}
+}
+
+// ListPosts handles GET /api/posts.
+//
+// Query parameters: status, category_id, author_id, tag (slugs), tag_id,
+// published_from, published_to, created_from, created_to, sort, order,
+// page, page_size, cursor, deleted (include/only, admins only), lang,
+// year and month (an archive period), calendar and date_format. Dates are
+// read, and posts' LocalDates written, in calendar (see dateOptions).
+// Anonymous users and authors only see published posts, one variant of
+// each translation group in the negotiated locale or its fallbacks; staff
+// see every locale unless lang picks one.
+func (pc *PostController) ListPosts(c *gin.Context) {
+	dates, err := parseDateOptions(c, pc.service.Location())
+	if err != nil {
+		utils.SendValidationError(c, gin.H{"query": err.Error()})
+		return
+	}
+	filter, err := parsePostFilter(c, dates)
+	if err != nil {
+		utils.SendValidationError(c, gin.H{"query": err.Error()})
+		return
+	}
+
+	if !middleware.IsStaff(c) {
+		filter.Status = models.PostStatusPublished
+	}
+	filter.Locales = readerLocales(c, pc.service.Locales())
+	if lang := c.Query("lang"); lang != "" && middleware.IsStaff(c) {
+		locale, ok := pc.service.Locales().Match(lang)
+		if !ok {
+			utils.SendValidationError(c, gin.H{"lang": "unsupported locale"})
+			return
+		}
+		filter.Locales = []string{locale}
+	}
+	if filter.Deleted != services.DeletedExclude && c.GetString(middleware.RoleKey) != models.UserRoleAdmin {
+		utils.SendError(c, http.StatusForbidden, "Only administrators can list deleted posts")
+		return
+	}
+
+	posts, pagination, err := pc.service.ListPosts(c.Request.Context(), filter)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+	for i := range posts {
+		dates.localizePost(&posts[i])
+	}
+
+	utils.SendPaginated(c, "", posts, pagination)
+}
+
+// GetPost handles GET /api/posts/:id, taking calendar and date_format like
+// ListPosts. Unpublished posts are only visible to their author and staff.
+// The response carries an ETag for the post's version and date options; a
+// matching If-None-Match gets 304 Not Modified.
+func (pc *PostController) GetPost(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	dates, err := parseDateOptions(c, pc.service.Location())
+	if err != nil {
+		utils.SendValidationError(c, gin.H{"query": err.Error()})
+		return
+	}
+
+	post, err := pc.service.GetPostByID(c.Request.Context(), id)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+	userID, _, _ := middleware.CurrentUser(c)
+	if !post.IsPublished() && post.AuthorID != userID && !middleware.IsStaff(c) {
+		utils.SendError(c, http.StatusNotFound, "post not found")
+		return
+	}
+
+	c.Header("Content-Language", post.Locale)
+	etag := dates.etag(postETag(post))
+	c.Header("ETag", etag)
+	if utils.ETagListContains(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	dates.localizePost(post)
+
+	utils.SendSuccess(c, "", post)
+}
+
+// Archive handles GET /api/posts/archive, the number of published posts
+// by year and month of publication. List a period's posts with the year
+// and month parameters of ListPosts.
+//
+// Query parameters: calendar (gregorian or jalali, default gregorian) and
+// category_id. Readers count posts in the negotiated locale and its
+// fallbacks.
+func (pc *PostController) Archive(c *gin.Context) {
+	dates, err := parseDateOptions(c, pc.service.Location())
+	if err != nil {
+		utils.SendValidationError(c, gin.H{"query": err.Error()})
+		return
+	}
+	filter := services.PostFilter{
+		Status:  models.PostStatusPublished,
+		Locales: readerLocales(c, pc.service.Locales()),
+	}
+	if filter.CategoryID, err = queryUint(c, "category_id"); err != nil {
+		utils.SendValidationError(c, gin.H{"category_id": err.Error()})
+		return
+	}
+
+	archive, err := pc.service.Archive(c.Request.Context(), filter, dates.calendar)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "", archive)
+}
+
+// HighlightCSS handles GET /api/posts/highlight.css, the stylesheet for
+// syntax-highlighted code blocks in rendered Markdown.
+func (pc *PostController) HighlightCSS(c *gin.Context) {
+	var css bytes.Buffer
+	if err := markup.WriteHighlightCSS(&css); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to generate stylesheet")
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "text/css; charset=utf-8", css.Bytes())
+}
+
+// CreatePostRequest is a new post. Content is written in content_format,
+// HTML by default, or given as blocks for the blocks format.
+type CreatePostRequest struct {
+	Title           string          `json:"title" binding:"required,min=3,max=255"`
+	Content         string          `json:"content"`
+	ContentFormat   string          `json:"content_format" binding:"omitempty,oneof=markdown html plain blocks"`
+	Blocks          json.RawMessage `json:"blocks"`
+	Excerpt         string          `json:"excerpt" binding:"max=500"`
+	Slug            string          `json:"slug" binding:"max=300"`
+	Locale          string          `json:"locale" binding:"max=10"`
+	MetaTitle       string          `json:"meta_title" binding:"max=255"`
+	MetaDescription string          `json:"meta_description" binding:"max=500"`
+	FeaturedImage   string          `json:"featured_image" binding:"max=512"`
+	Robots          string          `json:"robots" binding:"max=200"`
+	CategoryID      *uint           `json:"category_id"`
+	SearchLanguage  string          `json:"search_language"`
+}
+
+// CreatePost handles POST /api/posts for authors and staff. The current
+// user becomes the author of the new draft, written in locale or the
+// default one; publish or schedule it afterwards.
+func (pc *PostController) CreatePost(c *gin.Context) {
+	var req CreatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+	locale := pc.service.Locales().Default()
+	if req.Locale != "" {
+		var ok bool
+		if locale, ok = pc.service.Locales().Match(req.Locale); !ok {
+			utils.SendValidationError(c, gin.H{"locale": "unsupported locale"})
+			return
+		}
+	}
+
+	userID, _, _ := middleware.CurrentUser(c)
+	post := &models.Post{
+		Title:           req.Title,
+		Content:         req.Content,
+		ContentFormat:   req.ContentFormat,
+		Blocks:          req.Blocks,
+		Excerpt:         req.Excerpt,
+		Slug:            req.Slug,
+		Locale:          locale,
+		MetaTitle:       req.MetaTitle,
+		MetaDescription: req.MetaDescription,
+		FeaturedImage:   req.FeaturedImage,
+		Robots:          req.Robots,
+		CategoryID:      req.CategoryID,
+		SearchLanguage:  req.SearchLanguage,
+		AuthorID:        userID,
+		Status:          models.PostStatusDraft,
+	}
+	if err := pc.service.CreatePost(c.Request.Context(), post); err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.Header("ETag", postETag(post))
+	c.JSON(http.StatusCreated, utils.JSONResponse{Success: true, Message: "Post created", Data: post})
+}
+
+// UpdatePostRequest holds the editable post fields; omitted fields are left
+// unchanged.
+type UpdatePostRequest struct {
+	Title           *string         `json:"title" binding:"omitempty,min=3,max=255"`
+	Content         *string         `json:"content" binding:"omitempty,min=10"`
+	ContentFormat   *string         `json:"content_format" binding:"omitempty,oneof=markdown html plain blocks"`
+	Blocks          json.RawMessage `json:"blocks"`
+	Excerpt         *string         `json:"excerpt" binding:"omitempty,max=500"`
+	Status          *string         `json:"status" binding:"omitempty,oneof=draft published archived rejected"`
+	Slug            *string         `json:"slug" binding:"omitempty,max=300"`
+	MetaTitle       *string         `json:"meta_title" binding:"omitempty,max=255"`
+	MetaDescription *string         `json:"meta_description" binding:"omitempty,max=500"`
+	FeaturedImage   *string         `json:"featured_image" binding:"omitempty,max=512"`
+	Robots          *string         `json:"robots" binding:"omitempty,max=200"` // Robots meta directives, e.g. noindex,nofollow
+	CategoryID      *uint           `json:"category_id"`
+	SearchLanguage  *string         `json:"search_language"`
+	CommentsEnabled *bool           `json:"comments_enabled"`
+	// SourceRevision marks a translation up to date with this revision of
+	// its source post
+	SourceRevision *uint `json:"source_revision"`
+	// ClearCategory removes the post from its category; it can't be
+	// combined with category_id
+	ClearCategory bool `json:"clear_category"`
+}
+
+func (r *UpdatePostRequest) updates() map[string]interface{} {
+	updates := map[string]interface{}{}
+	set := func(column string, value *string) {
+		if value != nil {
+			updates[column] = *value
+		}
+	}
+	set("title", r.Title)
+	set("content", r.Content)
+	set("content_format", r.ContentFormat)
+	set("excerpt", r.Excerpt)
+	set("status", r.Status)
+	set("slug", r.Slug)
+	set("meta_title", r.MetaTitle)
+	set("meta_description", r.MetaDescription)
+	set("featured_image", r.FeaturedImage)
+	set("robots", r.Robots)
+	set("search_language", r.SearchLanguage)
+	if r.Blocks != nil {
+		updates["blocks"] = r.Blocks
+	}
+	if r.CategoryID != nil {
+		updates["category_id"] = *r.CategoryID
+	}
+	if r.ClearCategory {
+		updates["category_id"] = nil
+	}
+	if r.CommentsEnabled != nil {
+		updates["comments_enabled"] = *r.CommentsEnabled
+	}
+	if r.SourceRevision != nil {
+		updates["source_revision"] = *r.SourceRevision
+	}
+	return updates
 }
 
-func GetPost(c *gin.Context) {
-	c.JSON(200, gin.H{"message": "Get post"})
+// UpdatePost handles PUT /api/posts/:id for the post's author and staff.
+//
+// With an If-Match header holding the post's ETag the update only applies
+// if nobody saved the post since; otherwise it fails with 412 Precondition
+// Failed and the conflict, including the changes made in the meantime.
+// Only staff may publish or reject posts.
+func (pc *PostController) UpdatePost(c *gin.Context) {
+	id, ok := pc.authorizePostEdit(c)
+	if !ok {
+		return
+	}
+
+	var req UpdatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+	if req.Status != nil && !middleware.IsStaff(c) &&
+		(*req.Status == models.PostStatusPublished || *req.Status == models.PostStatusRejected) {
+		utils.SendError(c, http.StatusForbidden, "Only staff can publish or reject posts")
+		return
+	}
+	if req.ClearCategory && req.CategoryID != nil {
+		utils.SendValidationError(c, gin.H{"clear_category": "can't be combined with category_id"})
+		return
+	}
+	updates := req.updates()
+	if len(updates) == 0 {
+		utils.SendValidationError(c, gin.H{"body": "no fields to update"})
+		return
+	}
+
+	var (
+		post *models.Post
+		err  error
+	)
+	version, conditional, err := utils.IfMatchVersion(c.GetHeader("If-Match"), fmt.Sprintf("post-%d-v", id))
+	if err != nil {
+		utils.SendError(c, http.StatusPreconditionFailed, "If-Match does not match this post")
+		return
+	}
+	if conditional {
+		post, err = pc.service.UpdatePostIfMatch(c.Request.Context(), id, version, updates)
+	} else {
+		post, err = pc.service.UpdatePost(c.Request.Context(), id, updates)
+	}
+
+	var conflict *services.VersionConflictError
+	if errors.As(err, &conflict) {
+		c.Header("ETag", postETag(conflict.Current))
+		utils.SendErrorWithData(c, http.StatusPreconditionFailed, "Post was modified by someone else", conflict)
+		return
+	}
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.Header("ETag", postETag(post))
+	utils.SendSuccess(c, "Post updated", post)
 }
 
-func CreatePost(c *gin.Context) {
-	c.JSON(201, gin.H{"message": "Create post"})
+// ScheduleRequest sets when a post is published and archived. With only
+// unpublish_at it sets the expiry of an already published post.
+type ScheduleRequest struct {
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
 }
 
-func UpdatePost(c *gin.Context) {
-	c.JSON(200, gin.H{"message": "Update post"})
+// SchedulePost handles POST /api/posts/:id/schedule for staff.
+func (pc *PostController) SchedulePost(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+
+	var (
+		post *models.Post
+		err  error
+	)
+	switch {
+	case req.PublishAt != nil:
+		post, err = pc.service.SchedulePost(c.Request.Context(), id, *req.PublishAt, req.UnpublishAt)
+	case req.UnpublishAt != nil:
+		post, err = pc.service.SetPostExpiry(c.Request.Context(), id, req.UnpublishAt)
+	default:
+		utils.SendValidationError(c, gin.H{"publish_at": "publish_at or unpublish_at is required"})
+		return
+	}
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.Header("ETag", postETag(post))
+	utils.SendSuccess(c, "Post scheduled", post)
 }
 
-func ListPendingPosts(c *gin.Context) {
+// CancelSchedule handles DELETE /api/posts/:id/schedule for staff,
+// returning a scheduled post to draft.
+func (pc *PostController) CancelSchedule(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	post, err := pc.service.CancelSchedule(c.Request.Context(), id)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.Header("ETag", postETag(post))
+	utils.SendSuccess(c, "Schedule cancelled", post)
+}
+
+func (pc *PostController) ListPendingPosts(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "List pending posts"})
 }
 
-func ApprovePost(c *gin.Context) {
-	c.JSON(200, gin.H{"message": "Approve post"})
+// ApprovePost handles POST /api/posts/:id/approve for staff, publishing
+// the post and notifying its author.
+func (pc *PostController) ApprovePost(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	post, err := pc.service.ApprovePost(c.Request.Context(), id)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.Header("ETag", postETag(post))
+	utils.SendSuccess(c, "Post approved", post)
 }
 
-func RejectPost(c *gin.Context) {
-	c.JSON(200, gin.H{"message": "Reject post"})
-}
\ No newline at end of file
+// RejectPostRequest optionally explains a rejection to the author.
+type RejectPostRequest struct {
+	Reason string `json:"reason" binding:"max=1000"`
+}
+
+// RejectPost handles POST /api/posts/:id/reject for staff.
+func (pc *PostController) RejectPost(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req RejectPostRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.SendValidationError(c, gin.H{"body": err.Error()})
+			return
+		}
+	}
+
+	post, err := pc.service.RejectPost(c.Request.Context(), id, req.Reason)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.Header("ETag", postETag(post))
+	utils.SendSuccess(c, "Post rejected", post)
+}
+
+func parsePostFilter(c *gin.Context, dates dateOptions) (services.PostFilter, error) {
+	var (
+		filter services.PostFilter
+		err    error
+	)
+
+	filter.Status = c.Query("status")
+	filter.Sort = c.Query("sort")
+	filter.Order = c.Query("order")
+	filter.Cursor = c.Query("cursor")
+	filter.Deleted = c.Query("deleted")
+	filter.TagSlugs = queryList(c, "tag")
+
+	switch filter.Deleted {
+	case services.DeletedExclude, services.DeletedInclude, services.DeletedOnly:
+	default:
+		return filter, errors.New("deleted must be include or only")
+	}
+
+	if filter.CategoryID, err = queryUint(c, "category_id"); err != nil {
+		return filter, err
+	}
+	if filter.AuthorID, err = queryUint(c, "author_id"); err != nil {
+		return filter, err
+	}
+	if filter.TagIDs, err = queryUintList(c, "tag_id"); err != nil {
+		return filter, err
+	}
+	if filter.Page, err = queryInt(c, "page"); err != nil {
+		return filter, err
+	}
+	if filter.PageSize, err = queryInt(c, "page_size"); err != nil {
+		return filter, err
+	}
+	if filter.PublishedFrom, err = queryTime(c, "published_from", false, dates); err != nil {
+		return filter, err
+	}
+	if filter.PublishedTo, err = queryTime(c, "published_to", true, dates); err != nil {
+		return filter, err
+	}
+	if filter.CreatedFrom, err = queryTime(c, "created_from", false, dates); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = queryTime(c, "created_to", true, dates); err != nil {
+		return filter, err
+	}
+
+	// An archive year, or month of it, in the request's calendar
+	year, err := queryInt(c, "year")
+	if err != nil {
+		return filter, err
+	}
+	if year != 0 {
+		if filter.PublishedFrom != nil || filter.PublishedTo != nil {
+			return filter, errors.New("year can't be combined with published_from or published_to")
+		}
+		month, err := queryInt(c, "month")
+		if err != nil {
+			return filter, err
+		}
+		from, to, err := services.ArchiveRange(dates.calendar, year, month, dates.location)
+		if err != nil {
+			return filter, errors.New(strings.TrimPrefix(err.Error(), utils.ValidationFailedMsg+": "))
+		}
+		to = to.Add(-time.Nanosecond)
+		filter.PublishedFrom, filter.PublishedTo = &from, &to
+	}
+
+	return filter, nil
+}
+
+// postETag identifies a version of a post.
+func postETag(post *models.Post) string {
+	return fmt.Sprintf(`"post-%d-v%d"`, post.ID, post.Version)
+}
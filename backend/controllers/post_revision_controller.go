@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// ListRevisions handles GET /api/posts/:id/revisions.
+func (pc *PostController) ListRevisions(c *gin.Context) {
+	postID, ok := pc.authorizePostEdit(c)
+	if !ok {
+		return
+	}
+
+	revisions, err := pc.service.ListRevisions(c.Request.Context(), postID)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "", revisions)
+}
+
+// GetRevision handles GET /api/posts/:id/revisions/:rev.
+func (pc *PostController) GetRevision(c *gin.Context) {
+	postID, ok := pc.authorizePostEdit(c)
+	if !ok {
+		return
+	}
+	number, ok := parseIDParam(c, "rev")
+	if !ok {
+		return
+	}
+
+	revision, err := pc.service.GetRevision(c.Request.Context(), postID, number)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "", revision)
+}
+
+// DiffRevisions handles GET /api/posts/:id/revisions/diff.
+//
+// Query parameters: from and to (revision numbers, required) and mode
+// (line or word, default line).
+func (pc *PostController) DiffRevisions(c *gin.Context) {
+	postID, ok := pc.authorizePostEdit(c)
+	if !ok {
+		return
+	}
+
+	from, err := queryUint(c, "from")
+	if err != nil || from == 0 {
+		utils.SendValidationError(c, gin.H{"from": "must be a revision number"})
+		return
+	}
+	to, err := queryUint(c, "to")
+	if err != nil || to == 0 {
+		utils.SendValidationError(c, gin.H{"to": "must be a revision number"})
+		return
+	}
+
+	diff, err := pc.service.DiffRevisions(c.Request.Context(), postID, from, to, c.Query("mode"))
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "", diff)
+}
+
+// RestoreRevision handles POST /api/posts/:id/revisions/:rev/restore.
+func (pc *PostController) RestoreRevision(c *gin.Context) {
+	postID, ok := pc.authorizePostEdit(c)
+	if !ok {
+		return
+	}
+	number, ok := parseIDParam(c, "rev")
+	if !ok {
+		return
+	}
+
+	post, err := pc.service.RestoreRevision(c.Request.Context(), postID, number)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "Revision restored", post)
+}
+
+// authorizePostEdit reads the :id parameter and checks that the current
+// user may edit the post: its author or staff. It writes the error response
+// and returns false otherwise.
+func (pc *PostController) authorizePostEdit(c *gin.Context) (uint, bool) {
+	postID, ok := parseIDParam(c, "id")
+	if !ok {
+		return 0, false
+	}
+
+	post, err := pc.service.GetPostByID(c.Request.Context(), postID)
+	if err != nil {
+		sendServiceError(c, err)
+		return 0, false
+	}
+
+	userID, _, _ := middleware.CurrentUser(c)
+	if post.AuthorID != userID && !middleware.IsStaff(c) {
+		utils.SendError(c, http.StatusForbidden, "You can only edit your own posts")
+		return 0, false
+	}
+	return postID, true
+}
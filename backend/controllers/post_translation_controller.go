@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// GetPostBySlug handles GET /api/posts/slug/:slug. Slugs are unique per
+// locale, so the post is looked up in the negotiated locale and its
+// fallbacks; when its translation group has a variant in an earlier one,
+// that variant is returned instead. Readers only see published posts;
+// calendar and date_format work as for ListPosts.
+func (pc *PostController) GetPostBySlug(c *gin.Context) {
+	dates, err := parseDateOptions(c, pc.service.Location())
+	if err != nil {
+		utils.SendValidationError(c, gin.H{"query": err.Error()})
+		return
+	}
+	locale, _ := middleware.RequestLocale(c)
+	chain := pc.service.Locales().Chain(locale)
+
+	post, err := pc.service.GetPostBySlug(c.Request.Context(), c.Param("slug"), chain, !middleware.IsStaff(c))
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.Header("Content-Language", post.Locale)
+	dates.localizePost(post)
+	utils.SendSuccess(c, "", post)
+}
+
+// ListTranslations handles GET /api/posts/:id/translations, the state of
+// the post's translation into every supported locale: missing, outdated
+// (the original changed since it was translated) or current.
+func (pc *PostController) ListTranslations(c *gin.Context) {
+	postID, ok := pc.authorizePostEdit(c)
+	if !ok {
+		return
+	}
+
+	translations, err := pc.service.ListTranslations(c.Request.Context(), postID)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "", translations)
+}
+
+// CreateTranslationRequest is a new translation of a post.
+type CreateTranslationRequest struct {
+	Locale          string          `json:"locale" binding:"required,max=10"`
+	Title           string          `json:"title" binding:"required,min=3,max=255"`
+	Content         string          `json:"content"`
+	ContentFormat   string          `json:"content_format" binding:"omitempty,oneof=markdown html plain blocks"`
+	Blocks          json.RawMessage `json:"blocks"`
+	Excerpt         string          `json:"excerpt" binding:"max=500"`
+	Slug            string          `json:"slug" binding:"max=300"`
+	MetaTitle       string          `json:"meta_title" binding:"max=255"`
+	MetaDescription string          `json:"meta_description" binding:"max=500"`
+	FeaturedImage   string          `json:"featured_image" binding:"max=512"`
+	CategoryID      *uint           `json:"category_id"`
+	SearchLanguage  string          `json:"search_language"`
+	// SourceRevision is the revision of the original the translation was
+	// made from; it defaults to the latest
+	SourceRevision uint `json:"source_revision"`
+}
+
+// CreateTranslation handles POST /api/posts/:id/translations, adding a
+// draft translation of the post into a locale it has no variant in yet.
+// Only the original's author and staff may translate it; the current user
+// becomes the translation's author.
+func (pc *PostController) CreateTranslation(c *gin.Context) {
+	sourceID, ok := pc.authorizePostEdit(c)
+	if !ok {
+		return
+	}
+	var req CreateTranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+	locale, ok := pc.service.Locales().Match(req.Locale)
+	if !ok {
+		utils.SendValidationError(c, gin.H{"locale": "unsupported locale"})
+		return
+	}
+
+	userID, _, _ := middleware.CurrentUser(c)
+	post := &models.Post{
+		Title:           req.Title,
+		Content:         req.Content,
+		ContentFormat:   req.ContentFormat,
+		Blocks:          req.Blocks,
+		Excerpt:         req.Excerpt,
+		Slug:            req.Slug,
+		Locale:          locale,
+		SourceRevision:  req.SourceRevision,
+		MetaTitle:       req.MetaTitle,
+		MetaDescription: req.MetaDescription,
+		FeaturedImage:   req.FeaturedImage,
+		CategoryID:      req.CategoryID,
+		SearchLanguage:  req.SearchLanguage,
+		AuthorID:        userID,
+		Status:          models.PostStatusDraft,
+	}
+	if err := pc.service.CreateTranslation(c.Request.Context(), sourceID, post); err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.JSONResponse{Success: true, Message: "Translation created", Data: post})
+}
+
+// ListTranslationTasks handles GET /api/posts/translations, the original
+// posts whose translation into a locale is missing or outdated.
+//
+// Query parameters: locale (required), state (missing or outdated), page
+// and page_size.
+func (pc *PostController) ListTranslationTasks(c *gin.Context) {
+	locale, ok := pc.service.Locales().Match(c.Query("locale"))
+	if !ok {
+		utils.SendValidationError(c, gin.H{"locale": "must be a supported locale"})
+		return
+	}
+	page, pageSize, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	tasks, pagination, err := pc.service.ListTranslationTasks(c.Request.Context(), locale, c.Query("state"), page, pageSize)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendPaginated(c, "", tasks, pagination)
+}
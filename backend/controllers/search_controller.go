@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/i18n"
+	"github.com/sasanzare/go-cms/jobs"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+type SearchController struct {
+	service *services.SearchService
+	locales *i18n.Locales
+}
+
+func NewSearchController(service *services.SearchService, locales *i18n.Locales) *SearchController {
+	return &SearchController{service: service, locales: locales}
+}
+
+// Search handles GET /api/search.
+//
+// Query parameters: q (required), lang, prefix (default true), fuzzy,
+// status, category_id, tag (slugs), page and page_size. Non-staff users
+// only search published posts in the negotiated locale and its fallbacks.
+func (sc *SearchController) Search(c *gin.Context) {
+	query := services.SearchQuery{
+		Query:    c.Query("q"),
+		Language: c.Query("lang"),
+		Status:   c.Query("status"),
+		TagSlugs: queryList(c, "tag"),
+		Prefix:   true,
+	}
+
+	var err error
+	if raw := c.Query("prefix"); raw != "" {
+		if query.Prefix, err = strconv.ParseBool(raw); err != nil {
+			utils.SendValidationError(c, gin.H{"prefix": "must be a boolean"})
+			return
+		}
+	}
+	if raw := c.Query("fuzzy"); raw != "" {
+		if query.Fuzzy, err = strconv.ParseBool(raw); err != nil {
+			utils.SendValidationError(c, gin.H{"fuzzy": "must be a boolean"})
+			return
+		}
+	}
+	if query.CategoryID, err = queryUint(c, "category_id"); err != nil {
+		utils.SendValidationError(c, gin.H{"category_id": err.Error()})
+		return
+	}
+	if query.Page, err = queryInt(c, "page"); err != nil {
+		utils.SendValidationError(c, gin.H{"page": err.Error()})
+		return
+	}
+	if query.PageSize, err = queryInt(c, "page_size"); err != nil {
+		utils.SendValidationError(c, gin.H{"page_size": err.Error()})
+		return
+	}
+
+	if !middleware.IsStaff(c) {
+		query.Status = models.PostStatusPublished
+	}
+	query.Locales = readerLocales(c, sc.locales)
+
+	response, pagination, err := sc.service.Search(c.Request.Context(), query)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendPaginated(c, "", response, pagination)
+}
+
+// Reindex handles POST /api/admin/search/reindex, queueing a rebuild of
+// the search index from the database.
+func (sc *SearchController) Reindex(c *gin.Context) {
+	job, err := sc.service.ScheduleReindex(c.Request.Context())
+	if errors.Is(err, jobs.ErrDuplicate) {
+		c.JSON(http.StatusAccepted, utils.JSONResponse{Success: true, Message: "A search index rebuild is already queued"})
+		return
+	}
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, utils.JSONResponse{Success: true, Message: "Search index rebuild queued", Data: job})
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// SEOController serves the computed metadata of posts and categories:
+// title, description, canonical URL, robots directives, hreflang
+// alternates, Open Graph and Twitter card tags, and JSON-LD, for the
+// frontend to render into page heads.
+type SEOController struct {
+	service *services.SEOService
+	posts   *services.PostService
+}
+
+func NewSEOController(service *services.SEOService, posts *services.PostService) *SEOController {
+	return &SEOController{service: service, posts: posts}
+}
+
+// PostMeta handles GET /api/posts/:id/seo. Like GetPost, unpublished posts
+// are only visible to their author and staff.
+func (sc *SEOController) PostMeta(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	post, err := sc.posts.GetPostByID(c.Request.Context(), id)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+	userID, _, _ := middleware.CurrentUser(c)
+	if !post.IsPublished() && post.AuthorID != userID && !middleware.IsStaff(c) {
+		utils.SendError(c, http.StatusNotFound, "post not found")
+		return
+	}
+
+	meta, err := sc.service.PostMeta(c.Request.Context(), post)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+	c.Header("Content-Language", post.Locale)
+	utils.SendSuccess(c, "", meta)
+}
+
+// CategoryMeta handles GET /api/categories/:id/seo for published
+// categories.
+func (sc *SEOController) CategoryMeta(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	meta, err := sc.service.CategoryMeta(c.Request.Context(), id)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+	utils.SendSuccess(c, "", meta)
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// SitemapController serves the sitemap index and the sitemaps it lists,
+// with conditional GET on their ETag and Last-Modified.
+type SitemapController struct {
+	service *services.SitemapService
+}
+
+func NewSitemapController(service *services.SitemapService) *SitemapController {
+	return &SitemapController{service: service}
+}
+
+// Index handles GET /sitemap.xml.
+func (sc *SitemapController) Index(c *gin.Context) {
+	doc, err := sc.service.Index(c.Request.Context())
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+	sendSitemap(c, doc)
+}
+
+// Shard handles GET /sitemaps/:name, where name is a section and shard
+// number such as posts-2.xml.
+func (sc *SitemapController) Shard(c *gin.Context) {
+	name, ok := strings.CutSuffix(c.Param("name"), ".xml")
+	dash := strings.LastIndexByte(name, '-')
+	if !ok || dash < 0 {
+		utils.SendError(c, http.StatusNotFound, "sitemap not found")
+		return
+	}
+	n, err := strconv.Atoi(name[dash+1:])
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, "sitemap not found")
+		return
+	}
+
+	doc, err := sc.service.Shard(c.Request.Context(), name[:dash], n)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+	sendSitemap(c, doc)
+}
+
+func sendSitemap(c *gin.Context, doc *services.SitemapDocument) {
+	c.Header("ETag", doc.ETag)
+	c.Header("Cache-Control", "public, max-age=3600")
+	if !doc.Modified.IsZero() {
+		c.Header("Last-Modified", doc.Modified.UTC().Format(http.TimeFormat))
+	}
+	if notModified(c, doc.ETag, doc.Modified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", doc.Body)
+}
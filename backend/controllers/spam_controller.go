@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// SpamController serves what public forms need for spam screening.
+type SpamController struct {
+	service *services.SpamService
+}
+
+func NewSpamController(service *services.SpamService) *SpamController {
+	return &SpamController{service: service}
+}
+
+// FormToken handles GET /api/spam/form-token.
+//
+// Forms fetch a token when shown and send it back as form_token, so the
+// speed check can tell how long filling them in took.
+func (sc *SpamController) FormToken(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	utils.SendSuccess(c, "", gin.H{"form_token": sc.service.IssueFormToken()})
+}
@@ -0,0 +1,374 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// TaxonomyController serves categories and tags and their translations.
+// Readers see published categories and active tags in the negotiated
+// locale; staff manage them in every locale.
+type TaxonomyController struct {
+	service *services.TaxonomyService
+}
+
+func NewTaxonomyController(service *services.TaxonomyService) *TaxonomyController {
+	return &TaxonomyController{service: service}
+}
+
+// CategoryRequest is a new category, or a new translation of one.
+type CategoryRequest struct {
+	Locale          string `json:"locale" binding:"max=10"`
+	Name            string `json:"name" binding:"required,min=3,max=255"`
+	Description     string `json:"description"`
+	Slug            string `json:"slug" binding:"max=300"`
+	Status          string `json:"status" binding:"omitempty,oneof=draft published archived"`
+	MetaTitle       string `json:"meta_title" binding:"max=255"`
+	MetaDescription string `json:"meta_description" binding:"max=500"`
+	FeaturedImage   string `json:"featured_image" binding:"max=512"`
+	ParentID        *uint  `json:"parent_id"`
+}
+
+func (r *CategoryRequest) category() *models.Category {
+	return &models.Category{
+		Locale:          r.Locale,
+		Name:            r.Name,
+		Description:     r.Description,
+		Slug:            r.Slug,
+		Status:          r.Status,
+		MetaTitle:       r.MetaTitle,
+		MetaDescription: r.MetaDescription,
+		FeaturedImage:   r.FeaturedImage,
+		ParentID:        r.ParentID,
+	}
+}
+
+// UpdateCategoryRequest changes the fields of a category that are set.
+type UpdateCategoryRequest struct {
+	Name            *string `json:"name" binding:"omitempty,min=3,max=255"`
+	Description     *string `json:"description"`
+	Slug            *string `json:"slug" binding:"omitempty,max=300"`
+	Status          *string `json:"status" binding:"omitempty,oneof=draft published archived"`
+	MetaTitle       *string `json:"meta_title" binding:"omitempty,max=255"`
+	MetaDescription *string `json:"meta_description" binding:"omitempty,max=500"`
+	FeaturedImage   *string `json:"featured_image" binding:"omitempty,max=512"`
+	ParentID        *uint   `json:"parent_id"`
+}
+
+func (r *UpdateCategoryRequest) updates() map[string]interface{} {
+	updates := map[string]interface{}{}
+	set := func(column string, value *string) {
+		if value != nil {
+			updates[column] = *value
+		}
+	}
+	set("name", r.Name)
+	set("description", r.Description)
+	set("slug", r.Slug)
+	set("status", r.Status)
+	set("meta_title", r.MetaTitle)
+	set("meta_description", r.MetaDescription)
+	set("featured_image", r.FeaturedImage)
+	if r.ParentID != nil {
+		updates["parent_id"] = *r.ParentID
+	}
+	return updates
+}
+
+// TagRequest is a new tag, or a new translation of one.
+type TagRequest struct {
+	Locale string `json:"locale" binding:"max=10"`
+	Name   string `json:"name" binding:"required,min=2,max=255"`
+	Slug   string `json:"slug" binding:"max=300"`
+	Status string `json:"status" binding:"omitempty,oneof=active archived"`
+}
+
+func (r *TagRequest) tag() *models.Tag {
+	return &models.Tag{Locale: r.Locale, Name: r.Name, Slug: r.Slug, Status: r.Status}
+}
+
+// UpdateTagRequest changes the fields of a tag that are set.
+type UpdateTagRequest struct {
+	Name   *string `json:"name" binding:"omitempty,min=2,max=255"`
+	Slug   *string `json:"slug" binding:"omitempty,max=300"`
+	Status *string `json:"status" binding:"omitempty,oneof=active archived"`
+}
+
+func (r *UpdateTagRequest) updates() map[string]interface{} {
+	updates := map[string]interface{}{}
+	for column, value := range map[string]*string{"name": r.Name, "slug": r.Slug, "status": r.Status} {
+		if value != nil {
+			updates[column] = *value
+		}
+	}
+	return updates
+}
+
+// ListCategories handles GET /api/categories. Readers get the published
+// categories, one variant of each translation group in the negotiated
+// locale or its fallbacks; staff get every category in every locale.
+func (tc *TaxonomyController) ListCategories(c *gin.Context) {
+	locales := readerLocales(c, tc.service.Locales())
+	categories, err := tc.service.ListCategories(c.Request.Context(), locales, !middleware.IsStaff(c))
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+	utils.SendSuccess(c, "", categories)
+}
+
+// GetCategoryBySlug handles GET /api/categories/slug/:slug, resolving the
+// slug in the negotiated locale like GetPostBySlug.
+func (tc *TaxonomyController) GetCategoryBySlug(c *gin.Context) {
+	locale, _ := middleware.RequestLocale(c)
+	chain := tc.service.Locales().Chain(locale)
+
+	category, err := tc.service.GetCategoryBySlug(c.Request.Context(), c.Param("slug"), chain, !middleware.IsStaff(c))
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.Header("Content-Language", category.Locale)
+	utils.SendSuccess(c, "", category)
+}
+
+// CreateCategory handles POST /api/categories for staff. The locale
+// defaults to the site's.
+func (tc *TaxonomyController) CreateCategory(c *gin.Context) {
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+
+	category := req.category()
+	if err := tc.service.CreateCategory(c.Request.Context(), category); err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.JSONResponse{Success: true, Message: "Category created", Data: category})
+}
+
+// UpdateCategory handles PUT /api/categories/:id for staff.
+func (tc *TaxonomyController) UpdateCategory(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+	updates := req.updates()
+	if len(updates) == 0 {
+		utils.SendValidationError(c, gin.H{"body": "no fields to update"})
+		return
+	}
+
+	category, err := tc.service.UpdateCategory(c.Request.Context(), id, updates)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "Category updated", category)
+}
+
+// CreateCategoryTranslation handles POST /api/categories/:id/translations
+// for staff, adding a translation of the category into a locale its group
+// has no variant in yet.
+func (tc *TaxonomyController) CreateCategoryTranslation(c *gin.Context) {
+	sourceID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+
+	category := req.category()
+	if err := tc.service.CreateCategoryTranslation(c.Request.Context(), sourceID, category); err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.JSONResponse{Success: true, Message: "Translation created", Data: category})
+}
+
+// ListCategoryTranslations handles GET /api/categories/:id/translations
+// for staff: the state of the category's translation into every supported
+// locale, missing, outdated (the original changed since) or current.
+func (tc *TaxonomyController) ListCategoryTranslations(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	translations, err := tc.service.ListCategoryTranslations(c.Request.Context(), id)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "", translations)
+}
+
+// ListCategoryTranslationTasks handles GET /api/categories/translations
+// for staff, the original categories whose translation into a locale is
+// missing or outdated.
+//
+// Query parameters: locale (required), state (missing or outdated), page
+// and page_size.
+func (tc *TaxonomyController) ListCategoryTranslationTasks(c *gin.Context) {
+	tc.listTranslationTasks(c, tc.service.ListCategoryTranslationTasks)
+}
+
+// ListTags handles GET /api/tags. Readers get the active tags, one variant
+// of each translation group in the negotiated locale or its fallbacks;
+// staff get every tag in every locale.
+func (tc *TaxonomyController) ListTags(c *gin.Context) {
+	locales := readerLocales(c, tc.service.Locales())
+	tags, err := tc.service.ListTags(c.Request.Context(), locales, !middleware.IsStaff(c))
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+	utils.SendSuccess(c, "", tags)
+}
+
+// GetTagBySlug handles GET /api/tags/slug/:slug, resolving the slug in
+// the negotiated locale like GetPostBySlug.
+func (tc *TaxonomyController) GetTagBySlug(c *gin.Context) {
+	locale, _ := middleware.RequestLocale(c)
+	chain := tc.service.Locales().Chain(locale)
+
+	tag, err := tc.service.GetTagBySlug(c.Request.Context(), c.Param("slug"), chain, !middleware.IsStaff(c))
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.Header("Content-Language", tag.Locale)
+	utils.SendSuccess(c, "", tag)
+}
+
+// CreateTag handles POST /api/tags for staff. The locale defaults to the
+// site's.
+func (tc *TaxonomyController) CreateTag(c *gin.Context) {
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+
+	tag := req.tag()
+	if err := tc.service.CreateTag(c.Request.Context(), tag); err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.JSONResponse{Success: true, Message: "Tag created", Data: tag})
+}
+
+// UpdateTag handles PUT /api/tags/:id for staff.
+func (tc *TaxonomyController) UpdateTag(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req UpdateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+	updates := req.updates()
+	if len(updates) == 0 {
+		utils.SendValidationError(c, gin.H{"body": "no fields to update"})
+		return
+	}
+
+	tag, err := tc.service.UpdateTag(c.Request.Context(), id, updates)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "Tag updated", tag)
+}
+
+// CreateTagTranslation handles POST /api/tags/:id/translations for staff.
+func (tc *TaxonomyController) CreateTagTranslation(c *gin.Context) {
+	sourceID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, gin.H{"body": err.Error()})
+		return
+	}
+
+	tag := req.tag()
+	if err := tc.service.CreateTagTranslation(c.Request.Context(), sourceID, tag); err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.JSONResponse{Success: true, Message: "Translation created", Data: tag})
+}
+
+// ListTagTranslations handles GET /api/tags/:id/translations for staff,
+// like ListCategoryTranslations.
+func (tc *TaxonomyController) ListTagTranslations(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	translations, err := tc.service.ListTagTranslations(c.Request.Context(), id)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, "", translations)
+}
+
+// ListTagTranslationTasks handles GET /api/tags/translations for staff,
+// with the query parameters of ListCategoryTranslationTasks.
+func (tc *TaxonomyController) ListTagTranslationTasks(c *gin.Context) {
+	tc.listTranslationTasks(c, tc.service.ListTagTranslationTasks)
+}
+
+type listTermTasks func(ctx context.Context, locale, state string, page, pageSize int) ([]services.TermTranslationTask, *utils.Pagination, error)
+
+func (tc *TaxonomyController) listTranslationTasks(c *gin.Context, list listTermTasks) {
+	locale, ok := tc.service.Locales().Match(c.Query("locale"))
+	if !ok {
+		utils.SendValidationError(c, gin.H{"locale": "must be a supported locale"})
+		return
+	}
+	page, pageSize, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	tasks, pagination, err := list(c.Request.Context(), locale, c.Query("state"), page, pageSize)
+	if err != nil {
+		sendServiceError(c, err)
+		return
+	}
+
+	utils.SendPaginated(c, "", tasks, pagination)
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Event is something that happened in the application. Name identifies the
+// event type subscribers listen for.
+type Event interface {
+	Name() string
+}
+
+type handler func(ctx context.Context, e Event) error
+
+// Bus delivers events to the handlers subscribed to them. Handlers run
+// synchronously in the publisher's goroutine, in subscription order; a
+// failing handler is logged and doesn't stop the others. Handlers with
+// slow work should hand it to the job queue.
+//
+// A nil *Bus drops every event, so publishers need no checks.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: map[string][]handler{}}
+}
+
+// Subscribe calls fn for every published event of type T.
+func Subscribe[T Event](b *Bus, fn func(ctx context.Context, e T) error) {
+	var zero T
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[zero.Name()] = append(b.handlers[zero.Name()], func(ctx context.Context, e Event) error {
+		return fn(ctx, e.(T))
+	})
+}
+
+// Publish delivers e to its subscribers and returns once all have run.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers[e.Name()]
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		if err := call(ctx, h, e); err != nil {
+			slog.ErrorContext(ctx, "event handler failed", slog.String("event", e.Name()), slog.String("error", err.Error()))
+		}
+	}
+}
+
+func call(ctx context.Context, h handler, e Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return h(ctx, e)
+}
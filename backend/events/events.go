@@ -0,0 +1,49 @@
+package events
+
+// PostApproved is published when staff publish another user's post.
+type PostApproved struct {
+	PostID   uint
+	AuthorID uint
+	ActorID  uint
+	Title    string
+}
+
+func (PostApproved) Name() string { return "post.approved" }
+
+// PostRejected is published when staff reject a post.
+type PostRejected struct {
+	PostID   uint
+	AuthorID uint
+	ActorID  uint
+	Title    string
+	Reason   string
+}
+
+func (PostRejected) Name() string { return "post.rejected" }
+
+// PostCommented is published when someone comments on a post.
+type PostCommented struct {
+	PostID    uint
+	AuthorID  uint // the post's author
+	CommentID uint
+	ActorID   uint   // the commenter; zero for guests
+	ActorName string // the commenter's username or guest name
+	Title     string
+	Excerpt   string
+}
+
+func (PostCommented) Name() string { return "post.commented" }
+
+// Mentioned is published when published text mentions users by @username.
+// CommentID is zero for mentions in the post itself.
+type Mentioned struct {
+	Usernames []string
+	ActorID   uint   // zero for guests
+	ActorName string // username or guest name; looked up from ActorID when empty
+	PostID    uint
+	CommentID uint
+	Title     string
+	Excerpt   string
+}
+
+func (Mentioned) Name() string { return "user.mentioned" }
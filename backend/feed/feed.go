@@ -0,0 +1,69 @@
+// Package feed writes syndication feeds in the RSS 2.0, Atom and JSON Feed
+// 1.1 formats. Item content is HTML and is escaped as each format
+// requires, so feed readers get back exactly the markup given.
+package feed
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Formats a feed can be written in
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+	FormatJSON = "json"
+)
+
+// Feed is a list of items, newest first.
+type Feed struct {
+	Title       string
+	Description string
+	// Link is the page the feed syndicates; URL is the feed's own address
+	Link     string
+	URL      string
+	Language string
+	// Updated is when an item last changed
+	Updated time.Time
+	Items   []Item
+}
+
+// Item is an entry of a feed. Content is HTML; Summary is plain text.
+type Item struct {
+	ID         string
+	Title      string
+	Link       string
+	Summary    string
+	Content    string
+	Author     string
+	Categories []string
+	Published  time.Time
+	Updated    time.Time
+}
+
+// ContentType returns the media type of format.
+func ContentType(format string) string {
+	switch format {
+	case FormatRSS:
+		return "application/rss+xml; charset=utf-8"
+	case FormatAtom:
+		return "application/atom+xml; charset=utf-8"
+	case FormatJSON:
+		return "application/feed+json; charset=utf-8"
+	}
+	return ""
+}
+
+// Write writes f to w in format.
+func Write(w io.Writer, f *Feed, format string) error {
+	switch format {
+	case FormatRSS:
+		return WriteRSS(w, f)
+	case FormatAtom:
+		return WriteAtom(w, f)
+	case FormatJSON:
+		return WriteJSON(w, f)
+	}
+	return fmt.Errorf("unknown feed format %q", format)
+}
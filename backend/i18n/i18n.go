@@ -0,0 +1,200 @@
+// Package i18n negotiates the locale content is served in. A site has a
+// default locale and a list of supported ones; requests ask for a locale
+// with ?lang= or Accept-Language, and content missing in that locale is
+// looked up along a fallback chain ending in the default.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// Normalize lowercases a language tag and uses "-" as its separator, so
+// "fa_IR" becomes "fa-ir". It returns "" for malformed tags.
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if !localePattern.MatchString(tag) {
+		return ""
+	}
+	return tag
+}
+
+// base is the language of a tag without its region or script.
+func base(tag string) string {
+	lang, _, _ := strings.Cut(tag, "-")
+	return lang
+}
+
+// Locales is the set of locales a site serves content in.
+type Locales struct {
+	def       string
+	supported []string
+	fallbacks map[string][]string
+}
+
+// New returns the locales of a site whose content defaults to def.
+// supported lists the other locales content may be written in; fallbacks
+// maps a locale to those tried, in order, before def when content is
+// missing in it.
+func New(def string, supported []string, fallbacks map[string][]string) (*Locales, error) {
+	l := &Locales{def: Normalize(def), fallbacks: map[string][]string{}}
+	if l.def == "" {
+		return nil, fmt.Errorf("invalid default locale %q", def)
+	}
+	l.supported = []string{l.def}
+	for _, tag := range supported {
+		locale := Normalize(tag)
+		if locale == "" {
+			return nil, fmt.Errorf("invalid locale %q", tag)
+		}
+		if !l.IsSupported(locale) {
+			l.supported = append(l.supported, locale)
+		}
+	}
+	for tag, chain := range fallbacks {
+		locale := Normalize(tag)
+		if !l.IsSupported(locale) {
+			return nil, fmt.Errorf("fallbacks given for unsupported locale %q", tag)
+		}
+		for _, fallback := range chain {
+			if !l.IsSupported(Normalize(fallback)) {
+				return nil, fmt.Errorf("unsupported fallback locale %q for %s", fallback, locale)
+			}
+			l.fallbacks[locale] = append(l.fallbacks[locale], Normalize(fallback))
+		}
+	}
+	return l, nil
+}
+
+// Single returns locales supporting only locale, which must be a valid
+// language tag.
+func Single(locale string) *Locales {
+	l, err := New(locale, nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// Default is the locale content is written in unless stated otherwise.
+func (l *Locales) Default() string {
+	return l.def
+}
+
+// Supported lists every supported locale, the default first.
+func (l *Locales) Supported() []string {
+	return append([]string(nil), l.supported...)
+}
+
+// IsSupported reports whether locale is supported.
+func (l *Locales) IsSupported(locale string) bool {
+	for _, s := range l.supported {
+		if s == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns the supported locale for a language tag: the tag itself,
+// or its base language ("fa-IR" matches "fa").
+func (l *Locales) Match(tag string) (string, bool) {
+	tag = Normalize(tag)
+	if tag == "" {
+		return "", false
+	}
+	if l.IsSupported(tag) {
+		return tag, true
+	}
+	if lang := base(tag); l.IsSupported(lang) {
+		return lang, true
+	}
+	return "", false
+}
+
+// Negotiate picks the locale of a request from its ?lang= parameter, then
+// its Accept-Language header, falling back to the default.
+func (l *Locales) Negotiate(lang, acceptLanguage string) string {
+	if locale, ok := l.Match(lang); ok {
+		return locale
+	}
+	for _, tag := range ParseAcceptLanguage(acceptLanguage) {
+		if locale, ok := l.Match(tag); ok {
+			return locale
+		}
+	}
+	return l.def
+}
+
+// Chain lists the locales to look for content in when locale is asked
+// for: locale, its configured fallbacks and the default.
+func (l *Locales) Chain(locale string) []string {
+	chain := []string{locale}
+	add := func(next string) {
+		for _, c := range chain {
+			if c == next {
+				return
+			}
+		}
+		chain = append(chain, next)
+	}
+	for _, fallback := range l.fallbacks[locale] {
+		add(fallback)
+	}
+	add(l.def)
+	return chain
+}
+
+// ParseAcceptLanguage returns the language tags of an Accept-Language
+// header by preference. Tags with q=0 and the wildcard are left out.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
+}
+
+type localeKey struct{}
+
+// WithLocale returns a copy of ctx carrying the request's locale.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// FromContext returns the locale stored by WithLocale.
+func FromContext(ctx context.Context) (string, bool) {
+	locale, ok := ctx.Value(localeKey{}).(string)
+	return locale, ok && locale != ""
+}
@@ -0,0 +1,58 @@
+package jalali
+
+import (
+	"strconv"
+	"strings"
+)
+
+var monthNames = [12]string{"Farvardin", "Ordibehesht", "Khordad", "Tir", "Mordad", "Shahrivar",
+	"Mehr", "Aban", "Azar", "Dey", "Bahman", "Esfand"}
+
+var persianMonthNames = [12]string{"فروردین", "اردیبهشت", "خرداد", "تیر", "مرداد", "شهریور",
+	"مهر", "آبان", "آذر", "دی", "بهمن", "اسفند"}
+
+// MonthName returns the name of month, in Persian script when persian is
+// set and transliterated otherwise.
+func MonthName(month int, persian bool) string {
+	if month < 1 || month > 12 {
+		return ""
+	}
+	if persian {
+		return persianMonthNames[month-1]
+	}
+	return monthNames[month-1]
+}
+
+// Long formats d with its month name: "12 Mordad 1403", or "۱۲ مرداد ۱۴۰۳"
+// when persian is set.
+func (d Date) Long(persian bool) string {
+	s := strconv.Itoa(d.Day) + " " + MonthName(d.Month, persian) + " " + strconv.Itoa(d.Year)
+	if persian {
+		return PersianDigits(s)
+	}
+	return s
+}
+
+// PersianDigits replaces the Latin digits of s with Persian ones.
+func PersianDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return '۰' + r - '0'
+		}
+		return r
+	}, s)
+}
+
+// LatinDigits replaces the Persian and Arabic-Indic digits of s with Latin
+// ones.
+func LatinDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '۰' && r <= '۹':
+			return '0' + r - '۰'
+		case r >= '٠' && r <= '٩':
+			return '0' + r - '٠'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,161 @@
+// Package jalali converts between the Gregorian and the Jalali (Solar
+// Hijri) calendar used in Iran and Afghanistan. Leap years follow the
+// astronomical calendar through the break years of Borkowski's
+// algorithm, so conversions are exact for years 1 to 3177.
+package jalali
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinYear and MaxYear bound the years conversions are exact for
+const (
+	MinYear = 1
+	MaxYear = 3177
+)
+
+// Date is a day of the Jalali calendar. Months run from 1 (Farvardin) to
+// 12 (Esfand).
+type Date struct {
+	Year  int
+	Month int
+	Day   int
+}
+
+// breaks are the Jalali years the 33-year leap cycle restarts in.
+var breaks = [...]int{-61, 9, 38, 199, 426, 686, 756, 818, 1111, 1181, 1210,
+	1635, 2060, 2097, 2192, 2262, 2324, 2394, 2456, 3178}
+
+// newYear returns the day in March of the Gregorian year Jalali year
+// starts in, and whether year is a leap year.
+func newYear(year int) (march int, leap bool) {
+	leapJ := -14
+	jp := breaks[0]
+	jump := 0
+	for _, jm := range breaks[1:] {
+		jump = jm - jp
+		if year < jm {
+			break
+		}
+		leapJ += jump/33*8 + jump%33/4
+		jp = jm
+	}
+	n := year - jp
+	leapJ += n/33*8 + (n%33+3)/4
+	if jump%33 == 4 && jump-n == 4 {
+		leapJ++
+	}
+
+	gy := year + 621
+	leapG := gy/4 - (gy/100+1)*3/4 - 150
+	march = 20 + leapJ - leapG
+
+	if jump-n < 6 {
+		n = n - jump + (jump+4)/33*33
+	}
+	cycle := ((n+1)%33 - 1) % 4
+	return march, cycle == 0
+}
+
+// IsLeap reports whether year has 366 days, Esfand then having 30.
+func IsLeap(year int) bool {
+	_, leap := newYear(year)
+	return leap
+}
+
+// DaysIn returns the number of days of month in year.
+func DaysIn(year, month int) int {
+	switch {
+	case month <= 6:
+		return 31
+	case month <= 11:
+		return 30
+	case IsLeap(year):
+		return 30
+	default:
+		return 29
+	}
+}
+
+// New returns the date year/month/day, checking that it exists.
+func New(year, month, day int) (Date, error) {
+	if year < MinYear || year > MaxYear {
+		return Date{}, fmt.Errorf("year %d out of range", year)
+	}
+	if month < 1 || month > 12 {
+		return Date{}, fmt.Errorf("month %d out of range", month)
+	}
+	if day < 1 || day > DaysIn(year, month) {
+		return Date{}, fmt.Errorf("day %d out of range for %d/%d", day, year, month)
+	}
+	return Date{Year: year, Month: month, Day: day}, nil
+}
+
+// FromTime returns the Jalali date of t in t's location.
+func FromTime(t time.Time) Date {
+	gy, gm, gd := t.Date()
+	day := time.Date(gy, gm, gd, 0, 0, 0, 0, time.UTC)
+
+	year := gy - 621
+	march, _ := newYear(year)
+	k := int(day.Sub(time.Date(gy, time.March, march, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+	if k < 0 {
+		year--
+		k += 365
+		if IsLeap(year) {
+			k++
+		}
+	}
+	if k < 186 {
+		return Date{Year: year, Month: 1 + k/31, Day: 1 + k%31}
+	}
+	k -= 186
+	return Date{Year: year, Month: 7 + k/30, Day: 1 + k%30}
+}
+
+// Time returns midnight of d in loc.
+func (d Date) Time(loc *time.Location) time.Time {
+	march, _ := newYear(d.Year)
+	offset := (d.Month-1)*31 - d.Month/7*(d.Month-7) + d.Day - 1
+	return time.Date(d.Year+621, time.March, march+offset, 0, 0, 0, 0, loc)
+}
+
+// AddMonths returns the first day of the month months after d's.
+func (d Date) AddMonths(months int) Date {
+	index := d.Year*12 + d.Month - 1 + months
+	return Date{Year: index / 12, Month: index%12 + 1, Day: 1}
+}
+
+// String formats d as 1403/05/12.
+func (d Date) String() string {
+	return fmt.Sprintf("%04d/%02d/%02d", d.Year, d.Month, d.Day)
+}
+
+// MonthStart returns midnight in loc of the first day of a Jalali month.
+func MonthStart(year, month int, loc *time.Location) time.Time {
+	return Date{Year: year, Month: month, Day: 1}.Time(loc)
+}
+
+// Parse reads a date written year/month/day with "/", "-" or "." between
+// the parts, in Latin, Persian or Arabic-Indic digits: "1403/5/12",
+// "1403-05-12" and "۱۴۰۳/۰۵/۱۲" are the same day.
+func Parse(s string) (Date, error) {
+	s = LatinDigits(strings.TrimSpace(s))
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '/' || r == '-' || r == '.' })
+	if len(parts) != 3 {
+		return Date{}, errors.New("date must be written year/month/day")
+	}
+	var nums [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return Date{}, fmt.Errorf("invalid number %q", part)
+		}
+		nums[i] = n
+	}
+	return New(nums[0], nums[1], nums[2])
+}
@@ -0,0 +1,157 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/utils"
+	"gorm.io/gorm"
+)
+
+// QueueStats counts the jobs of one queue by state.
+type QueueStats struct {
+	Queue   string `json:"queue"`
+	Pending int64  `json:"pending"`
+	Running int64  `json:"running"`
+	Dead    int64  `json:"dead"`
+}
+
+// Stats returns job counts for every queue that has jobs.
+func (q *Queue) Stats(ctx context.Context) ([]QueueStats, error) {
+	db := q.db.WithContext(ctx)
+
+	var rows []struct {
+		Queue  string
+		Status string
+		Count  int64
+	}
+	if err := db.Model(&models.Job{}).Select("queue, status, COUNT(*) AS count").Group("queue, status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	var dead []struct {
+		Queue string
+		Count int64
+	}
+	if err := db.Model(&models.DeadJob{}).Select("queue, COUNT(*) AS count").Group("queue").Scan(&dead).Error; err != nil {
+		return nil, err
+	}
+
+	byQueue := map[string]*QueueStats{}
+	get := func(name string) *QueueStats {
+		if byQueue[name] == nil {
+			byQueue[name] = &QueueStats{Queue: name}
+		}
+		return byQueue[name]
+	}
+	for _, r := range rows {
+		switch r.Status {
+		case models.JobStatusPending:
+			get(r.Queue).Pending = r.Count
+		case models.JobStatusRunning:
+			get(r.Queue).Running = r.Count
+		}
+	}
+	for _, d := range dead {
+		get(d.Queue).Dead = d.Count
+	}
+
+	stats := make([]QueueStats, 0, len(byQueue))
+	for _, s := range byQueue {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Queue < stats[j].Queue })
+	return stats, nil
+}
+
+// ListJobs returns a page of pending and running jobs, optionally filtered
+// by queue and status, soonest first.
+func (q *Queue) ListJobs(ctx context.Context, queue, status string, page, pageSize int) ([]models.Job, *utils.Pagination, error) {
+	query := q.db.WithContext(ctx).Model(&models.Job{})
+	if queue != "" {
+		query = query.Where("queue = ?", queue)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var jobs []models.Job
+	pagination, err := paginate(query, page, pageSize, "run_at, id", &jobs)
+	return jobs, pagination, err
+}
+
+// ListDeadJobs returns a page of dead jobs, most recent failures first.
+func (q *Queue) ListDeadJobs(ctx context.Context, queue string, page, pageSize int) ([]models.DeadJob, *utils.Pagination, error) {
+	query := q.db.WithContext(ctx).Model(&models.DeadJob{})
+	if queue != "" {
+		query = query.Where("queue = ?", queue)
+	}
+
+	var jobs []models.DeadJob
+	pagination, err := paginate(query, page, pageSize, "failed_at DESC, id DESC", &jobs)
+	return jobs, pagination, err
+}
+
+// RetryDeadJob moves a dead job back to its queue with fresh attempts. It
+// returns ErrDuplicate if a job with the same unique key is queued already.
+func (q *Queue) RetryDeadJob(ctx context.Context, id uint) (*models.Job, error) {
+	var job *models.Job
+	err := q.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var dead models.DeadJob
+		if err := tx.First(&dead, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("dead job not found")
+			}
+			return err
+		}
+
+		job = &models.Job{
+			Queue:       dead.Queue,
+			Kind:        dead.Kind,
+			Payload:     dead.Payload,
+			Status:      models.JobStatusPending,
+			RunAt:       q.clock.Now(),
+			MaxAttempts: q.config.MaxAttempts,
+			UniqueKey:   dead.UniqueKey,
+		}
+		result := tx.Clauses(skipPendingDuplicate).Create(job)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrDuplicate
+		}
+		return tx.Delete(&dead).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// DeleteDeadJob discards a dead job.
+func (q *Queue) DeleteDeadJob(ctx context.Context, id uint) error {
+	result := q.db.WithContext(ctx).Delete(&models.DeadJob{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("dead job not found")
+	}
+	return nil
+}
+
+func paginate(query *gorm.DB, page, pageSize int, order string, dest interface{}) (*utils.Pagination, error) {
+	page, pageSize = utils.NormalizePage(page, pageSize)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	if err := query.Order(order).Offset((page - 1) * pageSize).Limit(pageSize).Find(dest).Error; err != nil {
+		return nil, err
+	}
+
+	return utils.NewPagination(page, pageSize, total), nil
+}
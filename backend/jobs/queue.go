@@ -0,0 +1,199 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultQueue runs jobs enqueued without OnQueue.
+const DefaultQueue = "default"
+
+// ErrDuplicate is returned by Enqueue when a job with the same unique key is
+// already pending.
+var ErrDuplicate = errors.New("duplicate job")
+
+// skipPendingDuplicate inserts nothing when a pending job has the same
+// unique key. The predicate is a literal so PostgreSQL matches it to the
+// partial unique index.
+var skipPendingDuplicate = clause.OnConflict{
+	Columns:     []clause.Column{{Name: "unique_key"}},
+	TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "status = '" + models.JobStatusPending + "'"}}},
+	DoNothing:   true,
+}
+
+// Args is a job payload. Kind names the handler that runs it and must stay
+// stable across deployments, since it is stored with the job.
+type Args interface {
+	Kind() string
+}
+
+// Config configures a Queue.
+type Config struct {
+	// Concurrency maps each queue name to the number of jobs one instance
+	// runs from it at a time. Only listed queues are worked.
+	Concurrency map[string]int
+	// PollInterval is how long an idle worker waits before checking again.
+	PollInterval time.Duration
+	// RescueAfter returns jobs left running longer than this, for instance
+	// by a crashed instance, to the queue.
+	RescueAfter time.Duration
+	// MaxAttempts is the default number of attempts per job.
+	MaxAttempts int
+}
+
+// Queue is a durable job queue stored in PostgreSQL. Any number of
+// instances may work it; jobs are claimed with FOR UPDATE SKIP LOCKED.
+type Queue struct {
+	db       *gorm.DB
+	clock    utils.Clock
+	config   Config
+	workerID string
+
+	mu       sync.RWMutex
+	handlers map[string]handler
+}
+
+type handler func(ctx context.Context, payload json.RawMessage) error
+
+func New(db *gorm.DB, clock utils.Clock, config Config) *Queue {
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.RescueAfter <= 0 {
+		config.RescueAfter = 15 * time.Minute
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.Concurrency == nil {
+		config.Concurrency = map[string]int{DefaultQueue: 1}
+	}
+
+	host, _ := os.Hostname()
+	return &Queue{
+		db:       db,
+		clock:    clock,
+		config:   config,
+		workerID: fmt.Sprintf("%s:%d", host, os.Getpid()),
+		handlers: map[string]handler{},
+	}
+}
+
+// Register sets the handler for jobs of kind T. The payload is decoded into
+// T before fn runs; returning an error schedules a retry.
+func Register[T Args](q *Queue, fn func(ctx context.Context, args T) error) {
+	var zero T
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[zero.Kind()] = func(ctx context.Context, payload json.RawMessage) error {
+		var args T
+		if err := json.Unmarshal(payload, &args); err != nil {
+			return fmt.Errorf("decode %s payload: %w", zero.Kind(), err)
+		}
+		return fn(ctx, args)
+	}
+}
+
+// EnqueueOption customizes a job when it is enqueued.
+type EnqueueOption func(*models.Job)
+
+// OnQueue puts the job on the named queue instead of DefaultQueue.
+func OnQueue(name string) EnqueueOption {
+	return func(j *models.Job) {
+		j.Queue = name
+	}
+}
+
+// RunAt delays the job until t.
+func RunAt(t time.Time) EnqueueOption {
+	return func(j *models.Job) {
+		j.RunAt = t
+	}
+}
+
+// MaxAttempts overrides the number of attempts before the job is dead.
+func MaxAttempts(n int) EnqueueOption {
+	return func(j *models.Job) {
+		j.MaxAttempts = n
+	}
+}
+
+// Unique skips the job if another with key is pending. A running job
+// with key doesn't count, as it may have started before the work this job
+// is for.
+func Unique(key string) EnqueueOption {
+	return func(j *models.Job) {
+		j.UniqueKey = &key
+	}
+}
+
+// Enqueue stores a job for args. With Unique it returns ErrDuplicate when
+// an equivalent job is already queued.
+func (q *Queue) Enqueue(ctx context.Context, args Args, opts ...EnqueueOption) (*models.Job, error) {
+	return q.enqueue(q.db.WithContext(ctx), args, opts...)
+}
+
+// EnqueueTx enqueues within tx, so the job only exists if tx commits.
+func (q *Queue) EnqueueTx(tx *gorm.DB, args Args, opts ...EnqueueOption) (*models.Job, error) {
+	return q.enqueue(tx, args, opts...)
+}
+
+func (q *Queue) enqueue(db *gorm.DB, args Args, opts ...EnqueueOption) (*models.Job, error) {
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s payload: %w", args.Kind(), err)
+	}
+
+	job := &models.Job{
+		Queue:       DefaultQueue,
+		Kind:        args.Kind(),
+		Payload:     payload,
+		Status:      models.JobStatusPending,
+		RunAt:       q.clock.Now(),
+		MaxAttempts: q.config.MaxAttempts,
+	}
+	for _, opt := range opts {
+		opt(job)
+	}
+
+	result := db.Clauses(skipPendingDuplicate).Create(job)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrDuplicate
+	}
+
+	slog.DebugContext(db.Statement.Context, "job enqueued",
+		slog.Uint64("job_id", uint64(job.ID)), slog.String("kind", job.Kind), slog.String("queue", job.Queue))
+	return job, nil
+}
+
+// Perform runs the handler for job without touching the database. Workers
+// use it and tests can call it directly. Panics are returned as errors.
+func (q *Queue) Perform(ctx context.Context, job *models.Job) (err error) {
+	q.mu.RLock()
+	h, ok := q.handlers[job.Kind]
+	q.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no handler registered for job kind %q", job.Kind)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return h(ctx, job.Payload)
+}
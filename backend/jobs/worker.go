@@ -0,0 +1,267 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/sasanzare/go-cms/metrics"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// Retry delays grow exponentially from backoffBase up to backoffMax.
+const (
+	backoffBase = 10 * time.Second
+	backoffMax  = time.Hour
+)
+
+// Backoff returns the delay before retrying a job that failed its
+// attempt-th attempt: exponential growth with up to 20% jitter so retries
+// of jobs that failed together spread out.
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := backoffMax
+	if attempt <= 20 {
+		delay = min(backoffBase<<(attempt-1), backoffMax)
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+// Run works every configured queue until ctx is cancelled, then waits for
+// running jobs to finish.
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for name, concurrency := range q.config.Concurrency {
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func(queue string) {
+				defer wg.Done()
+				q.work(ctx, queue)
+			}(name)
+		}
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		q.rescueLoop(ctx)
+	}()
+
+	slog.Info("job queue started", slog.Any("concurrency", q.config.Concurrency))
+	wg.Wait()
+	slog.Info("job queue stopped")
+}
+
+// work runs jobs from queue one at a time, sleeping when none are ready.
+func (q *Queue) work(ctx context.Context, queue string) {
+	for ctx.Err() == nil {
+		job, err := q.claim(ctx, queue)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "failed to claim job", slog.String("queue", queue), slog.String("error", err.Error()))
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(q.config.PollInterval):
+			}
+			continue
+		}
+
+		// Let the job finish even if shutdown starts meanwhile
+		q.execute(context.WithoutCancel(ctx), job)
+	}
+}
+
+// RunNext claims the next ready job on queue and runs it as a worker
+// would, reporting whether there was one. Tests use it to step through a
+// queue without starting workers.
+func (q *Queue) RunNext(ctx context.Context, queue string) (bool, error) {
+	job, err := q.claim(ctx, queue)
+	if err != nil || job == nil {
+		return false, err
+	}
+	q.execute(ctx, job)
+	return true, nil
+}
+
+// claim locks the next ready job on queue and marks it running.
+func (q *Queue) claim(ctx context.Context, queue string) (*models.Job, error) {
+	now := q.clock.Now()
+	var jobs []models.Job
+	err := q.db.WithContext(ctx).Raw(`UPDATE jobs
+		SET status = ?, attempts = attempts + 1, locked_at = ?, locked_by = ?, updated_at = ?
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE queue = ? AND status = ? AND run_at <= ?
+			ORDER BY run_at, id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING *`,
+		models.JobStatusRunning, now, q.workerID, now,
+		queue, models.JobStatusPending, now,
+	).Scan(&jobs).Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+func (q *Queue) execute(ctx context.Context, job *models.Job) {
+	ctx, span := tracing.Start(ctx, "jobs."+job.Kind,
+		attribute.Int("job.id", int(job.ID)),
+		attribute.String("job.queue", job.Queue),
+		attribute.Int("job.attempt", job.Attempts))
+	defer span.End()
+
+	stopHeartbeat := q.heartbeat(ctx, job)
+	start := time.Now()
+	err := q.Perform(ctx, job)
+	stopHeartbeat()
+	logger := slog.With(
+		slog.Uint64("job_id", uint64(job.ID)),
+		slog.String("kind", job.Kind),
+		slog.Int("attempt", job.Attempts),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	if err == nil {
+		metrics.JobsProcessed.WithLabelValues(job.Queue, job.Kind, "success").Inc()
+		logger.InfoContext(ctx, "job completed")
+		if err := q.db.WithContext(ctx).Delete(&models.Job{}, job.ID).Error; err != nil {
+			logger.ErrorContext(ctx, "failed to delete completed job", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	span.RecordError(err)
+	if job.Attempts >= job.MaxAttempts {
+		metrics.JobsProcessed.WithLabelValues(job.Queue, job.Kind, "dead").Inc()
+		logger.ErrorContext(ctx, "job failed permanently", slog.String("error", err.Error()))
+		if err := q.bury(ctx, job, err); err != nil {
+			logger.ErrorContext(ctx, "failed to move job to dead jobs", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	retryAt := q.clock.Now().Add(Backoff(job.Attempts))
+	metrics.JobsProcessed.WithLabelValues(job.Queue, job.Kind, "retry").Inc()
+	logger.WarnContext(ctx, "job failed, will retry", slog.String("error", err.Error()), slog.Time("retry_at", retryAt))
+	result := q.db.WithContext(ctx).Model(&models.Job{}).Where("id = ?", job.ID).Where(notSuperseded).Updates(map[string]interface{}{
+		"status":     models.JobStatusPending,
+		"run_at":     retryAt,
+		"last_error": err.Error(),
+		"locked_at":  nil,
+		"locked_by":  "",
+	})
+	switch {
+	case result.Error != nil:
+		logger.ErrorContext(ctx, "failed to reschedule job", slog.String("error", result.Error.Error()))
+	case result.RowsAffected == 0:
+		// A job with the same unique key was queued meanwhile and does
+		// this one's work
+		logger.InfoContext(ctx, "failed job superseded by a pending one")
+		if err := q.db.WithContext(ctx).Delete(&models.Job{}, job.ID).Error; err != nil {
+			logger.ErrorContext(ctx, "failed to delete superseded job", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// notSuperseded matches the jobs that can return to pending: those
+// without a pending twin holding their unique key.
+const notSuperseded = `(unique_key IS NULL OR NOT EXISTS (
+	SELECT 1 FROM jobs twin WHERE twin.unique_key = jobs.unique_key AND twin.status = 'pending'))`
+
+// heartbeat renews job's lock while it runs, so rescueLoop only returns
+// jobs whose worker stopped, not slow ones. The returned func stops it.
+func (q *Queue) heartbeat(ctx context.Context, job *models.Job) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(q.config.RescueAfter / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			err := q.db.WithContext(ctx).Model(&models.Job{}).
+				Where("id = ? AND status = ? AND locked_by = ?", job.ID, models.JobStatusRunning, q.workerID).
+				Update("locked_at", q.clock.Now()).Error
+			if err != nil && ctx.Err() == nil {
+				slog.WarnContext(ctx, "failed to renew job lock", slog.Uint64("job_id", uint64(job.ID)), slog.String("error", err.Error()))
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// bury moves a job that used up its attempts to the dead jobs table.
+func (q *Queue) bury(ctx context.Context, job *models.Job, cause error) error {
+	return q.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		dead := &models.DeadJob{
+			JobID:      job.ID,
+			Queue:      job.Queue,
+			Kind:       job.Kind,
+			Payload:    job.Payload,
+			Attempts:   job.Attempts,
+			UniqueKey:  job.UniqueKey,
+			LastError:  cause.Error(),
+			EnqueuedAt: job.CreatedAt,
+			FailedAt:   q.clock.Now(),
+		}
+		if err := tx.Create(dead).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Job{}, job.ID).Error
+	})
+}
+
+// rescueLoop periodically returns jobs whose worker stopped renewing
+// their lock to the queue. Those superseded by a pending job with the same
+// unique key are deleted instead.
+func (q *Queue) rescueLoop(ctx context.Context) {
+	ticker := time.NewTicker(min(q.config.RescueAfter, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stuck := q.db.WithContext(ctx).Model(&models.Job{}).
+			Where("status = ? AND locked_at < ?", models.JobStatusRunning, q.clock.Now().Add(-q.config.RescueAfter))
+		result := stuck.Session(&gorm.Session{}).Where(notSuperseded).Updates(map[string]interface{}{
+			"status":     models.JobStatusPending,
+			"last_error": "rescued after worker stopped responding",
+			"locked_at":  nil,
+			"locked_by":  "",
+		})
+		if result.Error == nil && result.RowsAffected > 0 {
+			slog.WarnContext(ctx, "rescued stuck jobs", slog.Int64("count", result.RowsAffected))
+		}
+		if result.Error == nil {
+			result = stuck.Session(&gorm.Session{}).Delete(&models.Job{})
+		}
+		if result.Error != nil && !errors.Is(result.Error, context.Canceled) {
+			slog.ErrorContext(ctx, "failed to rescue stuck jobs", slog.String("error", result.Error.Error()))
+		} else if result.RowsAffected > 0 {
+			slog.InfoContext(ctx, "deleted stuck jobs superseded by pending ones", slog.Int64("count", result.RowsAffected))
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger adapts GORM's logger interface to slog. SQL statements are
+// logged with the request ID from the query context so all queries issued
+// while serving one request can be correlated.
+type GormLogger struct {
+	logger        *slog.Logger
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+}
+
+// NewGormLogger creates a GORM logger writing to slog.Default().
+//
+// Parameters:
+//   - level: GORM log level (Silent, Error, Warn, Info)
+//   - slowThreshold: queries slower than this are logged as warnings; 0 disables
+func NewGormLogger(level gormlogger.LogLevel, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{
+		logger:        slog.Default().With(slog.String("component", "gorm")),
+		level:         level,
+		slowThreshold: slowThreshold,
+	}
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	attrs := func() []any {
+		sql, rows := fc()
+		return []any{
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Duration("elapsed", elapsed),
+		}
+	}
+
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		l.logger.ErrorContext(ctx, "query failed", append(attrs(), slog.String("error", err.Error()))...)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		l.logger.WarnContext(ctx, "slow query", append(attrs(), slog.Duration("threshold", l.slowThreshold))...)
+	case l.level >= gormlogger.Info:
+		l.logger.InfoContext(ctx, "query", attrs()...)
+	}
+}
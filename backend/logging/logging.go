@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Setup configures the process-wide slog logger and returns it.
+//
+// Parameters:
+//   - level: minimum level (debug, info, warn, error); defaults to info
+//   - format: "json" (default) or "text" for local development
+//
+// Returns:
+//   - *slog.Logger: the logger installed as slog.Default
+//
+// Note:
+//   - Records logged with a context carry its request ID automatically
+func Setup(level, format string) *slog.Logger {
+	logger := slog.New(NewHandler(os.Stdout, level, format))
+	slog.SetDefault(logger)
+	return logger
+}
+
+// NewHandler builds the handler used by Setup writing to w.
+func NewHandler(w io.Writer, level, format string) slog.Handler {
+	opts := &slog.HandlerOptions{Level: ParseLevel(level)}
+
+	var h slog.Handler
+	if strings.EqualFold(format, "text") {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return &contextHandler{Handler: h}
+}
+
+// ParseLevel converts a level name to a slog.Level, defaulting to info.
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// contextHandler decorates every record with values carried by the context
+// passed to the *Context logging methods.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
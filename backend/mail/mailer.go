@@ -0,0 +1,210 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Mailer delivers emails.
+type Mailer interface {
+	Send(ctx context.Context, email *Email) error
+}
+
+// SMTP connection security modes
+const (
+	SecurityStartTLS = "starttls" // Plain connection upgraded with STARTTLS, which is required
+	SecurityTLS      = "tls"      // Implicit TLS, usually on port 465
+	SecurityNone     = "none"     // No encryption; only for local relays and test servers
+)
+
+// SMTPConfig configures an SMTPMailer.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// Security is SecurityStartTLS (default), SecurityTLS or SecurityNone
+	Security string
+	Timeout  time.Duration
+}
+
+// SMTPMailer sends emails through an SMTP server, opening one connection
+// per email.
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	if config.Security == "" {
+		config.Security = SecurityStartTLS
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	return &SMTPMailer{config: config}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, email *Email) error {
+	msg, err := email.Bytes()
+	if err != nil {
+		return err
+	}
+
+	cfg := m.config
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	if cfg.Security == SecurityTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if cfg.Security == SecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if cfg.Username != "" {
+		// PlainAuth refuses to send credentials unencrypted except to localhost
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(email.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(email.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// FileMailer writes each email as an .eml file into a directory, for
+// development without a mail server. Most mail clients open .eml files.
+type FileMailer struct {
+	dir string
+}
+
+func NewFileMailer(dir string) *FileMailer {
+	return &FileMailer{dir: dir}
+}
+
+func (m *FileMailer) Send(ctx context.Context, email *Email) error {
+	msg, err := email.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	return os.WriteFile(filepath.Join(m.dir, name), msg, 0o644)
+}
+
+// MemoryMailer keeps sent emails in memory so tests can inspect them.
+type MemoryMailer struct {
+	mu     sync.Mutex
+	emails []Email
+	// Err, when set, is returned by Send instead of recording the email
+	Err error
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, email *Email) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.emails = append(m.emails, *email)
+	return nil
+}
+
+// Emails returns every email sent so far, oldest first.
+func (m *MemoryMailer) Emails() []Email {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Email(nil), m.emails...)
+}
+
+// SentTo returns the emails sent to address, oldest first.
+func (m *MemoryMailer) SentTo(address string) []Email {
+	var out []Email
+	for _, e := range m.Emails() {
+		if e.To == address {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Last returns the most recent email sent to address.
+func (m *MemoryMailer) Last(address string) (*Email, bool) {
+	sent := m.SentTo(address)
+	if len(sent) == 0 {
+		return nil, false
+	}
+	return &sent[len(sent)-1], true
+}
+
+// Reset forgets every sent email.
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.emails = nil
+}
@@ -0,0 +1,61 @@
+package mail
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+var linkPattern = regexp.MustCompile(`https?://[^\s<>"')]+`)
+
+// Email is a message ready for a Mailer. With both bodies set it is sent as
+// multipart/alternative with Text first.
+type Email struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Bytes encodes the email as an RFC 5322 message.
+func (e *Email) Bytes() ([]byte, error) {
+	m := gomail.NewMessage()
+	m.SetHeader("From", e.From)
+	m.SetHeader("To", e.To)
+	m.SetHeader("Subject", e.Subject)
+	m.SetDateHeader("Date", time.Now())
+
+	switch {
+	case e.Text != "" && e.HTML != "":
+		m.SetBody("text/plain", e.Text)
+		m.AddAlternative("text/html", e.HTML)
+	case e.HTML != "":
+		m.SetBody("text/html", e.HTML)
+	default:
+		m.SetBody("text/plain", e.Text)
+	}
+
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Links returns the URLs in the email's text body, or its HTML body when
+// it has no text, in order of appearance.
+func (e *Email) Links() []string {
+	body := e.Text
+	if body == "" {
+		body = e.HTML
+	}
+	links := linkPattern.FindAllString(body, -1)
+	for i, link := range links {
+		links[i] = strings.TrimRight(link, ".,;:!?")
+	}
+	return links
+}
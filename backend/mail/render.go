@@ -0,0 +1,239 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// DefaultLocale is used when a message is rendered for a locale without
+// templates.
+const DefaultLocale = "en"
+
+// Template names
+const (
+	TemplateVerification  = "verification"
+	TemplatePasswordReset = "password_reset"
+	TemplateWelcome       = "welcome"
+	TemplateNotification  = "notification"
+	TemplateDigest        = "digest"
+)
+
+// DigestItem is one notification listed in a digest email.
+type DigestItem struct {
+	Title string
+	Body  string
+	URL   string
+}
+
+// rtlLocales are written right to left.
+var rtlLocales = map[string]bool{"fa": true, "ar": true, "he": true, "ur": true}
+
+// localeDigits maps locales that don't use ASCII digits to their zero.
+var localeDigits = map[string]rune{"fa": '۰', "ar": '٠'}
+
+// Site is the branding shown in every email.
+type Site struct {
+	Name         string
+	URL          string
+	LogoURL      string
+	SupportEmail string
+}
+
+// Message is a rendered email: an HTML body and its plain-text alternative.
+type Message struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// templateData is what templates see: the site, the locale's direction and
+// the caller's data.
+type templateData struct {
+	Site   Site
+	Locale string
+	Dir    string
+	Data   map[string]interface{}
+}
+
+// Renderer renders the embedded email templates. Each locale directory
+// holds a footer and one file per email defining "subject" and "content";
+// layout.html wraps them for every locale.
+type Renderer struct {
+	site      Site
+	templates map[string]map[string]*template.Template // locale -> name -> template
+}
+
+func NewRenderer(site Site) (*Renderer, error) {
+	r := &Renderer{site: site, templates: map[string]map[string]*template.Template{}}
+
+	entries, err := fs.ReadDir(templateFS, "templates")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		locale := entry.Name()
+		if err := r.parseLocale(locale); err != nil {
+			return nil, fmt.Errorf("parse %s email templates: %w", locale, err)
+		}
+	}
+	if r.templates[DefaultLocale] == nil {
+		return nil, fmt.Errorf("missing %s email templates", DefaultLocale)
+	}
+	return r, nil
+}
+
+func (r *Renderer) parseLocale(locale string) error {
+	base, err := template.New("layout").
+		Funcs(templateFuncs(locale)).
+		ParseFS(templateFS, "templates/layout.html", path.Join("templates", locale, "footer.html"))
+	if err != nil {
+		return err
+	}
+
+	files, err := fs.Glob(templateFS, path.Join("templates", locale, "*.html"))
+	if err != nil {
+		return err
+	}
+	r.templates[locale] = map[string]*template.Template{}
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".html")
+		if name == "footer" {
+			continue
+		}
+		t, err := template.Must(base.Clone()).ParseFS(templateFS, file)
+		if err != nil {
+			return err
+		}
+		r.templates[locale][name] = t
+	}
+	return nil
+}
+
+// Render renders the named email for locale, falling back to DefaultLocale
+// when the locale has no such template.
+func (r *Renderer) Render(locale, name string, data map[string]interface{}) (*Message, error) {
+	t := r.templates[locale][name]
+	if t == nil {
+		locale = DefaultLocale
+		t = r.templates[locale][name]
+	}
+	if t == nil {
+		return nil, fmt.Errorf("email template %q not found", name)
+	}
+
+	td := templateData{Site: r.site, Locale: locale, Dir: direction(locale), Data: data}
+
+	var subject, body bytes.Buffer
+	if err := t.ExecuteTemplate(&subject, "subject", td); err != nil {
+		return nil, err
+	}
+	if err := t.ExecuteTemplate(&body, "layout", td); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Subject: strings.TrimSpace(html.UnescapeString(subject.String())),
+		HTML:    body.String(),
+		Text:    HTMLToText(body.String()),
+	}, nil
+}
+
+// Locales returns the locales that have templates.
+func (r *Renderer) Locales() []string {
+	locales := make([]string, 0, len(r.templates))
+	for locale := range r.templates {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Templates returns the names of the default locale's templates.
+func (r *Renderer) Templates() []string {
+	names := make([]string, 0, len(r.templates[DefaultLocale]))
+	for name := range r.templates[DefaultLocale] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SampleData returns placeholder data for previewing the named template.
+func (r *Renderer) SampleData(name string) map[string]interface{} {
+	data := map[string]interface{}{"Name": "Sara"}
+	switch name {
+	case TemplateVerification:
+		data["URL"] = strings.TrimRight(r.site.URL, "/") + "/verify-email?token=sample-token"
+	case TemplatePasswordReset:
+		data["URL"] = strings.TrimRight(r.site.URL, "/") + "/reset-password?token=sample-token"
+		data["ExpiresHours"] = 24
+	case TemplateNotification:
+		data["Title"] = "Your post was approved"
+		data["Body"] = `"Hello world" is now published.`
+		data["URL"] = strings.TrimRight(r.site.URL, "/") + "/posts/1"
+	case TemplateDigest:
+		data["Items"] = []DigestItem{
+			{Title: "Reza commented on your post", Body: "Great read, thanks!", URL: strings.TrimRight(r.site.URL, "/") + "/posts/1"},
+			{Title: "Mina mentioned you", Body: "As @sara explained…", URL: strings.TrimRight(r.site.URL, "/") + "/posts/2"},
+		}
+	}
+	return data
+}
+
+func direction(locale string) string {
+	if rtlLocales[locale] {
+		return "rtl"
+	}
+	return "ltr"
+}
+
+func templateFuncs(locale string) template.FuncMap {
+	return template.FuncMap{
+		// button renders a call-to-action link styled for email clients
+		"button": func(url, label string) template.HTML {
+			return template.HTML(fmt.Sprintf(
+				`<a href="%s" style="display:inline-block;padding:12px 24px;background:#2563eb;color:#ffffff;text-decoration:none;border-radius:6px;font-weight:bold;">%s</a>`,
+				html.EscapeString(url), html.EscapeString(label)))
+		},
+		// number formats an integer with the locale's digits
+		"number": func(n int) string {
+			s := strconv.Itoa(n)
+			zero, ok := localeDigits[locale]
+			if !ok {
+				return s
+			}
+			return strings.Map(func(r rune) rune {
+				if r >= '0' && r <= '9' {
+					return zero + (r - '0')
+				}
+				return r
+			}, s)
+		},
+		"fontFamily": func(dir string) template.CSS {
+			if dir == "rtl" {
+				return template.CSS(`Vazirmatn, Tahoma, "Segoe UI", Arial, sans-serif`)
+			}
+			return template.CSS(`-apple-system, "Segoe UI", Roboto, Helvetica, Arial, sans-serif`)
+		},
+		"startSide": func(dir string) template.CSS {
+			if dir == "rtl" {
+				return "right"
+			}
+			return "left"
+		},
+	}
+}
@@ -0,0 +1,100 @@
+package mail
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	spaceRun   = regexp.MustCompile(`[ \t\r\n\f]+`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// blockElements start on a new line in the text version.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Table: true, atom.Tr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Blockquote: true,
+	atom.Hr: true,
+}
+
+// HTMLToText converts an HTML email body into its plain-text alternative:
+// paragraphs become blank-line separated, list items get a dash, and links
+// are followed by their URL in parentheses.
+func HTMLToText(s string) string {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	writeText(&b, doc)
+
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text := blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text) + "\n"
+}
+
+func writeText(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(spaceRun.ReplaceAllString(n.Data, " "))
+		return
+	case html.ElementNode:
+		switch n.DataAtom {
+		case atom.Head, atom.Style, atom.Script, atom.Title:
+			return
+		case atom.Br:
+			b.WriteString("\n")
+			return
+		case atom.Img:
+			if alt := attr(n, "alt"); alt != "" {
+				b.WriteString(alt)
+			}
+			return
+		}
+	}
+
+	// List items and table rows start on a new line, other blocks are
+	// surrounded by blank lines
+	before, after := "", ""
+	if n.Type == html.ElementNode && blockElements[n.DataAtom] {
+		before, after = "\n\n", "\n\n"
+		if n.DataAtom == atom.Li || n.DataAtom == atom.Tr {
+			before, after = "\n", ""
+		}
+	}
+	b.WriteString(before)
+	if n.DataAtom == atom.Li {
+		b.WriteString("- ")
+	}
+
+	start := b.Len()
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeText(b, c)
+	}
+
+	if n.DataAtom == atom.A {
+		href := strings.TrimPrefix(attr(n, "href"), "mailto:")
+		label := strings.TrimSpace(b.String()[start:])
+		if href != "" && href != label {
+			b.WriteString(" (" + href + ")")
+		}
+	}
+	b.WriteString(after)
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -1,38 +1,302 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata" // Site time zones on hosts without a zoneinfo database
 
 	"github.com/gin-gonic/gin"
 	"github.com/sasanzare/go-cms/config"
+	"github.com/sasanzare/go-cms/i18n"
+	"github.com/sasanzare/go-cms/jobs"
+	"github.com/sasanzare/go-cms/logging"
+	"github.com/sasanzare/go-cms/mail"
+	"github.com/sasanzare/go-cms/metrics"
+	"github.com/sasanzare/go-cms/middleware"
 	"github.com/sasanzare/go-cms/migrations"
 	"github.com/sasanzare/go-cms/routes"
+	"github.com/sasanzare/go-cms/scheduler"
+	"github.com/sasanzare/go-cms/search"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/spam"
+	"github.com/sasanzare/go-cms/tracing"
+	"github.com/sasanzare/go-cms/utils"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"gorm.io/gorm"
 )
 
 func main() {
+	// Cancelled on SIGINT/SIGTERM so shutdown also interrupts startup
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Setup structured logging
+	logConfig := config.LoadLogConfig()
+	logging.Setup(logConfig.Level, logConfig.Format)
+
+	// Setup tracing
+	tracingConfig := config.LoadTracingConfig()
+	shutdownTracing, err := tracing.Init(ctx, tracing.Config{
+		ServiceName:  tracingConfig.ServiceName,
+		Exporter:     tracingConfig.Exporter,
+		OTLPEndpoint: tracingConfig.OTLPEndpoint,
+		FilePath:     tracingConfig.FilePath,
+		SampleRatio:  tracingConfig.SampleRatio,
+	})
+	if err != nil {
+		fatal("failed to initialize tracing", err)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("failed to flush traces", slog.String("error", err.Error()))
+		}
+	}()
+
 	// Load configuration
 	dbConfig := config.LoadDBConfig()
 
 	// Connect to database
-	db, err := config.ConnectDB(dbConfig)
+	db, err := config.ConnectDB(ctx, dbConfig)
+	if err != nil {
+		fatal("failed to connect to database", err)
+	}
+
+	// Expose connection pool statistics
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("failed to get sql.DB", err)
+	}
+	if err := metrics.RegisterDBStats(sqlDB, dbConfig.Name); err != nil {
+		fatal("failed to register database metrics", err)
+	}
+
+	// Content written before it had a locale is in the site's default one
+	siteConfig := config.LoadSiteConfig()
+	locales, err := i18n.New(siteConfig.DefaultLocale, siteConfig.Locales, siteConfig.LocaleFallbacks)
+	if err != nil {
+		fatal("invalid locale configuration", err)
 	}
 
 	// Run auto migrations
-	if err := migrations.InitAutoMigrations(db); err != nil {
-		log.Fatalf("Failed to run auto migrations: %v", err)
+	if err := migrations.InitAutoMigrations(db, locales.Default()); err != nil {
+		fatal("failed to run auto migrations", err)
+	}
+
+	// Setup search and services
+	index, err := newSearchIndex(config.LoadSearchConfig(), db)
+	if err != nil {
+		fatal("failed to initialize search", err)
+	}
+	jobsConfig, err := config.LoadJobsConfig()
+	if err != nil {
+		fatal("invalid job queue configuration", err)
+	}
+	queue := jobs.New(db, utils.RealClock{}, jobs.Config{
+		Concurrency:  jobsConfig.Concurrency,
+		PollInterval: jobsConfig.PollInterval,
+		RescueAfter:  jobsConfig.RescueAfter,
+		MaxAttempts:  jobsConfig.MaxAttempts,
+	})
+	renderer, err := mail.NewRenderer(mail.Site{
+		Name:         siteConfig.Name,
+		URL:          siteConfig.URL,
+		LogoURL:      siteConfig.LogoURL,
+		SupportEmail: siteConfig.SupportEmail,
+	})
+	if err != nil {
+		fatal("failed to load email templates", err)
+	}
+	location, err := time.LoadLocation(siteConfig.TimeZone)
+	if err != nil {
+		fatal("invalid site time zone", err)
+	}
+	mailConfig := config.LoadMailConfig()
+	mailer, err := newMailer(mailConfig)
+	if err != nil {
+		fatal("invalid mail configuration", err)
+	}
+	postConfig := config.LoadPostConfig()
+	feedConfig := config.LoadFeedConfig()
+	if feedConfig.Content != services.ContentFull && feedConfig.Content != services.ContentExcerpt {
+		fatal("invalid feed configuration", fmt.Errorf("FEED_CONTENT must be full or excerpt, not %q", feedConfig.Content))
+	}
+	notificationConfig, err := config.LoadNotificationConfig()
+	if err != nil {
+		fatal("invalid notification configuration", err)
+	}
+	webhookConfig := config.LoadWebhookConfig()
+	spamConfig, err := config.LoadSpamConfig()
+	if err != nil {
+		fatal("invalid spam configuration", err)
+	}
+	spamService, err := newSpamService(ctx, spamConfig, db)
+	if err != nil {
+		fatal("failed to load spam classifier", err)
+	}
+	svc := services.New(db, services.Options{
+		Index:            index,
+		Queue:            queue,
+		Mailer:           mailer,
+		MailFrom:         mailConfig.From,
+		Mail:             renderer,
+		DefaultLocale:    siteConfig.DefaultLocale,
+		Locales:          locales,
+		Location:         location,
+		SiteName:         siteConfig.Name,
+		SiteURL:          siteConfig.URL,
+		SiteLogoURL:      siteConfig.LogoURL,
+		FeedItems:        feedConfig.Items,
+		FeedExcerptsOnly: feedConfig.Content == services.ContentExcerpt,
+		DigestHour:       notificationConfig.DigestHour,
+		WebhookURLs:      webhookConfig.URLs,
+		WebhookSecret:    webhookConfig.Secret,
+		WebhookTimeout:   webhookConfig.Timeout,
+		Spam:             spamService,
+		PostOptions:      []services.PostServiceOption{services.WithRevisionLimit(postConfig.RevisionLimit)},
+	})
+
+	// "reindex" rebuilds the search index and exits
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		if _, err := svc.Search.Reindex(ctx); err != nil {
+			fatal("failed to rebuild search index", err)
+		}
+		return
+	}
+
+	// The embedded index lives in memory and starts empty
+	if _, ok := index.(*search.MemoryIndex); ok {
+		if _, err := svc.Search.Reindex(ctx); err != nil {
+			fatal("failed to build search index", err)
+		}
 	}
 
+	// Publish scheduled posts in the background
+	if interval := config.LoadSchedulerConfig().Interval; interval > 0 {
+		go scheduler.New(svc.Posts, interval).Run(ctx)
+	}
+
+	// Queue the next notification digest; each run queues the following one
+	if err := svc.Notifications.ScheduleDigest(ctx); err != nil {
+		slog.Error("failed to schedule notification digest", slog.String("error", err.Error()))
+	}
+
+	// Run background jobs; shutdown waits for running jobs to finish
+	jobsDone := make(chan struct{})
+	go func() {
+		defer close(jobsDone)
+		queue.Run(ctx)
+	}()
+
 	// Create Gin router
-	r := gin.Default()
+	r := gin.New()
+	r.Use(
+		otelgin.Middleware(tracingConfig.ServiceName),
+		middleware.RequestID(),
+		middleware.RequestLogger(),
+		middleware.Metrics(),
+		gin.Recovery(),
+	)
 
 	// Setup main routes
-	routes.SetupRouter(r, db)
+	routes.SetupRouter(r, svc)
 
 	// Start server
-	log.Println("Starting server on :8000")
-	if err := r.Run(":8000"); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	srv := &http.Server{Addr: ":8000", Handler: r}
+	go func() {
+		slog.Info("starting server", slog.String("addr", srv.Addr))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("failed to start server", err)
+		}
+	}()
+
+	<-ctx.Done()
+	slog.Info("shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("server shutdown error", slog.String("error", err.Error()))
 	}
-}
\ No newline at end of file
+
+	select {
+	case <-jobsDone:
+	case <-shutdownCtx.Done():
+		slog.Warn("timed out waiting for running jobs")
+	}
+}
+
+func newSearchIndex(cfg *config.SearchConfig, db *gorm.DB) (search.Index, error) {
+	switch cfg.Backend {
+	case "postgres", "":
+		return search.NewPostgresIndex(db), nil
+	case "memory":
+		return search.NewMemoryIndex(), nil
+	default:
+		return nil, fmt.Errorf("unknown search backend %q", cfg.Backend)
+	}
+}
+
+func newMailer(cfg *config.MailConfig) (mail.Mailer, error) {
+	switch cfg.Transport {
+	case "smtp", "":
+		if cfg.SMTPHost == "" {
+			slog.Warn("SMTP_HOST is not set; emails will fail to send")
+		}
+		switch cfg.SMTPSecurity {
+		case mail.SecurityStartTLS, mail.SecurityTLS, mail.SecurityNone:
+		default:
+			return nil, fmt.Errorf("unknown SMTP security mode %q", cfg.SMTPSecurity)
+		}
+		return mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUser,
+			Password: cfg.SMTPPass,
+			Security: cfg.SMTPSecurity,
+			Timeout:  cfg.SMTPTimeout,
+		}), nil
+	case "file":
+		slog.Info("writing emails to files", slog.String("dir", cfg.FileDir))
+		return mail.NewFileMailer(cfg.FileDir), nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q", cfg.Transport)
+	}
+}
+
+// newSpamService builds the spam pipeline. The honeypot and a blocklisted
+// word each flag a submission at the default threshold on their own; the
+// other rules need help from each other.
+func newSpamService(ctx context.Context, cfg *config.SpamConfig, db *gorm.DB) (*services.SpamService, error) {
+	classifier := spam.NewClassifier(spam.NewDBStore(db))
+	if err := classifier.Load(ctx); err != nil {
+		return nil, err
+	}
+	tokens := spam.NewFormTokens([]byte(cfg.FormSecret), utils.RealClock{})
+
+	rules := []spam.Rule{
+		spam.LinkRule{Allowed: cfg.MaxLinks, Weight: 0.5},
+		spam.HoneypotRule{Weight: 2},
+		spam.BayesRule{Classifier: classifier, Weight: 0.8},
+	}
+	if len(cfg.Blocklist) > 0 {
+		rules = append(rules, spam.BlocklistRule{Words: cfg.Blocklist, Weight: 1})
+	}
+	if cfg.MinSubmitTime > 0 {
+		rules = append(rules, spam.SpeedRule{Tokens: tokens, Min: cfg.MinSubmitTime, Weight: 0.8, MissingWeight: 0.4})
+	}
+	return services.NewSpamService(spam.NewPipeline(cfg.Threshold, rules...), classifier, tokens), nil
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, slog.String("error", err.Error()))
+	os.Exit(1)
+}
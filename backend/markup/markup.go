@@ -0,0 +1,170 @@
+// Package markup renders post content from its authoring format to HTML
+// and measures the result.
+//
+// Rendered HTML is not safe to serve as is: Markdown may embed raw HTML,
+// so callers run the output through a sanitize.Policy.
+package markup
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// Version identifies the renderer's output. Bump it whenever rendering
+// changes so HTML cached with an older version is rendered again.
+const Version = 1
+
+// wordsPerMinute is the reading speed reading times assume.
+const wordsPerMinute = 200
+
+// highlightStyle colours highlighted code, see WriteHighlightCSS.
+const highlightStyle = "github"
+
+var (
+	codeFormatter = chromahtml.New(chromahtml.WithClasses(true))
+
+	markdown = goldmark.New(
+		goldmark.WithExtensions(
+			extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+			extension.Strikethrough,
+			extension.Linkify,
+			extension.TaskList,
+		),
+		goldmark.WithRendererOptions(
+			gmhtml.WithUnsafe(),
+			renderer.WithNodeRenderers(util.Prioritized(&codeRenderer{}, 100)),
+		),
+	)
+)
+
+// Render converts source written in format to HTML.
+func Render(format, source string) (string, error) {
+	switch format {
+	case models.PostFormatMarkdown:
+		var b bytes.Buffer
+		if err := markdown.Convert([]byte(source), &b); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	case models.PostFormatHTML:
+		return source, nil
+	case models.PostFormatPlain:
+		return renderPlain(source), nil
+	}
+	return "", fmt.Errorf("unsupported content format %q", format)
+}
+
+// renderPlain escapes text, turning blank-line separated blocks into
+// paragraphs and other line breaks into <br>.
+func renderPlain(source string) string {
+	var b strings.Builder
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	for _, block := range strings.Split(source, "\n\n") {
+		if block = strings.TrimSpace(block); block == "" {
+			continue
+		}
+		lines := strings.Split(block, "\n")
+		for i, line := range lines {
+			lines[i] = html.EscapeString(strings.TrimSpace(line))
+		}
+		b.WriteString("<p>" + strings.Join(lines, "<br>\n") + "</p>\n")
+	}
+	return b.String()
+}
+
+// WordCount counts the words in plain text. Letters from scripts written
+// without spaces, such as Chinese and Japanese, count as a word each.
+func WordCount(text string) int {
+	count := 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana):
+			count++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			if !inWord {
+				count++
+			}
+			inWord = true
+		case r == '\'' || r == '’' || r == '-':
+			// part of words like "don't" and "well-known"
+		default:
+			inWord = false
+		}
+	}
+	return count
+}
+
+// ReadingTime is how many minutes reading words takes, rounded up; at
+// least one for any text.
+func ReadingTime(words int) int {
+	if words == 0 {
+		return 0
+	}
+	return int(math.Ceil(float64(words) / wordsPerMinute))
+}
+
+// WriteHighlightCSS writes the stylesheet for the classes highlighted
+// code blocks carry.
+func WriteHighlightCSS(w io.Writer) error {
+	return codeFormatter.WriteCSS(w, styles.Get(highlightStyle))
+}
+
+// codeRenderer highlights fenced code blocks whose language chroma knows.
+type codeRenderer struct{}
+
+func (r *codeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderFencedCodeBlock)
+}
+
+func (r *codeRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	block := node.(*ast.FencedCodeBlock)
+
+	var code strings.Builder
+	lines := block.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		code.Write(segment.Value(source))
+	}
+
+	language := string(block.Language(source))
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		w.WriteString("<pre><code")
+		if language != "" {
+			w.WriteString(` class="language-` + html.EscapeString(language) + `"`)
+		}
+		w.WriteString(">" + html.EscapeString(code.String()) + "</code></pre>\n")
+		return ast.WalkSkipChildren, nil
+	}
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code.String())
+	if err != nil {
+		return ast.WalkStop, err
+	}
+	if err := codeFormatter.Format(w, styles.Get(highlightStyle), iterator); err != nil {
+		return ast.WalkStop, err
+	}
+	return ast.WalkSkipChildren, nil
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "cms"
+
+// HTTP metrics. The route label is the Gin route template (c.FullPath()),
+// never the raw path, so label cardinality is bounded by the route table.
+var (
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+)
+
+// Business metrics
+var (
+	PostsPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_published_total",
+		Help:      "Posts transitioned to the published status.",
+	})
+
+	LoginsFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_failed_total",
+		Help:      "Failed login attempts by reason.",
+	}, []string{"reason"})
+
+	EmailsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "emails_sent_total",
+		Help:      "Outgoing emails by result (success or failure).",
+	}, []string{"result"})
+
+	JobsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_processed_total",
+		Help:      "Background job attempts by queue, kind and result (success, retry or dead).",
+	}, []string{"queue", "kind", "result"})
+
+	SpamChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "spam_checks_total",
+		Help:      "Spam checks by submission kind and result (spam or ham).",
+	}, []string{"kind", "result"})
+)
+
+// RegisterDBStats exposes connection pool statistics of db under the
+// go_sql_* metric family, labelled with db_name.
+func RegisterDBStats(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler returns the HTTP handler serving the /metrics endpoint.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
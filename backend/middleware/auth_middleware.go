@@ -1,17 +1,93 @@
 package middleware
 
 import (
-    "github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// Gin context keys set by the authentication middleware
+const (
+	UserIDKey = "userID"
+	RoleKey   = "role"
 )
 
+// AuthMiddleware requires a valid bearer token and stores the user's ID and
+// role in the Gin context and the request context.
 func AuthMiddleware() gin.HandlerFunc {
-    return func(c *gin.Context) {
-        c.Next()
-    }
+	return func(c *gin.Context) {
+		if !authenticate(c) {
+			utils.SendError(c, http.StatusUnauthorized, "Authentication required")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// OptionalAuthMiddleware authenticates the request when a valid bearer
+// token is present and lets anonymous requests through otherwise.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticate(c)
+		c.Next()
+	}
 }
 
+// AdminMiddleware only lets administrators through. It must run after
+// AuthMiddleware.
 func AdminMiddleware() gin.HandlerFunc {
-    return func(c *gin.Context) {
-        c.Next()
-    }
-}
\ No newline at end of file
+	return RequireRoles(models.UserRoleAdmin)
+}
+
+// RequireRoles only lets users with one of the given roles through. It must
+// run after AuthMiddleware.
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString(RoleKey)
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		utils.SendError(c, http.StatusForbidden, "Insufficient permissions")
+		c.Abort()
+	}
+}
+
+// CurrentUser returns the authenticated user's ID and role.
+func CurrentUser(c *gin.Context) (uint, string, bool) {
+	id, ok := c.Get(UserIDKey)
+	if !ok {
+		return 0, "", false
+	}
+	return id.(uint), c.GetString(RoleKey), true
+}
+
+// IsStaff reports whether the current user is an admin or editor.
+func IsStaff(c *gin.Context) bool {
+	role := c.GetString(RoleKey)
+	return role == models.UserRoleAdmin || role == models.UserRoleEditor
+}
+
+func authenticate(c *gin.Context) bool {
+	header := c.GetHeader("Authorization")
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found || token == "" {
+		return false
+	}
+
+	claims, err := utils.ValidateToken(token)
+	if err != nil {
+		return false
+	}
+
+	c.Set(UserIDKey, claims.UserID)
+	c.Set(RoleKey, claims.Role)
+	c.Request = c.Request.WithContext(utils.ContextWithClaims(c.Request.Context(), claims))
+	return true
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/i18n"
+)
+
+// LocaleKey is the gin context key holding the negotiated locale
+const LocaleKey = "locale"
+
+// Locale negotiates the locale a public request is served in from its
+// ?lang= parameter and Accept-Language header. The locale is stored in
+// the gin and request contexts; responses say which headers they vary on.
+func Locale(locales *i18n.Locales) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := locales.Negotiate(c.Query("lang"), c.GetHeader("Accept-Language"))
+
+		c.Set(LocaleKey, locale)
+		c.Header("Vary", "Accept-Language")
+		c.Request = c.Request.WithContext(i18n.WithLocale(c.Request.Context(), locale))
+
+		c.Next()
+	}
+}
+
+// RequestLocale returns the locale Locale negotiated, and false when the
+// middleware did not run.
+func RequestLocale(c *gin.Context) (string, bool) {
+	locale := c.GetString(LocaleKey)
+	return locale, locale != ""
+}
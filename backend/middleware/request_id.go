@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/logging"
+)
+
+const (
+	// RequestIDHeader is the header used to accept and echo request IDs
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey is the gin context key holding the request ID
+	RequestIDKey = "request_id"
+)
+
+// Incoming IDs are only trusted when they look like an opaque token, so a
+// client can't inject arbitrary content into the logs.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{8,128}$`)
+
+// RequestID assigns every request an ID, reusing a well-formed X-Request-ID
+// header if the client or a proxy supplied one. The ID is echoed in the
+// response and stored in the request context so services and GORM queries
+// log it.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
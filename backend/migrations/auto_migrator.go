@@ -3,7 +3,7 @@ package migrations
 import (
 	"fmt"
 	"time"
-	"log"
+	"log/slog"
 	"reflect"
 	"strings"
 
@@ -11,9 +11,18 @@ import (
 )
 
 type AutoMigrator struct {
-	db      *gorm.DB
-	models  []interface{}
-	verbose bool
+	db         *gorm.DB
+	models     []interface{}
+	migrations []Migration
+	verbose    bool
+}
+
+// Migration is a hand-written schema or data change that AutoMigrate can't
+// express, such as generated columns, special indexes or backfills. It runs
+// once, after all models are migrated, and is recorded under its ID.
+type Migration struct {
+	ID string
+	Up func(tx *gorm.DB) error
 }
 
 func NewAutoMigrator(db *gorm.DB, verbose bool) *AutoMigrator {
@@ -32,11 +41,28 @@ func (am *AutoMigrator) AddModels(models ...interface{}) {
 	am.models = append(am.models, models...)
 }
 
+// AddMigration registers a migration to run once inside a transaction.
+// IDs must be stable and unique; migrations run in registration order.
+func (am *AutoMigrator) AddMigration(id string, up func(tx *gorm.DB) error) {
+	am.migrations = append(am.migrations, Migration{ID: id, Up: up})
+}
+
+// AddSQLMigration registers a migration executing the given statements.
+func (am *AutoMigrator) AddSQLMigration(id string, statements ...string) {
+	am.AddMigration(id, func(tx *gorm.DB) error {
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 
 func (am *AutoMigrator) Run() error {
 	if am.verbose {
-		log.Println("Starting auto migration process...")
-		log.Printf("Models to migrate: %d\n", len(am.models))
+		slog.Info("starting auto migration process", slog.Int("models", len(am.models)))
 	}
 
 	if err := am.db.AutoMigrate(&MigrationRecord{}); err != nil {
@@ -54,7 +80,7 @@ func (am *AutoMigrator) Run() error {
 			}
 
 			if am.verbose {
-				log.Printf("Migrating model: %s\n", modelName)
+				slog.Info("migrating model", slog.String("model", modelName))
 			}
 
 			if err := am.db.AutoMigrate(model); err != nil {
@@ -69,17 +95,44 @@ func (am *AutoMigrator) Run() error {
 			}
 
 			if am.verbose {
-				log.Printf("Successfully migrated model: %s\n", modelName)
+				slog.Info("successfully migrated model", slog.String("model", modelName))
 			}
 		}
 	}
 
+	for _, m := range am.migrations {
+		if err := am.runMigration(m); err != nil {
+			return err
+		}
+	}
+
 	if am.verbose {
-		log.Println("Auto migration completed successfully")
+		slog.Info("auto migration completed successfully")
 	}
 	return nil
 }
 
+func (am *AutoMigrator) runMigration(m Migration) error {
+	var count int64
+	if err := am.db.Model(&MigrationRecord{}).Where("id = ?", m.ID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check migration record: %v", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if am.verbose {
+		slog.Info("running migration", slog.String("id", m.ID))
+	}
+
+	return am.db.Transaction(func(tx *gorm.DB) error {
+		if err := m.Up(tx); err != nil {
+			return fmt.Errorf("migration %s failed: %v", m.ID, err)
+		}
+		return tx.Create(&MigrationRecord{ID: m.ID, CreatedAt: time.Now()}).Error
+	})
+}
+
 
 func generateMigrationID(modelName string) string {
 	return fmt.Sprintf("auto_%s_%d", strings.ToLower(modelName), time.Now().Unix())
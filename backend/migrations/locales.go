@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// columnDefaultLocale is the locale column's default, which rows existing
+// when the column was added were given.
+const columnDefaultLocale = "en"
+
+// backfillLocale moves posts, categories and tags from the column default
+// to locale, deleted ones included.
+func backfillLocale(tx *gorm.DB, locale string) error {
+	if locale == "" || locale == columnDefaultLocale {
+		return nil
+	}
+	for _, table := range []string{"posts", "categories", "tags"} {
+		if err := tx.Exec("UPDATE "+table+" SET locale = ? WHERE locale = ?", locale, columnDefaultLocale).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,7 +1,7 @@
 package migrations
 
 import (
-	"log"
+	"log/slog"
 	"time"
 
 	"gorm.io/gorm"
@@ -14,17 +14,92 @@ type MigrationRecord struct {
 }
 
 
-func InitAutoMigrations(db *gorm.DB) error {
+// InitAutoMigrations migrates the schema and data. Content written before
+// it had a locale is assigned defaultLocale.
+func InitAutoMigrations(db *gorm.DB, defaultLocale string) error {
 	migrator := NewAutoMigrator(db, true)
 
 	migrator.AddModels(
-		// &models.User{},
+		&models.User{},
+		&models.Category{},
+		&models.Tag{},
 		&models.Post{},
-		// &models.Category{},
+		&models.PostRevision{},
+		&models.Media{},
+		&models.ContentType{},
+		&models.ContentEntry{},
+		&models.Comment{},
+		&models.Job{},
+		&models.DeadJob{},
+		&models.Notification{},
+		&models.NotificationPreference{},
+		&models.SpamToken{},
+	)
+
+	// Full-text search: a weighted tsvector generated from the post's own
+	// text search configuration, indexed with GIN
+	migrator.AddSQLMigration("20250601_posts_search_vector",
+		`ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (
+				setweight(to_tsvector(search_language, coalesce(title, '')), 'A') ||
+				setweight(to_tsvector(search_language, coalesce(excerpt, '')), 'B') ||
+				setweight(to_tsvector(search_language, coalesce(content, '')), 'C')
+			) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector)`,
+	)
+
+	// Trigram similarity backs typo-tolerant title search
+	migrator.AddSQLMigration("20250605_pg_trgm",
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE INDEX IF NOT EXISTS idx_posts_title_trgm ON posts USING GIN (title gin_trgm_ops)`,
+	)
+
+	// Posts written before block content become a single paragraph block
+	migrator.AddMigration("20250701_posts_blocks", migratePostsToBlocks)
+
+	// Runtime content types: entry data is filtered by JSONB containment and
+	// searched through a vector over the title and searchable text fields
+	migrator.AddSQLMigration("20250710_content_entries_search",
+		`CREATE INDEX IF NOT EXISTS idx_content_entries_data ON content_entries USING GIN (data jsonb_path_ops)`,
+		`ALTER TABLE content_entries ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (
+				setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+				setweight(to_tsvector('simple', coalesce(search_text, '')), 'B')
+			) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_content_entries_search_vector ON content_entries USING GIN (search_vector)`,
+	)
+
+	// Slugs and tag names became unique per locale, and a translation group
+	// has at most one post per locale. Existing content, given the column
+	// default, is moved to the site's default locale.
+	migrator.AddMigration("20250720_locale_unique_indexes", func(tx *gorm.DB) error {
+		for _, stmt := range []string{
+			`DROP INDEX IF EXISTS idx_posts_slug`,
+			`DROP INDEX IF EXISTS idx_categories_slug`,
+			`DROP INDEX IF EXISTS idx_tags_slug`,
+			`DROP INDEX IF EXISTS idx_tags_name`,
+		} {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		if err := backfillLocale(tx, defaultLocale); err != nil {
+			return err
+		}
+		return tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_translation_locale
+			ON posts ((COALESCE(source_id, id)), locale) WHERE deleted_at IS NULL`).Error
+	})
+
+	// Unique job keys only apply to pending jobs, so a job can be queued
+	// while its twin runs
+	migrator.AddSQLMigration("20250801_jobs_pending_unique_key",
+		`DROP INDEX IF EXISTS idx_jobs_unique_key`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_pending_unique_key
+			ON jobs (unique_key) WHERE status = 'pending'`,
 	)
 
 	if err := migrator.Run(); err != nil {
-		log.Printf("Migration error: %v", err)
+		slog.Error("migration error", slog.String("error", err.Error()))
 		return err
 	}
 
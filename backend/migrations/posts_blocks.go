@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	"log/slog"
+
+	"github.com/sasanzare/go-cms/blocks"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/utils"
+	"gorm.io/gorm"
+)
+
+// migratePostsToBlocks moves every HTML post, deleted ones included, into
+// the blocks format (see PostToBlocks). Rendering is left to the next
+// read, which finds the cached HTML outdated. A post that can't be
+// converted keeps its HTML and is logged, as user content must never stop
+// the server from starting.
+func migratePostsToBlocks(tx *gorm.DB) error {
+	var posts []models.Post
+	return tx.Unscoped().
+		Select("id", "content", "content_format").
+		Where("content_format = ?", models.PostFormatHTML).
+		FindInBatches(&posts, 200, func(batch *gorm.DB, _ int) error {
+			for i := range posts {
+				post := &posts[i]
+				converted, err := PostToBlocks(post)
+				if err != nil {
+					slog.Warn("post left in HTML, its content can't be converted to blocks",
+						slog.Uint64("post_id", uint64(post.ID)), slog.String("error", err.Error()))
+					continue
+				}
+				if !converted {
+					continue
+				}
+
+				err = tx.Unscoped().Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumns(map[string]interface{}{
+					"content_format": post.ContentFormat,
+					"blocks":         post.Blocks,
+					"content":        post.Content,
+					"render_version": 0,
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// PostToBlocks converts an HTML post to paragraph blocks, split to fit
+// the paragraph limit, and sets its content to their text, as for other
+// block posts. It reports whether the post changed: Markdown and plain
+// text posts keep their source, and posts without text are left as they
+// are.
+func PostToBlocks(post *models.Post) (bool, error) {
+	if post.ContentFormat != models.PostFormatHTML || utils.SanitizeText(post.Content) == "" {
+		return false, nil
+	}
+	doc, err := blocks.FromHTML(post.Content)
+	if err != nil {
+		return false, err
+	}
+	encoded, err := doc.JSON()
+	if err != nil {
+		return false, err
+	}
+	post.ContentFormat = models.PostFormatBlocks
+	post.Blocks = encoded
+	post.Content = doc.Text()
+	return true, nil
+}
@@ -10,7 +10,9 @@ type Category struct {
 	Name        	string         `gorm:"size:255;not null" validate:"required,min=3,max=255"`
 	Description 	string         `gorm:"type:text"`
 	Status      	string         `gorm:"size:20;not null;default:draft" validate:"oneof=draft published archived"`
-	Slug        	string         `gorm:"size:300;uniqueIndex" validate:"omitempty,alphanumdash"`
+	Slug        	string         `gorm:"size:300;uniqueIndex:idx_categories_locale_slug,priority:2" validate:"omitempty,alphanumdash"` // Unique per locale
+	Locale          string         `gorm:"size:10;not null;default:'en';uniqueIndex:idx_categories_locale_slug,priority:1"`
+	SourceID        *uint          `gorm:"index"` // Category this one translates; nil for originals
 	MetaTitle       string        `gorm:"size:255"`
 	MetaDescription string        `gorm:"size:500"`
 	FeaturedImage   string        `gorm:"size:512"`
@@ -35,6 +37,15 @@ func (c *Category) IsPublished() bool {
 	return c.Status == CategoryStatusPublished && c.PublishedAt != nil
 }
 
+// TranslationGroup is the ID shared by a category and its translations:
+// that of the original category
+func (c *Category) TranslationGroup() uint {
+	if c.SourceID != nil {
+		return *c.SourceID
+	}
+	return c.ID
+}
+
 // BeforeCreate hook for setting default values
 func (c *Category) BeforeCreate(tx *gorm.DB) error {
 	if c.Status == "" {
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Comment moderation states
+const (
+	CommentStatusPending  = "pending"
+	CommentStatusApproved = "approved"
+	CommentStatusSpam     = "spam"
+	CommentStatusTrashed  = "trashed"
+)
+
+// Comment is a reader's comment on a post, left by a signed-in user or a
+// guest who gives a name and email. Replies point at their parent and at
+// the top-level comment of their thread, so a page of threads loads with
+// one query.
+type Comment struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	PostID      uint            `gorm:"not null;index:idx_comments_post_status,priority:1" json:"post_id"`
+	ParentID    *uint           `gorm:"index" json:"parent_id"`
+	RootID      *uint           `gorm:"index" json:"root_id,omitempty"` // Top-level comment of the thread; nil for top-level comments
+	Depth       int             `gorm:"not null;default:0" json:"depth"`
+	UserID      *uint           `gorm:"index" json:"user_id,omitempty"`
+	GuestName   string          `gorm:"size:100" json:"guest_name,omitempty"`
+	GuestEmail  string          `gorm:"size:255" json:"-"`
+	Body        string          `gorm:"type:text;not null" json:"body"`
+	Status      string          `gorm:"size:20;not null;default:pending;index:idx_comments_post_status,priority:2" json:"status"`
+	IPAddress   string          `gorm:"size:45" json:"-"`
+	UserAgent   string          `gorm:"size:255" json:"-"`
+	SpamScore   float64         `gorm:"not null;default:0" json:"-"`
+	SpamChecks  json.RawMessage `gorm:"type:jsonb" json:"-"` // Per-rule breakdown of SpamScore
+	TrainedAs   string          `gorm:"size:10" json:"-"`    // Label the spam classifier learned from this comment
+	ModeratedBy *uint           `json:"-"`
+	ModeratedAt *time.Time      `json:"-"`
+	ApprovedAt  *time.Time      `json:"-"` // First approval; readers are notified only then
+	CreatedAt   time.Time       `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time       `gorm:"not null;autoUpdateTime" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt  `gorm:"index" json:"-"`
+
+	// Filled by queries that join the author and post
+	AuthorName string    `gorm:"->;-:migration" json:"author_name"`
+	PostTitle  string    `gorm:"->;-:migration" json:"post_title,omitempty"`
+	Replies    []Comment `gorm:"-" json:"replies,omitempty"`
+}
+
+// IsGuest reports whether the comment was left without an account.
+func (c *Comment) IsGuest() bool {
+	return c.UserID == nil
+}
+
+// IsCommentStatus reports whether s is a moderation state.
+func IsCommentStatus(s string) bool {
+	switch s {
+	case CommentStatusPending, CommentStatusApproved, CommentStatusSpam, CommentStatusTrashed:
+		return true
+	}
+	return false
+}
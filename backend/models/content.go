@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ContentType is a kind of content admins define at runtime, such as
+// "event" or "product". Fields holds its content.Schema.
+type ContentType struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	Name        string          `gorm:"size:100;not null" json:"name"`
+	Slug        string          `gorm:"size:63;not null;uniqueIndex" json:"slug"` // Names the type in URLs
+	Description string          `gorm:"size:1000" json:"description"`
+	Fields      json.RawMessage `gorm:"type:jsonb;not null" json:"fields"`
+	TitleField  string          `gorm:"size:63" json:"title_field"` // Text field whose value titles entries
+	CreatedAt   time.Time       `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time       `gorm:"not null;autoUpdateTime" json:"updated_at"`
+}
+
+// Status constants for ContentEntry
+const (
+	EntryStatusDraft     = "draft"
+	EntryStatusPublished = "published"
+)
+
+// ContentEntry is one item of a ContentType. Data holds its field values
+// as validated against the type's schema.
+type ContentEntry struct {
+	ID            uint            `gorm:"primaryKey" json:"id"`
+	ContentTypeID uint            `gorm:"not null;index" json:"content_type_id"`
+	Title         string          `gorm:"size:255" json:"title"` // Value of the type's title field
+	Data          json.RawMessage `gorm:"type:jsonb;not null" json:"data"`
+	SearchText    string          `gorm:"type:text" json:"-"` // Searchable text fields, joined
+	Status        string          `gorm:"size:20;not null;default:draft;index" json:"status"`
+	AuthorID      uint            `gorm:"not null" json:"author_id"`
+	CreatedAt     time.Time       `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time       `gorm:"not null;autoUpdateTime" json:"updated_at"`
+	PublishedAt   *time.Time      `gorm:"index" json:"published_at,omitempty"`
+	DeletedAt     gorm.DeletedAt  `gorm:"index" json:"-"`
+}
+
+// IsPublished checks if the entry is published
+func (e *ContentEntry) IsPublished() bool {
+	return e.Status == EntryStatusPublished
+}
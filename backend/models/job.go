@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job is a unit of background work waiting to run or running. Finished
+// jobs are deleted; jobs that exhaust their attempts move to DeadJob.
+type Job struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	Queue       string          `gorm:"size:64;not null;index:idx_jobs_ready,priority:1" json:"queue"`
+	Kind        string          `gorm:"size:128;not null" json:"kind"`
+	Payload     json.RawMessage `gorm:"type:jsonb;not null" json:"payload"`
+	Status      string          `gorm:"size:20;not null;default:pending;index:idx_jobs_ready,priority:2" json:"status"`
+	RunAt       time.Time       `gorm:"not null;index:idx_jobs_ready,priority:3" json:"run_at"`
+	Attempts    int             `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int             `gorm:"not null;default:5" json:"max_attempts"`
+	// UniqueKey, when set, prevents enqueueing another job with the same key
+	// while this one is pending. A running job doesn't, so work arriving
+	// meanwhile is queued behind it. The partial unique index is created by
+	// a migration.
+	UniqueKey *string    `gorm:"size:255" json:"unique_key,omitempty"`
+	LastError string     `gorm:"type:text" json:"last_error,omitempty"`
+	LockedAt  *time.Time `gorm:"index" json:"locked_at,omitempty"`
+	LockedBy  string     `gorm:"size:128" json:"locked_by,omitempty"`
+	CreatedAt time.Time  `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"not null;autoUpdateTime" json:"updated_at"`
+}
+
+// DeadJob is a job that failed on every attempt. It stays here until an
+// administrator retries or deletes it.
+type DeadJob struct {
+	ID         uint            `gorm:"primaryKey" json:"id"`
+	JobID      uint            `gorm:"not null" json:"job_id"` // ID the job had in the jobs table
+	Queue      string          `gorm:"size:64;not null;index" json:"queue"`
+	Kind       string          `gorm:"size:128;not null" json:"kind"`
+	Payload    json.RawMessage `gorm:"type:jsonb;not null" json:"payload"`
+	Attempts   int             `gorm:"not null" json:"attempts"`
+	UniqueKey  *string         `gorm:"size:255" json:"unique_key,omitempty"`
+	LastError  string          `gorm:"type:text" json:"last_error"`
+	EnqueuedAt time.Time       `gorm:"not null" json:"enqueued_at"`
+	FailedAt   time.Time       `gorm:"not null;index" json:"failed_at"`
+}
+
+const (
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+)
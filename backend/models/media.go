@@ -0,0 +1,30 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Media is an image or other file stored elsewhere, such as a CDN, that
+// content blocks refer to by ID.
+type Media struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	URL        string         `gorm:"size:1024;not null" json:"url"`
+	Filename   string         `gorm:"size:255" json:"filename"`
+	MimeType   string         `gorm:"size:100;not null" json:"mime_type"`
+	Size       int64          `gorm:"not null;default:0" json:"size"`
+	Width      int            `gorm:"not null;default:0" json:"width,omitempty"`
+	Height     int            `gorm:"not null;default:0" json:"height,omitempty"`
+	Alt        string         `gorm:"size:500" json:"alt"`
+	UploaderID uint           `gorm:"not null;index" json:"uploader_id"`
+	CreatedAt  time.Time      `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time      `gorm:"not null;autoUpdateTime" json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// IsImage reports whether the media item is an image
+func (m *Media) IsImage() bool {
+	return strings.HasPrefix(m.MimeType, "image/")
+}
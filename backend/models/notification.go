@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification types
+const (
+	NotificationPostApproved  = "post_approved"
+	NotificationPostRejected  = "post_rejected"
+	NotificationPostCommented = "post_commented"
+	NotificationMentioned     = "mentioned"
+)
+
+// NotificationTypes lists every notification type.
+var NotificationTypes = []string{
+	NotificationPostApproved,
+	NotificationPostRejected,
+	NotificationPostCommented,
+	NotificationMentioned,
+}
+
+// Email delivery modes for a notification type
+const (
+	NotificationEmailOff     = "off"
+	NotificationEmailInstant = "instant"
+	NotificationEmailDaily   = "daily"
+)
+
+// Notification tells a user about something that concerns them. It is
+// stored when the user sees the type in the app or gets it in the daily
+// digest; InApp and Digest record which.
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index:idx_notifications_user,priority:1" json:"user_id"`
+	Type      string     `gorm:"size:50;not null" json:"type"`
+	ActorID   *uint      `json:"actor_id,omitempty"` // User who caused it, if any
+	PostID    *uint      `json:"post_id,omitempty"`
+	CommentID *uint      `json:"comment_id,omitempty"`
+	Title     string     `gorm:"size:255;not null" json:"title"`
+	Body      string     `gorm:"size:1000" json:"body"`
+	Link      string     `gorm:"size:512" json:"link"` // Path on the site, e.g. /posts/12
+	InApp     bool       `gorm:"not null" json:"-"`
+	Digest    bool       `gorm:"not null;default:false;index:idx_notifications_digest,where:digest AND emailed_at IS NULL" json:"-"`
+	ReadAt    *time.Time `json:"read_at"`
+	EmailedAt *time.Time `json:"-"`
+	CreatedAt time.Time  `gorm:"not null;autoCreateTime;index:idx_notifications_user,priority:2" json:"created_at"`
+}
+
+// NotificationPreference is a user's choice of channels for one
+// notification type. Types without a row use DefaultNotificationPreference.
+type NotificationPreference struct {
+	ID     uint   `gorm:"primaryKey" json:"-"`
+	UserID uint   `gorm:"not null;uniqueIndex:idx_notification_preferences_user_type" json:"-"`
+	Type   string `gorm:"size:50;not null;uniqueIndex:idx_notification_preferences_user_type" json:"type"`
+	InApp  bool   `gorm:"not null" json:"in_app"`
+	Email  string `gorm:"size:10;not null" json:"email"` // off, instant or daily
+}
+
+// DefaultNotificationPreference returns the channels used for type until
+// the user chooses otherwise: decisions on their posts are emailed right
+// away, conversation goes into the daily digest.
+func DefaultNotificationPreference(userID uint, notificationType string) NotificationPreference {
+	email := NotificationEmailDaily
+	if notificationType == NotificationPostApproved || notificationType == NotificationPostRejected {
+		email = NotificationEmailInstant
+	}
+	return NotificationPreference{UserID: userID, Type: notificationType, InApp: true, Email: email}
+}
+
+// IsNotificationType reports whether t is a known notification type.
+func IsNotificationType(t string) bool {
+	for _, known := range NotificationTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
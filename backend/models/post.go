@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 	"gorm.io/gorm"
 )
@@ -9,39 +10,91 @@ type Post struct {
 	ID          	uint           `gorm:"primaryKey"`
 	Title       	string         `gorm:"size:255;not null" validate:"required,min=3,max=255"`
 	Content     	string         `gorm:"type:text;not null" validate:"required,min=10"`
+	ContentFormat   string         `gorm:"size:20;not null;default:'html'" validate:"oneof=markdown html plain blocks"` // How Content is written
+	Blocks          json.RawMessage `gorm:"type:jsonb"` // Block document for the blocks format; Content then holds its text
+	RenderedHTML    string         `gorm:"type:text"` // Content rendered and sanitized, cached
+	RenderVersion   int            `gorm:"not null;default:0" json:"-"` // markup.Version RenderedHTML was rendered with
+	WordCount       int            `gorm:"not null;default:0"`
+	ReadingTime     int            `gorm:"not null;default:0"` // Minutes
 	Excerpt         string         `gorm:"size:500"`
-	Status      	string         `gorm:"size:20;not null;default:draft" validate:"oneof=draft published archived rejected"`
+	Status      	string         `gorm:"size:20;not null;default:draft" validate:"oneof=draft scheduled published archived rejected"`
 	AuthorID    	uint           `gorm:"not null"`
 	ApprovedBy  	*uint
-	Slug        	string         `gorm:"size:300;uniqueIndex" validate:"omitempty,alphanumdash"`
+	RejectionReason string         `gorm:"size:1000"` // Why staff rejected the post, shown to its author
+	Slug        	string         `gorm:"size:300;uniqueIndex:idx_posts_locale_slug,priority:2" validate:"omitempty,alphanumdash"` // Unique per locale
+	Locale          string         `gorm:"size:10;not null;default:'en';uniqueIndex:idx_posts_locale_slug,priority:1"` // Language the post is written in
+	SourceID        *uint          `gorm:"index"` // Post this one translates; nil for originals
+	SourceRevision  uint           `gorm:"not null;default:0"` // Source revision number the translation is up to date with
 	MetaTitle       string     	   `gorm:"size:255"`
 	MetaDescription string    	   `gorm:"size:500"`
 	FeaturedImage   string    	   `gorm:"size:512"`
+	Robots          string         `gorm:"size:200;not null;default:''"` // Robots meta directives such as noindex,nofollow; empty allows indexing
 	CategoryID     	*uint
 	ViewCount		uint           `gorm:"default:0"`
+	CommentsEnabled bool           `gorm:"not null;default:true"` // Whether readers may comment
+	SearchLanguage  string         `gorm:"type:regconfig;not null;default:'english'"`
+	Version         uint           `gorm:"not null;default:1"` // Incremented on every update for optimistic locking
 	CreatedAt   	time.Time      `gorm:"not null;autoCreateTime"`
 	UpdatedAt  	 	time.Time      `gorm:"not null;autoUpdateTime"`
 	PublishedAt 	*time.Time     `gorm:"index"`
+	ScheduledAt     *time.Time     `gorm:"index"` // When a scheduled post is published
+	UnpublishAt     *time.Time     `gorm:"index"` // When a published post is archived
 	ApprovedAt  	*time.Time     `gorm:"index"`
 	DeletedAt   	gorm.DeletedAt `gorm:"index"`
 	Category       Category   `gorm:"foreignKey:CategoryID"`
 	Tags          []Tag      `gorm:"many2many:post_tags;"`
 	Author      User           `gorm:"foreignKey:AuthorID"`
 	Approver    User `gorm:"foreignKey:ApprovedBy"`
+	LocalDates  *LocalDates `gorm:"-" json:",omitempty"` // Dates in the calendar a request asked for; not stored
+}
+
+// LocalDates are a post's dates formatted in the calendar and time zone a
+// request asked for.
+type LocalDates struct {
+	Calendar    string
+	PublishedAt string `json:",omitempty"`
+	CreatedAt   string
 }
 
 const (
     PostStatusDraft     = "draft"
+    PostStatusScheduled = "scheduled"
     PostStatusPublished = "published"
     PostStatusArchived  = "archived"
     PostStatusRejected  = "rejected"
 )
 
+// Post content formats
+const (
+    PostFormatMarkdown = "markdown"
+    PostFormatHTML     = "html"
+    PostFormatPlain    = "plain"
+    PostFormatBlocks   = "blocks"
+)
+
+// IsPostFormat reports whether format is a known content format
+func IsPostFormat(format string) bool {
+    switch format {
+    case PostFormatMarkdown, PostFormatHTML, PostFormatPlain, PostFormatBlocks:
+        return true
+    }
+    return false
+}
+
 // IsPublished checks if the post is published
 func (p *Post) IsPublished() bool {
     return p.Status == PostStatusPublished && p.PublishedAt != nil
 }
 
+// TranslationGroup is the ID shared by a post and its translations: that
+// of the original post
+func (p *Post) TranslationGroup() uint {
+    if p.SourceID != nil {
+        return *p.SourceID
+    }
+    return p.ID
+}
+
 // IsApproved checks if the post is approved
 func (p *Post) IsApproved() bool {
     return p.ApprovedBy != nil && p.ApprovedAt != nil
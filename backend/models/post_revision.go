@@ -0,0 +1,60 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// PostRevision is a snapshot of a post's editable text and status taken on
+// every save. Number counts revisions of a post from 1 and is never reused,
+// even after older revisions are pruned. Saves changing only the status,
+// such as the scheduler publishing a post, record a StatusOnly revision.
+type PostRevision struct {
+	ID              uint            `gorm:"primaryKey"`
+	PostID          uint            `gorm:"not null;uniqueIndex:idx_post_revisions_post_number"`
+	Number          uint            `gorm:"not null;uniqueIndex:idx_post_revisions_post_number"`
+	PostVersion     uint            `gorm:"not null;default:1"` // Post.Version this revision was saved as
+	Title           string          `gorm:"size:255;not null"`
+	Content         string          `gorm:"type:text;not null"`
+	ContentFormat   string          `gorm:"size:20;not null;default:'html'"`
+	Blocks          json.RawMessage `gorm:"type:jsonb"`
+	Excerpt         string          `gorm:"size:500"`
+	MetaTitle       string          `gorm:"size:255"`
+	MetaDescription string          `gorm:"size:500"`
+	Status          string          `gorm:"size:20"`                // Post status when saved; empty for revisions saved before statuses were recorded
+	StatusOnly      bool            `gorm:"not null;default:false"` // The text is unchanged from the previous revision
+	AuthorID        uint            `gorm:"not null"`
+	RestoredFrom    *uint           // Number of the revision this one restored, if any
+	CreatedAt       time.Time       `gorm:"not null;autoCreateTime"`
+	Author          User            `gorm:"foreignKey:AuthorID"`
+}
+
+// NewPostRevision snapshots the revisioned fields of post.
+func NewPostRevision(post *Post, number, authorID uint) *PostRevision {
+	return &PostRevision{
+		PostID:          post.ID,
+		Number:          number,
+		PostVersion:     post.Version,
+		Title:           post.Title,
+		Content:         post.Content,
+		ContentFormat:   post.ContentFormat,
+		Blocks:          post.Blocks,
+		Excerpt:         post.Excerpt,
+		MetaTitle:       post.MetaTitle,
+		MetaDescription: post.MetaDescription,
+		Status:          post.Status,
+		AuthorID:        authorID,
+	}
+}
+
+// SameContent reports whether r and other hold identical text.
+func (r *PostRevision) SameContent(other *PostRevision) bool {
+	return r.Title == other.Title &&
+		r.Content == other.Content &&
+		r.ContentFormat == other.ContentFormat &&
+		bytes.Equal(r.Blocks, other.Blocks) &&
+		r.Excerpt == other.Excerpt &&
+		r.MetaTitle == other.MetaTitle &&
+		r.MetaDescription == other.MetaDescription
+}
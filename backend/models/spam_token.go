@@ -0,0 +1,9 @@
+package models
+
+// SpamToken counts the spam and legitimate submissions a word appeared in,
+// training the spam classifier.
+type SpamToken struct {
+	Token string `gorm:"primaryKey;size:128"` // At most spam.MaxTokenLength
+	Spam  int64  `gorm:"not null;default:0"`
+	Ham   int64  `gorm:"not null;default:0"`
+}
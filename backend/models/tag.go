@@ -7,8 +7,10 @@ import (
 
 type Tag struct {
 	ID          uint           `gorm:"primaryKey"`
-	Name        string         `gorm:"size:255;not null;uniqueIndex" validate:"required,min=2,max=255"`
-	Slug        string         `gorm:"size:300;uniqueIndex" validate:"omitempty,alphanumdash"`
+	Name        string         `gorm:"size:255;not null;uniqueIndex:idx_tags_locale_name,priority:2" validate:"required,min=2,max=255"` // Unique per locale
+	Slug        string         `gorm:"size:300;uniqueIndex:idx_tags_locale_slug,priority:2" validate:"omitempty,alphanumdash"` // Unique per locale
+	Locale      string         `gorm:"size:10;not null;default:'en';uniqueIndex:idx_tags_locale_name,priority:1;uniqueIndex:idx_tags_locale_slug,priority:1"`
+	SourceID    *uint          `gorm:"index"` // Tag this one translates; nil for originals
 	Status      string         `gorm:"size:20;not null;default:active" validate:"oneof=active archived"`
 	CreatedAt   time.Time      `gorm:"not null;autoCreateTime"`
 	UpdatedAt   time.Time      `gorm:"not null;autoUpdateTime"`
@@ -22,6 +24,15 @@ const (
 	TagStatusArchived = "archived"
 )
 
+// TranslationGroup is the ID shared by a tag and its translations: that of
+// the original tag
+func (t *Tag) TranslationGroup() uint {
+	if t.SourceID != nil {
+		return *t.SourceID
+	}
+	return t.ID
+}
+
 // BeforeCreate hook for setting default values
 func (t *Tag) BeforeCreate(tx *gorm.DB) error {
 	if t.Status == "" {
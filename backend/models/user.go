@@ -11,11 +11,13 @@ type User struct {
 	FirstName 	  string 		 `gorm:"size:100;not null" json:"first_name" validate:"required,min=3,max=100"`
 	LastName      string 		 `gorm:"size:100;not null" json:"last_name" validate:"required,min=3,max=100"`
 	Email         string         `gorm:"size:255;uniqueIndex;not null" validate:"required,email"`
-	Password      string         `gorm:"size:255;not null" validate:"required,min=8"`
+	Password      string         `gorm:"size:255;not null" json:"-" validate:"required,min=8"`
 	Bio           string         `gorm:"type:text"`
 	Avatar        string         `gorm:"size:512"`
 	Role          string         `gorm:"size:50;not null;default:user" validate:"oneof=admin editor author user"`
 	Status        string         `gorm:"size:20;not null;default:active" validate:"oneof=active suspended banned"`
+	Locale        string         `gorm:"size:10" json:"locale"` // Language for emails; empty uses the site default
+	EmailVerifiedAt *time.Time   `json:"email_verified_at"`
 	LastLoginAt   *time.Time
 	CreatedAt     time.Time      `gorm:"not null;autoCreateTime"`
 	UpdatedAt     time.Time      `gorm:"not null;autoUpdateTime"`
@@ -1 +1,28 @@
-package routes
\ No newline at end of file
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/controllers"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/services"
+)
+
+func SetupAdminRoutes(r *gin.Engine, svc *services.Services) {
+	searchController := controllers.NewSearchController(svc.Search, svc.Locales)
+	jobController := controllers.NewJobController(svc.Jobs)
+	emailController := controllers.NewEmailController(svc.Email)
+
+	admin := r.Group("/api/admin", middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	{
+		admin.POST("/search/reindex", searchController.Reindex)
+
+		admin.GET("/jobs", jobController.ListJobs)
+		admin.GET("/jobs/stats", jobController.Stats)
+		admin.GET("/jobs/dead", jobController.ListDeadJobs)
+		admin.POST("/jobs/dead/:id/retry", jobController.RetryDeadJob)
+		admin.DELETE("/jobs/dead/:id", jobController.DeleteDeadJob)
+
+		admin.GET("/emails/templates", emailController.ListTemplates)
+		admin.GET("/emails/preview/:template", emailController.Preview)
+	}
+}
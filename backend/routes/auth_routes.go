@@ -2,11 +2,35 @@ package routes
 
 import (
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"github.com/sasanzare/go-cms/controllers"
+	"github.com/sasanzare/go-cms/services"
 )
 
-func SetupRouter(r *gin.Engine, db *gorm.DB) {
+func SetupRouter(r *gin.Engine, svc *services.Services) {
 	// Setup all main routes
-	SetupPostRoutes(r)
+	SetupAuthRoutes(r, svc)
+	SetupPostRoutes(r, svc)
+	SetupTaxonomyRoutes(r, svc)
+	SetupCommentRoutes(r, svc)
+	SetupMediaRoutes(r, svc)
+	SetupContentRoutes(r, svc)
+	SetupSearchRoutes(r, svc)
+	SetupFeedRoutes(r, svc)
+	SetupSitemapRoutes(r, svc)
+	SetupSEORoutes(r, svc)
+	SetupNotificationRoutes(r, svc)
+	SetupSpamRoutes(r, svc)
+	SetupAdminRoutes(r, svc)
+	SetupMetricsRoutes(r)
 	// Add other route setups here as needed
 }
+
+func SetupAuthRoutes(r *gin.Engine, svc *services.Services) {
+	authController := controllers.NewAuthController(svc.Auth)
+
+	auth := r.Group("/api/auth")
+	{
+		auth.POST("/register", authController.Register)
+		auth.GET("/verify-email", authController.VerifyEmail)
+	}
+}
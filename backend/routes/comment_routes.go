@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/controllers"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+)
+
+// SetupCommentRoutes mounts comment management; listing and posting
+// comments live under /api/posts/:id/comments.
+func SetupCommentRoutes(r *gin.Engine, svc *services.Services) {
+	commentController := controllers.NewCommentController(svc.Comments)
+
+	comments := r.Group("/api/comments", middleware.AuthMiddleware())
+	{
+		comments.DELETE("/:id", commentController.DeleteComment)
+
+		staff := middleware.RequireRoles(models.UserRoleAdmin, models.UserRoleEditor)
+		comments.GET("/moderation", staff, commentController.ModerationQueue)
+		comments.GET("/moderation/counts", staff, commentController.ModerationCounts)
+		comments.PUT("/:id/status", staff, commentController.ModerateComment)
+	}
+}
@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/controllers"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+)
+
+func SetupContentRoutes(r *gin.Engine, svc *services.Services) {
+	contentController := controllers.NewContentController(svc.Content)
+
+	types := r.Group("/api/content-types")
+	{
+		types.GET("", contentController.ListContentTypes)
+		types.GET("/:type", contentController.GetContentType)
+
+		admin := types.Group("", middleware.AuthMiddleware(), middleware.AdminMiddleware())
+		admin.POST("", contentController.CreateContentType)
+		admin.PUT("/:type", contentController.UpdateContentType)
+		admin.DELETE("/:type", contentController.DeleteContentType)
+	}
+
+	staff := middleware.RequireRoles(models.UserRoleAdmin, models.UserRoleEditor)
+	entries := r.Group("/api/content/:type")
+	{
+		entries.GET("", middleware.OptionalAuthMiddleware(), contentController.ListEntries)
+		entries.GET("/search", middleware.OptionalAuthMiddleware(), contentController.SearchEntries)
+		entries.GET("/:id", middleware.OptionalAuthMiddleware(), contentController.GetEntry)
+		entries.POST("", middleware.AuthMiddleware(), staff, contentController.CreateEntry)
+		entries.PUT("/:id", middleware.AuthMiddleware(), staff, contentController.UpdateEntry)
+		entries.DELETE("/:id", middleware.AuthMiddleware(), staff, contentController.DeleteEntry)
+	}
+}
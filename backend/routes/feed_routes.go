@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/controllers"
+	"github.com/sasanzare/go-cms/feed"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/services"
+)
+
+func SetupFeedRoutes(r *gin.Engine, svc *services.Services) {
+	feedController := controllers.NewFeedController(svc.Feeds, svc.Locales)
+	locale := middleware.Locale(svc.Locales)
+
+	for _, format := range []string{feed.FormatRSS, feed.FormatAtom, feed.FormatJSON} {
+		r.GET("/feed."+format, locale, feedController.SiteFeed)
+		r.GET("/categories/:slug/feed."+format, locale, feedController.CategoryFeed)
+		r.GET("/tags/:slug/feed."+format, locale, feedController.TagFeed)
+		r.GET("/authors/:id/feed."+format, locale, feedController.AuthorFeed)
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/controllers"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+)
+
+func SetupMediaRoutes(r *gin.Engine, svc *services.Services) {
+	mediaController := controllers.NewMediaController(svc.Media)
+
+	authors := middleware.RequireRoles(models.UserRoleAdmin, models.UserRoleEditor, models.UserRoleAuthor)
+	media := r.Group("/api/media", middleware.AuthMiddleware(), authors)
+	{
+		media.GET("", mediaController.ListMedia)
+		media.POST("", mediaController.CreateMedia)
+		media.GET("/:id", mediaController.GetMedia)
+	}
+}
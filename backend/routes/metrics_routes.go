@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/metrics"
+)
+
+func SetupMetricsRoutes(r *gin.Engine) {
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/controllers"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/services"
+)
+
+func SetupNotificationRoutes(r *gin.Engine, svc *services.Services) {
+	notificationController := controllers.NewNotificationController(svc.Notifications)
+
+	notifications := r.Group("/api/notifications", middleware.AuthMiddleware())
+	{
+		notifications.GET("", notificationController.ListNotifications)
+		notifications.GET("/unread-count", notificationController.UnreadCount)
+		notifications.POST("/read-all", notificationController.MarkAllRead)
+		notifications.POST("/:id/read", notificationController.MarkRead)
+		notifications.GET("/preferences", notificationController.GetPreferences)
+		notifications.PUT("/preferences", notificationController.UpdatePreferences)
+	}
+}
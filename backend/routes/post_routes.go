@@ -4,14 +4,44 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sasanzare/go-cms/controllers"
 	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
 )
 
-func SetupPostRoutes(r *gin.Engine) {
+func SetupPostRoutes(r *gin.Engine, svc *services.Services) {
+	postController := controllers.NewPostController(svc.Posts)
+	commentController := controllers.NewCommentController(svc.Comments)
+
 	posts := r.Group("/api/posts")
 	{
-		posts.GET("", controllers.ListPosts)
-		posts.GET("/:id", controllers.GetPost)
-		posts.POST("", middleware.AuthMiddleware(), controllers.CreatePost)
-		posts.PUT("/:id", middleware.AuthMiddleware(), controllers.UpdatePost)
+		locale := middleware.Locale(svc.Locales)
+		posts.GET("", middleware.OptionalAuthMiddleware(), locale, postController.ListPosts)
+		posts.GET("/highlight.css", postController.HighlightCSS)
+		posts.GET("/archive", middleware.OptionalAuthMiddleware(), locale, postController.Archive)
+		posts.GET("/slug/:slug", middleware.OptionalAuthMiddleware(), locale, postController.GetPostBySlug)
+		posts.GET("/:id", middleware.OptionalAuthMiddleware(), postController.GetPost)
+		writers := middleware.RequireRoles(models.UserRoleAdmin, models.UserRoleEditor, models.UserRoleAuthor)
+		posts.POST("", middleware.AuthMiddleware(), writers, postController.CreatePost)
+		posts.PUT("/:id", middleware.AuthMiddleware(), postController.UpdatePost)
+
+		staff := middleware.RequireRoles(models.UserRoleAdmin, models.UserRoleEditor)
+		posts.GET("/translations", middleware.AuthMiddleware(), staff, postController.ListTranslationTasks)
+		posts.GET("/:id/translations", middleware.AuthMiddleware(), postController.ListTranslations)
+		posts.POST("/:id/translations", middleware.AuthMiddleware(), writers, postController.CreateTranslation)
+		posts.POST("/:id/schedule", middleware.AuthMiddleware(), staff, postController.SchedulePost)
+		posts.DELETE("/:id/schedule", middleware.AuthMiddleware(), staff, postController.CancelSchedule)
+		posts.POST("/:id/approve", middleware.AuthMiddleware(), staff, postController.ApprovePost)
+		posts.POST("/:id/reject", middleware.AuthMiddleware(), staff, postController.RejectPost)
+
+		posts.GET("/:id/comments", commentController.ListComments)
+		posts.POST("/:id/comments", middleware.OptionalAuthMiddleware(), commentController.CreateComment)
+
+		revisions := posts.Group("/:id/revisions", middleware.AuthMiddleware())
+		{
+			revisions.GET("", postController.ListRevisions)
+			revisions.GET("/diff", postController.DiffRevisions)
+			revisions.GET("/:rev", postController.GetRevision)
+			revisions.POST("/:rev/restore", postController.RestoreRevision)
+		}
 	}
 }
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/controllers"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/services"
+)
+
+func SetupSearchRoutes(r *gin.Engine, svc *services.Services) {
+	searchController := controllers.NewSearchController(svc.Search, svc.Locales)
+
+	r.GET("/api/search", middleware.OptionalAuthMiddleware(), middleware.Locale(svc.Locales), searchController.Search)
+}
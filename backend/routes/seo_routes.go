@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/controllers"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/services"
+)
+
+func SetupSEORoutes(r *gin.Engine, svc *services.Services) {
+	seoController := controllers.NewSEOController(svc.SEO, svc.Posts)
+
+	r.GET("/api/posts/:id/seo", middleware.OptionalAuthMiddleware(), seoController.PostMeta)
+	r.GET("/api/categories/:id/seo", seoController.CategoryMeta)
+}
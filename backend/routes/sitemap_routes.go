@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/controllers"
+	"github.com/sasanzare/go-cms/services"
+)
+
+func SetupSitemapRoutes(r *gin.Engine, svc *services.Services) {
+	sitemapController := controllers.NewSitemapController(svc.Sitemaps)
+
+	r.GET("/sitemap.xml", sitemapController.Index)
+	r.GET("/sitemaps/:name", sitemapController.Shard)
+}
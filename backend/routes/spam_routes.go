@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/controllers"
+	"github.com/sasanzare/go-cms/services"
+)
+
+func SetupSpamRoutes(r *gin.Engine, svc *services.Services) {
+	spamController := controllers.NewSpamController(svc.Spam)
+
+	spam := r.Group("/api/spam")
+	{
+		spam.GET("/form-token", spamController.FormToken)
+	}
+}
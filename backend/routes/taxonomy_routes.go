@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/controllers"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+)
+
+func SetupTaxonomyRoutes(r *gin.Engine, svc *services.Services) {
+	taxonomyController := controllers.NewTaxonomyController(svc.Taxonomy)
+	locale := middleware.Locale(svc.Locales)
+	staff := middleware.RequireRoles(models.UserRoleAdmin, models.UserRoleEditor)
+
+	categories := r.Group("/api/categories")
+	{
+		categories.GET("", middleware.OptionalAuthMiddleware(), locale, taxonomyController.ListCategories)
+		categories.GET("/slug/:slug", middleware.OptionalAuthMiddleware(), locale, taxonomyController.GetCategoryBySlug)
+		categories.POST("", middleware.AuthMiddleware(), staff, taxonomyController.CreateCategory)
+		categories.PUT("/:id", middleware.AuthMiddleware(), staff, taxonomyController.UpdateCategory)
+		categories.GET("/translations", middleware.AuthMiddleware(), staff, taxonomyController.ListCategoryTranslationTasks)
+		categories.GET("/:id/translations", middleware.AuthMiddleware(), staff, taxonomyController.ListCategoryTranslations)
+		categories.POST("/:id/translations", middleware.AuthMiddleware(), staff, taxonomyController.CreateCategoryTranslation)
+	}
+
+	tags := r.Group("/api/tags")
+	{
+		tags.GET("", middleware.OptionalAuthMiddleware(), locale, taxonomyController.ListTags)
+		tags.GET("/slug/:slug", middleware.OptionalAuthMiddleware(), locale, taxonomyController.GetTagBySlug)
+		tags.POST("", middleware.AuthMiddleware(), staff, taxonomyController.CreateTag)
+		tags.PUT("/:id", middleware.AuthMiddleware(), staff, taxonomyController.UpdateTag)
+		tags.GET("/translations", middleware.AuthMiddleware(), staff, taxonomyController.ListTagTranslationTasks)
+		tags.GET("/:id/translations", middleware.AuthMiddleware(), staff, taxonomyController.ListTagTranslations)
+		tags.POST("/:id/translations", middleware.AuthMiddleware(), staff, taxonomyController.CreateTagTranslation)
+	}
+}
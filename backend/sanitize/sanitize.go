@@ -0,0 +1,285 @@
+// Package sanitize cleans untrusted HTML against allowlist policies.
+//
+// A Policy names the elements and attributes it keeps. Everything else is
+// dropped: unknown elements are unwrapped so their text survives, while
+// elements such as script and style lose their contents too. Text and
+// attribute values are re-escaped for their context, URL attributes must
+// use an allowed scheme, and unclosed elements are closed, so the output
+// can be embedded in a page as is.
+package sanitize
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// dropContents are elements removed along with everything inside them.
+var dropContents = map[string]bool{
+	"script": true, "style": true, "iframe": true, "frame": true, "frameset": true,
+	"object": true, "embed": true, "applet": true, "noscript": true, "noembed": true,
+	"noframes": true, "template": true, "textarea": true, "select": true,
+	"svg": true, "math": true, "xmp": true, "title": true, "head": true,
+}
+
+// voidElements have no end tag.
+var voidElements = map[string]bool{
+	"br": true, "hr": true, "img": true, "wbr": true, "input": true,
+}
+
+// urlAttrs hold URLs and are checked against the policy's schemes.
+var urlAttrs = map[string]bool{
+	"href": true, "src": true, "cite": true,
+}
+
+// Policy is an allowlist of elements and attributes. Build one with
+// NewPolicy or use Comment or Post. Once built, a policy is safe for
+// concurrent use.
+type Policy struct {
+	elements map[string]map[string]bool
+	patterns map[string]*regexp.Regexp
+	schemes  map[string]bool
+	linkRel  []string
+}
+
+// NewPolicy returns a policy allowing no elements, with http, https and
+// mailto URLs.
+func NewPolicy() *Policy {
+	return &Policy{
+		elements: map[string]map[string]bool{},
+		patterns: map[string]*regexp.Regexp{},
+		schemes:  map[string]bool{"http": true, "https": true, "mailto": true},
+	}
+}
+
+// AllowElements keeps elements without attributes.
+func (p *Policy) AllowElements(names ...string) *Policy {
+	for _, name := range names {
+		if p.elements[name] == nil {
+			p.elements[name] = map[string]bool{}
+		}
+	}
+	return p
+}
+
+// AllowAttrs keeps element with attrs.
+func (p *Policy) AllowAttrs(element string, attrs ...string) *Policy {
+	p.AllowElements(element)
+	for _, attr := range attrs {
+		p.elements[element][attr] = true
+	}
+	return p
+}
+
+// MatchAttr keeps attr, wherever it is allowed, only when its value
+// matches pattern.
+func (p *Policy) MatchAttr(attr string, pattern *regexp.Regexp) *Policy {
+	p.patterns[attr] = pattern
+	return p
+}
+
+// AllowSchemes replaces the URL schemes links and images may use.
+// Relative URLs are always allowed.
+func (p *Policy) AllowSchemes(schemes ...string) *Policy {
+	p.schemes = map[string]bool{}
+	for _, s := range schemes {
+		p.schemes[strings.ToLower(s)] = true
+	}
+	return p
+}
+
+// RequireLinkRel sets rel on every link, replacing any rel given. Links
+// opening in a new window also get noopener and noreferrer.
+func (p *Policy) RequireLinkRel(rel ...string) *Policy {
+	p.linkRel = rel
+	return p
+}
+
+// Comment allows the basic formatting readers use in comments. Links are
+// marked nofollow and ugc so they pass no ranking to spammers.
+func Comment() *Policy {
+	return NewPolicy().
+		AllowElements("p", "br", "strong", "b", "em", "i", "u", "s", "code", "pre", "ul", "ol", "li").
+		AllowAttrs("blockquote", "cite").
+		AllowAttrs("a", "href", "title").
+		RequireLinkRel("nofollow", "ugc")
+}
+
+// Post allows the rich content authors write: headings, images, tables
+// and the like on top of Comment's formatting, with links left followable.
+// Code blocks keep the classes syntax highlighting uses, and task lists
+// their checkboxes.
+func Post() *Policy {
+	return NewPolicy().
+		AllowElements("p", "br", "hr", "strong", "b", "em", "i", "u", "s", "del", "ins",
+			"sub", "sup", "mark", "small", "kbd", "code", "pre", "span", "div",
+			"h1", "h2", "h3", "h4", "h5", "h6", "ul", "li", "dl", "dt", "dd",
+			"figure", "figcaption", "table", "caption", "thead", "tbody", "tfoot", "tr", "cite").
+		AllowAttrs("ol", "start", "reversed").
+		AllowAttrs("blockquote", "cite").
+		AllowAttrs("q", "cite").
+		AllowAttrs("abbr", "title").
+		AllowAttrs("a", "href", "title", "target").
+		AllowAttrs("img", "src", "alt", "title", "width", "height").
+		AllowAttrs("th", "colspan", "rowspan", "scope", "align").
+		AllowAttrs("td", "colspan", "rowspan", "align").
+		AllowAttrs("pre", "class").
+		AllowAttrs("code", "class").
+		AllowAttrs("span", "class").
+		AllowAttrs("input", "type", "checked", "disabled").
+		MatchAttr("class", regexp.MustCompile(`^[A-Za-z0-9 _-]*$`)).
+		MatchAttr("align", regexp.MustCompile(`^(left|center|right)$`)).
+		MatchAttr("type", regexp.MustCompile(`^checkbox$`))
+}
+
+// Sanitize returns input with everything the policy doesn't allow removed.
+func (p *Policy) Sanitize(input string) string {
+	var b strings.Builder
+	var open []string
+	skip := 0
+
+	z := html.NewTokenizer(strings.NewReader(input))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			for i := len(open) - 1; i >= 0; i-- {
+				b.WriteString("</" + open[i] + ">")
+			}
+			return b.String()
+
+		case html.TextToken:
+			if skip == 0 {
+				b.WriteString(html.EscapeString(string(z.Text())))
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			if dropContents[token.Data] {
+				if tt == html.StartTagToken && !voidElements[token.Data] {
+					skip++
+				}
+				continue
+			}
+			attrs, ok := p.elements[token.Data]
+			if skip > 0 || !ok {
+				continue
+			}
+			b.WriteString(p.startTag(token, attrs))
+			switch {
+			case voidElements[token.Data]:
+			case tt == html.SelfClosingTagToken:
+				b.WriteString("</" + token.Data + ">")
+			default:
+				open = append(open, token.Data)
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if dropContents[tag] {
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			// Close tag, and anything left open inside it; stray end tags
+			// are dropped
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == tag {
+					for j := len(open) - 1; j >= i; j-- {
+						b.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+		}
+	}
+}
+
+// startTag renders token with only the allowed attributes.
+func (p *Policy) startTag(token html.Token, allowed map[string]bool) string {
+	var b strings.Builder
+	b.WriteString("<" + token.Data)
+
+	seen := map[string]bool{}
+	newWindow := false
+	for _, attr := range token.Attr {
+		key := strings.ToLower(attr.Key)
+		if attr.Namespace != "" || !allowed[key] || seen[key] {
+			continue
+		}
+		value := attr.Val
+		if pattern := p.patterns[key]; pattern != nil && !pattern.MatchString(value) {
+			continue
+		}
+		switch {
+		case urlAttrs[key]:
+			var ok bool
+			if value, ok = p.safeURL(value); !ok {
+				continue
+			}
+		case key == "target":
+			if value != "_blank" {
+				continue
+			}
+			newWindow = true
+		}
+		seen[key] = true
+		b.WriteString(" " + key + `="` + html.EscapeString(value) + `"`)
+	}
+
+	if token.Data == "a" {
+		rel := append([]string(nil), p.linkRel...)
+		if newWindow {
+			rel = append(rel, "noopener", "noreferrer")
+		}
+		if len(rel) > 0 {
+			sort.Strings(rel)
+			b.WriteString(` rel="` + strings.Join(rel, " ") + `"`)
+		}
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// safeURL checks raw against the policy's schemes. Browsers ignore tabs
+// and newlines inside URLs, so "java\tscript:" is caught as well.
+func (p *Policy) safeURL(raw string) (string, bool) {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return -1
+		case r < 0x20 || r == 0x7f:
+			return 0
+		}
+		return r
+	}, strings.TrimSpace(raw))
+	if strings.ContainsRune(cleaned, 0) {
+		return "", false
+	}
+
+	u, err := url.Parse(cleaned)
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme == "" {
+		// A colon before any slash would make browsers read a scheme
+		// url.Parse rejected
+		if i := strings.IndexByte(cleaned, ':'); i >= 0 && !strings.ContainsAny(cleaned[:i], "/?#") {
+			return "", false
+		}
+		return cleaned, true
+	}
+	if !p.schemes[strings.ToLower(u.Scheme)] {
+		return "", false
+	}
+	return cleaned, true
+}
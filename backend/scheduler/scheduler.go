@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/tracing"
+)
+
+// batchSize bounds how many posts one transaction claims.
+const batchSize = 50
+
+// Scheduler publishes scheduled posts and archives expired ones in the
+// background. Every instance of the application may run one; the service
+// claims posts with SKIP LOCKED so each is handled once.
+type Scheduler struct {
+	posts    *services.PostService
+	interval time.Duration
+}
+
+func New(posts *services.PostService, interval time.Duration) *Scheduler {
+	return &Scheduler{posts: posts, interval: interval}
+}
+
+// Run ticks every interval until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	slog.Info("post scheduler started", slog.Duration("interval", s.interval))
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.Tick(ctx)
+		select {
+		case <-ctx.Done():
+			slog.Info("post scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick publishes every due post and archives every expired one. Errors are
+// logged; the next tick retries.
+func (s *Scheduler) Tick(ctx context.Context) {
+	ctx, span := tracing.Start(ctx, "Scheduler.Tick")
+	defer span.End()
+
+	drain(ctx, "publish scheduled posts", s.posts.PublishDue)
+	drain(ctx, "unpublish expired posts", s.posts.UnpublishExpired)
+}
+
+// drain runs step until it handles less than a full batch.
+func drain(ctx context.Context, name string, step func(context.Context, int) (int, error)) {
+	for ctx.Err() == nil {
+		n, err := step(ctx, batchSize)
+		if err != nil {
+			slog.ErrorContext(ctx, "scheduler failed to "+name, slog.String("error", err.Error()))
+			return
+		}
+		if n < batchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"context"
+	"time"
+
+	"github.com/sasanzare/go-cms/models"
+)
+
+// Index is a full-text index over posts. PostService keeps it up to date
+// on create, update, publish and delete; SearchService queries it.
+type Index interface {
+	// Index adds or replaces the document with doc.ID.
+	Index(ctx context.Context, doc Document) error
+	// Delete removes a document; deleting a missing document is not an error.
+	Delete(ctx context.Context, id uint) error
+	// Search returns matching document IDs ordered by relevance.
+	Search(ctx context.Context, q Query) (*Result, error)
+	// Reset removes every document before a full reindex.
+	Reset(ctx context.Context) error
+}
+
+// Document is the searchable projection of a post.
+type Document struct {
+	ID          uint
+	Title       string
+	Excerpt     string
+	Content     string
+	Language    string
+	Locale      string
+	Status      string
+	CategoryID  uint
+	TagSlugs    []string
+	PublishedAt *time.Time
+}
+
+// DocumentFromPost builds a Document from a post with its tags loaded.
+func DocumentFromPost(post *models.Post) Document {
+	doc := Document{
+		ID:          post.ID,
+		Title:       post.Title,
+		Excerpt:     post.Excerpt,
+		Content:     post.Content,
+		Language:    post.SearchLanguage,
+		Locale:      post.Locale,
+		Status:      post.Status,
+		PublishedAt: post.PublishedAt,
+	}
+	if post.CategoryID != nil {
+		doc.CategoryID = *post.CategoryID
+	}
+	for _, tag := range post.Tags {
+		doc.TagSlugs = append(doc.TagSlugs, tag.Slug)
+	}
+	return doc
+}
+
+// Query holds the search text and the filters combined with it.
+type Query struct {
+	Text string
+	// Language restricts results to documents in this language and parses
+	// the query with it. Empty searches all languages.
+	Language string
+	// Prefix matches query words as prefixes ("post" finds "postgres").
+	Prefix bool
+	// Fuzzy tolerates typos when the exact query finds nothing.
+	Fuzzy bool
+
+	// Locales restricts results to documents written in one of these
+	// locales. Empty searches every locale.
+	Locales []string
+
+	Status     string
+	CategoryID uint
+	TagSlugs   []string
+
+	// Offset and Limit select the page of hits; facets and Total always
+	// cover every match.
+	Offset int
+	Limit  int
+}
+
+// Hit is one matching document. Highlights are HTML-escaped text with
+// matched words wrapped in <mark> tags.
+type Hit struct {
+	ID             uint
+	Score          float64
+	TitleHighlight string
+	Snippet        string
+}
+
+// Facets count all matches per category ID and tag slug.
+type Facets struct {
+	Categories map[uint]int64   `json:"categories"`
+	Tags       map[string]int64 `json:"tags"`
+}
+
+// Result is a page of hits with totals and facet counts.
+type Result struct {
+	Hits   []Hit
+	Total  int64
+	Facets Facets
+	// Fuzzy is set when the hits come from typo-tolerant matching.
+	Fuzzy bool
+}
@@ -0,0 +1,354 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Relative weights of matches in each field, mirroring the A/B/C weights
+// of the PostgreSQL search vector.
+const (
+	titleWeight   = 3.0
+	excerptWeight = 2.0
+	contentWeight = 1.0
+)
+
+// Score multipliers for inexact term matches.
+const (
+	prefixPenalty = 0.8
+	fuzzyPenalty  = 0.5
+)
+
+const snippetWords = 30
+
+// MemoryIndex is an embedded, pure-Go inverted index. It needs no database
+// features and suits small deployments and tests; its contents are lost on
+// restart, so it must be rebuilt with a reindex at startup.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	docs     map[uint]*memoryDoc
+	postings map[string]map[uint]float64 // term -> document -> weighted frequency
+}
+
+type memoryDoc struct {
+	doc   Document
+	terms []string
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		docs:     map[uint]*memoryDoc{},
+		postings: map[string]map[uint]float64{},
+	}
+}
+
+func (m *MemoryIndex) Index(ctx context.Context, doc Document) error {
+	weights := map[string]float64{}
+	for _, field := range []struct {
+		text   string
+		weight float64
+	}{
+		{doc.Title, titleWeight},
+		{doc.Excerpt, excerptWeight},
+		{stripTags(doc.Content), contentWeight},
+	} {
+		for _, term := range Tokenize(field.text) {
+			weights[term] += field.weight
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(doc.ID)
+	entry := &memoryDoc{doc: doc, terms: make([]string, 0, len(weights))}
+	for term, w := range weights {
+		if m.postings[term] == nil {
+			m.postings[term] = map[uint]float64{}
+		}
+		m.postings[term][doc.ID] = w
+		entry.terms = append(entry.terms, term)
+	}
+	m.docs[doc.ID] = entry
+	return nil
+}
+
+func (m *MemoryIndex) Delete(ctx context.Context, id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(id)
+	return nil
+}
+
+func (m *MemoryIndex) Reset(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.docs = map[uint]*memoryDoc{}
+	m.postings = map[string]map[uint]float64{}
+	return nil
+}
+
+// Len returns the number of indexed documents.
+func (m *MemoryIndex) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.docs)
+}
+
+func (m *MemoryIndex) remove(id uint) {
+	entry, ok := m.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range entry.terms {
+		delete(m.postings[term], id)
+		if len(m.postings[term]) == 0 {
+			delete(m.postings, term)
+		}
+	}
+	delete(m.docs, id)
+}
+
+// Search matches every query word (AND). With q.Prefix words also match as
+// prefixes; with q.Fuzzy and no exact results, words within a small edit
+// distance match too.
+func (m *MemoryIndex) Search(ctx context.Context, q Query) (*Result, error) {
+	terms := Tokenize(q.Text)
+	result := &Result{Facets: newFacets()}
+	if len(terms) == 0 {
+		return result, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	scores, matched := m.match(terms, q, false)
+	if len(scores) == 0 && q.Fuzzy {
+		scores, matched = m.match(terms, q, true)
+		result.Fuzzy = true
+	}
+
+	ids := make([]uint, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] > ids[j]
+	})
+
+	result.Total = int64(len(ids))
+	for _, id := range ids {
+		doc := m.docs[id].doc
+		if doc.CategoryID != 0 {
+			result.Facets.Categories[doc.CategoryID]++
+		}
+		for _, slug := range doc.TagSlugs {
+			result.Facets.Tags[slug]++
+		}
+	}
+
+	start := q.Offset
+	if start > len(ids) {
+		start = len(ids)
+	}
+	end := start + limitOrDefault(q.Limit)
+	if end > len(ids) {
+		end = len(ids)
+	}
+	for _, id := range ids[start:end] {
+		doc := m.docs[id].doc
+		result.Hits = append(result.Hits, Hit{
+			ID:             id,
+			Score:          scores[id],
+			TitleHighlight: formatHighlight(markTerms(doc.Title, matched[id], 0)),
+			Snippet:        formatHighlight(markTerms(stripTags(doc.Content), matched[id], snippetWords)),
+		})
+	}
+	return result, nil
+}
+
+// match scores documents containing every query term and records which
+// index terms matched in each document for highlighting.
+func (m *MemoryIndex) match(terms []string, q Query, fuzzy bool) (map[uint]float64, map[uint]map[string]bool) {
+	total := float64(len(m.docs))
+	var scores map[uint]float64
+	matched := map[uint]map[string]bool{}
+
+	for _, term := range terms {
+		termScores := map[uint]float64{}
+		for indexTerm, factor := range m.expand(term, q.Prefix, fuzzy) {
+			postings := m.postings[indexTerm]
+			idf := math.Log(1 + total/float64(len(postings)))
+			for id, w := range postings {
+				if !m.accepts(m.docs[id].doc, q) {
+					continue
+				}
+				termScores[id] += w * idf * factor
+				if matched[id] == nil {
+					matched[id] = map[string]bool{}
+				}
+				matched[id][indexTerm] = true
+			}
+		}
+
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for id := range scores {
+			if s, ok := termScores[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+	return scores, matched
+}
+
+// expand returns the index terms matching a query term with their score factor.
+func (m *MemoryIndex) expand(term string, prefix, fuzzy bool) map[string]float64 {
+	out := map[string]float64{}
+	if !fuzzy {
+		if _, ok := m.postings[term]; ok {
+			out[term] = 1
+		}
+		if prefix {
+			for indexTerm := range m.postings {
+				if indexTerm != term && strings.HasPrefix(indexTerm, term) {
+					out[indexTerm] = prefixPenalty
+				}
+			}
+		}
+		return out
+	}
+
+	maxEdits := allowedEdits(term)
+	if maxEdits == 0 {
+		return out
+	}
+	for indexTerm := range m.postings {
+		if levenshtein(term, indexTerm, maxEdits) <= maxEdits {
+			out[indexTerm] = fuzzyPenalty
+		}
+	}
+	return out
+}
+
+func (m *MemoryIndex) accepts(doc Document, q Query) bool {
+	if q.Language != "" && doc.Language != q.Language {
+		return false
+	}
+	if len(q.Locales) > 0 && !contains(q.Locales, doc.Locale) {
+		return false
+	}
+	if q.Status != "" && doc.Status != q.Status {
+		return false
+	}
+	if q.CategoryID != 0 && doc.CategoryID != q.CategoryID {
+		return false
+	}
+	if len(q.TagSlugs) > 0 {
+		for _, want := range q.TagSlugs {
+			for _, have := range doc.TagSlugs {
+				if want == have {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedEdits scales typo tolerance with word length so short words
+// don't match unrelated ones.
+func allowedEdits(term string) int {
+	switch n := len([]rune(term)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// levenshtein returns the edit distance between a and b, or max+1 as soon
+// as the distance is known to exceed max.
+func levenshtein(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// markTerms wraps words containing a matched term in highlight markers.
+// With window > 0 only that many words around the first match are kept.
+func markTerms(text string, terms map[string]bool, window int) string {
+	words := strings.Fields(text)
+	first := -1
+	for i, w := range words {
+		for _, t := range Tokenize(w) {
+			if terms[t] {
+				words[i] = highlightStart + w + highlightStop
+				if first < 0 {
+					first = i
+				}
+				break
+			}
+		}
+	}
+
+	if window <= 0 || len(words) <= window {
+		return strings.Join(words, " ")
+	}
+	start := max(first-window/3, 0)
+	end := min(start+window, len(words))
+	snippet := strings.Join(words[start:end], " ")
+	if start > 0 {
+		snippet = "… " + snippet
+	}
+	if end < len(words) {
+		snippet += " …"
+	}
+	return snippet
+}
@@ -0,0 +1,256 @@
+package search
+
+import (
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// fuzzyThreshold is the minimum pg_trgm word similarity for typo-tolerant
+// title matches.
+const fuzzyThreshold = 0.4
+
+// PostgresIndex searches the posts table directly using the generated
+// search_vector column, so writes need no work: PostgreSQL keeps the
+// vector in sync with the row.
+type PostgresIndex struct {
+	db *gorm.DB
+}
+
+func NewPostgresIndex(db *gorm.DB) *PostgresIndex {
+	return &PostgresIndex{db: db}
+}
+
+func (p *PostgresIndex) Index(ctx context.Context, doc Document) error {
+	return nil
+}
+
+func (p *PostgresIndex) Delete(ctx context.Context, id uint) error {
+	return nil
+}
+
+// Reset is a no-op; the generated column can't drift from the row.
+func (p *PostgresIndex) Reset(ctx context.Context) error {
+	return nil
+}
+
+func (p *PostgresIndex) Search(ctx context.Context, q Query) (*Result, error) {
+	tsquery, tsArgs, ok := buildTSQuery(q)
+	if !ok {
+		return &Result{Facets: newFacets()}, nil
+	}
+
+	// Full-text match, ranked by cover density over the weighted vector
+	m := match{
+		from:     "posts CROSS JOIN (SELECT " + tsquery + " AS query) AS q",
+		fromArgs: tsArgs,
+		cond:     "posts.search_vector @@ q.query",
+		rank:     "ts_rank_cd(posts.search_vector, q.query)",
+		headlines: `ts_headline(search_language, title, query,
+				'HighlightAll=true, StartSel="` + highlightStart + `", StopSel="` + highlightStop + `"') AS title_highlight,
+			ts_headline(search_language, content, query,
+				'MaxFragments=2, MaxWords=30, MinWords=10, FragmentDelimiter=" … ", StartSel="` + highlightStart + `", StopSel="` + highlightStop + `"') AS snippet`,
+		carry: "posts.title, posts.content, posts.search_language, q.query",
+	}
+	result, err := p.run(ctx, q, m)
+	if err != nil || result.Total > 0 || !q.Fuzzy {
+		return result, err
+	}
+
+	// Nothing matched: fall back to trigram similarity on titles to catch typos
+	m = match{
+		from:      "posts",
+		cond:      "word_similarity(?, posts.title) >= ?",
+		condArgs:  []interface{}{q.Text, fuzzyThreshold},
+		rank:      "word_similarity(?, posts.title)",
+		rankArgs:  []interface{}{q.Text},
+		headlines: "title AS title_highlight, left(content, 240) AS snippet",
+		carry:     "posts.title, posts.content",
+	}
+	result, err = p.run(ctx, q, m)
+	if result != nil {
+		result.Fuzzy = true
+	}
+	return result, err
+}
+
+// match describes how rows are matched and ranked for one search strategy.
+type match struct {
+	from      string
+	fromArgs  []interface{}
+	cond      string
+	condArgs  []interface{}
+	rank      string
+	rankArgs  []interface{}
+	headlines string
+	carry     string
+}
+
+func (p *PostgresIndex) run(ctx context.Context, q Query, m match) (*Result, error) {
+	db := p.db.WithContext(ctx)
+	where, whereArgs := filterConditions(q)
+	where = append([]string{m.cond}, where...)
+	whereSQL := strings.Join(where, " AND ")
+	filterArgs := concat(m.fromArgs, m.condArgs, whereArgs)
+
+	// Headlines are expensive, so they are computed only for the page rows
+	sql := `WITH matches AS (
+			SELECT posts.id, ` + m.carry + `, ` + m.rank + ` AS rank, COUNT(*) OVER () AS total
+			FROM ` + m.from + `
+			WHERE ` + whereSQL + `
+			ORDER BY rank DESC, posts.id DESC
+			LIMIT ? OFFSET ?
+		)
+		SELECT id, rank, total, ` + m.headlines + `
+		FROM matches
+		ORDER BY rank DESC, id DESC`
+
+	// The rank expression precedes FROM in the statement
+	args := concat(m.rankArgs, filterArgs, []interface{}{limitOrDefault(q.Limit), q.Offset})
+
+	var rows []struct {
+		ID             uint
+		Rank           float64
+		Total          int64
+		TitleHighlight string
+		Snippet        string
+	}
+	if err := db.Raw(sql, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := &Result{Facets: newFacets()}
+	for _, row := range rows {
+		result.Total = row.Total
+		result.Hits = append(result.Hits, Hit{
+			ID:             row.ID,
+			Score:          row.Rank,
+			TitleHighlight: formatHighlight(row.TitleHighlight),
+			Snippet:        formatHighlight(row.Snippet),
+		})
+	}
+	if result.Total == 0 && q.Offset > 0 {
+		// The page is past the end; count separately so totals stay right
+		if err := db.Raw(`SELECT COUNT(*) FROM `+m.from+` WHERE `+whereSQL, filterArgs...).Scan(&result.Total).Error; err != nil {
+			return nil, err
+		}
+	}
+	if result.Total == 0 {
+		return result, nil
+	}
+
+	var categories []struct {
+		CategoryID uint
+		Count      int64
+	}
+	if err := db.Raw(`SELECT posts.category_id, COUNT(*) AS count FROM `+m.from+`
+		WHERE `+whereSQL+` AND posts.category_id IS NOT NULL
+		GROUP BY posts.category_id`, filterArgs...).Scan(&categories).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range categories {
+		result.Facets.Categories[c.CategoryID] = c.Count
+	}
+
+	var tags []struct {
+		Slug  string
+		Count int64
+	}
+	if err := db.Raw(`SELECT tags.slug, COUNT(DISTINCT posts.id) AS count FROM `+m.from+`
+		JOIN post_tags ON post_tags.post_id = posts.id
+		JOIN tags ON tags.id = post_tags.tag_id AND tags.deleted_at IS NULL
+		WHERE `+whereSQL+`
+		GROUP BY tags.slug`, filterArgs...).Scan(&tags).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range tags {
+		result.Facets.Tags[t.Slug] = t.Count
+	}
+
+	return result, nil
+}
+
+func filterConditions(q Query) ([]string, []interface{}) {
+	where := []string{"posts.deleted_at IS NULL"}
+	var args []interface{}
+
+	if q.Language != "" {
+		where = append(where, "posts.search_language = ?::regconfig")
+		args = append(args, q.Language)
+	}
+	if len(q.Locales) > 0 {
+		where = append(where, "posts.locale IN ?")
+		args = append(args, q.Locales)
+	}
+	if q.Status != "" {
+		where = append(where, "posts.status = ?")
+		args = append(args, q.Status)
+	}
+	if q.CategoryID != 0 {
+		where = append(where, "posts.category_id = ?")
+		args = append(args, q.CategoryID)
+	}
+	if len(q.TagSlugs) > 0 {
+		where = append(where, `posts.id IN (
+			SELECT post_tags.post_id FROM post_tags
+			JOIN tags ON tags.id = post_tags.tag_id
+			WHERE tags.slug IN ? AND tags.deleted_at IS NULL)`)
+		args = append(args, q.TagSlugs)
+	}
+	return where, args
+}
+
+// buildTSQuery returns the SQL tsquery expression for q and its arguments.
+// Without a language filter the query is parsed with every supported
+// configuration and OR-ed, so it stays constant across rows and the GIN
+// index remains usable.
+func buildTSQuery(q Query) (string, []interface{}, bool) {
+	text := q.Text
+	fn := "websearch_to_tsquery"
+	if q.Prefix {
+		terms := Tokenize(q.Text)
+		if len(terms) == 0 {
+			return "", nil, false
+		}
+		for i, t := range terms {
+			terms[i] = t + ":*"
+		}
+		text = strings.Join(terms, " & ")
+		fn = "to_tsquery"
+	} else if strings.TrimSpace(text) == "" {
+		return "", nil, false
+	}
+
+	languages := Languages
+	if q.Language != "" {
+		languages = []string{q.Language}
+	}
+
+	parts := make([]string, len(languages))
+	args := make([]interface{}, 0, len(languages)*2)
+	for i, lang := range languages {
+		parts[i] = fn + "(?::regconfig, ?)"
+		args = append(args, lang, text)
+	}
+	return "(" + strings.Join(parts, " || ") + ")", args, true
+}
+
+func newFacets() Facets {
+	return Facets{Categories: map[uint]int64{}, Tags: map[string]int64{}}
+}
+
+func limitOrDefault(limit int) int {
+	if limit <= 0 {
+		return 20
+	}
+	return limit
+}
+
+func concat(parts ...[]interface{}) []interface{} {
+	var out []interface{}
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package search
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Languages lists the PostgreSQL text search configurations a post may be
+// indexed with. "simple" does no stemming and suits languages PostgreSQL
+// has no dictionary for, such as Persian.
+var Languages = []string{"simple", "english", "arabic", "french", "german", "italian", "russian", "spanish", "turkish"}
+
+// DefaultLanguage is used for posts that don't set one.
+const DefaultLanguage = "english"
+
+// IsValidLanguage reports whether lang is a supported text search configuration.
+func IsValidLanguage(lang string) bool {
+	for _, l := range Languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// Highlight markers placed around matches before escaping; they are
+// swapped for <mark> tags once the text is safe HTML.
+const (
+	highlightStart = "⟦"
+	highlightStop  = "⟧"
+)
+
+var (
+	termPattern    = regexp.MustCompile(`[\p{L}\p{N}]+`)
+	htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+	// Arabic code points commonly typed in place of their Persian forms
+	persianNormalizer = strings.NewReplacer("ي", "ی", "ك", "ک", "ى", "ی", "ۀ", "ه", "ة", "ه", "‌", " ")
+)
+
+// Tokenize splits text into lowercase words, normalizing Arabic/Persian
+// letter variants so either spelling matches.
+func Tokenize(text string) []string {
+	return termPattern.FindAllString(strings.ToLower(persianNormalizer.Replace(text)), -1)
+}
+
+// formatHighlight turns marked-up text into safe HTML: markup from the
+// source is stripped, the text escaped and the markers turned into <mark>.
+func formatHighlight(s string) string {
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	s = html.EscapeString(html.UnescapeString(s))
+	s = strings.ReplaceAll(s, highlightStart, "<mark>")
+	s = strings.ReplaceAll(s, highlightStop, "</mark>")
+	return strings.TrimSpace(s)
+}
+
+// stripTags removes HTML tags and decodes entities, leaving plain text.
+func stripTags(s string) string {
+	return html.UnescapeString(htmlTagPattern.ReplaceAllString(s, " "))
+}
@@ -0,0 +1,71 @@
+package seo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultRobots lets search engines index a page and follow its links.
+const DefaultRobots = "index,follow"
+
+var robotsDirectives = map[string]bool{
+	"index": true, "noindex": true, "follow": true, "nofollow": true,
+	"noarchive": true, "nosnippet": true, "noimageindex": true, "notranslate": true,
+}
+
+// NormalizeRobots checks a comma-separated list of robots meta directives
+// and returns it lowercased without duplicates or spaces. "none" becomes
+// "noindex,nofollow" and "all" is dropped, since it is the default.
+func NormalizeRobots(raw string) (string, error) {
+	var directives []string
+	seen := map[string]bool{}
+	add := func(d string) {
+		if !seen[d] {
+			seen[d] = true
+			directives = append(directives, d)
+		}
+	}
+	for _, d := range strings.Split(raw, ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		name, value, hasValue := strings.Cut(d, ":")
+		switch {
+		case d == "" || d == "all":
+		case d == "none":
+			add("noindex")
+			add("nofollow")
+		case robotsDirectives[d]:
+			add(d)
+		case hasValue && (name == "max-snippet" || name == "max-video-preview"):
+			if n, err := strconv.Atoi(value); err != nil || n < -1 {
+				return "", fmt.Errorf("%s must be a number of characters or seconds, or -1", name)
+			}
+			add(d)
+		case hasValue && name == "max-image-preview":
+			if value != "none" && value != "standard" && value != "large" {
+				return "", fmt.Errorf("max-image-preview must be none, standard or large")
+			}
+			add(d)
+		default:
+			return "", fmt.Errorf("unknown robots directive %q", d)
+		}
+	}
+	if seen["index"] && seen["noindex"] {
+		return "", fmt.Errorf("index and noindex contradict each other")
+	}
+	if seen["follow"] && seen["nofollow"] {
+		return "", fmt.Errorf("follow and nofollow contradict each other")
+	}
+	return strings.Join(directives, ","), nil
+}
+
+// NoIndex reports whether robots, as returned by NormalizeRobots, keeps
+// the page out of search results.
+func NoIndex(robots string) bool {
+	for _, d := range strings.Split(robots, ",") {
+		if d == "noindex" {
+			return true
+		}
+	}
+	return false
+}
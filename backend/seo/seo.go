@@ -0,0 +1,215 @@
+// Package seo computes the metadata search engines and social networks
+// read from a page: its title, description, canonical URL and robots
+// directives, Open Graph and Twitter card tags, and schema.org JSON-LD.
+package seo
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// Page types
+const (
+	TypeArticle = "article"
+	TypeWebsite = "website"
+)
+
+// MaxDescription is the length descriptions are cut to, as search engines
+// show about that much.
+const MaxDescription = 160
+
+// maxHeadline is the longest Article headline search engines accept.
+const maxHeadline = 110
+
+// Page describes a page of the site. URL is its canonical address.
+type Page struct {
+	Type        string
+	Title       string
+	Description string
+	URL         string
+	Image       string
+	Locale      string
+	Robots      string
+	SiteName    string
+	SiteURL     string
+	LogoURL     string
+	// Article details
+	Author    string
+	Section   string
+	Tags      []string
+	Published *time.Time
+	Modified  *time.Time
+	// Breadcrumbs lead from the home page to the page itself
+	Breadcrumbs []Crumb
+	// Alternates are the page's translations, itself included
+	Alternates []Alternate
+}
+
+// Crumb is a step of a breadcrumb trail.
+type Crumb struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Alternate is a version of a page in another language. Lang is a
+// language tag or "x-default".
+type Alternate struct {
+	Lang string `json:"hreflang"`
+	Href string `json:"href"`
+}
+
+// Tag is a meta tag: <meta property> for Open Graph, <meta name> for
+// Twitter cards.
+type Tag struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// Meta is the computed metadata of a page.
+type Meta struct {
+	Title       string                   `json:"title"`
+	Description string                   `json:"description"`
+	Canonical   string                   `json:"canonical"`
+	Robots      string                   `json:"robots"`
+	Image       string                   `json:"image,omitempty"`
+	Alternates  []Alternate              `json:"alternates,omitempty"`
+	OpenGraph   []Tag                    `json:"open_graph"`
+	Twitter     []Tag                    `json:"twitter"`
+	JSONLD      []map[string]interface{} `json:"json_ld"`
+}
+
+// Build computes the metadata of p. Descriptions are cut to
+// MaxDescription and robots defaults to DefaultRobots.
+func Build(p Page) *Meta {
+	m := &Meta{
+		Title:       p.Title,
+		Description: utils.Excerpt(strings.Join(strings.Fields(p.Description), " "), MaxDescription),
+		Canonical:   p.URL,
+		Robots:      p.Robots,
+		Image:       p.Image,
+		Alternates:  p.Alternates,
+	}
+	if m.Robots == "" {
+		m.Robots = DefaultRobots
+	}
+
+	og := func(name, content string) {
+		if content != "" {
+			m.OpenGraph = append(m.OpenGraph, Tag{Name: name, Content: content})
+		}
+	}
+	og("og:type", p.Type)
+	og("og:title", m.Title)
+	og("og:description", m.Description)
+	og("og:url", p.URL)
+	og("og:image", p.Image)
+	og("og:site_name", p.SiteName)
+	og("og:locale", ogLocale(p.Locale))
+	for _, alt := range p.Alternates {
+		if alt.Lang != p.Locale && alt.Lang != "x-default" {
+			og("og:locale:alternate", ogLocale(alt.Lang))
+		}
+	}
+	if p.Type == TypeArticle {
+		og("article:published_time", formatTime(p.Published))
+		og("article:modified_time", formatTime(p.Modified))
+		og("article:author", p.Author)
+		og("article:section", p.Section)
+		for _, tag := range p.Tags {
+			og("article:tag", tag)
+		}
+	}
+
+	card := "summary"
+	if p.Image != "" {
+		card = "summary_large_image"
+	}
+	m.Twitter = []Tag{{Name: "twitter:card", Content: card}, {Name: "twitter:title", Content: m.Title}}
+	if m.Description != "" {
+		m.Twitter = append(m.Twitter, Tag{Name: "twitter:description", Content: m.Description})
+	}
+	if p.Image != "" {
+		m.Twitter = append(m.Twitter, Tag{Name: "twitter:image", Content: p.Image})
+	}
+
+	m.JSONLD = []map[string]interface{}{}
+	if p.Type == TypeArticle {
+		m.JSONLD = append(m.JSONLD, article(p, m))
+	}
+	if len(p.Breadcrumbs) > 0 {
+		m.JSONLD = append(m.JSONLD, breadcrumbList(p.Breadcrumbs))
+	}
+	return m
+}
+
+func article(p Page, m *Meta) map[string]interface{} {
+	ld := map[string]interface{}{
+		"@context":         "https://schema.org",
+		"@type":            "Article",
+		"headline":         utils.Excerpt(m.Title, maxHeadline),
+		"mainEntityOfPage": map[string]interface{}{"@type": "WebPage", "@id": p.URL},
+	}
+	set := func(key, value string) {
+		if value != "" {
+			ld[key] = value
+		}
+	}
+	set("description", m.Description)
+	set("inLanguage", p.Locale)
+	set("datePublished", formatTime(p.Published))
+	set("dateModified", formatTime(p.Modified))
+	set("articleSection", p.Section)
+	if len(p.Tags) > 0 {
+		ld["keywords"] = strings.Join(p.Tags, ", ")
+	}
+	if p.Image != "" {
+		ld["image"] = []string{p.Image}
+	}
+	if p.Author != "" {
+		ld["author"] = map[string]interface{}{"@type": "Person", "name": p.Author}
+	}
+	publisher := map[string]interface{}{"@type": "Organization", "name": p.SiteName}
+	if p.SiteURL != "" {
+		publisher["url"] = p.SiteURL
+	}
+	if p.LogoURL != "" {
+		publisher["logo"] = map[string]interface{}{"@type": "ImageObject", "url": p.LogoURL}
+	}
+	ld["publisher"] = publisher
+	return ld
+}
+
+func breadcrumbList(crumbs []Crumb) map[string]interface{} {
+	items := make([]map[string]interface{}, len(crumbs))
+	for i, crumb := range crumbs {
+		items[i] = map[string]interface{}{
+			"@type":    "ListItem",
+			"position": i + 1,
+			"name":     crumb.Name,
+			"item":     crumb.URL,
+		}
+	}
+	return map[string]interface{}{
+		"@context":        "https://schema.org",
+		"@type":           "BreadcrumbList",
+		"itemListElement": items,
+	}
+}
+
+// ogLocale writes a language tag as Open Graph does: "en-us" is "en_US".
+func ogLocale(tag string) string {
+	lang, region, ok := strings.Cut(tag, "-")
+	if !ok {
+		return lang
+	}
+	return lang + "_" + strings.ToUpper(region)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
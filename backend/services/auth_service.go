@@ -1,9 +1,18 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sasanzare/go-cms/metrics"
 	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/spam"
+	"github.com/sasanzare/go-cms/tracing"
 	"github.com/sasanzare/go-cms/utils"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -12,25 +21,58 @@ import (
 // AuthService provides authentication and authorization operations
 // including user registration, login, role checking and user management.
 type AuthService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	email     *EmailService
+	verifyURL string
+	spam      *SpamService
 }
 
+// AuthServiceOption configures optional AuthService dependencies
+type AuthServiceOption func(*AuthService)
+
+// WithEmailVerification sends new users a verification email through
+// email. The link is verifyURL with the token appended as ?token=.
+func WithEmailVerification(email *EmailService, verifyURL string) AuthServiceOption {
+	return func(s *AuthService) {
+		s.email = email
+		s.verifyURL = verifyURL
+	}
+}
+
+// WithSpamChecks screens registrations that come with request signals
+// through spam.
+func WithSpamChecks(spam *SpamService) AuthServiceOption {
+	return func(s *AuthService) {
+		s.spam = spam
+	}
+}
+
+// verificationTTL is how long email verification links stay valid
+const verificationTTL = 48 * time.Hour
+
 // NewAuthService creates a new instance of AuthService with database dependency
 //
 // Parameters:
 //   - db: GORM database instance
+//   - opts: optional dependencies such as WithEmailVerification
 //
 // Returns:
 //   - *AuthService: initialized AuthService
-func NewAuthService(db *gorm.DB) *AuthService {
-	return &AuthService{db: db}
+func NewAuthService(db *gorm.DB, opts ...AuthServiceOption) *AuthService {
+	s := &AuthService{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // RegisterUser registers a new user with validation and role assignment
 //
 // Parameters:
+//   - ctx: Request context for cancellation and logging
 //   - user: User model with registration data
 //   - role: Desired role for the user (defaults to UserRoleUser)
+//   - signals: Request details of a public signup, screened for spam; nil for internal callers
 //
 // Returns:
 //   - *models.User: Registered user data
@@ -43,21 +85,43 @@ func NewAuthService(db *gorm.DB) *AuthService {
 //
 // Security:
 //   - Password is hashed before storage
-func (s *AuthService) RegisterUser(user *models.User, role string) (*models.User, error) {
+func (s *AuthService) RegisterUser(ctx context.Context, user *models.User, role string, signals *spam.Signals) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.RegisterUser")
+	defer tracing.End(span, &err)
+
 	// Validate email format
 	if !utils.ValidateEmail(user.Email) {
-		return nil, errors.New("invalid email format")
+		return nil, errors.New(utils.ValidationFailedMsg + ": invalid email format")
 	}
 
 	// Validate password complexity
 	if !utils.ValidatePassword(user.Password) {
-		return nil, errors.New("password must contain at least 8 characters, uppercase, lowercase, number and special character")
+		return nil, errors.New(utils.ValidationFailedMsg + ": password must contain at least 8 characters, uppercase, lowercase, number and special character")
+	}
+
+	// Store names and bio as plain text
+	user.FirstName = utils.SanitizeText(user.FirstName)
+	user.LastName = utils.SanitizeText(user.LastName)
+	user.Bio = utils.SanitizeText(user.Bio)
+
+	// Screen public signups for spam
+	if signals != nil {
+		verdict := s.spam.Check(ctx, &spam.Submission{
+			Kind:    spam.KindRegistration,
+			Body:    user.Bio,
+			Name:    strings.Join([]string{user.Username, user.FirstName, user.LastName}, " "),
+			Email:   user.Email,
+			Signals: *signals,
+		})
+		if verdict.Spam {
+			return nil, errors.New(utils.ValidationFailedMsg + ": registration rejected")
+		}
 	}
 
 	// Check for existing email
 	var existingUser models.User
-	if err := s.db.Where("email = ?", user.Email).First(&existingUser).Error; err == nil {
-		return nil, errors.New("email already registered")
+	if err := s.db.WithContext(ctx).Where("email = ?", user.Email).First(&existingUser).Error; err == nil {
+		return nil, errors.New(utils.ValidationFailedMsg + ": email already registered")
 	}
 
 	// Hash password using bcrypt
@@ -80,16 +144,85 @@ func (s *AuthService) RegisterUser(user *models.User, role string) (*models.User
 	}
 
 	// Create user record
-	if err := s.db.Create(&user).Error; err != nil {
+	if err := s.db.WithContext(ctx).Create(&user).Error; err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	slog.InfoContext(ctx, "user registered", slog.Uint64("user_id", uint64(user.ID)), slog.String("role", user.Role))
+
+	// The account exists either way; the user can ask for another email
+	if err := s.SendVerificationEmail(ctx, user); err != nil {
+		slog.ErrorContext(ctx, "failed to send verification email", slog.Uint64("user_id", uint64(user.ID)), slog.String("error", err.Error()))
+	}
+
 	return user, nil
 }
 
+// SendVerificationEmail emails the user a link confirming their address
+//
+// Parameters:
+//   - ctx: Request context for cancellation and logging
+//   - user: User to verify; ID, Email, FirstName and Locale are used
+//
+// Returns:
+//   - error: Token or delivery error; nil when verification is not configured
+func (s *AuthService) SendVerificationEmail(ctx context.Context, user *models.User) (err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.SendVerificationEmail")
+	defer tracing.End(span, &err)
+
+	if s.email == nil {
+		return nil
+	}
+
+	token, err := utils.GenerateActionToken(user.ID, user.Email, utils.PurposeEmailVerification, verificationTTL)
+	if err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
+	}
+	link := s.verifyURL + "?token=" + url.QueryEscape(token)
+
+	return s.email.SendVerificationEmail(ctx, user.Email, user.Locale, user.FirstName, link)
+}
+
+// VerifyEmail marks the user's email address as verified
+//
+// Parameters:
+//   - ctx: Request context for cancellation and logging
+//   - token: Token from the verification link
+//
+// Returns:
+//   - *models.User: The verified user
+//   - error: Error if the token is invalid or expired, or the address changed since it was sent
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.VerifyEmail")
+	defer tracing.End(span, &err)
+
+	claims, err := utils.ValidateActionToken(token, utils.PurposeEmailVerification)
+	if err != nil {
+		return nil, errors.New(utils.ValidationFailedMsg + ": invalid or expired verification link")
+	}
+
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, claims.UserID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.Email != claims.Email {
+		return nil, errors.New(utils.ValidationFailedMsg + ": verification link is for a previous email address")
+	}
+
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		if err := s.db.WithContext(ctx).Model(&user).Update("email_verified_at", &now).Error; err != nil {
+			return nil, err
+		}
+		slog.InfoContext(ctx, "email verified", slog.Uint64("user_id", uint64(user.ID)))
+	}
+	return &user, nil
+}
+
 // Login authenticates user and generates JWT token
 //
 // Parameters:
+//   - ctx: Request context for cancellation and logging
 //   - email: User's email address
 //   - password: User's password
 //
@@ -102,20 +235,28 @@ func (s *AuthService) RegisterUser(user *models.User, role string) (*models.User
 //   2. Checks user existence
 //   3. Verifies password
 //   4. Generates JWT token
-func (s *AuthService) Login(email, password string) (string, error) {
+func (s *AuthService) Login(ctx context.Context, email, password string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Login")
+	defer tracing.End(span, &err)
+
 	// Validate email format
 	if !utils.ValidateEmail(email) {
+		metrics.LoginsFailed.WithLabelValues("invalid_email").Inc()
 		return "", errors.New("invalid email format")
 	}
 
 	// Find user by email
 	var user models.User
-	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		slog.WarnContext(ctx, "login failed", slog.String("reason", "user_not_found"))
+		metrics.LoginsFailed.WithLabelValues("user_not_found").Inc()
 		return "", errors.New("user not found")
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		slog.WarnContext(ctx, "login failed", slog.String("reason", "invalid_password"), slog.Uint64("user_id", uint64(user.ID)))
+		metrics.LoginsFailed.WithLabelValues("invalid_password").Inc()
 		return "", errors.New("invalid password")
 	}
 
@@ -131,6 +272,7 @@ func (s *AuthService) Login(email, password string) (string, error) {
 // CheckUserRole verifies if user has the required role
 //
 // Parameters:
+//   - ctx: Request context for cancellation and logging
 //   - userID: ID of the user to check
 //   - requiredRole: Role to verify against
 //
@@ -139,9 +281,12 @@ func (s *AuthService) Login(email, password string) (string, error) {
 //
 // Note:
 //   - Typically used for middleware authorization
-func (s *AuthService) CheckUserRole(userID uint, requiredRole string) bool {
+func (s *AuthService) CheckUserRole(ctx context.Context, userID uint, requiredRole string) bool {
+	ctx, span := tracing.Start(ctx, "AuthService.CheckUserRole")
+	defer span.End()
+
 	var user models.User
-	if err := s.db.First(&user, userID).Error; err != nil {
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
 		return false
 	}
 	return user.Role == requiredRole
@@ -150,14 +295,18 @@ func (s *AuthService) CheckUserRole(userID uint, requiredRole string) bool {
 // GetUserByID retrieves user by ID for access control purposes
 //
 // Parameters:
+//   - ctx: Request context for cancellation and logging
 //   - userID: ID of the user to retrieve
 //
 // Returns:
 //   - *models.User: User data if found
 //   - error: Error if user not found
-func (s *AuthService) GetUserByID(userID uint) (*models.User, error) {
+func (s *AuthService) GetUserByID(ctx context.Context, userID uint) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.GetUserByID")
+	defer tracing.End(span, &err)
+
 	var user models.User
-	if err := s.db.First(&user, userID).Error; err != nil {
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
 		return nil, errors.New("user not found")
 	}
 	return &user, nil
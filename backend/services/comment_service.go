@@ -0,0 +1,481 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sasanzare/go-cms/events"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/sanitize"
+	"github.com/sasanzare/go-cms/spam"
+	"github.com/sasanzare/go-cms/tracing"
+	"github.com/sasanzare/go-cms/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// Comment limits
+const (
+	// MaxCommentDepth is how deeply replies nest; top-level comments have
+	// depth 0
+	MaxCommentDepth  = 5
+	maxCommentLength = 5000
+)
+
+// commentPolicy is the markup comments may contain.
+var commentPolicy = sanitize.Comment()
+
+// commentColumns selects comments with their author's display name.
+const commentColumns = "comments.*, COALESCE(users.username, comments.guest_name) AS author_name"
+
+// CommentService manages comments on posts and their moderation.
+type CommentService struct {
+	db     *gorm.DB
+	events *events.Bus
+	spam   *SpamService
+	clock  utils.Clock
+}
+
+func NewCommentService(db *gorm.DB, bus *events.Bus) *CommentService {
+	return &CommentService{db: db, events: bus, clock: utils.RealClock{}}
+}
+
+// CommentInput is a new comment. Signed-in users are taken from the
+// context; guests must give a name and email.
+type CommentInput struct {
+	PostID     uint
+	ParentID   *uint
+	Body       string
+	GuestName  string
+	GuestEmail string
+	spam.Signals
+}
+
+// CreateComment validates and stores a comment. Comments by staff and by
+// users with an approved comment are approved right away, others wait for
+// moderation. Except for staff, comments are scored for spam first and
+// those flagged go straight to the spam folder.
+func (s *CommentService) CreateComment(ctx context.Context, in CommentInput) (_ *models.Comment, err error) {
+	ctx, span := tracing.Start(ctx, "CommentService.CreateComment", attribute.Int("post.id", int(in.PostID)))
+	defer tracing.End(span, &err)
+
+	comment := &models.Comment{
+		PostID:    in.PostID,
+		Body:      strings.TrimSpace(commentPolicy.Sanitize(in.Body)),
+		IPAddress: in.IP,
+		UserAgent: truncateRunes(in.UserAgent, 255),
+	}
+	text := utils.SanitizeText(comment.Body)
+	if text == "" {
+		return nil, errors.New(utils.ValidationFailedMsg + ": body is required")
+	}
+	if utf8.RuneCountInString(text) > maxCommentLength {
+		return nil, errors.New(utils.ValidationFailedMsg + ": body exceeds 5000 characters")
+	}
+
+	claims, signedIn := utils.ClaimsFromContext(ctx)
+	if signedIn {
+		comment.UserID = &claims.UserID
+	} else {
+		comment.GuestName = utils.SanitizeText(in.GuestName)
+		comment.GuestEmail = in.GuestEmail
+		if comment.GuestName == "" || utf8.RuneCountInString(comment.GuestName) > 100 {
+			return nil, errors.New(utils.ValidationFailedMsg + ": name is required and may have up to 100 characters")
+		}
+		if !utils.ValidateEmail(comment.GuestEmail) {
+			return nil, errors.New(utils.ValidationFailedMsg + ": a valid email is required")
+		}
+	}
+
+	db := s.db.WithContext(ctx)
+	post, err := s.commentablePost(db, in.PostID)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.ParentID != nil {
+		var parent models.Comment
+		err := db.Where("id = ? AND post_id = ? AND status = ?", *in.ParentID, in.PostID, models.CommentStatusApproved).First(&parent).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("parent comment not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if parent.Depth >= MaxCommentDepth {
+			return nil, errors.New(utils.ValidationFailedMsg + ": replies are nested too deeply")
+		}
+		comment.ParentID = &parent.ID
+		comment.RootID = parent.RootID
+		if comment.RootID == nil {
+			comment.RootID = &parent.ID
+		}
+		comment.Depth = parent.Depth + 1
+	}
+
+	comment.Status, err = s.initialStatus(db, claims, signedIn)
+	if err != nil {
+		return nil, err
+	}
+	if !signedIn || !isStaffRole(claims.Role) {
+		verdict := s.spam.Check(ctx, &spam.Submission{
+			Kind:    spam.KindComment,
+			Body:    comment.Body,
+			Name:    comment.GuestName,
+			Email:   comment.GuestEmail,
+			Signals: in.Signals,
+		})
+		comment.SpamScore = verdict.Score
+		if comment.SpamChecks, err = json.Marshal(verdict.Results); err != nil {
+			return nil, err
+		}
+		if verdict.Spam {
+			comment.Status = models.CommentStatusSpam
+		}
+	}
+	if comment.Status == models.CommentStatusApproved {
+		now := s.clock.Now()
+		comment.ApprovedAt = &now
+	}
+	if err := db.Create(comment).Error; err != nil {
+		return nil, err
+	}
+
+	created, err := s.GetComment(ctx, comment.ID)
+	if err != nil {
+		return nil, err
+	}
+	if created.Status == models.CommentStatusApproved {
+		s.publishApproved(ctx, post, created)
+	}
+
+	slog.InfoContext(ctx, "comment created",
+		slog.Uint64("comment_id", uint64(created.ID)), slog.Uint64("post_id", uint64(post.ID)), slog.String("status", created.Status))
+	return created, nil
+}
+
+// commentablePost loads a published post that accepts comments.
+func (s *CommentService) commentablePost(db *gorm.DB, postID uint) (*models.Post, error) {
+	var post models.Post
+	err := db.Select("id, title, author_id, status, published_at, comments_enabled").First(&post, postID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !post.IsPublished()) {
+		return nil, errors.New("post not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !post.CommentsEnabled {
+		return nil, errors.New(utils.ValidationFailedMsg + ": comments are closed on this post")
+	}
+	return &post, nil
+}
+
+func (s *CommentService) initialStatus(db *gorm.DB, claims *utils.Claims, signedIn bool) (string, error) {
+	if !signedIn {
+		return models.CommentStatusPending, nil
+	}
+	if isStaffRole(claims.Role) {
+		return models.CommentStatusApproved, nil
+	}
+
+	var approved int64
+	err := db.Model(&models.Comment{}).
+		Where("user_id = ? AND status = ?", claims.UserID, models.CommentStatusApproved).
+		Count(&approved).Error
+	if err != nil {
+		return "", err
+	}
+	if approved > 0 {
+		return models.CommentStatusApproved, nil
+	}
+	return models.CommentStatusPending, nil
+}
+
+func isStaffRole(role string) bool {
+	return role == models.UserRoleAdmin || role == models.UserRoleEditor
+}
+
+// GetComment retrieves a comment in any state.
+func (s *CommentService) GetComment(ctx context.Context, id uint) (_ *models.Comment, err error) {
+	ctx, span := tracing.Start(ctx, "CommentService.GetComment", attribute.Int("comment.id", int(id)))
+	defer tracing.End(span, &err)
+
+	var comment models.Comment
+	err = s.withAuthor(s.db.WithContext(ctx)).Where("comments.id = ?", id).First(&comment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("comment not found")
+	}
+	return &comment, err
+}
+
+// ListComments returns a page of a published post's approved threads,
+// oldest first, each with its approved replies nested. Replies to
+// comments that aren't approved are left out with their parent.
+func (s *CommentService) ListComments(ctx context.Context, postID uint, page, pageSize int) (_ []models.Comment, _ *utils.Pagination, err error) {
+	ctx, span := tracing.Start(ctx, "CommentService.ListComments", attribute.Int("post.id", int(postID)))
+	defer tracing.End(span, &err)
+
+	db := s.db.WithContext(ctx)
+	var post models.Post
+	err = db.Select("id, status, published_at").First(&post, postID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !post.IsPublished()) {
+		return nil, nil, errors.New("post not found")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page, pageSize = utils.NormalizePage(page, pageSize)
+	roots := db.Model(&models.Comment{}).
+		Where("comments.post_id = ? AND comments.parent_id IS NULL AND comments.status = ?", postID, models.CommentStatusApproved)
+
+	var total int64
+	if err := roots.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+	var threads []models.Comment
+	err = s.withAuthor(roots).Order("comments.created_at, comments.id").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&threads).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(threads) > 0 {
+		rootIDs := make([]uint, len(threads))
+		for i, c := range threads {
+			rootIDs[i] = c.ID
+		}
+		var replies []models.Comment
+		err = s.withAuthor(db.Model(&models.Comment{})).
+			Where("comments.root_id IN ? AND comments.status = ?", rootIDs, models.CommentStatusApproved).
+			Order("comments.created_at, comments.id").
+			Find(&replies).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		threads = nestReplies(threads, replies)
+	}
+
+	return threads, utils.NewPagination(page, pageSize, total), nil
+}
+
+// nestReplies attaches replies, ordered oldest first, beneath their
+// parents among roots and the replies themselves.
+func nestReplies(roots, replies []models.Comment) []models.Comment {
+	children := map[uint][]models.Comment{}
+	for _, r := range replies {
+		children[*r.ParentID] = append(children[*r.ParentID], r)
+	}
+
+	var attach func(c *models.Comment)
+	attach = func(c *models.Comment) {
+		c.Replies = children[c.ID]
+		for i := range c.Replies {
+			attach(&c.Replies[i])
+		}
+	}
+	for i := range roots {
+		attach(&roots[i])
+	}
+	return roots
+}
+
+// ModerationItem is a comment in the moderation queue with the spam score
+// it got when submitted and each rule's share of it.
+type ModerationItem struct {
+	models.Comment
+	SpamScore  float64           `json:"spam_score"`
+	SpamChecks []spam.RuleResult `json:"spam_checks"`
+}
+
+// ModerationQueue returns a page of comments in status (pending by
+// default), optionally for one post, oldest first.
+func (s *CommentService) ModerationQueue(ctx context.Context, status string, postID uint, page, pageSize int) (_ []ModerationItem, _ *utils.Pagination, err error) {
+	ctx, span := tracing.Start(ctx, "CommentService.ModerationQueue")
+	defer tracing.End(span, &err)
+
+	if status == "" {
+		status = models.CommentStatusPending
+	}
+	if !models.IsCommentStatus(status) {
+		return nil, nil, errors.New(utils.ValidationFailedMsg + ": unknown comment status")
+	}
+	page, pageSize = utils.NormalizePage(page, pageSize)
+
+	query := s.db.WithContext(ctx).Model(&models.Comment{}).Where("comments.status = ?", status)
+	if postID != 0 {
+		query = query.Where("comments.post_id = ?", postID)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+	var comments []models.Comment
+	err = query.
+		Select(commentColumns + ", posts.title AS post_title").
+		Joins("LEFT JOIN users ON users.id = comments.user_id").
+		Joins("JOIN posts ON posts.id = comments.post_id").
+		Order("comments.created_at, comments.id").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&comments).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pagination := utils.NewPagination(page, pageSize, total)
+
+	items := make([]ModerationItem, len(comments))
+	for i, c := range comments {
+		items[i] = ModerationItem{Comment: c, SpamScore: c.SpamScore, SpamChecks: []spam.RuleResult{}}
+		if len(c.SpamChecks) > 0 {
+			if err := json.Unmarshal(c.SpamChecks, &items[i].SpamChecks); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return items, pagination, nil
+}
+
+// ModerationCounts returns the number of comments in each state.
+func (s *CommentService) ModerationCounts(ctx context.Context) (_ map[string]int64, err error) {
+	ctx, span := tracing.Start(ctx, "CommentService.ModerationCounts")
+	defer tracing.End(span, &err)
+
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err = s.db.WithContext(ctx).Model(&models.Comment{}).
+		Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int64{
+		models.CommentStatusPending:  0,
+		models.CommentStatusApproved: 0,
+		models.CommentStatusSpam:     0,
+		models.CommentStatusTrashed:  0,
+	}
+	for _, r := range rows {
+		counts[r.Status] = r.Count
+	}
+	return counts, nil
+}
+
+// ModerateComment moves a comment to status on behalf of the user in ctx.
+// The post's author and mentioned users are notified the first time a
+// comment is approved. Approving and marking as spam train the spam
+// classifier, undoing any earlier training on the comment.
+func (s *CommentService) ModerateComment(ctx context.Context, id uint, status string) (_ *models.Comment, err error) {
+	ctx, span := tracing.Start(ctx, "CommentService.ModerateComment",
+		attribute.Int("comment.id", int(id)), attribute.String("comment.status", status))
+	defer tracing.End(span, &err)
+
+	if !models.IsCommentStatus(status) {
+		return nil, errors.New(utils.ValidationFailedMsg + ": unknown comment status")
+	}
+	comment, err := s.GetComment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Status == status {
+		return comment, nil
+	}
+
+	now := s.clock.Now()
+	updates := map[string]interface{}{"status": status, "moderated_at": &now}
+	if claims, ok := utils.ClaimsFromContext(ctx); ok {
+		updates["moderated_by"] = claims.UserID
+	}
+	firstApproval := status == models.CommentStatusApproved && comment.ApprovedAt == nil
+	if firstApproval {
+		updates["approved_at"] = &now
+	}
+	if label := trainingLabel(status); label != "" && label != comment.TrainedAs {
+		text := (&spam.Submission{Body: comment.Body, Name: comment.GuestName}).Text()
+		if err := s.spam.Relabel(ctx, text, comment.TrainedAs, label); err != nil {
+			slog.ErrorContext(ctx, "failed to train spam classifier", slog.Uint64("comment_id", uint64(id)), slog.String("error", err.Error()))
+		} else {
+			updates["trained_as"] = label
+		}
+	}
+	if err := s.db.WithContext(ctx).Model(&models.Comment{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	comment, err = s.GetComment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if firstApproval {
+		var post models.Post
+		if err := s.db.WithContext(ctx).Select("id, title, author_id").First(&post, comment.PostID).Error; err == nil {
+			s.publishApproved(ctx, &post, comment)
+		}
+	}
+
+	slog.InfoContext(ctx, "comment moderated", slog.Uint64("comment_id", uint64(id)), slog.String("status", status))
+	return comment, nil
+}
+
+// trainingLabel is what the spam classifier learns from a comment moved to
+// status; pending and trashed comments teach it nothing.
+func trainingLabel(status string) string {
+	switch status {
+	case models.CommentStatusSpam:
+		return spam.LabelSpam
+	case models.CommentStatusApproved:
+		return spam.LabelHam
+	}
+	return ""
+}
+
+// publishApproved announces a newly visible comment to the post's author
+// and the users it mentions.
+func (s *CommentService) publishApproved(ctx context.Context, post *models.Post, comment *models.Comment) {
+	var actorID uint
+	if comment.UserID != nil {
+		actorID = *comment.UserID
+	}
+	text := utils.SanitizeText(comment.Body)
+	excerpt := utils.Excerpt(text, mentionExcerptLength)
+
+	s.events.Publish(ctx, events.PostCommented{
+		PostID:    post.ID,
+		AuthorID:  post.AuthorID,
+		CommentID: comment.ID,
+		ActorID:   actorID,
+		ActorName: comment.AuthorName,
+		Title:     post.Title,
+		Excerpt:   excerpt,
+	})
+	if usernames := utils.ExtractMentions(text); len(usernames) > 0 {
+		s.events.Publish(ctx, events.Mentioned{
+			Usernames: usernames,
+			ActorID:   actorID,
+			ActorName: comment.AuthorName,
+			PostID:    post.ID,
+			CommentID: comment.ID,
+			Title:     post.Title,
+			Excerpt:   excerpt,
+		})
+	}
+}
+
+// withAuthor joins the comment's author for AuthorName.
+func (s *CommentService) withAuthor(query *gorm.DB) *gorm.DB {
+	return query.Select(commentColumns).Joins("LEFT JOIN users ON users.id = comments.user_id")
+}
+
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
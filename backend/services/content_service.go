@@ -0,0 +1,583 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/sasanzare/go-cms/content"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/search"
+	"github.com/sasanzare/go-cms/tracing"
+	"github.com/sasanzare/go-cms/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// entryDataColumn is the JSONB column content filters and sorts read.
+const entryDataColumn = "content_entries.data"
+
+// entrySorts maps the entry columns lists can sort by to SQL.
+var entrySorts = map[string]string{
+	"created_at":   "content_entries.created_at",
+	"updated_at":   "content_entries.updated_at",
+	"published_at": "content_entries.published_at",
+	"title":        "content_entries.title",
+}
+
+// ContentService manages content types defined at runtime and their
+// entries. A type's fields are a content.Schema stored as JSON; entries
+// are validated against it on every write.
+type ContentService struct {
+	db    *gorm.DB
+	clock utils.Clock
+}
+
+func NewContentService(db *gorm.DB) *ContentService {
+	return &ContentService{db: db, clock: utils.RealClock{}}
+}
+
+// ListContentTypes returns every content type by name.
+func (s *ContentService) ListContentTypes(ctx context.Context) (_ []models.ContentType, err error) {
+	ctx, span := tracing.Start(ctx, "ContentService.ListContentTypes")
+	defer tracing.End(span, &err)
+
+	types := []models.ContentType{}
+	err = s.db.WithContext(ctx).Order("name, id").Find(&types).Error
+	return types, err
+}
+
+// GetContentType retrieves a content type by slug.
+func (s *ContentService) GetContentType(ctx context.Context, slug string) (_ *models.ContentType, err error) {
+	ctx, span := tracing.Start(ctx, "ContentService.GetContentType", attribute.String("content_type", slug))
+	defer tracing.End(span, &err)
+
+	return s.contentType(s.db.WithContext(ctx), slug)
+}
+
+func (s *ContentService) contentType(db *gorm.DB, slug string) (*models.ContentType, error) {
+	var ct models.ContentType
+	err := db.Where("slug = ?", slug).First(&ct).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("content type not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ct, nil
+}
+
+// CreateContentType validates and stores a content type. Reference fields
+// must target existing types or the new type itself.
+func (s *ContentService) CreateContentType(ctx context.Context, ct *models.ContentType) (err error) {
+	ctx, span := tracing.Start(ctx, "ContentService.CreateContentType")
+	defer tracing.End(span, &err)
+
+	ct.Slug = strings.ToLower(strings.TrimSpace(ct.Slug))
+	if !content.IsValidSlug(ct.Slug) {
+		return errors.New(utils.ValidationFailedMsg + ": slug must be lowercase letters, digits and dashes, starting with a letter")
+	}
+	db := s.db.WithContext(ctx)
+	if _, err := s.contentType(db, ct.Slug); err == nil {
+		return errors.New(utils.ValidationFailedMsg + ": slug is already taken")
+	}
+	if _, err := s.prepareContentType(db, ct); err != nil {
+		return err
+	}
+
+	if err := db.Create(ct).Error; err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "content type created", slog.String("content_type", ct.Slug))
+	return nil
+}
+
+// prepareContentType validates ct and normalizes its fields.
+func (s *ContentService) prepareContentType(db *gorm.DB, ct *models.ContentType) (content.Schema, error) {
+	ct.Name = utils.SanitizeText(strings.TrimSpace(ct.Name))
+	ct.Description = utils.SanitizeText(strings.TrimSpace(ct.Description))
+	if ct.Name == "" {
+		return nil, errors.New(utils.ValidationFailedMsg + ": name is required")
+	}
+
+	schema, err := content.ParseSchema(ct.Fields)
+	if err != nil {
+		return nil, errors.New(utils.ValidationFailedMsg + ": " + err.Error())
+	}
+	if ct.TitleField, err = schema.TitleField(ct.TitleField); err != nil {
+		return nil, errors.New(utils.ValidationFailedMsg + ": " + err.Error())
+	}
+
+	for _, target := range schema.Targets() {
+		if target == ct.Slug {
+			continue
+		}
+		if _, err := s.contentType(db, target); err != nil {
+			if strings.HasSuffix(err.Error(), "not found") {
+				return nil, errors.New(utils.ValidationFailedMsg + ": reference target " + target + " does not exist")
+			}
+			return nil, err
+		}
+	}
+
+	if ct.Fields, err = json.Marshal(schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// UpdateContentType replaces the name, description, fields and title
+// field of the type with slug. Once the type has entries, existing fields
+// keep their type and new fields can't be required; entries are then
+// re-titled and re-indexed for the new schema.
+func (s *ContentService) UpdateContentType(ctx context.Context, slug string, changes *models.ContentType) (_ *models.ContentType, err error) {
+	ctx, span := tracing.Start(ctx, "ContentService.UpdateContentType", attribute.String("content_type", slug))
+	defer tracing.End(span, &err)
+
+	var ct *models.ContentType
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if ct, err = s.contentType(tx.Clauses(clause.Locking{Strength: "UPDATE"}), slug); err != nil {
+			return err
+		}
+		old, err := content.ParseSchema(ct.Fields)
+		if err != nil {
+			return err
+		}
+
+		ct.Name, ct.Description, ct.Fields, ct.TitleField = changes.Name, changes.Description, changes.Fields, changes.TitleField
+		schema, err := s.prepareContentType(tx, ct)
+		if err != nil {
+			return err
+		}
+
+		var entries int64
+		if err := tx.Unscoped().Model(&models.ContentEntry{}).Where("content_type_id = ?", ct.ID).Count(&entries).Error; err != nil {
+			return err
+		}
+		if entries > 0 {
+			if err := schema.CheckChange(old); err != nil {
+				return errors.New(utils.ValidationFailedMsg + ": " + err.Error())
+			}
+		}
+
+		if err := tx.Save(ct).Error; err != nil {
+			return err
+		}
+		if entries > 0 {
+			return retitleEntries(tx, ct, schema)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ct, nil
+}
+
+// retitleEntries recomputes the title and search text of ct's entries,
+// and drops the values of fields the schema no longer has, so they
+// neither fail validation nor clash with a later field of the same name.
+func retitleEntries(tx *gorm.DB, ct *models.ContentType, schema content.Schema) error {
+	var entries []models.ContentEntry
+	return tx.Unscoped().Select("id", "data").Where("content_type_id = ?", ct.ID).
+		FindInBatches(&entries, reindexBatchSize, func(batch *gorm.DB, _ int) error {
+			for _, entry := range entries {
+				data, pruned, err := schema.Prune(entry.Data)
+				if err != nil {
+					return err
+				}
+				var values content.Values
+				if err := json.Unmarshal(data, &values); err != nil {
+					return err
+				}
+				columns := map[string]interface{}{
+					"title":       entryTitle(values, ct),
+					"search_text": values.SearchText(schema),
+				}
+				if pruned {
+					columns["data"] = data
+				}
+				err = tx.Unscoped().Model(&models.ContentEntry{}).Where("id = ?", entry.ID).UpdateColumns(columns).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// DeleteContentType deletes the type with slug. Types with entries, or
+// referred to by another type's fields, can't be deleted.
+func (s *ContentService) DeleteContentType(ctx context.Context, slug string) (err error) {
+	ctx, span := tracing.Start(ctx, "ContentService.DeleteContentType", attribute.String("content_type", slug))
+	defer tracing.End(span, &err)
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ct, err := s.contentType(tx.Clauses(clause.Locking{Strength: "UPDATE"}), slug)
+		if err != nil {
+			return err
+		}
+
+		var entries int64
+		if err := tx.Model(&models.ContentEntry{}).Where("content_type_id = ?", ct.ID).Count(&entries).Error; err != nil {
+			return err
+		}
+		if entries > 0 {
+			return errors.New(utils.ValidationFailedMsg + ": content type still has entries")
+		}
+
+		var referrers []string
+		err = tx.Model(&models.ContentType{}).
+			Where("id <> ? AND fields @> ?::jsonb", ct.ID, `[{"type": "`+content.FieldReference+`", "target": "`+ct.Slug+`"}]`).
+			Pluck("slug", &referrers).Error
+		if err != nil {
+			return err
+		}
+		if len(referrers) > 0 {
+			return errors.New(utils.ValidationFailedMsg + ": content type is referenced by " + strings.Join(referrers, ", "))
+		}
+
+		// Deleted entries go with their type
+		if err := tx.Unscoped().Where("content_type_id = ?", ct.ID).Delete(&models.ContentEntry{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(ct).Error; err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "content type deleted", slog.String("content_type", slug))
+		return nil
+	})
+}
+
+// EntryQuery selects a page of entries.
+type EntryQuery struct {
+	// Filters maps field names to filters in content.Schema.ParseFilter's
+	// syntax; several filters on one field must all match.
+	Filters map[string][]string
+	// Status restricts entries to draft or published ones; empty lists both.
+	Status string
+	// Sort is a field name or one of created_at, updated_at, published_at
+	// and title, prefixed with "-" for descending order. Defaults to
+	// -created_at.
+	Sort string
+
+	Page     int
+	PageSize int
+}
+
+// ListEntries returns a page of the entries of the type with slug that
+// match q.
+func (s *ContentService) ListEntries(ctx context.Context, slug string, q EntryQuery) (_ []models.ContentEntry, _ *utils.Pagination, err error) {
+	ctx, span := tracing.Start(ctx, "ContentService.ListEntries", attribute.String("content_type", slug))
+	defer tracing.End(span, &err)
+
+	db := s.db.WithContext(ctx)
+	ct, schema, err := s.typeSchema(db, slug)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query, err := entryQuery(db, ct, schema, q.Status, q.Filters)
+	if err != nil {
+		return nil, nil, err
+	}
+	order, err := entryOrder(schema, q.Sort)
+	if err != nil {
+		return nil, nil, err
+	}
+	return paginateEntries(query, q.Page, q.PageSize, order)
+}
+
+// SearchEntries returns a page of the entries of the type with slug whose
+// title or searchable text match text, best matches first. Queries use web
+// search syntax: "quoted phrases", or, -not.
+func (s *ContentService) SearchEntries(ctx context.Context, slug, text string, q EntryQuery) (_ []models.ContentEntry, _ *utils.Pagination, err error) {
+	ctx, span := tracing.Start(ctx, "ContentService.SearchEntries", attribute.String("content_type", slug))
+	defer tracing.End(span, &err)
+
+	if len(search.Tokenize(text)) == 0 {
+		return nil, nil, errors.New(utils.ValidationFailedMsg + ": search query is empty")
+	}
+	db := s.db.WithContext(ctx)
+	ct, schema, err := s.typeSchema(db, slug)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query, err := entryQuery(db, ct, schema, q.Status, q.Filters)
+	if err != nil {
+		return nil, nil, err
+	}
+	query = query.Where("content_entries.search_vector @@ websearch_to_tsquery('simple', ?)", text)
+	order := clause.OrderBy{Expression: clause.Expr{
+		SQL:  "ts_rank_cd(content_entries.search_vector, websearch_to_tsquery('simple', ?)) DESC, content_entries.id DESC",
+		Vars: []interface{}{text},
+	}}
+	return paginateEntries(query, q.Page, q.PageSize, order)
+}
+
+func (s *ContentService) typeSchema(db *gorm.DB, slug string) (*models.ContentType, content.Schema, error) {
+	ct, err := s.contentType(db, slug)
+	if err != nil {
+		return nil, nil, err
+	}
+	schema, err := content.ParseSchema(ct.Fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ct, schema, nil
+}
+
+func entryQuery(db *gorm.DB, ct *models.ContentType, schema content.Schema, status string, filters map[string][]string) (*gorm.DB, error) {
+	query := db.Model(&models.ContentEntry{}).Where("content_entries.content_type_id = ?", ct.ID)
+	if status != "" {
+		if status != models.EntryStatusDraft && status != models.EntryStatusPublished {
+			return nil, errors.New(utils.ValidationFailedMsg + ": status must be draft or published")
+		}
+		query = query.Where("content_entries.status = ?", status)
+	}
+
+	// Apply filters in a stable order so equal queries build equal SQL
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, raw := range filters[name] {
+			filter, err := schema.ParseFilter(name, raw)
+			if err != nil {
+				return nil, errors.New(utils.ValidationFailedMsg + ": " + err.Error())
+			}
+			cond, args := filter.SQL(entryDataColumn)
+			query = query.Where(cond, args...)
+		}
+	}
+	return query, nil
+}
+
+func entryOrder(schema content.Schema, sort string) (interface{}, error) {
+	if sort == "" {
+		sort = "-created_at"
+	}
+	name, desc := strings.CutPrefix(sort, "-")
+	direction := " ASC"
+	if desc {
+		direction = " DESC"
+	}
+
+	var order string
+	if column, ok := entrySorts[name]; ok {
+		order = column + direction
+	} else {
+		var err error
+		if order, err = schema.Order(entryDataColumn, sort); err != nil {
+			return nil, errors.New(utils.ValidationFailedMsg + ": " + err.Error())
+		}
+	}
+	return order + ", content_entries.id" + direction, nil
+}
+
+func paginateEntries(query *gorm.DB, page, pageSize int, order interface{}) ([]models.ContentEntry, *utils.Pagination, error) {
+	page, pageSize = utils.NormalizePage(page, pageSize)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+	entries := []models.ContentEntry{}
+	if err := query.Order(order).Offset((page - 1) * pageSize).Limit(pageSize).Find(&entries).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return entries, utils.NewPagination(page, pageSize, total), nil
+}
+
+// GetEntry retrieves an entry of the type with slug by ID.
+func (s *ContentService) GetEntry(ctx context.Context, slug string, id uint) (_ *models.ContentEntry, err error) {
+	ctx, span := tracing.Start(ctx, "ContentService.GetEntry", attribute.String("content_type", slug), attribute.Int("entry.id", int(id)))
+	defer tracing.End(span, &err)
+
+	db := s.db.WithContext(ctx)
+	ct, err := s.contentType(db, slug)
+	if err != nil {
+		return nil, err
+	}
+	return findEntry(db, ct, id)
+}
+
+func findEntry(db *gorm.DB, ct *models.ContentType, id uint) (*models.ContentEntry, error) {
+	var entry models.ContentEntry
+	err := db.Where("content_type_id = ?", ct.ID).First(&entry, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("entry not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// CreateEntry validates entry.Data against the schema of the type with
+// slug and stores the entry. Referenced entries and media must exist.
+func (s *ContentService) CreateEntry(ctx context.Context, slug string, entry *models.ContentEntry) (err error) {
+	ctx, span := tracing.Start(ctx, "ContentService.CreateEntry", attribute.String("content_type", slug))
+	defer tracing.End(span, &err)
+
+	db := s.db.WithContext(ctx)
+	ct, schema, err := s.typeSchema(db, slug)
+	if err != nil {
+		return err
+	}
+	if entry.Status == "" {
+		entry.Status = models.EntryStatusDraft
+	}
+	entry.ContentTypeID = ct.ID
+	if err := prepareEntry(db, ct, schema, entry); err != nil {
+		return err
+	}
+	if entry.IsPublished() {
+		now := s.clock.Now()
+		entry.PublishedAt = &now
+	}
+
+	if err := db.Create(entry).Error; err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "entry created", slog.String("content_type", slug), slog.Uint64("entry_id", uint64(entry.ID)))
+	return nil
+}
+
+// UpdateEntry replaces the data of an entry of the type with slug when
+// data is given, and sets its status when status is. Stored data is
+// checked against the current schema, less any removed fields.
+func (s *ContentService) UpdateEntry(ctx context.Context, slug string, id uint, data json.RawMessage, status *string) (_ *models.ContentEntry, err error) {
+	ctx, span := tracing.Start(ctx, "ContentService.UpdateEntry", attribute.String("content_type", slug), attribute.Int("entry.id", int(id)))
+	defer tracing.End(span, &err)
+
+	db := s.db.WithContext(ctx)
+	ct, schema, err := s.typeSchema(db, slug)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := findEntry(db, ct, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if data == nil {
+		// Stored data may hold fields removed before they were pruned
+		if data, _, err = schema.Prune(entry.Data); err != nil {
+			return nil, err
+		}
+	}
+	entry.Data = data
+	if status != nil {
+		if *status == models.EntryStatusPublished && entry.PublishedAt == nil {
+			now := s.clock.Now()
+			entry.PublishedAt = &now
+		}
+		entry.Status = *status
+	}
+	if err := prepareEntry(db, ct, schema, entry); err != nil {
+		return nil, err
+	}
+
+	if err := db.Save(entry).Error; err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// prepareEntry validates entry against schema and derives its title and
+// search text.
+func prepareEntry(db *gorm.DB, ct *models.ContentType, schema content.Schema, entry *models.ContentEntry) error {
+	if entry.Status != models.EntryStatusDraft && entry.Status != models.EntryStatusPublished {
+		return errors.New(utils.ValidationFailedMsg + ": status must be draft or published")
+	}
+	values, err := schema.Validate(entry.Data)
+	if err != nil {
+		return errors.New(utils.ValidationFailedMsg + ": " + err.Error())
+	}
+	if err := checkEntryRefs(db, values, schema); err != nil {
+		return err
+	}
+
+	if entry.Data, err = json.Marshal(values); err != nil {
+		return err
+	}
+	entry.Title = entryTitle(values, ct)
+	entry.SearchText = values.SearchText(schema)
+	return nil
+}
+
+// checkEntryRefs checks that the entries and media values refer to exist.
+func checkEntryRefs(db *gorm.DB, values content.Values, schema content.Schema) error {
+	for target, ids := range values.References(schema) {
+		var found []uint
+		err := db.Model(&models.ContentEntry{}).
+			Joins("JOIN content_types ON content_types.id = content_entries.content_type_id").
+			Where("content_types.slug = ? AND content_entries.id IN ?", target, ids).
+			Pluck("content_entries.id", &found).Error
+		if err != nil {
+			return err
+		}
+		if missing, ok := firstMissing(ids, found); ok {
+			return fmt.Errorf("%s: %s entry %d not found", utils.ValidationFailedMsg, target, missing)
+		}
+	}
+
+	if ids := values.MediaIDs(schema); len(ids) > 0 {
+		var found []uint
+		if err := db.Model(&models.Media{}).Where("id IN ?", ids).Pluck("id", &found).Error; err != nil {
+			return err
+		}
+		if missing, ok := firstMissing(ids, found); ok {
+			return fmt.Errorf("%s: media item %d not found", utils.ValidationFailedMsg, missing)
+		}
+	}
+	return nil
+}
+
+func firstMissing(ids, found []uint) (uint, bool) {
+	have := make(map[uint]bool, len(found))
+	for _, id := range found {
+		have[id] = true
+	}
+	for _, id := range ids {
+		if !have[id] {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+func entryTitle(values content.Values, ct *models.ContentType) string {
+	return utils.Excerpt(values.Text(ct.TitleField), 255)
+}
+
+// DeleteEntry soft-deletes an entry of the type with slug.
+func (s *ContentService) DeleteEntry(ctx context.Context, slug string, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "ContentService.DeleteEntry", attribute.String("content_type", slug), attribute.Int("entry.id", int(id)))
+	defer tracing.End(span, &err)
+
+	db := s.db.WithContext(ctx)
+	ct, err := s.contentType(db, slug)
+	if err != nil {
+		return err
+	}
+	result := db.Where("content_type_id = ?", ct.ID).Delete(&models.ContentEntry{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entry not found")
+	}
+	return nil
+}
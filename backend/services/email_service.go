@@ -1,140 +1,187 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 	"strings"
 
-	"gopkg.in/gomail.v2"
+	"github.com/sasanzare/go-cms/jobs"
+	"github.com/sasanzare/go-cms/mail"
+	"github.com/sasanzare/go-cms/metrics"
 	"github.com/sasanzare/go-cms/utils"
+	"gorm.io/gorm"
 )
 
 // EmailService handles sending emails
 type EmailService struct {
-	dialer *gomail.Dialer
-	sender string
+	mailer        mail.Mailer
+	sender        string
+	queue         *jobs.Queue
+	renderer      *mail.Renderer
+	defaultLocale string
 }
 
-// NewEmailService creates a new EmailService instance
-func NewEmailService() *EmailService {
-	smtpHost := os.Getenv("SMTP_HOST")
-	smtpPort := 587 // default port, can be configured via env
-	smtpUser := os.Getenv("SMTP_USER")
-	smtpPass := os.Getenv("SMTP_PASS")
-	sender := os.Getenv("EMAIL_SENDER")
-
-	if smtpHost == "" || smtpUser == "" || smtpPass == "" {
-		log.Println("Warning: SMTP configuration not fully set in environment variables")
-	}
-
+// NewEmailService creates a new EmailService instance sending from sender
+// through mailer. Templated emails are rendered with renderer in
+// defaultLocale unless a locale is given.
+func NewEmailService(mailer mail.Mailer, sender string, renderer *mail.Renderer, defaultLocale string) *EmailService {
 	return &EmailService{
-		dialer: gomail.NewDialer(smtpHost, smtpPort, smtpUser, smtpPass),
-		sender: sender,
+		mailer:        mailer,
+		sender:        sender,
+		renderer:      renderer,
+		defaultLocale: defaultLocale,
 	}
 }
 
 // EmailContent represents the content of an email
 type EmailContent struct {
-	To      string
-	Subject string
-	Body    string
-	HTML    bool
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	HTML    bool   `json:"html"`
+	// TextBody is the plain-text alternative sent with an HTML Body
+	TextBody string `json:"text_body,omitempty"`
+}
+
+// Send queues an email for delivery in the background, or delivers it
+// right away when no job queue is configured
+func (es *EmailService) Send(ctx context.Context, content EmailContent) error {
+	return es.send(ctx, nil, content)
 }
 
-// Send sends an email with the given content
-func (es *EmailService) Send(content EmailContent) error {
+// send queues content within tx when it is not nil, so the email is only
+// sent if tx commits.
+func (es *EmailService) send(ctx context.Context, tx *gorm.DB, content EmailContent) error {
 	if !utils.ValidateEmail(content.To) {
 		return fmt.Errorf("invalid recipient email address: %s", content.To)
 	}
+	if es.queue == nil {
+		return es.Deliver(ctx, content)
+	}
 
-	m := gomail.NewMessage()
-	m.SetHeader("From", es.sender)
-	m.SetHeader("To", content.To)
-	m.SetHeader("Subject", content.Subject)
+	args := SendEmailArgs{Content: content}
+	var err error
+	if tx != nil {
+		_, err = es.queue.EnqueueTx(tx, args, jobs.OnQueue(EmailQueue))
+	} else {
+		_, err = es.queue.Enqueue(ctx, args, jobs.OnQueue(EmailQueue))
+	}
+	return err
+}
 
+// Deliver hands an email to the mailer, blocking until it is accepted
+func (es *EmailService) Deliver(ctx context.Context, content EmailContent) error {
+	if !utils.ValidateEmail(content.To) {
+		return fmt.Errorf("invalid recipient email address: %s", content.To)
+	}
+
+	email := &mail.Email{
+		From:    es.sender,
+		To:      content.To,
+		Subject: content.Subject,
+	}
 	if content.HTML {
-		m.SetBody("text/html", content.Body)
+		email.HTML = content.Body
+		email.Text = content.TextBody
 	} else {
-		m.SetBody("text/plain", content.Body)
+		email.Text = content.Body
 	}
 
-	if err := es.dialer.DialAndSend(m); err != nil {
-		return fmt.Errorf("failed to send email: %v", err)
+	if err := es.mailer.Send(ctx, email); err != nil {
+		slog.ErrorContext(ctx, "failed to send email", slog.String("subject", content.Subject), slog.String("error", err.Error()))
+		metrics.EmailsSent.WithLabelValues("failure").Inc()
+		return fmt.Errorf("failed to send email: %w", err)
 	}
 
+	metrics.EmailsSent.WithLabelValues("success").Inc()
+	slog.InfoContext(ctx, "email sent", slog.String("subject", content.Subject))
+
 	return nil
 }
 
-// SendVerificationEmail sends an email with a verification link
-func (es *EmailService) SendVerificationEmail(to, name, verificationURL string) error {
-	subject := "Verify Your Email Address"
-	body := fmt.Sprintf(`
-Hello %s,
+// passwordResetTTLHours is how long password reset links stay valid
+const passwordResetTTLHours = 24
 
-Please click the following link to verify your email address:
-%s
+// SendTemplate renders the named email template for locale and sends it.
+// An empty locale uses the site's default.
+func (es *EmailService) SendTemplate(ctx context.Context, to, locale, name string, data map[string]interface{}) error {
+	return es.SendTemplateTx(ctx, nil, to, locale, name, data)
+}
 
-If you didn't request this, please ignore this email.
+// SendTemplateTx is SendTemplate queuing the email within tx, so it is
+// only sent if tx commits. Without a job queue the email is delivered
+// right away.
+func (es *EmailService) SendTemplateTx(ctx context.Context, tx *gorm.DB, to, locale, name string, data map[string]interface{}) error {
+	msg, err := es.render(locale, name, data)
+	if err != nil {
+		return err
+	}
 
-Thanks,
-The Team
-`, name, verificationURL)
+	return es.send(ctx, tx, EmailContent{
+		To:       to,
+		Subject:  msg.Subject,
+		Body:     msg.HTML,
+		HTML:     true,
+		TextBody: msg.Text,
+	})
+}
 
-	return es.Send(EmailContent{
-		To:      to,
-		Subject: subject,
-		Body:    strings.TrimSpace(body),
-		HTML:    false,
+// SendVerificationEmail sends an email with a verification link
+func (es *EmailService) SendVerificationEmail(ctx context.Context, to, locale, name, verificationURL string) error {
+	return es.SendTemplate(ctx, to, locale, mail.TemplateVerification, map[string]interface{}{
+		"Name": name,
+		"URL":  verificationURL,
 	})
 }
 
 // SendPasswordResetEmail sends an email with a password reset link
-func (es *EmailService) SendPasswordResetEmail(to, name, resetURL string) error {
-	subject := "Password Reset Request"
-	body := fmt.Sprintf(`
-Hello %s,
-
-We received a request to reset your password. Click the link below to proceed:
-%s
-
-This link will expire in 24 hours. If you didn't request a password reset, please ignore this email.
-
-Thanks,
-The Team
-`, name, resetURL)
-
-	return es.Send(EmailContent{
-		To:      to,
-		Subject: subject,
-		Body:    strings.TrimSpace(body),
-		HTML:    false,
+func (es *EmailService) SendPasswordResetEmail(ctx context.Context, to, locale, name, resetURL string) error {
+	return es.SendTemplate(ctx, to, locale, mail.TemplatePasswordReset, map[string]interface{}{
+		"Name":         name,
+		"URL":          resetURL,
+		"ExpiresHours": passwordResetTTLHours,
 	})
 }
 
 // SendWelcomeEmail sends a welcome email to new users
-func (es *EmailService) SendWelcomeEmail(to, name string) error {
-	subject := "Welcome to Our Platform!"
-	body := fmt.Sprintf(`
-Hello %s,
-
-Welcome to our platform! We're excited to have you on board.
+func (es *EmailService) SendWelcomeEmail(ctx context.Context, to, locale, name string) error {
+	return es.SendTemplate(ctx, to, locale, mail.TemplateWelcome, map[string]interface{}{
+		"Name": name,
+	})
+}
 
-Here are some things you can do:
-- Complete your profile
-- Explore our features
-- Contact support if you need help
+// Preview renders the named template for locale with sample data.
+func (es *EmailService) Preview(locale, name string) (*mail.Message, error) {
+	if es.renderer == nil {
+		return nil, errors.New("email templates are not configured")
+	}
+	return es.render(locale, name, es.renderer.SampleData(name))
+}
 
-Thanks for joining us!
+// PreviewOptions lists the templates and locales available for previews.
+func (es *EmailService) PreviewOptions() (templates, locales []string) {
+	if es.renderer == nil {
+		return nil, nil
+	}
+	return es.renderer.Templates(), es.renderer.Locales()
+}
 
-Best regards,
-The Team
-`, name)
+func (es *EmailService) render(locale, name string, data map[string]interface{}) (*mail.Message, error) {
+	if es.renderer == nil {
+		return nil, errors.New("email templates are not configured")
+	}
+	if locale == "" {
+		locale = es.defaultLocale
+	}
 
-	return es.Send(EmailContent{
-		To:      to,
-		Subject: subject,
-		Body:    strings.TrimSpace(body),
-		HTML:    false,
-	})
-}
\ No newline at end of file
+	msg, err := es.renderer.Render(locale, name, data)
+	if err != nil {
+		if strings.HasSuffix(err.Error(), "not found") {
+			return nil, err
+		}
+		return nil, fmt.Errorf("render %s email: %w", name, err)
+	}
+	return msg, nil
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/sasanzare/go-cms/feed"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/tracing"
+	"github.com/sasanzare/go-cms/utils"
+	"gorm.io/gorm"
+)
+
+// defaultFeedItems is how many posts a feed lists unless configured.
+const defaultFeedItems = 20
+
+// FeedService builds syndication feeds of the newest published posts.
+type FeedService struct {
+	db       *gorm.DB
+	posts    *PostService
+	siteName string
+	siteURL  string
+	// items is the number of posts per feed; excerptsOnly leaves post
+	// content out of feeds unless asked for
+	items        int
+	excerptsOnly bool
+}
+
+func NewFeedService(db *gorm.DB, posts *PostService, siteName, siteURL string) *FeedService {
+	return &FeedService{
+		db:       db,
+		posts:    posts,
+		siteName: siteName,
+		siteURL:  strings.TrimRight(siteURL, "/"),
+		items:    defaultFeedItems,
+	}
+}
+
+// FeedQuery selects the posts of a feed. At most one of CategorySlug,
+// TagSlug and AuthorID narrows it down. Path is the feed's own path.
+type FeedQuery struct {
+	Path         string
+	CategorySlug string
+	TagSlug      string
+	AuthorID     uint
+	// Locales restricts the feed to one variant of each translation group
+	// in the first of them a post is written in; the first names the
+	// feed's language
+	Locales []string
+	// Content is ContentFull or ContentExcerpt; empty uses the configured
+	// default
+	Content string
+}
+
+// Values for FeedQuery.Content
+const (
+	ContentFull    = "full"
+	ContentExcerpt = "excerpt"
+)
+
+// Feed returns the feed q asks for, newest posts first.
+func (s *FeedService) Feed(ctx context.Context, q FeedQuery) (_ *feed.Feed, err error) {
+	ctx, span := tracing.Start(ctx, "FeedService.Feed")
+	defer tracing.End(span, &err)
+
+	full := !s.excerptsOnly
+	switch q.Content {
+	case "":
+	case ContentFull:
+		full = true
+	case ContentExcerpt:
+		full = false
+	default:
+		return nil, errors.New(utils.ValidationFailedMsg + ": content must be full or excerpt")
+	}
+
+	f := &feed.Feed{
+		Title:       s.siteName,
+		Description: "Latest posts from " + s.siteName,
+		Link:        s.siteURL + "/",
+		URL:         s.siteURL + q.Path,
+	}
+	if len(q.Locales) > 0 {
+		f.Language = q.Locales[0]
+	}
+	filter := PostFilter{
+		Status:   models.PostStatusPublished,
+		AuthorID: q.AuthorID,
+		Locales:  q.Locales,
+		Sort:     "published_at",
+		Order:    "desc",
+		PageSize: s.items,
+	}
+
+	db := s.db.WithContext(ctx)
+	switch {
+	case q.CategorySlug != "":
+		var category models.Category
+		if err := localized(db.Where("slug = ?", q.CategorySlug), q.Locales).First(&category).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("category not found")
+			}
+			return nil, err
+		}
+		filter.CategoryID = category.ID
+		f.Title = s.siteName + ": " + category.Name
+		f.Description = cmp.Or(category.Description, "Posts in "+category.Name)
+		f.Link = s.siteURL + categoryLink(category.Slug, category.Locale)
+	case q.TagSlug != "":
+		var tag models.Tag
+		if err := localized(db.Where("slug = ?", q.TagSlug), q.Locales).First(&tag).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("tag not found")
+			}
+			return nil, err
+		}
+		filter.TagIDs = []uint{tag.ID}
+		f.Title = s.siteName + ": #" + tag.Name
+		f.Description = "Posts tagged " + tag.Name
+		f.Link = s.siteURL + tagLink(tag.Slug, tag.Locale)
+	case q.AuthorID != 0:
+		// Only authors of published posts have a feed, so feeds don't
+		// reveal which other user IDs exist
+		var author models.User
+		err := db.Where(`EXISTS (SELECT 1 FROM posts
+			WHERE posts.author_id = users.id AND posts.status = ? AND posts.deleted_at IS NULL)`, models.PostStatusPublished).
+			First(&author, q.AuthorID).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("author not found")
+			}
+			return nil, err
+		}
+		f.Title = s.siteName + ": " + authorName(author)
+		f.Description = "Posts by " + authorName(author)
+		f.Link = s.siteURL + "/authors/" + author.Username
+	}
+
+	posts, _, err := s.posts.ListPosts(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range posts {
+		post := &posts[i]
+		s.posts.refreshRendered(ctx, post)
+
+		link := s.siteURL + postLink(post.ID, 0)
+		item := feed.Item{
+			ID:      link,
+			Title:   post.Title,
+			Link:    link,
+			Summary: post.Excerpt,
+			Author:  authorName(post.Author),
+			Updated: post.UpdatedAt,
+		}
+		if post.PublishedAt != nil {
+			item.Published = *post.PublishedAt
+		}
+		if full {
+			item.Content = post.RenderedHTML
+		}
+		if post.Category.Name != "" {
+			item.Categories = []string{post.Category.Name}
+		}
+		if post.UpdatedAt.After(f.Updated) {
+			f.Updated = post.UpdatedAt
+		}
+		f.Items = append(f.Items, item)
+	}
+	return f, nil
+}
+
+// localized prefers the row written in the earliest of locales, the
+// others coming after; without locales it keeps the oldest row.
+func localized(query *gorm.DB, locales []string) *gorm.DB {
+	if len(locales) > 0 {
+		query = query.Where("locale IN ?", locales).Order(localeRank(locales))
+	}
+	return query.Order("id")
+}
+
+// authorName is how a user is credited as the author of posts.
+func authorName(user models.User) string {
+	if name := strings.TrimSpace(user.FirstName + " " + user.LastName); name != "" {
+		return name
+	}
+	return user.Username
+}
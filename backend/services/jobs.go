@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/sasanzare/go-cms/jobs"
+)
+
+// Queues used by the application's jobs
+const (
+	EmailQueue   = "email"
+	SearchQueue  = "search"
+	WebhookQueue = "webhooks"
+)
+
+// SendEmailArgs delivers one email.
+type SendEmailArgs struct {
+	Content EmailContent `json:"content"`
+}
+
+func (SendEmailArgs) Kind() string { return "email.send" }
+
+// ReindexArgs rebuilds the search index.
+type ReindexArgs struct{}
+
+func (ReindexArgs) Kind() string { return "search.reindex" }
+
+// ReindexJobKey makes reindex jobs unique: one queued rebuild covers every
+// change made before it starts.
+const ReindexJobKey = "search.reindex"
+
+// DigestArgs sends the daily notification digests.
+type DigestArgs struct{}
+
+func (DigestArgs) Kind() string { return "notifications.digest" }
+
+// DigestJobKey, suffixed with the run's date, makes each day's digest
+// unique.
+const DigestJobKey = "notifications.digest"
+
+// DeliverWebhookArgs posts one event to one webhook endpoint.
+type DeliverWebhookArgs struct {
+	URL   string          `json:"url"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+func (DeliverWebhookArgs) Kind() string { return "webhook.deliver" }
+
+func registerJobHandlers(queue *jobs.Queue, s *Services) {
+	jobs.Register(queue, func(ctx context.Context, args SendEmailArgs) error {
+		return s.Email.Deliver(ctx, args.Content)
+	})
+	jobs.Register(queue, func(ctx context.Context, args ReindexArgs) error {
+		_, err := s.Search.Reindex(ctx)
+		return err
+	})
+	jobs.Register(queue, func(ctx context.Context, args DigestArgs) error {
+		// Queue tomorrow's run first so a failing run doesn't end the chain
+		if err := s.Notifications.ScheduleDigest(ctx); err != nil {
+			return err
+		}
+		_, err := s.Notifications.SendDigests(ctx)
+		return err
+	})
+	jobs.Register(queue, func(ctx context.Context, args DeliverWebhookArgs) error {
+		return s.Webhooks.Deliver(ctx, args)
+	})
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/tracing"
+	"github.com/sasanzare/go-cms/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// MediaService keeps the library of media items content blocks refer to.
+// Files themselves are stored elsewhere, such as on a CDN; items record
+// their URL and metadata.
+type MediaService struct {
+	db *gorm.DB
+}
+
+func NewMediaService(db *gorm.DB) *MediaService {
+	return &MediaService{db: db}
+}
+
+// CreateMedia validates and stores a media item.
+func (s *MediaService) CreateMedia(ctx context.Context, media *models.Media) (err error) {
+	ctx, span := tracing.Start(ctx, "MediaService.CreateMedia")
+	defer tracing.End(span, &err)
+
+	u, err := url.Parse(media.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.New(utils.ValidationFailedMsg + ": url must be an absolute http or https URL")
+	}
+	media.MimeType = strings.ToLower(strings.TrimSpace(media.MimeType))
+	if !strings.Contains(media.MimeType, "/") {
+		return errors.New(utils.ValidationFailedMsg + ": mime_type is required")
+	}
+	media.Alt = utils.SanitizeText(media.Alt)
+	media.Filename = utils.SanitizeText(media.Filename)
+
+	return s.db.WithContext(ctx).Create(media).Error
+}
+
+// GetMedia retrieves a media item by ID.
+func (s *MediaService) GetMedia(ctx context.Context, id uint) (_ *models.Media, err error) {
+	ctx, span := tracing.Start(ctx, "MediaService.GetMedia", attribute.Int("media.id", int(id)))
+	defer tracing.End(span, &err)
+
+	var media models.Media
+	err = s.db.WithContext(ctx).First(&media, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("media not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &media, nil
+}
+
+// ListMedia returns a page of media items, newest first, optionally only
+// those of a MIME type prefix such as "image/".
+func (s *MediaService) ListMedia(ctx context.Context, mimePrefix string, page, pageSize int) (_ []models.Media, _ *utils.Pagination, err error) {
+	ctx, span := tracing.Start(ctx, "MediaService.ListMedia")
+	defer tracing.End(span, &err)
+
+	page, pageSize = utils.NormalizePage(page, pageSize)
+	query := s.db.WithContext(ctx).Model(&models.Media{})
+	if mimePrefix != "" {
+		query = query.Where("mime_type LIKE ?", strings.NewReplacer("%", `\%`, "_", `\_`).Replace(mimePrefix)+"%")
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+	var media []models.Media
+	err = query.Order("created_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&media).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return media, utils.NewPagination(page, pageSize, total), nil
+}
@@ -0,0 +1,430 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/sasanzare/go-cms/events"
+	"github.com/sasanzare/go-cms/jobs"
+	"github.com/sasanzare/go-cms/mail"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/tracing"
+	"github.com/sasanzare/go-cms/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationService records notifications for users and delivers them
+// in the app, by email right away or in a daily digest, following each
+// user's preferences.
+type NotificationService struct {
+	db      *gorm.DB
+	email   *EmailService
+	queue   *jobs.Queue
+	clock   utils.Clock
+	siteURL string
+	// digestHour is the local hour digests are sent at; negative disables
+	// digests
+	digestHour int
+}
+
+func NewNotificationService(db *gorm.DB, email *EmailService, siteURL string) *NotificationService {
+	return &NotificationService{
+		db:      db,
+		email:   email,
+		clock:   utils.RealClock{},
+		siteURL: strings.TrimRight(siteURL, "/"),
+	}
+}
+
+// Subscribe creates notifications for the events published on bus.
+func (s *NotificationService) Subscribe(bus *events.Bus) {
+	events.Subscribe(bus, func(ctx context.Context, e events.PostApproved) error {
+		return s.Notify(ctx, &models.Notification{
+			UserID:  e.AuthorID,
+			Type:    models.NotificationPostApproved,
+			ActorID: &e.ActorID,
+			PostID:  &e.PostID,
+			Title:   fmt.Sprintf("Your post %q was approved", e.Title),
+			Body:    "It is now published.",
+			Link:    postLink(e.PostID, 0),
+		})
+	})
+	events.Subscribe(bus, func(ctx context.Context, e events.PostRejected) error {
+		return s.Notify(ctx, &models.Notification{
+			UserID:  e.AuthorID,
+			Type:    models.NotificationPostRejected,
+			ActorID: optionalID(e.ActorID),
+			PostID:  &e.PostID,
+			Title:   fmt.Sprintf("Your post %q was rejected", e.Title),
+			Body:    e.Reason,
+			Link:    postLink(e.PostID, 0),
+		})
+	})
+	events.Subscribe(bus, func(ctx context.Context, e events.PostCommented) error {
+		if e.ActorID == e.AuthorID {
+			return nil
+		}
+		return s.Notify(ctx, &models.Notification{
+			UserID:    e.AuthorID,
+			Type:      models.NotificationPostCommented,
+			ActorID:   optionalID(e.ActorID),
+			PostID:    &e.PostID,
+			CommentID: optionalID(e.CommentID),
+			Title:     fmt.Sprintf("%s commented on your post %q", s.actorName(ctx, e.ActorID, e.ActorName), e.Title),
+			Body:      e.Excerpt,
+			Link:      postLink(e.PostID, e.CommentID),
+		})
+	})
+	events.Subscribe(bus, s.notifyMentioned)
+}
+
+// notifyMentioned notifies each mentioned user, except the author of the
+// mention.
+func (s *NotificationService) notifyMentioned(ctx context.Context, e events.Mentioned) error {
+	var users []models.User
+	if err := s.db.WithContext(ctx).Where("username IN ?", e.Usernames).Find(&users).Error; err != nil {
+		return err
+	}
+
+	actor := s.actorName(ctx, e.ActorID, e.ActorName)
+	var errs []error
+	for _, user := range users {
+		if user.ID == e.ActorID {
+			continue
+		}
+		errs = append(errs, s.Notify(ctx, &models.Notification{
+			UserID:    user.ID,
+			Type:      models.NotificationMentioned,
+			ActorID:   optionalID(e.ActorID),
+			PostID:    &e.PostID,
+			CommentID: optionalID(e.CommentID),
+			Title:     fmt.Sprintf("%s mentioned you in %q", actor, e.Title),
+			Body:      e.Excerpt,
+			Link:      postLink(e.PostID, e.CommentID),
+		}))
+	}
+	return errors.Join(errs...)
+}
+
+// Notify delivers n on the channels its recipient enabled for its type. It
+// is stored when shown in the app or held for the digest.
+func (s *NotificationService) Notify(ctx context.Context, n *models.Notification) (err error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.Notify",
+		attribute.String("notification.type", n.Type), attribute.Int("user.id", int(n.UserID)))
+	defer tracing.End(span, &err)
+
+	pref, err := s.preference(ctx, n.UserID, n.Type)
+	if err != nil {
+		return err
+	}
+
+	n.InApp = pref.InApp
+	n.Digest = pref.Email == models.NotificationEmailDaily && s.digestHour >= 0
+	if n.InApp || n.Digest {
+		if err := s.db.WithContext(ctx).Create(n).Error; err != nil {
+			return err
+		}
+	}
+
+	if pref.Email == models.NotificationEmailInstant {
+		return s.sendInstant(ctx, n)
+	}
+	return nil
+}
+
+func (s *NotificationService) sendInstant(ctx context.Context, n *models.Notification) error {
+	if s.email == nil {
+		return nil
+	}
+	user, ok, err := s.recipient(ctx, n.UserID)
+	if err != nil || !ok {
+		return err
+	}
+
+	return s.email.SendTemplate(ctx, user.Email, user.Locale, mail.TemplateNotification, map[string]interface{}{
+		"Name":  user.FirstName,
+		"Title": n.Title,
+		"Body":  n.Body,
+		"URL":   s.siteURL + n.Link,
+	})
+}
+
+// ListNotifications returns a page of the user's in-app notifications,
+// newest first.
+func (s *NotificationService) ListNotifications(ctx context.Context, userID uint, unreadOnly bool, page, pageSize int) (_ []models.Notification, _ *utils.Pagination, err error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.ListNotifications", attribute.Int("user.id", int(userID)))
+	defer tracing.End(span, &err)
+
+	page, pageSize = utils.NormalizePage(page, pageSize)
+	query := s.inbox(ctx, userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+	var notifications []models.Notification
+	err = query.Order("created_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&notifications).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return notifications, utils.NewPagination(page, pageSize, total), nil
+}
+
+// UnreadCount returns how many in-app notifications the user hasn't read.
+func (s *NotificationService) UnreadCount(ctx context.Context, userID uint) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.UnreadCount", attribute.Int("user.id", int(userID)))
+	defer tracing.End(span, &err)
+
+	var count int64
+	err = s.inbox(ctx, userID).Where("read_at IS NULL").Count(&count).Error
+	return count, err
+}
+
+// MarkRead marks one of the user's notifications as read.
+func (s *NotificationService) MarkRead(ctx context.Context, userID, id uint) (_ *models.Notification, err error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.MarkRead", attribute.Int("notification.id", int(id)))
+	defer tracing.End(span, &err)
+
+	var n models.Notification
+	if err := s.inbox(ctx, userID).First(&n, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("notification not found")
+		}
+		return nil, err
+	}
+	if n.ReadAt == nil {
+		now := s.clock.Now()
+		if err := s.db.WithContext(ctx).Model(&n).Update("read_at", &now).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &n, nil
+}
+
+// MarkAllRead marks every unread notification of the user as read and
+// returns how many there were.
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID uint) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.MarkAllRead", attribute.Int("user.id", int(userID)))
+	defer tracing.End(span, &err)
+
+	result := s.inbox(ctx, userID).Where("read_at IS NULL").Update("read_at", s.clock.Now())
+	return result.RowsAffected, result.Error
+}
+
+// inbox selects the user's in-app notifications.
+func (s *NotificationService) inbox(ctx context.Context, userID uint) *gorm.DB {
+	return s.db.WithContext(ctx).Model(&models.Notification{}).Where("user_id = ? AND in_app", userID)
+}
+
+// Preferences returns the user's channels for every notification type,
+// filling in defaults for types they never changed.
+func (s *NotificationService) Preferences(ctx context.Context, userID uint) (_ []models.NotificationPreference, err error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.Preferences", attribute.Int("user.id", int(userID)))
+	defer tracing.End(span, &err)
+
+	var saved []models.NotificationPreference
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&saved).Error; err != nil {
+		return nil, err
+	}
+	byType := make(map[string]models.NotificationPreference, len(saved))
+	for _, p := range saved {
+		byType[p.Type] = p
+	}
+
+	prefs := make([]models.NotificationPreference, 0, len(models.NotificationTypes))
+	for _, t := range models.NotificationTypes {
+		p, ok := byType[t]
+		if !ok {
+			p = models.DefaultNotificationPreference(userID, t)
+		}
+		prefs = append(prefs, p)
+	}
+	return prefs, nil
+}
+
+// UpdatePreferences saves the given per-type preferences of the user and
+// returns the full set.
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID uint, prefs []models.NotificationPreference) (_ []models.NotificationPreference, err error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.UpdatePreferences", attribute.Int("user.id", int(userID)))
+	defer tracing.End(span, &err)
+
+	if len(prefs) == 0 {
+		return nil, errors.New(utils.ValidationFailedMsg + ": no preferences given")
+	}
+	for i := range prefs {
+		if !models.IsNotificationType(prefs[i].Type) {
+			return nil, fmt.Errorf("%s: unknown notification type %q", utils.ValidationFailedMsg, prefs[i].Type)
+		}
+		switch prefs[i].Email {
+		case models.NotificationEmailOff, models.NotificationEmailInstant, models.NotificationEmailDaily:
+		default:
+			return nil, fmt.Errorf("%s: email must be off, instant or daily", utils.ValidationFailedMsg)
+		}
+		prefs[i].ID = 0
+		prefs[i].UserID = userID
+	}
+
+	err = s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"in_app", "email"}),
+	}).Create(&prefs).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.Preferences(ctx, userID)
+}
+
+func (s *NotificationService) preference(ctx context.Context, userID uint, notificationType string) (models.NotificationPreference, error) {
+	var pref models.NotificationPreference
+	err := s.db.WithContext(ctx).Where("user_id = ? AND type = ?", userID, notificationType).First(&pref).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.DefaultNotificationPreference(userID, notificationType), nil
+	}
+	return pref, err
+}
+
+// SendDigests emails each user the notifications held for their digest
+// and returns the number of digests sent. A user whose digest fails keeps
+// their notifications for the next run.
+func (s *NotificationService) SendDigests(ctx context.Context) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.SendDigests")
+	defer tracing.End(span, &err)
+
+	var userIDs []uint
+	err = s.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("digest AND emailed_at IS NULL").
+		Distinct().Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, userID := range userIDs {
+		ok, err := s.sendDigest(ctx, userID)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to send notification digest", slog.Uint64("user_id", uint64(userID)), slog.String("error", err.Error()))
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	slog.InfoContext(ctx, "notification digests sent", slog.Int("count", sent))
+	return sent, nil
+}
+
+// sendDigest emails one user's pending digest. The notifications are
+// marked and the email queued in the same transaction, so they are sent
+// exactly once even if another instance runs digests at the same time.
+func (s *NotificationService) sendDigest(ctx context.Context, userID uint) (bool, error) {
+	sent := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var pending []models.Notification
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("user_id = ? AND digest AND emailed_at IS NULL", userID).
+			Order("created_at, id").
+			Find(&pending).Error
+		if err != nil || len(pending) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(pending))
+		items := make([]mail.DigestItem, len(pending))
+		for i, n := range pending {
+			ids[i] = n.ID
+			items[i] = mail.DigestItem{Title: n.Title, Body: n.Body, URL: s.siteURL + n.Link}
+		}
+		if err := tx.Model(&models.Notification{}).Where("id IN ?", ids).Update("emailed_at", s.clock.Now()).Error; err != nil {
+			return err
+		}
+
+		user, ok, err := s.recipient(ctx, userID)
+		if err != nil || !ok || s.email == nil {
+			return err
+		}
+		sent = true
+		return s.email.SendTemplateTx(ctx, tx, user.Email, user.Locale, mail.TemplateDigest, map[string]interface{}{
+			"Name":  user.FirstName,
+			"Items": items,
+		})
+	})
+	return sent && err == nil, err
+}
+
+// ScheduleDigest queues the next digest run. Runs are keyed by date, so
+// every instance may call it at startup and the job itself schedules the
+// following day.
+func (s *NotificationService) ScheduleDigest(ctx context.Context) error {
+	if s.queue == nil || s.digestHour < 0 {
+		return nil
+	}
+
+	next := nextDigestAt(s.clock.Now(), s.digestHour)
+	_, err := s.queue.Enqueue(ctx, DigestArgs{}, jobs.RunAt(next), jobs.Unique(DigestJobKey+":"+next.Format(time.DateOnly)))
+	if errors.Is(err, jobs.ErrDuplicate) {
+		return nil
+	}
+	return err
+}
+
+// nextDigestAt returns the first time after now at hour o'clock.
+func nextDigestAt(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// recipient loads the user a notification email goes to. Users who can't
+// receive email anymore report ok=false.
+func (s *NotificationService) recipient(ctx context.Context, userID uint) (*models.User, bool, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).First(&user, userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &user, user.Status == models.UserStatusActive, nil
+}
+
+// actorName names a notification's actor in its title: name when the
+// event carries one, otherwise the username of userID.
+func (s *NotificationService) actorName(ctx context.Context, userID uint, name string) string {
+	if name != "" {
+		return name
+	}
+	var user models.User
+	if userID == 0 || s.db.WithContext(ctx).Select("username").First(&user, userID).Error != nil {
+		return "Someone"
+	}
+	return user.Username
+}
+
+// postLink is the site path of a post, or of a comment on it.
+func postLink(postID, commentID uint) string {
+	if commentID != 0 {
+		return fmt.Sprintf("/posts/%d#comment-%d", postID, commentID)
+	}
+	return fmt.Sprintf("/posts/%d", postID)
+}
+
+func optionalID(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sasanzare/go-cms/jalali"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/tracing"
+	"github.com/sasanzare/go-cms/utils"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Calendars post dates can be archived, filtered and shown in
+const (
+	CalendarGregorian = "gregorian"
+	CalendarJalali    = "jalali"
+)
+
+// IsCalendar reports whether calendar is supported.
+func IsCalendar(calendar string) bool {
+	return calendar == CalendarGregorian || calendar == CalendarJalali
+}
+
+// ArchiveMonth is a month of the post archive. From and To bound its
+// publication dates, To being the start of the next month.
+type ArchiveMonth struct {
+	Month int       `json:"month"`
+	Count int64     `json:"count"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+}
+
+// ArchiveYear is a year of the post archive, its months newest first.
+type ArchiveYear struct {
+	Year   int            `json:"year"`
+	Count  int64          `json:"count"`
+	Months []ArchiveMonth `json:"months"`
+}
+
+// ArchiveRange returns the start of a year, or of a month of it when
+// month isn't 0, in calendar and loc, and the start of the next one.
+func ArchiveRange(calendar string, year, month int, loc *time.Location) (from, to time.Time, err error) {
+	if month < 0 || month > 12 {
+		return from, to, errors.New(utils.ValidationFailedMsg + ": month must be between 1 and 12")
+	}
+	switch calendar {
+	case CalendarGregorian:
+		if year < 1 || year > 9999 {
+			return from, to, errors.New(utils.ValidationFailedMsg + ": year out of range")
+		}
+		if month == 0 {
+			from = time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+			return from, from.AddDate(1, 0, 0), nil
+		}
+		from = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, loc)
+		return from, from.AddDate(0, 1, 0), nil
+	case CalendarJalali:
+		if year < jalali.MinYear || year >= jalali.MaxYear {
+			return from, to, errors.New(utils.ValidationFailedMsg + ": year out of range")
+		}
+		if month == 0 {
+			return jalali.MonthStart(year, 1, loc), jalali.MonthStart(year+1, 1, loc), nil
+		}
+		next := jalali.Date{Year: year, Month: month, Day: 1}.AddMonths(1)
+		return jalali.MonthStart(year, month, loc), next.Time(loc), nil
+	default:
+		return from, to, errors.New(utils.ValidationFailedMsg + ": calendar must be gregorian or jalali")
+	}
+}
+
+// Archive counts the posts matching filter by year and month of
+// publication in calendar, newest first. Days are those of the service's
+// time zone.
+func (s *PostService) Archive(ctx context.Context, filter PostFilter, calendar string) (_ []ArchiveYear, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.Archive", attribute.String("calendar", calendar))
+	defer tracing.End(span, &err)
+
+	if !IsCalendar(calendar) {
+		return nil, errors.New(utils.ValidationFailedMsg + ": calendar must be gregorian or jalali")
+	}
+
+	// Counting per day lets months of either calendar be summed up here
+	var days []struct {
+		Day   time.Time
+		Count int64
+	}
+	err = applyPostFilter(s.db.WithContext(ctx).Model(&models.Post{}), filter).
+		Where("posts.published_at IS NOT NULL").
+		Select("(posts.published_at AT TIME ZONE ?)::date AS day, COUNT(*) AS count", s.location.String()).
+		Group("day").Order("day DESC").
+		Scan(&days).Error
+	if err != nil {
+		return nil, err
+	}
+
+	years := []ArchiveYear{}
+	for _, d := range days {
+		gy, gm, gd := d.Day.Date()
+		year, month := gy, int(gm)
+		if calendar == CalendarJalali {
+			date := jalali.FromTime(time.Date(gy, gm, gd, 0, 0, 0, 0, time.UTC))
+			year, month = date.Year, date.Month
+		}
+
+		if n := len(years); n == 0 || years[n-1].Year != year {
+			years = append(years, ArchiveYear{Year: year})
+		}
+		y := &years[len(years)-1]
+		y.Count += d.Count
+		if n := len(y.Months); n == 0 || y.Months[n-1].Month != month {
+			from, to, err := ArchiveRange(calendar, year, month, s.location)
+			if err != nil {
+				return nil, err
+			}
+			y.Months = append(y.Months, ArchiveMonth{Month: month, From: from, To: to})
+		}
+		y.Months[len(y.Months)-1].Count += d.Count
+	}
+	return years, nil
+}
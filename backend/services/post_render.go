@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/sasanzare/go-cms/blocks"
+	"github.com/sasanzare/go-cms/markup"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/utils"
+	"gorm.io/gorm"
+)
+
+// autoExcerptLength is how much of a post's text fills an empty excerpt.
+const autoExcerptLength = 300
+
+// renderPost renders post's content into RenderedHTML and counts its
+// words. An empty excerpt is filled from the rendered text.
+//
+// Block posts have their document validated and normalized, and Content
+// set to its text. With checkMedia, referring to a media item that doesn't
+// exist is an error; otherwise such images are left out.
+func renderPost(db *gorm.DB, post *models.Post, checkMedia bool) error {
+	var rendered string
+	if post.ContentFormat == models.PostFormatBlocks {
+		doc, err := blocks.Parse(post.Blocks)
+		if err != nil {
+			return errors.New(utils.ValidationFailedMsg + ": " + err.Error())
+		}
+		media, err := loadMedia(db, doc.MediaIDs(), checkMedia)
+		if err != nil {
+			return err
+		}
+		if post.Blocks, err = doc.JSON(); err != nil {
+			return err
+		}
+		post.Content = doc.Text()
+		rendered = doc.HTML(media)
+	} else {
+		var err error
+		if rendered, err = markup.Render(post.ContentFormat, post.Content); err != nil {
+			return err
+		}
+		post.Blocks = nil
+	}
+	post.RenderedHTML = postPolicy.Sanitize(rendered)
+	post.RenderVersion = markup.Version
+
+	text := utils.SanitizeText(post.RenderedHTML)
+	post.WordCount = markup.WordCount(text)
+	post.ReadingTime = markup.ReadingTime(post.WordCount)
+	if post.Excerpt == "" {
+		post.Excerpt = utils.Excerpt(text, autoExcerptLength)
+	}
+	return nil
+}
+
+// loadMedia returns the media items with ids by ID. With strict, a missing
+// item is a validation error.
+func loadMedia(db *gorm.DB, ids []uint, strict bool) (map[uint]models.Media, error) {
+	media := make(map[uint]models.Media, len(ids))
+	if len(ids) == 0 {
+		return media, nil
+	}
+	var items []models.Media
+	if err := db.Where("id IN ?", ids).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		media[item.ID] = item
+	}
+	if strict {
+		for _, id := range ids {
+			if _, ok := media[id]; !ok {
+				return nil, fmt.Errorf("%s: media item %d not found", utils.ValidationFailedMsg, id)
+			}
+		}
+	}
+	return media, nil
+}
+
+// autoExcerpt is the excerpt renderPost generates for post's current text.
+func autoExcerpt(post *models.Post) string {
+	return utils.Excerpt(utils.SanitizeText(post.RenderedHTML), autoExcerptLength)
+}
+
+// renderUpdates renders post as updates would leave it and adds the
+// rendered columns to updates. An excerpt that was generated follows the
+// new text unless updates set one. HTML content is stored sanitized.
+func renderUpdates(db *gorm.DB, post *models.Post, updates map[string]interface{}) error {
+	content, contentSet := updates["content"].(string)
+	format, formatSet := updates["content_format"].(string)
+	excerpt, excerptSet := updates["excerpt"].(string)
+	doc, blocksSet := updates["blocks"].(json.RawMessage)
+	if !contentSet && !formatSet && !excerptSet && !blocksSet && post.RenderVersion == markup.Version {
+		return nil
+	}
+	if formatSet && !models.IsPostFormat(format) {
+		return errors.New(utils.ValidationFailedMsg + ": unsupported content format")
+	}
+
+	next := *post
+	if contentSet {
+		next.Content = content
+	}
+	if formatSet {
+		next.ContentFormat = format
+	}
+	if blocksSet {
+		next.Blocks = doc
+		if !formatSet && doc != nil {
+			next.ContentFormat = models.PostFormatBlocks
+			updates["content_format"] = models.PostFormatBlocks
+		}
+	}
+	switch {
+	case excerptSet:
+		next.Excerpt = excerpt
+	case post.Excerpt == autoExcerpt(post):
+		next.Excerpt = ""
+	}
+	if next.ContentFormat == models.PostFormatHTML && (contentSet || formatSet) {
+		updates["content"] = postPolicy.Sanitize(next.Content)
+	}
+
+	if err := renderPost(db, &next, contentSet || formatSet || blocksSet); err != nil {
+		return err
+	}
+	if next.ContentFormat == models.PostFormatBlocks {
+		updates["content"] = next.Content
+		updates["blocks"] = next.Blocks
+	} else {
+		updates["blocks"] = gorm.Expr("NULL")
+	}
+	updates["rendered_html"] = next.RenderedHTML
+	updates["render_version"] = next.RenderVersion
+	updates["word_count"] = next.WordCount
+	updates["reading_time"] = next.ReadingTime
+	updates["excerpt"] = next.Excerpt
+	return nil
+}
+
+// refreshRendered re-renders a post cached with an older renderer version
+// and stores the result without touching its version or update time.
+// Failures are logged and leave the stale HTML in place.
+func (s *PostService) refreshRendered(ctx context.Context, post *models.Post) {
+	if post.RenderVersion == markup.Version {
+		return
+	}
+	wasAuto := post.Excerpt == "" || post.Excerpt == autoExcerpt(post)
+	if wasAuto {
+		post.Excerpt = ""
+	}
+	db := s.db.WithContext(ctx)
+	if err := renderPost(db, post, false); err != nil {
+		slog.ErrorContext(ctx, "failed to render post", slog.Uint64("post_id", uint64(post.ID)), slog.String("error", err.Error()))
+		return
+	}
+
+	columns := map[string]interface{}{
+		"rendered_html":  post.RenderedHTML,
+		"render_version": post.RenderVersion,
+		"word_count":     post.WordCount,
+		"reading_time":   post.ReadingTime,
+	}
+	if wasAuto {
+		columns["excerpt"] = post.Excerpt
+	}
+	if post.ContentFormat == models.PostFormatBlocks {
+		columns["blocks"] = post.Blocks
+		columns["content"] = post.Content
+	}
+	if err := db.Model(post).UpdateColumns(columns).Error; err != nil {
+		slog.ErrorContext(ctx, "failed to cache rendered post", slog.Uint64("post_id", uint64(post.ID)), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"unicode/utf8"
+
+	"github.com/sasanzare/go-cms/events"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/tracing"
+	"github.com/sasanzare/go-cms/utils"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// mentionExcerptLength is how much of a post's text a mention notification
+// quotes.
+const mentionExcerptLength = 200
+
+// ApprovePost publishes a post on behalf of the staff member in ctx and
+// records them as its approver.
+func (s *PostService) ApprovePost(ctx context.Context, id uint) (_ *models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.ApprovePost", attribute.Int("post.id", int(id)))
+	defer tracing.End(span, &err)
+
+	claims, ok := utils.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, errors.New("approving a post requires a signed-in user")
+	}
+	post, err := s.GetPostByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.Status == models.PostStatusPublished {
+		return nil, errors.New(utils.ValidationFailedMsg + ": post is already published")
+	}
+
+	now := s.clock.Now()
+	return s.updatePost(ctx, id, 0, map[string]interface{}{
+		"status":           models.PostStatusPublished,
+		"approved_by":      claims.UserID,
+		"approved_at":      &now,
+		"rejection_reason": "",
+	}, nil)
+}
+
+// RejectPost rejects a post, keeping reason for its author.
+func (s *PostService) RejectPost(ctx context.Context, id uint, reason string) (_ *models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.RejectPost", attribute.Int("post.id", int(id)))
+	defer tracing.End(span, &err)
+
+	if utf8.RuneCountInString(reason) > 1000 {
+		return nil, errors.New(utils.ValidationFailedMsg + ": reason exceeds 1000 characters")
+	}
+	post, err := s.GetPostByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.Status == models.PostStatusRejected {
+		return nil, errors.New(utils.ValidationFailedMsg + ": post is already rejected")
+	}
+
+	return s.updatePost(ctx, id, 0, map[string]interface{}{
+		"status":           models.PostStatusRejected,
+		"rejection_reason": reason,
+		"approved_by":      nil,
+		"approved_at":      nil,
+	}, nil)
+}
+
+// publishStatusEvents tells subscribers that a post moved from status
+// from: staff publishing someone else's post approves it, and a post going
+// live for the first time mentions the users it names.
+func (s *PostService) publishStatusEvents(ctx context.Context, post *models.Post, from string, firstPublish bool) {
+	if s.events == nil || post.Status == from {
+		return
+	}
+
+	var actorID uint
+	if claims, ok := utils.ClaimsFromContext(ctx); ok {
+		actorID = claims.UserID
+	}
+
+	switch post.Status {
+	case models.PostStatusPublished:
+		if actorID != 0 && actorID != post.AuthorID {
+			s.events.Publish(ctx, events.PostApproved{
+				PostID:   post.ID,
+				AuthorID: post.AuthorID,
+				ActorID:  actorID,
+				Title:    post.Title,
+			})
+		}
+		if firstPublish {
+			s.publishMentions(ctx, post)
+		}
+	case models.PostStatusRejected:
+		s.events.Publish(ctx, events.PostRejected{
+			PostID:   post.ID,
+			AuthorID: post.AuthorID,
+			ActorID:  actorID,
+			Title:    post.Title,
+			Reason:   post.RejectionReason,
+		})
+	}
+}
+
+// publishMentions announces the users a newly published post mentions.
+func (s *PostService) publishMentions(ctx context.Context, post *models.Post) {
+	if s.events == nil {
+		return
+	}
+	text := utils.SanitizeText(post.RenderedHTML)
+	usernames := utils.ExtractMentions(text)
+	if len(usernames) == 0 {
+		return
+	}
+
+	s.events.Publish(ctx, events.Mentioned{
+		Usernames: usernames,
+		ActorID:   post.AuthorID,
+		PostID:    post.ID,
+		Title:     post.Title,
+		Excerpt:   utils.Excerpt(text, mentionExcerptLength),
+	})
+	slog.DebugContext(ctx, "post mentions published", slog.Uint64("post_id", uint64(post.ID)), slog.Int("mentions", len(usernames)))
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/tracing"
+	"github.com/sasanzare/go-cms/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// Diff granularities for DiffRevisions
+const (
+	DiffModeLine = "line"
+	DiffModeWord = "word"
+)
+
+// RevisionDiff holds the changes between two revisions of a post, field by
+// field. Content is compared at the requested granularity; the shorter
+// fields always word by word.
+type RevisionDiff struct {
+	PostID          uint           `json:"post_id"`
+	From            uint           `json:"from"`
+	To              uint           `json:"to"`
+	Mode            string         `json:"mode"`
+	Title           []utils.DiffOp `json:"title"`
+	Excerpt         []utils.DiffOp `json:"excerpt"`
+	Content         []utils.DiffOp `json:"content"`
+	MetaTitle       []utils.DiffOp `json:"meta_title"`
+	MetaDescription []utils.DiffOp `json:"meta_description"`
+}
+
+// ListRevisions returns a post's revisions, newest first. Content is left
+// out; fetch a single revision for the full text.
+func (s *PostService) ListRevisions(ctx context.Context, postID uint) (_ []models.PostRevision, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.ListRevisions", attribute.Int("post.id", int(postID)))
+	defer tracing.End(span, &err)
+
+	db := s.db.WithContext(ctx)
+	if err := db.Select("id").First(&models.Post{}, postID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("post not found")
+		}
+		return nil, err
+	}
+
+	var revisions []models.PostRevision
+	err = db.Omit("content", "blocks").
+		Preload("Author").
+		Where("post_id = ?", postID).
+		Order("number DESC").
+		Find(&revisions).Error
+	return revisions, err
+}
+
+// GetRevision returns revision number of a post.
+func (s *PostService) GetRevision(ctx context.Context, postID, number uint) (_ *models.PostRevision, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetRevision",
+		attribute.Int("post.id", int(postID)), attribute.Int("revision.number", int(number)))
+	defer tracing.End(span, &err)
+
+	var revision models.PostRevision
+	err = s.db.WithContext(ctx).
+		Preload("Author").
+		Where("post_id = ? AND number = ?", postID, number).
+		First(&revision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("revision not found")
+	}
+	return &revision, err
+}
+
+// DiffRevisions compares revision from with revision to of a post.
+//
+// Parameters:
+//   - mode: DiffModeLine (default) or DiffModeWord for the content field
+func (s *PostService) DiffRevisions(ctx context.Context, postID, from, to uint, mode string) (_ *RevisionDiff, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.DiffRevisions", attribute.Int("post.id", int(postID)))
+	defer tracing.End(span, &err)
+
+	diffContent := utils.DiffLines
+	switch mode {
+	case "", DiffModeLine:
+		mode = DiffModeLine
+	case DiffModeWord:
+		diffContent = utils.DiffWords
+	default:
+		return nil, errors.New(utils.ValidationFailedMsg + ": mode must be line or word")
+	}
+
+	a, err := s.GetRevision(ctx, postID, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.GetRevision(ctx, postID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := diffRevisionText(a, b, diffContent)
+	diff.PostID, diff.From, diff.To, diff.Mode = postID, from, to, mode
+	return diff, nil
+}
+
+func diffRevisionText(a, b *models.PostRevision, diffContent func(a, b string) []utils.DiffOp) *RevisionDiff {
+	return &RevisionDiff{
+		Title:           utils.DiffWords(a.Title, b.Title),
+		Excerpt:         utils.DiffWords(a.Excerpt, b.Excerpt),
+		Content:         diffContent(a.Content, b.Content),
+		MetaTitle:       utils.DiffWords(a.MetaTitle, b.MetaTitle),
+		MetaDescription: utils.DiffWords(a.MetaDescription, b.MetaDescription),
+	}
+}
+
+// RestoreRevision copies a revision's text back onto its post. The restore
+// is itself saved as a new revision, so it can be undone in turn.
+func (s *PostService) RestoreRevision(ctx context.Context, postID, number uint) (_ *models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.RestoreRevision",
+		attribute.Int("post.id", int(postID)), attribute.Int("revision.number", int(number)))
+	defer tracing.End(span, &err)
+
+	revision, err := s.GetRevision(ctx, postID, number)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.updatePost(ctx, postID, 0, map[string]interface{}{
+		"title":            revision.Title,
+		"content":          revision.Content,
+		"content_format":   revision.ContentFormat,
+		"blocks":           revision.Blocks,
+		"excerpt":          revision.Excerpt,
+		"meta_title":       revision.MetaTitle,
+		"meta_description": revision.MetaDescription,
+	}, &revision.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "post revision restored", slog.Uint64("post_id", uint64(postID)), slog.Uint64("revision", uint64(number)))
+	return post, nil
+}
+
+// recordRevision saves the post's text and status as its next revision
+// unless both match the latest one, then prunes revisions beyond the
+// retention limit.
+// The editor is the authenticated user, falling back to the post's author.
+func (s *PostService) recordRevision(ctx context.Context, tx *gorm.DB, post *models.Post, restoredFrom *uint) error {
+	editorID := post.AuthorID
+	if claims, ok := utils.ClaimsFromContext(ctx); ok {
+		editorID = claims.UserID
+	}
+
+	var latest models.PostRevision
+	err := tx.Where("post_id = ?", post.ID).Order("number DESC").Limit(1).Find(&latest).Error
+	if err != nil {
+		return err
+	}
+
+	revision := models.NewPostRevision(post, latest.Number+1, editorID)
+	revision.RestoredFrom = restoredFrom
+	if latest.ID != 0 && restoredFrom == nil && revision.SameContent(&latest) {
+		if revision.Status == latest.Status {
+			return nil
+		}
+		revision.StatusOnly = true
+	}
+	if err := tx.Create(revision).Error; err != nil {
+		return err
+	}
+
+	if s.revisionLimit > 0 && revision.Number > uint(s.revisionLimit) {
+		return tx.Where("post_id = ? AND number <= ?", post.ID, revision.Number-uint(s.revisionLimit)).
+			Delete(&models.PostRevision{}).Error
+	}
+	return nil
+}
+
+// ensureBaseRevision records the post's current text as its first revision
+// when it has none yet.
+func (s *PostService) ensureBaseRevision(tx *gorm.DB, post *models.Post) error {
+	var count int64
+	if err := tx.Model(&models.PostRevision{}).Where("post_id = ?", post.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	revision := models.NewPostRevision(post, 1, post.AuthorID)
+	revision.CreatedAt = post.UpdatedAt
+	return tx.Create(revision).Error
+}
+
+// VersionConflictError is returned when a post changed since the version an
+// edit was based on. Changes diffs the text at that version against the
+// current text so clients can merge.
+type VersionConflictError struct {
+	PostID          uint         `json:"post_id"`
+	ExpectedVersion uint         `json:"expected_version"`
+	CurrentVersion  uint         `json:"current_version"`
+	Current         *models.Post `json:"current"`
+	// ChangedFields lists the revisioned fields that differ; empty when only
+	// other fields, such as the status, changed
+	ChangedFields []string `json:"changed_fields"`
+	// Changes diffs from the base revision to the current text (To is zero)
+	Changes *RevisionDiff `json:"changes,omitempty"`
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("post was modified: version %d is current, not %d", e.CurrentVersion, e.ExpectedVersion)
+}
+
+// versionConflict builds the conflict for post against the newest revision
+// saved at or before expected.
+func (s *PostService) versionConflict(tx *gorm.DB, post *models.Post, expected uint) error {
+	var base models.PostRevision
+	err := tx.Where("post_id = ? AND post_version <= ?", post.ID, expected).
+		Order("number DESC").Limit(1).Find(&base).Error
+	if err != nil {
+		return err
+	}
+	if base.ID == 0 {
+		return NewVersionConflict(post, expected, nil)
+	}
+	return NewVersionConflict(post, expected, &base)
+}
+
+// NewVersionConflict describes an edit based on version expected of post,
+// diffing base, the revision saved at that version, against the current
+// text. Without a base, for instance after pruning, it carries no diff.
+func NewVersionConflict(post *models.Post, expected uint, base *models.PostRevision) *VersionConflictError {
+	conflict := &VersionConflictError{
+		PostID:          post.ID,
+		ExpectedVersion: expected,
+		CurrentVersion:  post.Version,
+		Current:         post,
+		ChangedFields:   []string{},
+	}
+	if base == nil {
+		return conflict
+	}
+
+	current := models.NewPostRevision(post, 0, 0)
+	conflict.Changes = diffRevisionText(base, current, utils.DiffLines)
+	conflict.Changes.PostID, conflict.Changes.From, conflict.Changes.Mode = post.ID, base.Number, DiffModeLine
+	for field, changed := range map[string]bool{
+		"title":            base.Title != current.Title,
+		"excerpt":          base.Excerpt != current.Excerpt,
+		"content":          base.Content != current.Content,
+		"content_format":   base.ContentFormat != current.ContentFormat,
+		"meta_title":       base.MetaTitle != current.MetaTitle,
+		"meta_description": base.MetaDescription != current.MetaDescription,
+	} {
+		if changed {
+			conflict.ChangedFields = append(conflict.ChangedFields, field)
+		}
+	}
+	sort.Strings(conflict.ChangedFields)
+	return conflict
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/sasanzare/go-cms/metrics"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/tracing"
+	"github.com/sasanzare/go-cms/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SchedulePost sets a post to be published at publishAt and, if unpublishAt
+// is set, archived again at that time.
+func (s *PostService) SchedulePost(ctx context.Context, id uint, publishAt time.Time, unpublishAt *time.Time) (_ *models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.SchedulePost", attribute.Int("post.id", int(id)))
+	defer tracing.End(span, &err)
+
+	return s.updatePost(ctx, id, 0, map[string]interface{}{
+		"status":       models.PostStatusScheduled,
+		"scheduled_at": &publishAt,
+		"unpublish_at": unpublishAt,
+	}, nil)
+}
+
+// CancelSchedule returns a scheduled post to draft.
+func (s *PostService) CancelSchedule(ctx context.Context, id uint) (_ *models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.CancelSchedule", attribute.Int("post.id", int(id)))
+	defer tracing.End(span, &err)
+
+	post, err := s.GetPostByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.Status != models.PostStatusScheduled {
+		return nil, errors.New(utils.ValidationFailedMsg + ": post is not scheduled")
+	}
+
+	return s.updatePost(ctx, id, 0, map[string]interface{}{
+		"status":       models.PostStatusDraft,
+		"scheduled_at": nil,
+	}, nil)
+}
+
+// SetPostExpiry sets when a post is archived; nil removes the expiry.
+func (s *PostService) SetPostExpiry(ctx context.Context, id uint, unpublishAt *time.Time) (_ *models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.SetPostExpiry", attribute.Int("post.id", int(id)))
+	defer tracing.End(span, &err)
+
+	if unpublishAt != nil && !unpublishAt.After(s.clock.Now()) {
+		return nil, errors.New(utils.ValidationFailedMsg + ": unpublish_at must be in the future")
+	}
+	return s.updatePost(ctx, id, 0, map[string]interface{}{"unpublish_at": unpublishAt}, nil)
+}
+
+// PublishDue publishes up to limit scheduled posts whose time has come.
+// Rows are claimed with FOR UPDATE SKIP LOCKED, so several instances can run
+// the scheduler and each post is published exactly once.
+//
+// Returns:
+//   - int: number of posts published
+//   - error: database error if any
+func (s *PostService) PublishDue(ctx context.Context, limit int) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.PublishDue")
+	defer tracing.End(span, &err)
+
+	now := s.clock.Now()
+	firstPublish := map[uint]bool{}
+	posts, err := s.transitionDue(ctx, "posts.status = ? AND posts.scheduled_at <= ?",
+		[]interface{}{models.PostStatusScheduled, now}, "scheduled_at", limit,
+		func(post *models.Post) map[string]interface{} {
+			updates := map[string]interface{}{"status": models.PostStatusPublished}
+			if post.PublishedAt == nil {
+				firstPublish[post.ID] = true
+				// Publish as of the scheduled time, not the scheduler's tick
+				updates["published_at"] = post.ScheduledAt
+			}
+			return updates
+		})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, post := range posts {
+		metrics.PostsPublished.Inc()
+		s.syncIndex(ctx, post.ID)
+		if firstPublish[post.ID] {
+			s.publishMentions(ctx, &post)
+		}
+		slog.InfoContext(ctx, "scheduled post published", slog.Uint64("post_id", uint64(post.ID)))
+	}
+	return len(posts), nil
+}
+
+// UnpublishExpired archives up to limit published posts whose unpublish_at
+// has passed, claiming rows like PublishDue.
+func (s *PostService) UnpublishExpired(ctx context.Context, limit int) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.UnpublishExpired")
+	defer tracing.End(span, &err)
+
+	now := s.clock.Now()
+	posts, err := s.transitionDue(ctx, "posts.status = ? AND posts.unpublish_at <= ?",
+		[]interface{}{models.PostStatusPublished, now}, "unpublish_at", limit,
+		func(*models.Post) map[string]interface{} {
+			return map[string]interface{}{"status": models.PostStatusArchived}
+		})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, post := range posts {
+		s.syncIndex(ctx, post.ID)
+		slog.InfoContext(ctx, "expired post unpublished", slog.Uint64("post_id", uint64(post.ID)))
+	}
+	return len(posts), nil
+}
+
+// transitionDue locks up to limit posts matching cond, skipping rows other
+// instances hold, and applies the updates returned by change to each,
+// recording the new status as a revision like updatePost does.
+func (s *PostService) transitionDue(ctx context.Context, cond string, args []interface{}, orderBy string, limit int,
+	change func(*models.Post) map[string]interface{}) ([]models.Post, error) {
+	var posts []models.Post
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where(cond, args...).
+			Order(orderBy).
+			Limit(limit).
+			Find(&posts).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range posts {
+			post := &posts[i]
+			if err := s.ensureBaseRevision(tx, post); err != nil {
+				return err
+			}
+			updates := change(post)
+			updates["updated_at"] = s.clock.Now()
+			updates["version"] = gorm.Expr("version + 1")
+			if err := tx.Model(post).Updates(updates).Error; err != nil {
+				return err
+			}
+			if err := tx.First(post, post.ID).Error; err != nil {
+				return err
+			}
+			if err := s.recordRevision(ctx, tx, post, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return posts, err
+}
+
+// validateSchedule checks the publish and unpublish times a post will have
+// after updates are applied.
+func (s *PostService) validateSchedule(post *models.Post, updates map[string]interface{}) error {
+	scheduledAt := post.ScheduledAt
+	if v, ok := updates["scheduled_at"]; ok {
+		scheduledAt = timeValue(v)
+	}
+	unpublishAt := post.UnpublishAt
+	if v, ok := updates["unpublish_at"]; ok {
+		unpublishAt = timeValue(v)
+	}
+
+	if scheduledAt == nil || !scheduledAt.After(s.clock.Now()) {
+		return errors.New(utils.ValidationFailedMsg + ": scheduled_at must be in the future")
+	}
+	if unpublishAt != nil && !unpublishAt.After(*scheduledAt) {
+		return errors.New(utils.ValidationFailedMsg + ": unpublish_at must be after scheduled_at")
+	}
+	return nil
+}
+
+// timeValue reads a time from an updates map value.
+func timeValue(v interface{}) *time.Time {
+	switch t := v.(type) {
+	case time.Time:
+		return &t
+	case *time.Time:
+		return t
+	default:
+		return nil
+	}
+}
@@ -1,44 +1,170 @@
 package services
 
 import (
+	"context"
+	"log/slog"
+
+	"github.com/sasanzare/go-cms/events"
+	"github.com/sasanzare/go-cms/i18n"
+	"github.com/sasanzare/go-cms/metrics"
 	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/sanitize"
+	"github.com/sasanzare/go-cms/search"
+	"github.com/sasanzare/go-cms/seo"
+	"github.com/sasanzare/go-cms/tracing"
 	"github.com/sasanzare/go-cms/utils"
+	"go.opentelemetry.io/otel/attribute"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
 )
 
 type PostService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	index         search.Index
+	revisionLimit int
+	clock         utils.Clock
+	events        *events.Bus
+	locales       *i18n.Locales
+	location      *time.Location
+}
+
+// PostServiceOption configures optional PostService dependencies
+type PostServiceOption func(*PostService)
+
+// WithSearchIndex keeps index in sync with post changes
+func WithSearchIndex(index search.Index) PostServiceOption {
+	return func(s *PostService) {
+		s.index = index
+	}
+}
+
+// WithRevisionLimit keeps at most limit revisions per post; zero keeps all
+func WithRevisionLimit(limit int) PostServiceOption {
+	return func(s *PostService) {
+		s.revisionLimit = limit
+	}
+}
+
+// WithClock replaces the system clock, letting tests control scheduling
+func WithClock(clock utils.Clock) PostServiceOption {
+	return func(s *PostService) {
+		s.clock = clock
+	}
+}
+
+// WithEvents publishes approvals, rejections and mentions to bus
+func WithEvents(bus *events.Bus) PostServiceOption {
+	return func(s *PostService) {
+		s.events = bus
+	}
+}
+
+// WithLocales sets the locales posts may be written in; by default only
+// English is supported
+func WithLocales(locales *i18n.Locales) PostServiceOption {
+	return func(s *PostService) {
+		s.locales = locales
+	}
+}
+
+// WithLocation sets the time zone post dates are archived in; by default
+// UTC
+func WithLocation(loc *time.Location) PostServiceOption {
+	return func(s *PostService) {
+		s.location = loc
+	}
 }
 
-func NewPostService(db *gorm.DB) *PostService {
-	return &PostService{db: db}
+func NewPostService(db *gorm.DB, opts ...PostServiceOption) *PostService {
+	s := &PostService{db: db, clock: utils.RealClock{}, locales: i18n.Single("en"), location: time.UTC}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Locales returns the locales posts may be written in
+func (s *PostService) Locales() *i18n.Locales {
+	return s.locales
+}
+
+// Location returns the time zone post dates are archived in
+func (s *PostService) Location() *time.Location {
+	return s.location
 }
 
 // CreatePost creates a new post with validation
-func (s *PostService) CreatePost(post *models.Post) error {
+func (s *PostService) CreatePost(ctx context.Context, post *models.Post) (err error) {
+	ctx, span := tracing.Start(ctx, "PostService.CreatePost")
+	defer tracing.End(span, &err)
+
 	// Validation
+	post.Title = utils.SanitizeText(post.Title)
+	post.Excerpt = utils.SanitizeText(post.Excerpt)
+	post.MetaTitle = utils.SanitizeText(post.MetaTitle)
+	post.MetaDescription = utils.SanitizeText(post.MetaDescription)
 	if post.Title == "" {
 		return errors.New(utils.ValidationFailedMsg + ": title is required")
 	}
 	if len(post.Title) > 255 {
 		return errors.New(utils.ValidationFailedMsg + ": title exceeds 255 characters")
 	}
-	if post.Content == "" {
+	switch {
+	case post.ContentFormat == "" && post.Blocks != nil:
+		post.ContentFormat = models.PostFormatBlocks
+	case post.ContentFormat == "":
+		post.ContentFormat = models.PostFormatHTML
+	case !models.IsPostFormat(post.ContentFormat):
+		return errors.New(utils.ValidationFailedMsg + ": unsupported content format")
+	}
+	if post.ContentFormat == models.PostFormatHTML {
+		post.Content = postPolicy.Sanitize(post.Content)
+	}
+	if post.ContentFormat != models.PostFormatBlocks && strings.TrimSpace(post.Content) == "" {
 		return errors.New(utils.ValidationFailedMsg + ": content is required")
 	}
+	robots, err := seo.NormalizeRobots(post.Robots)
+	if err != nil {
+		return errors.New(utils.ValidationFailedMsg + ": " + err.Error())
+	}
+	post.Robots = robots
+	if err := renderPost(s.db.WithContext(ctx), post, true); err != nil {
+		return err
+	}
 	if post.AuthorID == 0 {
 		return errors.New(utils.ValidationFailedMsg + ": author ID is required")
 	}
+	if post.SearchLanguage == "" {
+		post.SearchLanguage = search.DefaultLanguage
+	} else if !search.IsValidLanguage(post.SearchLanguage) {
+		return errors.New(utils.ValidationFailedMsg + ": unsupported search language")
+	}
+	if post.Locale == "" {
+		post.Locale = s.locales.Default()
+	} else if !s.locales.IsSupported(post.Locale) {
+		return errors.New(utils.ValidationFailedMsg + ": unsupported locale")
+	}
+	if post.SourceID != nil {
+		if err := s.prepareTranslation(s.db.WithContext(ctx), post); err != nil {
+			return err
+		}
+	}
 
 	// Set defaults
 	if post.Status == "" {
 		post.Status = models.PostStatusDraft
 	}
-	now := time.Now()
+	now := s.clock.Now()
+	if post.Status == models.PostStatusScheduled && (post.ScheduledAt == nil || !post.ScheduledAt.After(now)) {
+		return errors.New(utils.ValidationFailedMsg + ": scheduled posts need a future scheduled_at")
+	}
 	post.CreatedAt = now
 	post.UpdatedAt = now
 
@@ -47,13 +173,29 @@ func (s *PostService) CreatePost(post *models.Post) error {
 		post.Slug = generateSlug(post.Title)
 	}
 
-	return s.db.Create(post).Error
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(post).Error; err != nil {
+			return err
+		}
+		return s.recordRevision(ctx, tx, post, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.syncIndex(ctx, post.ID)
+
+	slog.InfoContext(ctx, "post created", slog.Uint64("post_id", uint64(post.ID)), slog.Uint64("author_id", uint64(post.AuthorID)))
+	return nil
 }
 
 // GetPostByID retrieves a post by ID with relationships
-func (s *PostService) GetPostByID(id uint) (*models.Post, error) {
+func (s *PostService) GetPostByID(ctx context.Context, id uint) (_ *models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetPostByID", attribute.Int("post.id", int(id)))
+	defer tracing.End(span, &err)
+
 	var post models.Post
-	err := s.db.
+	err = s.db.WithContext(ctx).
 		Preload("Author").
 		Preload("Category").
 		Preload("Tags").
@@ -62,98 +204,463 @@ func (s *PostService) GetPostByID(id uint) (*models.Post, error) {
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, errors.New("post not found")
 	}
-	return &post, err
+	if err != nil {
+		return nil, err
+	}
+	s.refreshRendered(ctx, &post)
+	return &post, nil
 }
 
-// UpdatePost updates an existing post
-func (s *PostService) UpdatePost(id uint, updates map[string]interface{}) (*models.Post, error) {
-	var post models.Post
-	if err := s.db.First(&post, id).Error; err != nil {
-		return nil, errors.New("post not found")
+// UpdatePost updates an existing post and records a revision when its text
+// changes
+func (s *PostService) UpdatePost(ctx context.Context, id uint, updates map[string]interface{}) (_ *models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.UpdatePost", attribute.Int("post.id", int(id)))
+	defer tracing.End(span, &err)
+
+	return s.updatePost(ctx, id, 0, updates, nil)
+}
+
+// UpdatePostIfMatch updates a post only if it is still at version. When
+// another edit got there first it returns a *VersionConflictError describing
+// the changes since version.
+func (s *PostService) UpdatePostIfMatch(ctx context.Context, id, version uint, updates map[string]interface{}) (_ *models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.UpdatePostIfMatch",
+		attribute.Int("post.id", int(id)), attribute.Int("post.version", int(version)))
+	defer tracing.End(span, &err)
+
+	if version == 0 {
+		return nil, errors.New(utils.ValidationFailedMsg + ": version is required")
 	}
+	return s.updatePost(ctx, id, version, updates, nil)
+}
 
-	// Validate status transition
-	if status, ok := updates["status"].(string); ok {
-		if !isValidStatusTransition(post.Status, status) {
-			return nil, errors.New("invalid status transition")
-		}
-		if status == models.PostStatusPublished && post.PublishedAt == nil {
-			now := time.Now()
-			updates["published_at"] = &now
+// updatePost applies updates in a transaction holding the post's row lock,
+// so concurrent saves get consecutive versions and revision numbers. A
+// non-zero expectedVersion must match the stored version. restoredFrom
+// marks the new revision as a restore.
+func (s *PostService) updatePost(ctx context.Context, id, expectedVersion uint, updates map[string]interface{}, restoredFrom *uint) (*models.Post, error) {
+	sanitizeTextUpdates(updates, "title", "excerpt", "meta_title", "meta_description")
+	if updates["title"] == "" {
+		return nil, errors.New(utils.ValidationFailedMsg + ": title is required")
+	}
+	if lang, ok := updates["search_language"].(string); ok && !search.IsValidLanguage(lang) {
+		return nil, errors.New(utils.ValidationFailedMsg + ": unsupported search language")
+	}
+	if raw, ok := updates["robots"].(string); ok {
+		robots, err := seo.NormalizeRobots(raw)
+		if err != nil {
+			return nil, errors.New(utils.ValidationFailedMsg + ": " + err.Error())
 		}
+		updates["robots"] = robots
 	}
 
-	updates["updated_at"] = time.Now()
+	var post models.Post
+	var (
+		publishing   bool
+		fromStatus   string
+		firstPublish bool
+	)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&post, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("post not found")
+			}
+			return err
+		}
 
-	if err := s.db.Model(&post).Updates(updates).Error; err != nil {
+		if expectedVersion != 0 && post.Version != expectedVersion {
+			return s.versionConflict(tx, &post, expectedVersion)
+		}
+		fromStatus = post.Status
+		firstPublish = post.PublishedAt == nil
+
+		// Validate status transition
+		if status, ok := updates["status"].(string); ok {
+			if !isValidStatusTransition(post.Status, status) {
+				return errors.New("invalid status transition")
+			}
+			if status == models.PostStatusPublished && post.PublishedAt == nil {
+				now := s.clock.Now()
+				updates["published_at"] = &now
+			}
+			if status == models.PostStatusScheduled {
+				if err := s.validateSchedule(&post, updates); err != nil {
+					return err
+				}
+			}
+			publishing = status == models.PostStatusPublished && post.Status != models.PostStatusPublished
+		}
+
+		if revision, ok := updates["source_revision"].(uint); ok {
+			if err := checkSourceRevision(tx, &post, revision); err != nil {
+				return err
+			}
+		}
+
+		// Posts created before revisions existed get their current text
+		// saved first so the edit can be undone
+		if err := s.ensureBaseRevision(tx, &post); err != nil {
+			return err
+		}
+		if err := renderUpdates(tx, &post, updates); err != nil {
+			return err
+		}
+
+		updates["updated_at"] = s.clock.Now()
+		updates["version"] = gorm.Expr("version + 1")
+		if err := tx.Model(&post).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&post, id).Error; err != nil {
+			return err
+		}
+		return s.recordRevision(ctx, tx, &post, restoredFrom)
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	if publishing {
+		metrics.PostsPublished.Inc()
+	}
+	s.syncIndex(ctx, post.ID)
+	s.publishStatusEvents(ctx, &post, fromStatus, firstPublish)
+
+	slog.InfoContext(ctx, "post updated", slog.Uint64("post_id", uint64(post.ID)))
+
 	return &post, nil
 }
 
 // DeletePost soft-deletes a post
-func (s *PostService) DeletePost(id uint) error {
-	result := s.db.Delete(&models.Post{}, id)
+func (s *PostService) DeletePost(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "PostService.DeletePost", attribute.Int("post.id", int(id)))
+	defer tracing.End(span, &err)
+
+	result := s.db.WithContext(ctx).Delete(&models.Post{}, id)
 	if result.Error != nil {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
 		return errors.New("post not found")
 	}
+
+	if s.index != nil {
+		if err := s.index.Delete(ctx, id); err != nil {
+			slog.ErrorContext(ctx, "failed to remove post from search index", slog.Uint64("post_id", uint64(id)), slog.String("error", err.Error()))
+		}
+	}
 	return nil
 }
 
-// ListPosts retrieves posts with filters
-func (s *PostService) ListPosts(filter PostFilter) ([]models.Post, error) {
-	var posts []models.Post
-	query := s.db.Preload("Author").Preload("Category")
+// ListPosts retrieves a page of posts matching the filter. When
+// filter.Cursor is set keyset pagination is used, otherwise filter.Page
+// selects an offset page. Both modes return a cursor for the next page.
+func (s *PostService) ListPosts(ctx context.Context, filter PostFilter) (_ []models.Post, _ *utils.Pagination, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.ListPosts")
+	defer tracing.End(span, &err)
 
-	if filter.Status != "" {
-		query = query.Where("status = ?", filter.Status)
+	sortField, sortExpr, desc, err := resolvePostSort(filter.Sort, filter.Order)
+	if err != nil {
+		return nil, nil, err
 	}
-	if filter.CategoryID != 0 {
-		query = query.Where("category_id = ?", filter.CategoryID)
+	page, pageSize := utils.NormalizePage(filter.Page, filter.PageSize)
+
+	query := applyPostFilter(s.db.WithContext(ctx).Model(&models.Post{}), filter)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, nil, err
 	}
-	if filter.AuthorID != 0 {
-		query = query.Where("author_id = ?", filter.AuthorID)
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	query = query.Order(sortExpr + " " + direction).Order("posts.id " + direction)
+
+	pagination := &utils.Pagination{
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: utils.TotalPages(total, pageSize),
+	}
+
+	if filter.Cursor != "" {
+		var cursor postCursor
+		if err := utils.DecodeCursor(filter.Cursor, &cursor); err != nil {
+			return nil, nil, err
+		}
+		if cursor.Sort != sortField || cursor.Desc != desc {
+			return nil, nil, fmt.Errorf("%w: cursor does not match sort order", utils.ErrInvalidCursor)
+		}
+		value, err := cursor.sortValue()
+		if err != nil {
+			return nil, nil, err
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		query = query.Where("("+sortExpr+", posts.id) "+op+" (?, ?)", value, cursor.ID)
+	} else {
+		pagination.Page = page
+		query = query.Offset((page - 1) * pageSize)
+	}
+
+	// Fetch one extra row to know whether another page follows
+	var posts []models.Post
+	if err := query.Preload("Author").Preload("Category").Limit(pageSize + 1).Find(&posts).Error; err != nil {
+		return nil, nil, err
 	}
 
-	err := query.Order("created_at DESC").Find(&posts).Error
-	return posts, err
+	if len(posts) > pageSize {
+		posts = posts[:pageSize]
+		pagination.HasMore = true
+		next, err := newPostCursor(sortField, desc, &posts[len(posts)-1])
+		if err != nil {
+			return nil, nil, err
+		}
+		pagination.NextCursor = next
+	}
+
+	return posts, pagination, nil
 }
 
 // PublishPost changes post status to published
-func (s *PostService) PublishPost(id uint) (*models.Post, error) {
-	now := time.Now()
-	return s.UpdatePost(id, map[string]interface{}{
+func (s *PostService) PublishPost(ctx context.Context, id uint) (_ *models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.PublishPost", attribute.Int("post.id", int(id)))
+	defer tracing.End(span, &err)
+
+	now := s.clock.Now()
+	return s.UpdatePost(ctx, id, map[string]interface{}{
 		"status":       models.PostStatusPublished,
 		"published_at": &now,
 	})
 }
 
 // IncrementViewCount increments the view count
-func (s *PostService) IncrementViewCount(id uint) error {
-	return s.db.Model(&models.Post{}).
+func (s *PostService) IncrementViewCount(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "PostService.IncrementViewCount", attribute.Int("post.id", int(id)))
+	defer tracing.End(span, &err)
+
+	return s.db.WithContext(ctx).Model(&models.Post{}).
 		Where("id = ?", id).
 		UpdateColumn("view_count", gorm.Expr("view_count + 1")).
 		Error
 }
 
+// syncIndex reloads a post and updates the search index. Index failures
+// are logged rather than returned: the post itself was saved and a reindex
+// repairs the index.
+func (s *PostService) syncIndex(ctx context.Context, id uint) {
+	if s.index == nil {
+		return
+	}
+
+	var post models.Post
+	if err := s.db.WithContext(ctx).Clauses(dbresolver.Write).Preload("Tags").First(&post, id).Error; err != nil {
+		slog.ErrorContext(ctx, "failed to load post for indexing", slog.Uint64("post_id", uint64(id)), slog.String("error", err.Error()))
+		return
+	}
+	if err := s.index.Index(ctx, search.DocumentFromPost(&post)); err != nil {
+		slog.ErrorContext(ctx, "failed to index post", slog.Uint64("post_id", uint64(id)), slog.String("error", err.Error()))
+	}
+}
+
+// postPolicy is the markup post content may contain.
+var postPolicy = sanitize.Post()
+
 // Helper functions
 func generateSlug(title string) string {
 	// Implement your slug generation logic
 	return strings.ToLower(strings.ReplaceAll(title, " ", "-"))
 }
 
+// sanitizeTextUpdates reduces the values of the plain-text columns among
+// updates to plain text.
+func sanitizeTextUpdates(updates map[string]interface{}, columns ...string) {
+	for _, column := range columns {
+		if value, ok := updates[column].(string); ok {
+			updates[column] = utils.SanitizeText(value)
+		}
+	}
+}
+
 func isValidStatusTransition(oldStatus, newStatus string) bool {
 	// Implement your status transition rules
 	return true
 }
 
-// PostFilter defines filtering options for listing posts
+// Values for PostFilter.Deleted
+const (
+	DeletedExclude = ""
+	DeletedInclude = "include"
+	DeletedOnly    = "only"
+)
+
+// PostFilter defines filtering, sorting and pagination options for listing posts
 type PostFilter struct {
 	Status     string
 	CategoryID uint
 	AuthorID   uint
+
+	// Posts tagged with any of the given tag IDs or slugs
+	TagIDs   []uint
+	TagSlugs []string
+
+	// Inclusive date ranges; nil bounds are open
+	PublishedFrom *time.Time
+	PublishedTo   *time.Time
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+
+	// Locales restricts posts to those written in these locales, showing
+	// each translation group once: in the first of the locales it has
+	Locales []string
+
+	// Deleted controls soft-deleted rows: DeletedExclude, DeletedInclude or
+	// DeletedOnly. Callers must restrict the latter two to administrators.
+	Deleted string
+
+	// Sort is one of created_at (default), published_at, view_count or title
+	Sort string
+	// Order is asc or desc; defaults to desc
+	Order string
+
+	Page     int
+	PageSize int
+	Cursor   string
+}
+
+// postSortColumns whitelists sortable fields and maps them to SQL. Drafts
+// have no published_at, so they sort by creation time instead of as NULLs,
+// which keeps keyset comparisons well defined.
+var postSortColumns = map[string]string{
+	"created_at":   "posts.created_at",
+	"published_at": "COALESCE(posts.published_at, posts.created_at)",
+	"view_count":   "posts.view_count",
+	"title":        "posts.title",
+}
+
+func resolvePostSort(field, order string) (string, string, bool, error) {
+	if field == "" {
+		field = "created_at"
+	}
+	expr, ok := postSortColumns[field]
+	if !ok {
+		return "", "", false, fmt.Errorf("%s: unsupported sort field %q", utils.ValidationFailedMsg, field)
+	}
+
+	switch strings.ToLower(order) {
+	case "", "desc":
+		return field, expr, true, nil
+	case "asc":
+		return field, expr, false, nil
+	default:
+		return "", "", false, fmt.Errorf("%s: order must be asc or desc", utils.ValidationFailedMsg)
+	}
+}
+
+func applyPostFilter(query *gorm.DB, filter PostFilter) *gorm.DB {
+	switch filter.Deleted {
+	case DeletedInclude:
+		query = query.Unscoped()
+	case DeletedOnly:
+		query = query.Unscoped().Where("posts.deleted_at IS NOT NULL")
+	}
+
+	if filter.Status != "" {
+		query = query.Where("posts.status = ?", filter.Status)
+	}
+	if filter.CategoryID != 0 {
+		query = query.Where("posts.category_id = ?", filter.CategoryID)
+	}
+	if len(filter.Locales) > 0 {
+		query = query.Where("posts.locale IN ?", filter.Locales)
+	}
+	if len(filter.Locales) > 1 {
+		// Leave out posts with a variant in a preferred locale; variants in
+		// other locales don't count
+		variant := `NOT EXISTS (SELECT 1 FROM posts AS variant
+			WHERE COALESCE(variant.source_id, variant.id) = COALESCE(posts.source_id, posts.id)
+			AND variant.deleted_at IS NULL
+			AND array_position(ARRAY[?]::text[], variant.locale) < array_position(ARRAY[?]::text[], posts.locale)`
+		args := []interface{}{filter.Locales, filter.Locales}
+		if filter.Status != "" {
+			variant += " AND variant.status = ?"
+			args = append(args, filter.Status)
+		}
+		query = query.Where(variant+")", args...)
+	}
+	if filter.AuthorID != 0 {
+		query = query.Where("posts.author_id = ?", filter.AuthorID)
+	}
+	if len(filter.TagIDs) > 0 {
+		query = query.Where("posts.id IN (SELECT post_id FROM post_tags WHERE tag_id IN ?)", filter.TagIDs)
+	}
+	if len(filter.TagSlugs) > 0 {
+		query = query.Where(`posts.id IN (
+			SELECT post_tags.post_id FROM post_tags
+			JOIN tags ON tags.id = post_tags.tag_id
+			WHERE tags.slug IN ? AND tags.deleted_at IS NULL)`, filter.TagSlugs)
+	}
+	if filter.PublishedFrom != nil {
+		query = query.Where("posts.published_at >= ?", *filter.PublishedFrom)
+	}
+	if filter.PublishedTo != nil {
+		query = query.Where("posts.published_at <= ?", *filter.PublishedTo)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("posts.created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("posts.created_at <= ?", *filter.CreatedTo)
+	}
+	return query
+}
+
+// postCursor is the keyset position encoded in PostFilter.Cursor: the sort
+// value and ID of the last post on the previous page.
+type postCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+func newPostCursor(sort string, desc bool, last *models.Post) (string, error) {
+	c := postCursor{Sort: sort, Desc: desc, ID: last.ID}
+	switch sort {
+	case "published_at":
+		at := last.CreatedAt
+		if last.PublishedAt != nil {
+			at = *last.PublishedAt
+		}
+		c.Value = at.Format(time.RFC3339Nano)
+	case "view_count":
+		c.Value = strconv.FormatUint(uint64(last.ViewCount), 10)
+	case "title":
+		c.Value = last.Title
+	default:
+		c.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	}
+	return utils.EncodeCursor(c)
+}
+
+func (c postCursor) sortValue() (interface{}, error) {
+	switch c.Sort {
+	case "view_count":
+		n, err := strconv.ParseUint(c.Value, 10, 64)
+		if err != nil {
+			return nil, utils.ErrInvalidCursor
+		}
+		return n, nil
+	case "title":
+		return c.Value, nil
+	default:
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, utils.ErrInvalidCursor
+		}
+		return t, nil
+	}
 }
\ No newline at end of file
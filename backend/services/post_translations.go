@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/tracing"
+	"github.com/sasanzare/go-cms/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Translation states
+const (
+	TranslationSource   = "source"
+	TranslationCurrent  = "current"
+	TranslationOutdated = "outdated"
+	TranslationMissing  = "missing"
+)
+
+// PostTranslation is one locale of a post's translation group. PostID is
+// nil for missing translations. A translation is outdated when the source
+// post's text changed after the revision it was translated from.
+type PostTranslation struct {
+	Locale         string `json:"locale"`
+	State          string `json:"state"`
+	PostID         *uint  `json:"post_id,omitempty"`
+	Title          string `json:"title,omitempty"`
+	Slug           string `json:"slug,omitempty"`
+	Status         string `json:"status,omitempty"`
+	SourceRevision uint   `json:"source_revision,omitempty"`
+	LatestRevision uint   `json:"latest_revision,omitempty"`
+}
+
+// latestRevision is the number of the newest revision of a post that
+// changed its text, or 0.
+func latestRevision(db *gorm.DB, postID uint) (uint, error) {
+	var number uint
+	err := db.Model(&models.PostRevision{}).Where("post_id = ? AND NOT status_only", postID).
+		Select("COALESCE(MAX(number), 0)").Scan(&number).Error
+	return number, err
+}
+
+// prepareTranslation checks that post can join the translation group of
+// the post its SourceID names, pointing SourceID at the group's original.
+// A translation starts up to date with the original's latest revision
+// unless it says otherwise.
+func (s *PostService) prepareTranslation(db *gorm.DB, post *models.Post) error {
+	var source models.Post
+	if err := db.First(&source, *post.SourceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New(utils.ValidationFailedMsg + ": source post not found")
+		}
+		return err
+	}
+	group := source.TranslationGroup()
+	post.SourceID = &group
+
+	var existing int64
+	err := db.Model(&models.Post{}).
+		Where("(id = ? OR source_id = ?) AND locale = ?", group, group, post.Locale).
+		Count(&existing).Error
+	if err != nil {
+		return err
+	}
+	if existing > 0 {
+		return fmt.Errorf("%s: post already has a %s translation", utils.ValidationFailedMsg, post.Locale)
+	}
+
+	latest, err := latestRevision(db, group)
+	if err != nil {
+		return err
+	}
+	if post.SourceRevision == 0 || post.SourceRevision > latest {
+		post.SourceRevision = latest
+	}
+	return nil
+}
+
+// checkSourceRevision checks that a translation can be marked up to date
+// with revision of its source.
+func checkSourceRevision(tx *gorm.DB, post *models.Post, revision uint) error {
+	if post.SourceID == nil {
+		return errors.New(utils.ValidationFailedMsg + ": source_revision only applies to translations")
+	}
+	latest, err := latestRevision(tx, *post.SourceID)
+	if err != nil {
+		return err
+	}
+	if revision > latest {
+		return fmt.Errorf("%s: source post has no revision %d", utils.ValidationFailedMsg, revision)
+	}
+	return nil
+}
+
+// CreateTranslation adds post as the translation of the post with
+// sourceID into post.Locale, which the group must not have yet.
+func (s *PostService) CreateTranslation(ctx context.Context, sourceID uint, post *models.Post) (err error) {
+	ctx, span := tracing.Start(ctx, "PostService.CreateTranslation", attribute.Int("post.id", int(sourceID)))
+	defer tracing.End(span, &err)
+
+	if post.Locale == "" {
+		return errors.New(utils.ValidationFailedMsg + ": locale is required")
+	}
+	var source models.Post
+	if err := s.db.WithContext(ctx).Select("id").First(&source, sourceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("post not found")
+		}
+		return err
+	}
+	post.SourceID = &sourceID
+	return s.CreatePost(ctx, post)
+}
+
+// ListTranslations returns the translation status of the group of the post
+// with id in every supported locale, the original's first.
+func (s *PostService) ListTranslations(ctx context.Context, id uint) (_ []PostTranslation, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.ListTranslations", attribute.Int("post.id", int(id)))
+	defer tracing.End(span, &err)
+
+	db := s.db.WithContext(ctx)
+	var post models.Post
+	if err := db.Select("id", "source_id").First(&post, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("post not found")
+		}
+		return nil, err
+	}
+	group := post.TranslationGroup()
+
+	var variants []models.Post
+	err = db.Select("id", "title", "slug", "status", "locale", "source_id", "source_revision").
+		Where("id = ? OR source_id = ?", group, group).Find(&variants).Error
+	if err != nil {
+		return nil, err
+	}
+	latest, err := latestRevision(db, group)
+	if err != nil {
+		return nil, err
+	}
+
+	byLocale := make(map[string]models.Post, len(variants))
+	translations := []PostTranslation{}
+	for _, v := range variants {
+		byLocale[v.Locale] = v
+		if v.SourceID == nil {
+			translations = append(translations, newPostTranslation(v, TranslationSource, latest))
+		}
+	}
+	for _, locale := range s.locales.Supported() {
+		v, ok := byLocale[locale]
+		switch {
+		case !ok:
+			translations = append(translations, PostTranslation{Locale: locale, State: TranslationMissing, LatestRevision: latest})
+		case v.SourceID == nil:
+		case v.SourceRevision < latest:
+			translations = append(translations, newPostTranslation(v, TranslationOutdated, latest))
+		default:
+			translations = append(translations, newPostTranslation(v, TranslationCurrent, latest))
+		}
+	}
+	return translations, nil
+}
+
+func newPostTranslation(post models.Post, state string, latest uint) PostTranslation {
+	id := post.ID
+	t := PostTranslation{
+		Locale:         post.Locale,
+		State:          state,
+		PostID:         &id,
+		Title:          post.Title,
+		Slug:           post.Slug,
+		Status:         post.Status,
+		LatestRevision: latest,
+	}
+	if state != TranslationSource {
+		t.SourceRevision = post.SourceRevision
+	}
+	return t
+}
+
+// TranslationTask is an original post whose translation into a locale is
+// missing or outdated. TranslationID is nil for missing translations.
+type TranslationTask struct {
+	PostID         uint   `json:"post_id"`
+	Title          string `json:"title"`
+	Locale         string `json:"locale"`
+	Status         string `json:"status"`
+	State          string `json:"state"`
+	TranslationID  *uint  `json:"translation_id,omitempty"`
+	SourceRevision uint   `json:"source_revision,omitempty"`
+	LatestRevision uint   `json:"latest_revision"`
+}
+
+// ListTranslationTasks returns a page of the original posts whose
+// translation into locale is missing or outdated, most recently updated
+// first. state restricts the page to TranslationMissing or
+// TranslationOutdated ones.
+func (s *PostService) ListTranslationTasks(ctx context.Context, locale, state string, page, pageSize int) (_ []TranslationTask, _ *utils.Pagination, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.ListTranslationTasks", attribute.String("locale", locale))
+	defer tracing.End(span, &err)
+
+	if !s.locales.IsSupported(locale) {
+		return nil, nil, errors.New(utils.ValidationFailedMsg + ": unsupported locale")
+	}
+	stateCond := "(translation.id IS NULL OR translation.source_revision < latest.number)"
+	switch state {
+	case "":
+	case TranslationMissing:
+		stateCond = "translation.id IS NULL"
+	case TranslationOutdated:
+		stateCond = "translation.source_revision < latest.number"
+	default:
+		return nil, nil, errors.New(utils.ValidationFailedMsg + ": state must be missing or outdated")
+	}
+	page, pageSize = utils.NormalizePage(page, pageSize)
+
+	from := `FROM posts
+		LEFT JOIN posts AS translation ON translation.source_id = posts.id
+			AND translation.locale = ? AND translation.deleted_at IS NULL
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(MAX(number), 0) AS number FROM post_revisions
+			WHERE post_id = posts.id AND NOT status_only
+		) AS latest
+		WHERE posts.source_id IS NULL AND posts.deleted_at IS NULL
+			AND posts.locale <> ? AND posts.status <> ? AND ` + stateCond
+	args := []interface{}{locale, locale, models.PostStatusRejected}
+
+	db := s.db.WithContext(ctx)
+	var total int64
+	if err := db.Raw("SELECT COUNT(*) "+from, args...).Scan(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var rows []struct {
+		ID             uint
+		Title          string
+		Locale         string
+		Status         string
+		TranslationID  *uint
+		SourceRevision *uint
+		LatestRevision uint
+	}
+	err = db.Raw(`SELECT posts.id, posts.title, posts.locale, posts.status,
+			translation.id AS translation_id, translation.source_revision,
+			latest.number AS latest_revision `+from+`
+		ORDER BY posts.updated_at DESC, posts.id DESC
+		LIMIT ? OFFSET ?`, append(args, pageSize, (page-1)*pageSize)...).Scan(&rows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tasks := make([]TranslationTask, len(rows))
+	for i, row := range rows {
+		tasks[i] = TranslationTask{
+			PostID:         row.ID,
+			Title:          row.Title,
+			Locale:         row.Locale,
+			Status:         row.Status,
+			State:          TranslationMissing,
+			TranslationID:  row.TranslationID,
+			LatestRevision: row.LatestRevision,
+		}
+		if row.TranslationID != nil {
+			tasks[i].State = TranslationOutdated
+			tasks[i].SourceRevision = *row.SourceRevision
+		}
+	}
+
+	return tasks, utils.NewPagination(page, pageSize, total), nil
+}
+
+// GetPostBySlug retrieves the post with slug, preferring the one written in
+// the earliest of locales since slugs are unique per locale. When the
+// post's translation group has a variant in an earlier locale, that
+// variant is returned instead. With publishedOnly, unpublished posts are
+// ignored.
+func (s *PostService) GetPostBySlug(ctx context.Context, slug string, locales []string, publishedOnly bool) (_ *models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.GetPostBySlug", attribute.String("post.slug", slug))
+	defer tracing.End(span, &err)
+
+	db := s.db.WithContext(ctx)
+	published := func(q *gorm.DB) *gorm.DB {
+		if publishedOnly {
+			return q.Where("status = ?", models.PostStatusPublished)
+		}
+		return q
+	}
+	rank := localeRank(locales)
+
+	var match models.Post
+	err = published(db.Select("id", "source_id", "locale").Where("slug = ?", slug)).Order(rank).Order("id").First(&match).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("post not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	group := match.TranslationGroup()
+	var best models.Post
+	err = published(db.Select("id", "locale").Where("(id = ? OR source_id = ?) AND locale IN ?", group, group, locales)).
+		Order(rank).Order("id").First(&best).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		best = match
+	case err != nil:
+		return nil, err
+	}
+	return s.GetPostByID(ctx, best.ID)
+}
+
+// localeRank orders rows by the position of their locale in locales;
+// locales outside the list rank last.
+func localeRank(locales []string) clause.OrderBy {
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL:  "COALESCE(array_position(ARRAY[?]::text[], locale::text), ?)",
+		Vars: []interface{}{locales, len(locales) + 1},
+	}}
+}
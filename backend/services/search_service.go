@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/sasanzare/go-cms/jobs"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/search"
+	"github.com/sasanzare/go-cms/tracing"
+	"github.com/sasanzare/go-cms/utils"
+	"gorm.io/gorm"
+)
+
+const reindexBatchSize = 200
+
+// SearchService runs ranked full-text searches over posts through a
+// pluggable search.Index.
+type SearchService struct {
+	db    *gorm.DB
+	index search.Index
+	queue *jobs.Queue
+}
+
+func NewSearchService(db *gorm.DB, index search.Index) *SearchService {
+	return &SearchService{db: db, index: index}
+}
+
+// SearchQuery holds the search text and the filters combined with it
+type SearchQuery struct {
+	Query string
+	// Language restricts results to posts indexed with this configuration
+	// and parses the query with it. Empty searches all languages.
+	Language string
+	// Prefix matches the query words as prefixes ("post" finds "postgres").
+	// When false the query uses web search syntax: "quoted phrases", or, -not.
+	Prefix bool
+	// Fuzzy retries with typo-tolerant matching when nothing matches exactly.
+	Fuzzy bool
+	// Locales restricts results to posts written in these locales
+	Locales []string
+
+	Status     string
+	CategoryID uint
+	TagSlugs   []string
+
+	Page     int
+	PageSize int
+}
+
+// SearchResult is a matching post with its rank and highlighted snippets.
+// Highlights are HTML-escaped text with matches wrapped in <mark> tags.
+type SearchResult struct {
+	Post           models.Post `json:"post"`
+	Rank           float64     `json:"rank"`
+	TitleHighlight string      `json:"title_highlight"`
+	Snippet        string      `json:"snippet"`
+}
+
+// SearchResponse is a page of results with facet counts over all matches.
+type SearchResponse struct {
+	Results []SearchResult `json:"results"`
+	Facets  search.Facets  `json:"facets"`
+	// Fuzzy is true when the results come from typo-tolerant matching
+	Fuzzy bool `json:"fuzzy"`
+}
+
+// Search returns posts matching q ordered by relevance. Title matches weigh
+// more than excerpt matches, which weigh more than content matches.
+func (s *SearchService) Search(ctx context.Context, q SearchQuery) (_ *SearchResponse, _ *utils.Pagination, err error) {
+	ctx, span := tracing.Start(ctx, "SearchService.Search")
+	defer tracing.End(span, &err)
+
+	if q.Language != "" && !search.IsValidLanguage(q.Language) {
+		return nil, nil, errors.New(utils.ValidationFailedMsg + ": unsupported search language")
+	}
+	if len(search.Tokenize(q.Query)) == 0 {
+		return nil, nil, errors.New(utils.ValidationFailedMsg + ": search query is empty")
+	}
+	page, pageSize := utils.NormalizePage(q.Page, q.PageSize)
+
+	result, err := s.index.Search(ctx, search.Query{
+		Text:       q.Query,
+		Language:   q.Language,
+		Prefix:     q.Prefix,
+		Fuzzy:      q.Fuzzy,
+		Locales:    q.Locales,
+		Status:     q.Status,
+		CategoryID: q.CategoryID,
+		TagSlugs:   q.TagSlugs,
+		Offset:     (page - 1) * pageSize,
+		Limit:      pageSize,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pagination := utils.NewPagination(page, pageSize, result.Total)
+
+	response := &SearchResponse{Results: []SearchResult{}, Facets: result.Facets, Fuzzy: result.Fuzzy}
+	if len(result.Hits) == 0 {
+		return response, pagination, nil
+	}
+
+	ids := make([]uint, len(result.Hits))
+	for i, hit := range result.Hits {
+		ids[i] = hit.ID
+	}
+	var posts []models.Post
+	if err := s.db.WithContext(ctx).Preload("Author").Preload("Category").Where("id IN ?", ids).Find(&posts).Error; err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[uint]models.Post, len(posts))
+	for _, p := range posts {
+		byID[p.ID] = p
+	}
+
+	for _, hit := range result.Hits {
+		post, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		response.Results = append(response.Results, SearchResult{
+			Post:           post,
+			Rank:           hit.Score,
+			TitleHighlight: hit.TitleHighlight,
+			Snippet:        hit.Snippet,
+		})
+	}
+	return response, pagination, nil
+}
+
+// ScheduleReindex queues a rebuild of the search index. If one is already
+// queued it returns jobs.ErrDuplicate; that rebuild will cover this request.
+func (s *SearchService) ScheduleReindex(ctx context.Context) (*models.Job, error) {
+	if s.queue == nil {
+		return nil, errors.New("job queue is not configured")
+	}
+	return s.queue.Enqueue(ctx, ReindexArgs{}, jobs.OnQueue(SearchQueue), jobs.Unique(ReindexJobKey))
+}
+
+// Reindex rebuilds the search index from every non-deleted post.
+//
+// Returns:
+//   - int: number of posts indexed
+//   - error: database or index error if any
+func (s *SearchService) Reindex(ctx context.Context) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "SearchService.Reindex")
+	defer tracing.End(span, &err)
+
+	if err := s.index.Reset(ctx); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	var posts []models.Post
+	result := s.db.WithContext(ctx).Preload("Tags").FindInBatches(&posts, reindexBatchSize, func(tx *gorm.DB, batch int) error {
+		for i := range posts {
+			if err := s.index.Index(ctx, search.DocumentFromPost(&posts[i])); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return count, result.Error
+	}
+
+	slog.InfoContext(ctx, "search index rebuilt", slog.Int("posts", count))
+	return count, nil
+}
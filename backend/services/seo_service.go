@@ -0,0 +1,200 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/sasanzare/go-cms/i18n"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/seo"
+	"github.com/sasanzare/go-cms/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// maxCategoryDepth bounds walks up the category tree, in case a parent
+// loop slipped in.
+const maxCategoryDepth = 16
+
+// SEOService computes the metadata of posts and categories for search
+// engines and social networks. Meta titles and descriptions fall back to
+// titles and excerpts, and breadcrumbs follow the category tree.
+type SEOService struct {
+	db       *gorm.DB
+	siteName string
+	siteURL  string
+	logoURL  string
+	locales  *i18n.Locales
+}
+
+func NewSEOService(db *gorm.DB, siteName, siteURL, logoURL string, locales *i18n.Locales) *SEOService {
+	siteURL = strings.TrimRight(siteURL, "/")
+	return &SEOService{
+		db:       db,
+		siteName: siteName,
+		siteURL:  siteURL,
+		logoURL:  absoluteURL(siteURL, logoURL),
+		locales:  locales,
+	}
+}
+
+// PostMeta returns the metadata of post, loaded with its author, category
+// and tags. Posts that aren't published are never indexed.
+func (s *SEOService) PostMeta(ctx context.Context, post *models.Post) (_ *seo.Meta, err error) {
+	ctx, span := tracing.Start(ctx, "SEOService.PostMeta", attribute.Int("post.id", int(post.ID)))
+	defer tracing.End(span, &err)
+	db := s.db.WithContext(ctx)
+
+	page := s.page(seo.TypeArticle, post.Locale, postLink(post.ID, 0))
+	page.Title = cmp.Or(post.MetaTitle, post.Title)
+	page.Description = cmp.Or(post.MetaDescription, post.Excerpt)
+	page.Image = absoluteURL(s.siteURL, post.FeaturedImage)
+	page.Robots = post.Robots
+	if !post.IsPublished() {
+		page.Robots = "noindex,nofollow"
+	}
+	page.Author = authorName(post.Author)
+	page.Published = post.PublishedAt
+	page.Modified = &post.UpdatedAt
+	for _, tag := range post.Tags {
+		page.Tags = append(page.Tags, tag.Name)
+	}
+
+	if post.CategoryID != nil {
+		trail, err := s.categoryTrail(db, *post.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		if len(trail) > 0 {
+			page.Section = trail[len(trail)-1].Name
+		}
+		page.Breadcrumbs = append(page.Breadcrumbs, trail...)
+	}
+	page.Breadcrumbs = append(page.Breadcrumbs, seo.Crumb{Name: post.Title, URL: page.URL})
+
+	if post.IsPublished() {
+		if page.Alternates, err = s.alternates(db, "posts", models.PostStatusPublished, translationGroup(post.ID, post.SourceID)); err != nil {
+			return nil, err
+		}
+	}
+	return seo.Build(page), nil
+}
+
+// CategoryMeta returns the metadata of the published category id.
+func (s *SEOService) CategoryMeta(ctx context.Context, id uint) (_ *seo.Meta, err error) {
+	ctx, span := tracing.Start(ctx, "SEOService.CategoryMeta", attribute.Int("category.id", int(id)))
+	defer tracing.End(span, &err)
+	db := s.db.WithContext(ctx)
+
+	var category models.Category
+	if err := db.First(&category, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("category not found")
+		}
+		return nil, err
+	}
+	if !category.IsPublished() {
+		return nil, errors.New("category not found")
+	}
+
+	page := s.page(seo.TypeWebsite, category.Locale, categoryLink(category.Slug, category.Locale))
+	page.Title = cmp.Or(category.MetaTitle, category.Name)
+	page.Description = cmp.Or(category.MetaDescription, category.Description)
+	page.Image = absoluteURL(s.siteURL, category.FeaturedImage)
+	if page.Breadcrumbs, err = s.categoryTrail(db, category.ID); err != nil {
+		return nil, err
+	}
+	page.Alternates, err = s.alternates(db, "categories", models.CategoryStatusPublished, translationGroup(category.ID, category.SourceID))
+	if err != nil {
+		return nil, err
+	}
+	return seo.Build(page), nil
+}
+
+func (s *SEOService) page(typ, locale, path string) seo.Page {
+	return seo.Page{
+		Type:        typ,
+		URL:         s.siteURL + path,
+		Locale:      locale,
+		SiteName:    s.siteName,
+		SiteURL:     s.siteURL,
+		LogoURL:     s.logoURL,
+		Breadcrumbs: []seo.Crumb{{Name: s.siteName, URL: s.siteURL + "/"}},
+	}
+}
+
+// categoryTrail returns the breadcrumbs of category id and its ancestors,
+// root first.
+func (s *SEOService) categoryTrail(db *gorm.DB, id uint) ([]seo.Crumb, error) {
+	var trail []seo.Crumb
+	seen := map[uint]bool{}
+	for next := &id; next != nil && !seen[*next] && len(seen) < maxCategoryDepth; {
+		seen[*next] = true
+		var category models.Category
+		err := db.Select("id", "name", "slug", "locale", "parent_id").First(&category, *next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		trail = append([]seo.Crumb{{Name: category.Name, URL: s.siteURL + categoryLink(category.Slug, category.Locale)}}, trail...)
+		next = category.ParentID
+	}
+	return trail, nil
+}
+
+// alternates returns the hreflang alternates of a translation group
+// published in more than one locale. The variant in the default locale is
+// also the x-default.
+func (s *SEOService) alternates(db *gorm.DB, table, status string, group uint) ([]seo.Alternate, error) {
+	var variants []struct {
+		ID     uint
+		Slug   string
+		Locale string
+	}
+	err := db.Table(table).
+		Select("id", "slug", "locale").
+		Where("status = ? AND deleted_at IS NULL AND COALESCE(source_id, id) = ?", status, group).
+		Order("id").
+		Scan(&variants).Error
+	if err != nil || len(variants) < 2 {
+		return nil, err
+	}
+
+	var alternates []seo.Alternate
+	for _, v := range variants {
+		href := s.siteURL + postLink(v.ID, 0)
+		if table == "categories" {
+			href = s.siteURL + categoryLink(v.Slug, v.Locale)
+		}
+		alternates = append(alternates, seo.Alternate{Lang: v.Locale, Href: href})
+		if v.Locale == s.locales.Default() {
+			alternates = append(alternates, seo.Alternate{Lang: "x-default", Href: href})
+		}
+	}
+	return alternates, nil
+}
+
+// categoryLink is the public path of a category. Slugs are only unique
+// per locale, so the path names the category's locale too.
+func categoryLink(slug, locale string) string {
+	return "/categories/" + url.PathEscape(slug) + "?lang=" + url.QueryEscape(locale)
+}
+
+// tagLink is the public path of a tag, which like categories names its
+// locale.
+func tagLink(slug, locale string) string {
+	return "/tags/" + url.PathEscape(slug) + "?lang=" + url.QueryEscape(locale)
+}
+
+// translationGroup is the ID shared by a page and its translations.
+func translationGroup(id uint, sourceID *uint) uint {
+	if sourceID != nil {
+		return *sourceID
+	}
+	return id
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"cmp"
+	"strings"
+	"time"
+
+	"github.com/sasanzare/go-cms/events"
+	"github.com/sasanzare/go-cms/i18n"
+	"github.com/sasanzare/go-cms/jobs"
+	"github.com/sasanzare/go-cms/mail"
+	"github.com/sasanzare/go-cms/search"
+	"gorm.io/gorm"
+)
+
+// Services bundles the application's service instances so that routes,
+// background workers and commands share the same dependencies.
+type Services struct {
+	DB       *gorm.DB
+	Auth     *AuthService
+	Posts    *PostService
+	Comments *CommentService
+	Media    *MediaService
+	Content  *ContentService
+	// Taxonomy manages categories and tags and their translations
+	Taxonomy *TaxonomyService
+	Search   *SearchService
+	Email    *EmailService
+	Jobs     *jobs.Queue
+	Spam     *SpamService
+	// Events carries domain events such as post approvals between services
+	Events        *events.Bus
+	Notifications *NotificationService
+	Webhooks      *WebhookService
+	Feeds         *FeedService
+	Sitemaps      *SitemapService
+	SEO           *SEOService
+	// Locales are those content is written and served in
+	Locales *i18n.Locales
+}
+
+// Options holds the dependencies services share.
+type Options struct {
+	// Index is kept in sync with posts and queried by searches
+	Index search.Index
+	// Queue runs background work such as email delivery
+	Queue *jobs.Queue
+	// Mailer delivers email from MailFrom; Mail renders templated emails in
+	// DefaultLocale unless told otherwise
+	Mailer        mail.Mailer
+	MailFrom      string
+	Mail          *mail.Renderer
+	DefaultLocale string
+	// Locales are those content is written and served in; nil supports
+	// DefaultLocale only
+	Locales *i18n.Locales
+	// Location is the time zone dates are shown and archived in; nil uses
+	// UTC
+	Location *time.Location
+	// SiteName and SiteURL, the public base URL, brand feeds and the links
+	// sent to users
+	SiteName string
+	SiteURL  string
+	// SiteLogoURL is the publisher logo in posts' structured data
+	SiteLogoURL string
+	// FeedItems is the number of posts per feed, 20 when zero;
+	// FeedExcerptsOnly leaves post content out of feeds by default
+	FeedItems        int
+	FeedExcerptsOnly bool
+	// DigestHour is the local hour daily notification digests are sent at;
+	// negative disables digests
+	DigestHour int
+	// WebhookURLs receive domain events, signed with WebhookSecret when
+	// set; deliveries time out after WebhookTimeout
+	WebhookURLs    []string
+	WebhookSecret  string
+	WebhookTimeout time.Duration
+	// Spam screens comments and registrations; nil lets everything through
+	Spam *SpamService
+	// PostOptions configure the PostService further
+	PostOptions []PostServiceOption
+}
+
+// New wires every service against db.
+func New(db *gorm.DB, opts Options) *Services {
+	bus := events.NewBus()
+	locales := opts.Locales
+	if locales == nil {
+		locales = i18n.Single(cmp.Or(opts.DefaultLocale, "en"))
+	}
+	location := opts.Location
+	if location == nil {
+		location = time.UTC
+	}
+	email := NewEmailService(opts.Mailer, opts.MailFrom, opts.Mail, opts.DefaultLocale)
+	s := &Services{
+		DB:            db,
+		Auth:          NewAuthService(db, WithEmailVerification(email, strings.TrimRight(opts.SiteURL, "/")+"/api/auth/verify-email"), WithSpamChecks(opts.Spam)),
+		Posts:         NewPostService(db, append([]PostServiceOption{WithSearchIndex(opts.Index), WithEvents(bus), WithLocales(locales), WithLocation(location)}, opts.PostOptions...)...),
+		Comments:      NewCommentService(db, bus),
+		Media:         NewMediaService(db),
+		Content:       NewContentService(db),
+		Taxonomy:      NewTaxonomyService(db, locales),
+		Search:        NewSearchService(db, opts.Index),
+		Email:         email,
+		Jobs:          opts.Queue,
+		Spam:          opts.Spam,
+		Events:        bus,
+		Notifications: NewNotificationService(db, email, opts.SiteURL),
+		Webhooks:      NewWebhookService(opts.WebhookURLs, opts.WebhookSecret, opts.WebhookTimeout),
+		Locales:       locales,
+	}
+	s.Feeds = NewFeedService(db, s.Posts, opts.SiteName, opts.SiteURL)
+	s.Sitemaps = NewSitemapService(db, opts.SiteURL, locales)
+	s.SEO = NewSEOService(db, opts.SiteName, opts.SiteURL, opts.SiteLogoURL, locales)
+	s.Email.queue = opts.Queue
+	s.Search.queue = opts.Queue
+	s.Notifications.queue = opts.Queue
+	s.Webhooks.queue = opts.Queue
+	s.Comments.spam = opts.Spam
+	s.Notifications.digestHour = opts.DigestHour
+	if opts.FeedItems > 0 {
+		s.Feeds.items = opts.FeedItems
+	}
+	s.Feeds.excerptsOnly = opts.FeedExcerptsOnly
+	s.Notifications.Subscribe(bus)
+	s.Webhooks.Subscribe(bus)
+	if opts.Queue != nil {
+		registerJobHandlers(opts.Queue, s)
+	}
+	return s
+}
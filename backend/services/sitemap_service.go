@@ -0,0 +1,326 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sasanzare/go-cms/i18n"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/sitemap"
+	"github.com/sasanzare/go-cms/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// SitemapDocument is a generated sitemap or sitemap index. Modified is
+// when its newest page changed.
+type SitemapDocument struct {
+	Body     []byte
+	ETag     string
+	Modified time.Time
+}
+
+// sitemapSection is a kind of page listed in its own sitemaps.
+type sitemapSection struct {
+	name   string
+	table  string
+	status string
+	// listed selects the pages the sitemaps list, given status
+	listed string
+	path   func(page sitemapPage) string
+}
+
+var sitemapSections = []sitemapSection{
+	{
+		name:   "posts",
+		table:  "posts",
+		status: models.PostStatusPublished,
+		listed: "status = ? AND deleted_at IS NULL AND robots NOT LIKE '%noindex%'",
+		path:   func(page sitemapPage) string { return postLink(page.ID, 0) },
+	},
+	{
+		name:   "categories",
+		table:  "categories",
+		status: models.CategoryStatusPublished,
+		listed: "status = ? AND deleted_at IS NULL",
+		path:   func(page sitemapPage) string { return categoryLink(page.Slug, page.Locale) },
+	},
+}
+
+// sectionState identifies the published pages of a section: adding,
+// changing, unpublishing or deleting one changes it.
+type sectionState struct {
+	Count   int64
+	Updated *time.Time
+}
+
+type cachedSitemap struct {
+	state []sectionState
+	doc   *SitemapDocument
+}
+
+// SitemapService generates the sitemap index at /sitemap.xml and the
+// sitemaps it lists, one series of shards per section. Documents are
+// generated when asked for and cached until a page of their sections
+// changes.
+type SitemapService struct {
+	db        *gorm.DB
+	siteURL   string
+	locales   *i18n.Locales
+	shardSize int
+
+	mu    sync.Mutex
+	cache map[string]cachedSitemap
+}
+
+func NewSitemapService(db *gorm.DB, siteURL string, locales *i18n.Locales) *SitemapService {
+	return &SitemapService{
+		db:        db,
+		siteURL:   strings.TrimRight(siteURL, "/"),
+		locales:   locales,
+		shardSize: sitemap.MaxURLs,
+		cache:     map[string]cachedSitemap{},
+	}
+}
+
+// SitemapPath is the path of shard n, counted from 1, of a section's
+// sitemaps.
+func SitemapPath(section string, n int) string {
+	return fmt.Sprintf("/sitemaps/%s-%d.xml", section, n)
+}
+
+// Index returns the sitemap index listing every shard of every section.
+func (s *SitemapService) Index(ctx context.Context) (_ *SitemapDocument, err error) {
+	ctx, span := tracing.Start(ctx, "SitemapService.Index")
+	defer tracing.End(span, &err)
+
+	return s.cached(ctx, "/sitemap.xml", sitemapSections, func(db *gorm.DB) (*SitemapDocument, error) {
+		var (
+			entries  []sitemap.Sitemap
+			modified time.Time
+		)
+		for _, section := range sitemapSections {
+			var shards []struct {
+				Shard   int
+				Updated time.Time
+			}
+			err := db.Raw(`SELECT shard, MAX(updated_at) AS updated FROM (
+					SELECT (ROW_NUMBER() OVER (ORDER BY id) - 1) / ? AS shard, updated_at
+					FROM `+section.table+` WHERE `+section.listed+`
+				) AS pages GROUP BY shard ORDER BY shard`, s.shardSize, section.status).
+				Scan(&shards).Error
+			if err != nil {
+				return nil, err
+			}
+			for _, shard := range shards {
+				entries = append(entries, sitemap.Sitemap{
+					Loc:     s.siteURL + SitemapPath(section.name, shard.Shard+1),
+					LastMod: shard.Updated,
+				})
+				if shard.Updated.After(modified) {
+					modified = shard.Updated
+				}
+			}
+		}
+		if len(entries) > sitemap.MaxURLs {
+			entries = entries[:sitemap.MaxURLs]
+		}
+
+		var body bytes.Buffer
+		if err := sitemap.WriteIndex(&body, entries); err != nil {
+			return nil, err
+		}
+		return newSitemapDocument(body.Bytes(), modified), nil
+	})
+}
+
+// Shard returns shard n, counted from 1, of the sitemaps of section.
+func (s *SitemapService) Shard(ctx context.Context, section string, n int) (_ *SitemapDocument, err error) {
+	ctx, span := tracing.Start(ctx, "SitemapService.Shard",
+		attribute.String("sitemap.section", section), attribute.Int("sitemap.shard", n))
+	defer tracing.End(span, &err)
+
+	var sec *sitemapSection
+	for i := range sitemapSections {
+		if sitemapSections[i].name == section {
+			sec = &sitemapSections[i]
+		}
+	}
+	if sec == nil || n < 1 {
+		return nil, errors.New("sitemap not found")
+	}
+
+	return s.cached(ctx, SitemapPath(section, n), []sitemapSection{*sec}, func(db *gorm.DB) (*SitemapDocument, error) {
+		var pages []sitemapPage
+		err := db.Table(sec.table).
+			Select("id", "slug", "locale", "source_id", "updated_at", "featured_image").
+			Where(sec.listed, sec.status).
+			Order("id").Limit(s.shardSize).Offset((n - 1) * s.shardSize).
+			Scan(&pages).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(pages) == 0 {
+			return nil, errors.New("sitemap not found")
+		}
+
+		alternates, err := s.alternates(db, *sec, pages)
+		if err != nil {
+			return nil, err
+		}
+		var modified time.Time
+		urls := make([]sitemap.URL, len(pages))
+		for i, page := range pages {
+			urls[i] = sitemap.URL{
+				Loc:        s.siteURL + sec.path(page),
+				LastMod:    page.UpdatedAt,
+				Alternates: alternates[page.group()],
+			}
+			if image := absoluteURL(s.siteURL, page.FeaturedImage); image != "" {
+				urls[i].Images = []string{image}
+			}
+			if page.UpdatedAt.After(modified) {
+				modified = page.UpdatedAt
+			}
+		}
+
+		var body bytes.Buffer
+		if err := sitemap.WriteURLSet(&body, urls); err != nil {
+			return nil, err
+		}
+		return newSitemapDocument(body.Bytes(), modified), nil
+	})
+}
+
+// sitemapPage is a post or category listed in a sitemap.
+type sitemapPage struct {
+	ID            uint
+	Slug          string
+	Locale        string
+	SourceID      *uint
+	UpdatedAt     time.Time
+	FeaturedImage string
+}
+
+func (p sitemapPage) group() uint {
+	if p.SourceID != nil {
+		return *p.SourceID
+	}
+	return p.ID
+}
+
+// alternates returns the hreflang alternates of the translation groups of
+// pages that are published in more than one locale, by group. The variant
+// in the default locale is also the x-default.
+func (s *SitemapService) alternates(db *gorm.DB, section sitemapSection, pages []sitemapPage) (map[uint][]sitemap.Alternate, error) {
+	groups := make([]uint, 0, len(pages))
+	for _, page := range pages {
+		groups = append(groups, page.group())
+	}
+	var variants []sitemapPage
+	err := db.Table(section.table).
+		Select("id", "slug", "locale", "source_id").
+		Where(section.listed, section.status).Where("COALESCE(source_id, id) IN ?", groups).
+		Order("id").
+		Scan(&variants).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byGroup := map[uint][]sitemapPage{}
+	for _, v := range variants {
+		byGroup[v.group()] = append(byGroup[v.group()], v)
+	}
+	alternates := map[uint][]sitemap.Alternate{}
+	for group, vs := range byGroup {
+		if len(vs) < 2 {
+			continue
+		}
+		for _, v := range vs {
+			href := s.siteURL + section.path(v)
+			alternates[group] = append(alternates[group], sitemap.Alternate{Lang: v.Locale, Href: href})
+			if v.Locale == s.locales.Default() {
+				alternates[group] = append(alternates[group], sitemap.Alternate{Lang: "x-default", Href: href})
+			}
+		}
+	}
+	return alternates, nil
+}
+
+// absoluteURL resolves a link stored relative to siteURL, leaving out
+// those that aren't http(s).
+func absoluteURL(siteURL, link string) string {
+	if link == "" {
+		return ""
+	}
+	base, err := url.Parse(siteURL + "/")
+	if err != nil {
+		return ""
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	resolved := base.ResolveReference(ref)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return ""
+	}
+	return resolved.String()
+}
+
+// cached returns the document at path, generating it when its sections
+// changed since it was cached.
+func (s *SitemapService) cached(ctx context.Context, path string, sections []sitemapSection, generate func(*gorm.DB) (*SitemapDocument, error)) (*SitemapDocument, error) {
+	db := s.db.WithContext(ctx)
+	state := make([]sectionState, len(sections))
+	for i, section := range sections {
+		err := db.Table(section.table).
+			Select("COUNT(*) AS count, MAX(updated_at) AS updated").
+			Where(section.listed, section.status).
+			Scan(&state[i]).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	entry, ok := s.cache[path]
+	s.mu.Unlock()
+	if ok && sameSectionStates(entry.state, state) {
+		return entry.doc, nil
+	}
+
+	doc, err := generate(db)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.cache[path] = cachedSitemap{state: state, doc: doc}
+	s.mu.Unlock()
+	return doc, nil
+}
+
+func sameSectionStates(a, b []sectionState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Count != b[i].Count || (a[i].Updated == nil) != (b[i].Updated == nil) ||
+			(a[i].Updated != nil && !a[i].Updated.Equal(*b[i].Updated)) {
+			return false
+		}
+	}
+	return true
+}
+
+func newSitemapDocument(body []byte, modified time.Time) *SitemapDocument {
+	sum := sha256.Sum256(body)
+	return &SitemapDocument{Body: body, ETag: fmt.Sprintf(`"sitemap-%x"`, sum[:16]), Modified: modified}
+}
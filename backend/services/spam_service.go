@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sasanzare/go-cms/metrics"
+	"github.com/sasanzare/go-cms/spam"
+)
+
+// SpamService screens comments and registrations and trains the spam
+// classifier from moderation decisions. A nil *SpamService lets
+// everything through.
+type SpamService struct {
+	pipeline   *spam.Pipeline
+	classifier *spam.Classifier
+	tokens     *spam.FormTokens
+}
+
+// NewSpamService scores submissions with pipeline, trains classifier and
+// issues form tokens for the submission speed check. classifier and tokens
+// may be nil when the pipeline doesn't use them.
+func NewSpamService(pipeline *spam.Pipeline, classifier *spam.Classifier, tokens *spam.FormTokens) *SpamService {
+	return &SpamService{pipeline: pipeline, classifier: classifier, tokens: tokens}
+}
+
+// Check scores a submission.
+func (s *SpamService) Check(ctx context.Context, sub *spam.Submission) spam.Verdict {
+	if s == nil {
+		return spam.Verdict{Results: []spam.RuleResult{}}
+	}
+
+	verdict := s.pipeline.Check(ctx, sub)
+	result := "ham"
+	if verdict.Spam {
+		result = "spam"
+		slog.InfoContext(ctx, "submission flagged as spam",
+			slog.String("kind", sub.Kind), slog.String("ip", sub.IP), slog.Float64("score", verdict.Score))
+	}
+	metrics.SpamChecks.WithLabelValues(sub.Kind, result).Inc()
+	return verdict
+}
+
+// IssueFormToken returns a token for a form shown now; empty when speed
+// checks are disabled.
+func (s *SpamService) IssueFormToken() string {
+	if s == nil || s.tokens == nil {
+		return ""
+	}
+	return s.tokens.Issue()
+}
+
+// Relabel moves text from one training label to another: from is undone
+// and to learned. Either may be empty for no label.
+func (s *SpamService) Relabel(ctx context.Context, text, from, to string) error {
+	if s == nil || s.classifier == nil || from == to {
+		return nil
+	}
+	if from != "" {
+		if err := s.classifier.Unlearn(ctx, text, from == spam.LabelSpam); err != nil {
+			return err
+		}
+	}
+	if to != "" {
+		return s.classifier.Learn(ctx, text, to == spam.LabelSpam)
+	}
+	return nil
+}
@@ -0,0 +1,580 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sasanzare/go-cms/i18n"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/tracing"
+	"github.com/sasanzare/go-cms/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// TaxonomyService manages categories and tags. Like posts, a category or
+// tag and its translations form a translation group named after the
+// original, with at most one variant per locale. Categories and tags have
+// no revisions, so a translation is outdated when its original was updated
+// after it.
+type TaxonomyService struct {
+	db      *gorm.DB
+	locales *i18n.Locales
+}
+
+func NewTaxonomyService(db *gorm.DB, locales *i18n.Locales) *TaxonomyService {
+	return &TaxonomyService{db: db, locales: locales}
+}
+
+// Locales returns the locales categories and tags are written in.
+func (s *TaxonomyService) Locales() *i18n.Locales {
+	return s.locales
+}
+
+// taxonomy describes the table of categories or tags to the queries they
+// share. Readers only see rows in the visible status.
+type taxonomy struct {
+	table   string
+	name    string
+	visible string
+}
+
+var (
+	categoryTaxonomy = taxonomy{table: "categories", name: "category", visible: models.CategoryStatusPublished}
+	tagTaxonomy      = taxonomy{table: "tags", name: "tag", visible: models.TagStatusActive}
+)
+
+// TermTranslation is one locale of a category's or tag's translation
+// group. ID is nil for missing translations.
+type TermTranslation struct {
+	Locale    string     `json:"locale"`
+	State     string     `json:"state"`
+	ID        *uint      `json:"id,omitempty"`
+	Name      string     `json:"name,omitempty"`
+	Slug      string     `json:"slug,omitempty"`
+	Status    string     `json:"status,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// TermTranslationTask is an original category or tag whose translation
+// into a locale is missing or outdated. TranslationID is nil for missing
+// translations.
+type TermTranslationTask struct {
+	ID            uint   `json:"id"`
+	Name          string `json:"name"`
+	Locale        string `json:"locale"`
+	Status        string `json:"status"`
+	State         string `json:"state"`
+	TranslationID *uint  `json:"translation_id,omitempty"`
+}
+
+// termRow holds the columns categories and tags share.
+type termRow struct {
+	ID        uint
+	SourceID  *uint
+	Name      string
+	Slug      string
+	Status    string
+	Locale    string
+	UpdatedAt time.Time
+}
+
+// CreateCategory adds a category in category.Locale, the default locale
+// when empty. With SourceID set, it joins that category's translation
+// group.
+func (s *TaxonomyService) CreateCategory(ctx context.Context, category *models.Category) (err error) {
+	ctx, span := tracing.Start(ctx, "TaxonomyService.CreateCategory")
+	defer tracing.End(span, &err)
+
+	category.Name = utils.SanitizeText(category.Name)
+	category.Description = utils.SanitizeText(category.Description)
+	category.MetaTitle = utils.SanitizeText(category.MetaTitle)
+	category.MetaDescription = utils.SanitizeText(category.MetaDescription)
+	if category.Name == "" {
+		return errors.New(utils.ValidationFailedMsg + ": name is required")
+	}
+	if category.Slug == "" {
+		category.Slug = generateSlug(category.Name)
+	}
+	db := s.db.WithContext(ctx)
+	if err := s.prepareTerm(db, categoryTaxonomy, &category.Locale, &category.SourceID); err != nil {
+		return err
+	}
+	if err := checkTermUnique(db, categoryTaxonomy, 0, category.Locale, "slug", category.Slug); err != nil {
+		return err
+	}
+	if category.Status == models.CategoryStatusPublished && category.PublishedAt == nil {
+		now := time.Now()
+		category.PublishedAt = &now
+	}
+
+	if err := db.Create(category).Error; err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "category created", slog.Uint64("category_id", uint64(category.ID)), slog.String("locale", category.Locale))
+	return nil
+}
+
+// CreateCategoryTranslation adds category as the translation of the
+// category with sourceID into category.Locale, which the group must not
+// have yet.
+func (s *TaxonomyService) CreateCategoryTranslation(ctx context.Context, sourceID uint, category *models.Category) (err error) {
+	ctx, span := tracing.Start(ctx, "TaxonomyService.CreateCategoryTranslation", attribute.Int("category.id", int(sourceID)))
+	defer tracing.End(span, &err)
+
+	if category.Locale == "" {
+		return errors.New(utils.ValidationFailedMsg + ": locale is required")
+	}
+	category.SourceID = &sourceID
+	return s.CreateCategory(ctx, category)
+}
+
+// UpdateCategory applies updates, keyed by column, to the category with
+// id. Publishing a category stamps its publication date.
+func (s *TaxonomyService) UpdateCategory(ctx context.Context, id uint, updates map[string]interface{}) (_ *models.Category, err error) {
+	ctx, span := tracing.Start(ctx, "TaxonomyService.UpdateCategory", attribute.Int("category.id", int(id)))
+	defer tracing.End(span, &err)
+
+	db := s.db.WithContext(ctx)
+	var category models.Category
+	if err := db.First(&category, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("category not found")
+		}
+		return nil, err
+	}
+	sanitizeTextUpdates(updates, "name", "description", "meta_title", "meta_description")
+	if updates["name"] == "" {
+		return nil, errors.New(utils.ValidationFailedMsg + ": name is required")
+	}
+	if slug, ok := updates["slug"].(string); ok {
+		if err := checkTermUnique(db, categoryTaxonomy, id, category.Locale, "slug", slug); err != nil {
+			return nil, err
+		}
+	}
+	if parentID, ok := updates["parent_id"].(uint); ok && parentID == id {
+		return nil, errors.New(utils.ValidationFailedMsg + ": a category can't be its own parent")
+	}
+	if updates["status"] == models.CategoryStatusPublished && category.PublishedAt == nil {
+		updates["published_at"] = time.Now()
+	}
+
+	if err := db.Model(&category).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	if err := db.First(&category, id).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// ListCategories returns the categories shown in locales, by name: each
+// translation group once, in the earliest of locales it has a variant in.
+// Without locales every category is listed; with publishedOnly only
+// published ones are.
+func (s *TaxonomyService) ListCategories(ctx context.Context, locales []string, publishedOnly bool) (_ []models.Category, err error) {
+	ctx, span := tracing.Start(ctx, "TaxonomyService.ListCategories")
+	defer tracing.End(span, &err)
+
+	categories := []models.Category{}
+	err = localizedTerms(s.db.WithContext(ctx).Model(&models.Category{}), categoryTaxonomy, locales, publishedOnly).
+		Order("categories.name").Order("categories.id").Find(&categories).Error
+	return categories, err
+}
+
+// GetCategoryBySlug retrieves the category with slug, resolved like
+// PostService.GetPostBySlug: slugs are unique per locale, and a variant of
+// the category's group in an earlier locale is preferred.
+func (s *TaxonomyService) GetCategoryBySlug(ctx context.Context, slug string, locales []string, publishedOnly bool) (_ *models.Category, err error) {
+	ctx, span := tracing.Start(ctx, "TaxonomyService.GetCategoryBySlug", attribute.String("category.slug", slug))
+	defer tracing.End(span, &err)
+
+	id, err := termBySlug(s.db.WithContext(ctx), categoryTaxonomy, slug, locales, publishedOnly)
+	if err != nil {
+		return nil, err
+	}
+	var category models.Category
+	if err := s.db.WithContext(ctx).First(&category, id).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// ListCategoryTranslations returns the translation state of the group of
+// the category with id in every supported locale, the original's first.
+func (s *TaxonomyService) ListCategoryTranslations(ctx context.Context, id uint) (_ []TermTranslation, err error) {
+	ctx, span := tracing.Start(ctx, "TaxonomyService.ListCategoryTranslations", attribute.Int("category.id", int(id)))
+	defer tracing.End(span, &err)
+
+	return s.listTranslations(s.db.WithContext(ctx), categoryTaxonomy, id)
+}
+
+// ListCategoryTranslationTasks returns a page of the original categories
+// whose translation into locale is missing or outdated, like
+// PostService.ListTranslationTasks.
+func (s *TaxonomyService) ListCategoryTranslationTasks(ctx context.Context, locale, state string, page, pageSize int) (_ []TermTranslationTask, _ *utils.Pagination, err error) {
+	ctx, span := tracing.Start(ctx, "TaxonomyService.ListCategoryTranslationTasks", attribute.String("locale", locale))
+	defer tracing.End(span, &err)
+
+	return s.listTranslationTasks(s.db.WithContext(ctx), categoryTaxonomy, locale, state, page, pageSize)
+}
+
+// CreateTag adds a tag in tag.Locale, the default locale when empty. With
+// SourceID set, it joins that tag's translation group.
+func (s *TaxonomyService) CreateTag(ctx context.Context, tag *models.Tag) (err error) {
+	ctx, span := tracing.Start(ctx, "TaxonomyService.CreateTag")
+	defer tracing.End(span, &err)
+
+	tag.Name = utils.SanitizeText(tag.Name)
+	if tag.Name == "" {
+		return errors.New(utils.ValidationFailedMsg + ": name is required")
+	}
+	if tag.Slug == "" {
+		tag.Slug = generateSlug(tag.Name)
+	}
+	db := s.db.WithContext(ctx)
+	if err := s.prepareTerm(db, tagTaxonomy, &tag.Locale, &tag.SourceID); err != nil {
+		return err
+	}
+	if err := checkTermUnique(db, tagTaxonomy, 0, tag.Locale, "name", tag.Name); err != nil {
+		return err
+	}
+	if err := checkTermUnique(db, tagTaxonomy, 0, tag.Locale, "slug", tag.Slug); err != nil {
+		return err
+	}
+
+	if err := db.Create(tag).Error; err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "tag created", slog.Uint64("tag_id", uint64(tag.ID)), slog.String("locale", tag.Locale))
+	return nil
+}
+
+// CreateTagTranslation adds tag as the translation of the tag with
+// sourceID into tag.Locale, which the group must not have yet.
+func (s *TaxonomyService) CreateTagTranslation(ctx context.Context, sourceID uint, tag *models.Tag) (err error) {
+	ctx, span := tracing.Start(ctx, "TaxonomyService.CreateTagTranslation", attribute.Int("tag.id", int(sourceID)))
+	defer tracing.End(span, &err)
+
+	if tag.Locale == "" {
+		return errors.New(utils.ValidationFailedMsg + ": locale is required")
+	}
+	tag.SourceID = &sourceID
+	return s.CreateTag(ctx, tag)
+}
+
+// UpdateTag applies updates, keyed by column, to the tag with id.
+func (s *TaxonomyService) UpdateTag(ctx context.Context, id uint, updates map[string]interface{}) (_ *models.Tag, err error) {
+	ctx, span := tracing.Start(ctx, "TaxonomyService.UpdateTag", attribute.Int("tag.id", int(id)))
+	defer tracing.End(span, &err)
+
+	db := s.db.WithContext(ctx)
+	var tag models.Tag
+	if err := db.First(&tag, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("tag not found")
+		}
+		return nil, err
+	}
+	sanitizeTextUpdates(updates, "name")
+	if updates["name"] == "" {
+		return nil, errors.New(utils.ValidationFailedMsg + ": name is required")
+	}
+	for _, column := range []string{"name", "slug"} {
+		if value, ok := updates[column].(string); ok {
+			if err := checkTermUnique(db, tagTaxonomy, id, tag.Locale, column, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := db.Model(&tag).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	if err := db.First(&tag, id).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// ListTags returns the tags shown in locales, by name, negotiated like
+// ListCategories. With activeOnly archived tags are left out.
+func (s *TaxonomyService) ListTags(ctx context.Context, locales []string, activeOnly bool) (_ []models.Tag, err error) {
+	ctx, span := tracing.Start(ctx, "TaxonomyService.ListTags")
+	defer tracing.End(span, &err)
+
+	tags := []models.Tag{}
+	err = localizedTerms(s.db.WithContext(ctx).Model(&models.Tag{}), tagTaxonomy, locales, activeOnly).
+		Order("tags.name").Order("tags.id").Find(&tags).Error
+	return tags, err
+}
+
+// GetTagBySlug retrieves the tag with slug, resolved like
+// GetCategoryBySlug.
+func (s *TaxonomyService) GetTagBySlug(ctx context.Context, slug string, locales []string, activeOnly bool) (_ *models.Tag, err error) {
+	ctx, span := tracing.Start(ctx, "TaxonomyService.GetTagBySlug", attribute.String("tag.slug", slug))
+	defer tracing.End(span, &err)
+
+	id, err := termBySlug(s.db.WithContext(ctx), tagTaxonomy, slug, locales, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	var tag models.Tag
+	if err := s.db.WithContext(ctx).First(&tag, id).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// ListTagTranslations returns the translation state of the group of the
+// tag with id in every supported locale, the original's first.
+func (s *TaxonomyService) ListTagTranslations(ctx context.Context, id uint) (_ []TermTranslation, err error) {
+	ctx, span := tracing.Start(ctx, "TaxonomyService.ListTagTranslations", attribute.Int("tag.id", int(id)))
+	defer tracing.End(span, &err)
+
+	return s.listTranslations(s.db.WithContext(ctx), tagTaxonomy, id)
+}
+
+// ListTagTranslationTasks returns a page of the original tags whose
+// translation into locale is missing or outdated.
+func (s *TaxonomyService) ListTagTranslationTasks(ctx context.Context, locale, state string, page, pageSize int) (_ []TermTranslationTask, _ *utils.Pagination, err error) {
+	ctx, span := tracing.Start(ctx, "TaxonomyService.ListTagTranslationTasks", attribute.String("locale", locale))
+	defer tracing.End(span, &err)
+
+	return s.listTranslationTasks(s.db.WithContext(ctx), tagTaxonomy, locale, state, page, pageSize)
+}
+
+// prepareTerm defaults and checks the locale of a new category or tag.
+// For a translation it also checks that its group has no variant in that
+// locale yet, and points sourceID at the group's original.
+func (s *TaxonomyService) prepareTerm(db *gorm.DB, t taxonomy, locale *string, sourceID **uint) error {
+	if *locale == "" {
+		*locale = s.locales.Default()
+	}
+	matched, ok := s.locales.Match(*locale)
+	if !ok {
+		return errors.New(utils.ValidationFailedMsg + ": unsupported locale")
+	}
+	*locale = matched
+	if *sourceID == nil {
+		return nil
+	}
+
+	group, err := termGroup(db, t, **sourceID)
+	if err != nil {
+		return err
+	}
+	var existing int64
+	err = db.Table(t.table).Where("(id = ? OR source_id = ?) AND locale = ? AND deleted_at IS NULL", group, group, *locale).
+		Count(&existing).Error
+	if err != nil {
+		return err
+	}
+	if existing > 0 {
+		return fmt.Errorf("%s: %s already has a %s translation", utils.ValidationFailedMsg, t.name, *locale)
+	}
+	*sourceID = &group
+	return nil
+}
+
+// termGroup returns the translation group of the category or tag with id.
+func termGroup(db *gorm.DB, t taxonomy, id uint) (uint, error) {
+	var row termRow
+	err := db.Table(t.table).Select("id", "source_id").Where("id = ? AND deleted_at IS NULL", id).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, fmt.Errorf("%s not found", t.name)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return translationGroup(row.ID, row.SourceID), nil
+}
+
+// checkTermUnique checks that no other category or tag than id uses value
+// in column within locale.
+func checkTermUnique(db *gorm.DB, t taxonomy, id uint, locale, column, value string) error {
+	var taken int64
+	err := db.Table(t.table).Where("locale = ? AND id <> ? AND deleted_at IS NULL", locale, id).
+		Where(column+" = ?", value).Count(&taken).Error
+	if err != nil {
+		return err
+	}
+	if taken > 0 {
+		return fmt.Errorf("%s: %s is already used by another %s in this locale", utils.ValidationFailedMsg, column, t.name)
+	}
+	return nil
+}
+
+// localizedTerms restricts query to the rows shown in locales: each
+// translation group once, in the earliest of locales it has a variant in.
+func localizedTerms(query *gorm.DB, t taxonomy, locales []string, visibleOnly bool) *gorm.DB {
+	if visibleOnly {
+		query = query.Where(t.table+".status = ?", t.visible)
+	}
+	if len(locales) == 0 {
+		return query
+	}
+	query = query.Where(t.table+".locale IN ?", locales)
+	if len(locales) > 1 {
+		variant := `NOT EXISTS (SELECT 1 FROM ` + t.table + ` AS variant
+			WHERE COALESCE(variant.source_id, variant.id) = COALESCE(` + t.table + `.source_id, ` + t.table + `.id)
+			AND variant.deleted_at IS NULL
+			AND array_position(ARRAY[?]::text[], variant.locale) < array_position(ARRAY[?]::text[], ` + t.table + `.locale)`
+		args := []interface{}{locales, locales}
+		if visibleOnly {
+			variant += " AND variant.status = ?"
+			args = append(args, t.visible)
+		}
+		query = query.Where(variant+")", args...)
+	}
+	return query
+}
+
+// termBySlug returns the ID of the category or tag with slug, preferring
+// the variant of its group in the earliest of locales.
+func termBySlug(db *gorm.DB, t taxonomy, slug string, locales []string, visibleOnly bool) (uint, error) {
+	visible := func(q *gorm.DB) *gorm.DB {
+		q = q.Table(t.table).Where("deleted_at IS NULL")
+		if visibleOnly {
+			return q.Where("status = ?", t.visible)
+		}
+		return q
+	}
+	rank := localeRank(locales)
+
+	var match termRow
+	err := visible(db.Select("id", "source_id")).Where("slug = ?", slug).Order(rank).Order("id").Take(&match).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, fmt.Errorf("%s not found", t.name)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	group := translationGroup(match.ID, match.SourceID)
+	var best termRow
+	err = visible(db.Select("id")).Where("(id = ? OR source_id = ?) AND locale IN ?", group, group, locales).
+		Order(rank).Order("id").Take(&best).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return match.ID, nil
+	case err != nil:
+		return 0, err
+	}
+	return best.ID, nil
+}
+
+func (s *TaxonomyService) listTranslations(db *gorm.DB, t taxonomy, id uint) ([]TermTranslation, error) {
+	group, err := termGroup(db, t, id)
+	if err != nil {
+		return nil, err
+	}
+	var variants []termRow
+	err = db.Table(t.table).Select("id", "source_id", "name", "slug", "status", "locale", "updated_at").
+		Where("(id = ? OR source_id = ?) AND deleted_at IS NULL", group, group).Find(&variants).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var source termRow
+	byLocale := make(map[string]termRow, len(variants))
+	translations := []TermTranslation{}
+	for _, v := range variants {
+		byLocale[v.Locale] = v
+		if v.SourceID == nil {
+			source = v
+			translations = append(translations, newTermTranslation(v, TranslationSource))
+		}
+	}
+	for _, locale := range s.locales.Supported() {
+		v, ok := byLocale[locale]
+		switch {
+		case !ok:
+			translations = append(translations, TermTranslation{Locale: locale, State: TranslationMissing})
+		case v.SourceID == nil:
+		case v.UpdatedAt.Before(source.UpdatedAt):
+			translations = append(translations, newTermTranslation(v, TranslationOutdated))
+		default:
+			translations = append(translations, newTermTranslation(v, TranslationCurrent))
+		}
+	}
+	return translations, nil
+}
+
+func newTermTranslation(row termRow, state string) TermTranslation {
+	id, updatedAt := row.ID, row.UpdatedAt
+	return TermTranslation{
+		Locale:    row.Locale,
+		State:     state,
+		ID:        &id,
+		Name:      row.Name,
+		Slug:      row.Slug,
+		Status:    row.Status,
+		UpdatedAt: &updatedAt,
+	}
+}
+
+func (s *TaxonomyService) listTranslationTasks(db *gorm.DB, t taxonomy, locale, state string, page, pageSize int) ([]TermTranslationTask, *utils.Pagination, error) {
+	if !s.locales.IsSupported(locale) {
+		return nil, nil, errors.New(utils.ValidationFailedMsg + ": unsupported locale")
+	}
+	stateCond := "(translation.id IS NULL OR translation.updated_at < terms.updated_at)"
+	switch state {
+	case "":
+	case TranslationMissing:
+		stateCond = "translation.id IS NULL"
+	case TranslationOutdated:
+		stateCond = "translation.updated_at < terms.updated_at"
+	default:
+		return nil, nil, errors.New(utils.ValidationFailedMsg + ": state must be missing or outdated")
+	}
+	page, pageSize = utils.NormalizePage(page, pageSize)
+
+	// Archived originals need no translation
+	from := `FROM ` + t.table + ` AS terms
+		LEFT JOIN ` + t.table + ` AS translation ON translation.source_id = terms.id
+			AND translation.locale = ? AND translation.deleted_at IS NULL
+		WHERE terms.source_id IS NULL AND terms.deleted_at IS NULL
+			AND terms.locale <> ? AND terms.status <> 'archived' AND ` + stateCond
+	args := []interface{}{locale, locale}
+
+	var total int64
+	if err := db.Raw("SELECT COUNT(*) "+from, args...).Scan(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var rows []struct {
+		ID            uint
+		Name          string
+		Locale        string
+		Status        string
+		TranslationID *uint
+	}
+	err := db.Raw(`SELECT terms.id, terms.name, terms.locale, terms.status,
+			translation.id AS translation_id `+from+`
+		ORDER BY terms.updated_at DESC, terms.id DESC
+		LIMIT ? OFFSET ?`, append(args, pageSize, (page-1)*pageSize)...).Scan(&rows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tasks := make([]TermTranslationTask, len(rows))
+	for i, row := range rows {
+		tasks[i] = TermTranslationTask{
+			ID:            row.ID,
+			Name:          row.Name,
+			Locale:        row.Locale,
+			Status:        row.Status,
+			State:         TranslationMissing,
+			TranslationID: row.TranslationID,
+		}
+		if row.TranslationID != nil {
+			tasks[i].State = TranslationOutdated
+		}
+	}
+
+	return tasks, utils.NewPagination(page, pageSize, total), nil
+}
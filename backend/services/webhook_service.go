@@ -0,0 +1,131 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/sasanzare/go-cms/events"
+	"github.com/sasanzare/go-cms/jobs"
+)
+
+// Headers sent with webhook deliveries
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// WebhookService posts domain events to the endpoints configured for
+// them. Each delivery is a job of its own, so a slow or failing endpoint
+// is retried without holding up the request or the other endpoints.
+type WebhookService struct {
+	urls   []string
+	secret string
+	client *http.Client
+	queue  *jobs.Queue
+}
+
+// NewWebhookService creates a WebhookService delivering to urls. Bodies
+// are signed with secret when set; requests give up after timeout.
+func NewWebhookService(urls []string, secret string, timeout time.Duration) *WebhookService {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &WebhookService{
+		urls:   urls,
+		secret: secret,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// WebhookPayload is the body of a webhook delivery.
+type WebhookPayload struct {
+	Event  string          `json:"event"`
+	Data   json.RawMessage `json:"data"`
+	SentAt time.Time       `json:"sent_at"`
+}
+
+// Subscribe sends the events endpoints are told about.
+func (s *WebhookService) Subscribe(bus *events.Bus) {
+	events.Subscribe(bus, func(ctx context.Context, e events.PostApproved) error {
+		return s.Dispatch(ctx, e.Name(), e)
+	})
+	events.Subscribe(bus, func(ctx context.Context, e events.PostRejected) error {
+		return s.Dispatch(ctx, e.Name(), e)
+	})
+	events.Subscribe(bus, func(ctx context.Context, e events.PostCommented) error {
+		return s.Dispatch(ctx, e.Name(), e)
+	})
+}
+
+// Dispatch queues a delivery of event to every endpoint, or delivers
+// right away when no job queue is configured.
+func (s *WebhookService) Dispatch(ctx context.Context, event string, data interface{}) error {
+	if len(s.urls) == 0 {
+		return nil
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encode %s webhook: %w", event, err)
+	}
+
+	for _, url := range s.urls {
+		args := DeliverWebhookArgs{URL: url, Event: event, Data: encoded}
+		if s.queue == nil {
+			err = s.Deliver(ctx, args)
+		} else {
+			_, err = s.queue.Enqueue(ctx, args, jobs.OnQueue(WebhookQueue))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deliver posts one event to its endpoint. Responses other than 2xx are
+// errors, so the job is retried.
+func (s *WebhookService) Deliver(ctx context.Context, args DeliverWebhookArgs) error {
+	body, err := json.Marshal(WebhookPayload{Event: args.Event, Data: args.Data, SentAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, args.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, args.Event)
+	if s.secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("deliver %s webhook: %w", args.Event, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("deliver %s webhook: endpoint answered %s", args.Event, resp.Status)
+	}
+
+	slog.InfoContext(ctx, "webhook delivered", slog.String("event", args.Event), slog.String("url", args.URL))
+	return nil
+}
+
+// SignWebhook returns the signature header of body: "sha256=" and the hex
+// HMAC-SHA256 of body under secret.
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,125 @@
+// Package sitemap writes XML sitemaps and sitemap indexes following the
+// sitemaps.org protocol, with Google's image extension and hreflang
+// alternates for translated pages.
+package sitemap
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// MaxURLs is the most URLs a sitemap may list, and the most sitemaps an
+// index may list.
+const MaxURLs = 50000
+
+// URL is a page of a sitemap.
+type URL struct {
+	Loc     string
+	LastMod time.Time
+	// Images are the addresses of images on the page
+	Images []string
+	// Alternates are the page's translations, itself included
+	Alternates []Alternate
+}
+
+// Alternate is a version of a page in another language. Lang is a
+// language tag or "x-default" for the page shown to other languages.
+type Alternate struct {
+	Lang string
+	Href string
+}
+
+// Sitemap is an entry of a sitemap index.
+type Sitemap struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type urlSet struct {
+	XMLName xml.Name  `xml:"urlset"`
+	NS      string    `xml:"xmlns,attr"`
+	ImageNS string    `xml:"xmlns:image,attr"`
+	XHTMLNS string    `xml:"xmlns:xhtml,attr"`
+	URLs    []urlNode `xml:"url"`
+}
+
+type urlNode struct {
+	Loc        string      `xml:"loc"`
+	LastMod    string      `xml:"lastmod,omitempty"`
+	Alternates []linkNode  `xml:"xhtml:link"`
+	Images     []imageNode `xml:"image:image"`
+}
+
+type linkNode struct {
+	Rel      string `xml:"rel,attr"`
+	HrefLang string `xml:"hreflang,attr"`
+	Href     string `xml:"href,attr"`
+}
+
+type imageNode struct {
+	Loc string `xml:"image:loc"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name      `xml:"sitemapindex"`
+	NS       string        `xml:"xmlns,attr"`
+	Sitemaps []sitemapNode `xml:"sitemap"`
+}
+
+type sitemapNode struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+const sitemapNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+func lastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// WriteURLSet writes a sitemap of urls, of which there may be at most
+// MaxURLs.
+func WriteURLSet(w io.Writer, urls []URL) error {
+	doc := urlSet{
+		NS:      sitemapNS,
+		ImageNS: "http://www.google.com/schemas/sitemap-image/1.1",
+		XHTMLNS: "http://www.w3.org/1999/xhtml",
+		URLs:    make([]urlNode, len(urls)),
+	}
+	for i, u := range urls {
+		node := urlNode{Loc: u.Loc, LastMod: lastMod(u.LastMod)}
+		for _, alt := range u.Alternates {
+			node.Alternates = append(node.Alternates, linkNode{Rel: "alternate", HrefLang: alt.Lang, Href: alt.Href})
+		}
+		for _, image := range u.Images {
+			node.Images = append(node.Images, imageNode{Loc: image})
+		}
+		doc.URLs[i] = node
+	}
+	return write(w, doc)
+}
+
+// WriteIndex writes a sitemap index of sitemaps, of which there may be at
+// most MaxURLs.
+func WriteIndex(w io.Writer, sitemaps []Sitemap) error {
+	doc := sitemapIndex{NS: sitemapNS, Sitemaps: make([]sitemapNode, len(sitemaps))}
+	for i, s := range sitemaps {
+		doc.Sitemaps[i] = sitemapNode{Loc: s.Loc, LastMod: lastMod(s.LastMod)}
+	}
+	return write(w, doc)
+}
+
+func write(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if err := xml.NewEncoder(w).Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,151 @@
+package spam
+
+import (
+	"context"
+	"math"
+	"sync"
+)
+
+// minTrainingDocs is how many spam and how many legitimate examples the
+// classifier needs before it gives an opinion.
+const minTrainingDocs = 5
+
+// docsToken holds the number of documents learned per class. Tokenize
+// never produces it.
+const docsToken = "#docs"
+
+// Counts is how many spam and legitimate documents contained a token.
+type Counts struct {
+	Spam int64
+	Ham  int64
+}
+
+// Store persists the classifier's token counts.
+type Store interface {
+	// Load returns every token's counts
+	Load(ctx context.Context) (map[string]Counts, error)
+	// Add adds deltas, which may be negative, to the stored counts
+	Add(ctx context.Context, deltas map[string]Counts) error
+}
+
+// Classifier is a naive Bayes spam classifier trained on moderators'
+// decisions. It keeps the token counts in memory and writes every change
+// through to its store; Load reads them back at startup.
+type Classifier struct {
+	store Store
+
+	mu     sync.RWMutex
+	tokens map[string]Counts
+}
+
+func NewClassifier(store Store) *Classifier {
+	return &Classifier{store: store, tokens: map[string]Counts{}}
+}
+
+// Load replaces the in-memory counts with the store's.
+func (c *Classifier) Load(ctx context.Context) error {
+	tokens, err := c.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.tokens = tokens
+	c.mu.Unlock()
+	return nil
+}
+
+// Learn trains the classifier with text labelled as spam or not.
+func (c *Classifier) Learn(ctx context.Context, text string, spam bool) error {
+	return c.update(ctx, text, spam, 1)
+}
+
+// Unlearn reverses an earlier Learn with the same arguments, for when a
+// moderator changes their mind.
+func (c *Classifier) Unlearn(ctx context.Context, text string, spam bool) error {
+	return c.update(ctx, text, spam, -1)
+}
+
+func (c *Classifier) update(ctx context.Context, text string, spam bool, n int64) error {
+	delta := Counts{Ham: n}
+	if spam {
+		delta = Counts{Spam: n}
+	}
+	deltas := map[string]Counts{docsToken: delta}
+	for _, t := range Tokenize(text) {
+		deltas[t] = delta
+	}
+
+	if err := c.store.Add(ctx, deltas); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for t, d := range deltas {
+		counts := c.tokens[t]
+		counts.Spam = max(counts.Spam+d.Spam, 0)
+		counts.Ham = max(counts.Ham+d.Ham, 0)
+		c.tokens[t] = counts
+	}
+	return nil
+}
+
+// SpamProbability estimates the probability that text is spam. ok is
+// false until the classifier has seen enough examples of both classes.
+//
+// Each known token contributes the Laplace-smoothed share of spam and
+// legitimate documents containing it. Both classes get the same prior, so
+// an unbalanced training set doesn't tilt every verdict.
+func (c *Classifier) SpamProbability(text string) (p float64, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	docs := c.tokens[docsToken]
+	if docs.Spam < minTrainingDocs || docs.Ham < minTrainingDocs {
+		return 0, false
+	}
+
+	var logSpam, logHam float64
+	for _, t := range Tokenize(text) {
+		counts, known := c.tokens[t]
+		if !known || counts.Spam+counts.Ham == 0 {
+			continue
+		}
+		logSpam += math.Log(float64(counts.Spam+1) / float64(docs.Spam+2))
+		logHam += math.Log(float64(counts.Ham+1) / float64(docs.Ham+2))
+	}
+	return 1 / (1 + math.Exp(logHam-logSpam)), true
+}
+
+// MemoryStore keeps counts in memory only. It suits tests and
+// single-instance development setups.
+type MemoryStore struct {
+	mu     sync.Mutex
+	tokens map[string]Counts
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tokens: map[string]Counts{}}
+}
+
+func (s *MemoryStore) Load(context.Context) (map[string]Counts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens := make(map[string]Counts, len(s.tokens))
+	for t, c := range s.tokens {
+		tokens[t] = c
+	}
+	return tokens, nil
+}
+
+func (s *MemoryStore) Add(_ context.Context, deltas map[string]Counts) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for t, d := range deltas {
+		counts := s.tokens[t]
+		counts.Spam = max(counts.Spam+d.Spam, 0)
+		counts.Ham = max(counts.Ham+d.Ham, 0)
+		s.tokens[t] = counts
+	}
+	return nil
+}
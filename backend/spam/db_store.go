@@ -0,0 +1,53 @@
+package spam
+
+import (
+	"context"
+
+	"github.com/sasanzare/go-cms/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DBStore keeps classifier counts in the spam_tokens table, so every
+// instance learns from every moderator. Instances pick up each other's
+// training when they Load.
+type DBStore struct {
+	db *gorm.DB
+}
+
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+func (s *DBStore) Load(ctx context.Context) (map[string]Counts, error) {
+	var rows []models.SpamToken
+	if err := s.db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	tokens := make(map[string]Counts, len(rows))
+	for _, r := range rows {
+		tokens[r.Token] = Counts{Spam: max(r.Spam, 0), Ham: max(r.Ham, 0)}
+	}
+	return tokens, nil
+}
+
+// Add applies deltas with one upsert per batch. Existing counts never go
+// below zero; Load ignores the negative counts unlearning a token that was
+// never stored would insert.
+func (s *DBStore) Add(ctx context.Context, deltas map[string]Counts) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	rows := make([]models.SpamToken, 0, len(deltas))
+	for t, d := range deltas {
+		rows = append(rows, models.SpamToken{Token: t, Spam: d.Spam, Ham: d.Ham})
+	}
+
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "token"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"spam": gorm.Expr("GREATEST(spam_tokens.spam + excluded.spam, 0)"),
+			"ham":  gorm.Expr("GREATEST(spam_tokens.ham + excluded.ham, 0)"),
+		}),
+	}).CreateInBatches(rows, 500).Error
+}
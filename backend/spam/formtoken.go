@@ -0,0 +1,61 @@
+package spam
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sasanzare/go-cms/utils"
+)
+
+// formTokenTTL is how long a form may stay open before submitting.
+const formTokenTTL = 24 * time.Hour
+
+// FormTokens issues signed tokens recording when a form was shown, so a
+// submission's speed can be checked without server-side state.
+type FormTokens struct {
+	secret []byte
+	clock  utils.Clock
+}
+
+func NewFormTokens(secret []byte, clock utils.Clock) *FormTokens {
+	return &FormTokens{secret: secret, clock: clock}
+}
+
+// Issue returns a token for a form shown now.
+func (t *FormTokens) Issue() string {
+	issued := strconv.FormatInt(t.clock.Now().UnixMilli(), 10)
+	return issued + "." + t.sign(issued)
+}
+
+// Age returns how long ago token was issued. It fails for missing,
+// tampered or expired tokens.
+func (t *FormTokens) Age(token string) (time.Duration, error) {
+	if token == "" {
+		return 0, errors.New("no form token")
+	}
+	issued, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(t.sign(issued))) {
+		return 0, errors.New("invalid form token")
+	}
+	ms, err := strconv.ParseInt(issued, 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid form token")
+	}
+
+	age := t.clock.Now().Sub(time.UnixMilli(ms))
+	if age < 0 || age > formTokenTTL {
+		return 0, errors.New("expired form token")
+	}
+	return age, nil
+}
+
+func (t *FormTokens) sign(payload string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte("spam-form-token:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
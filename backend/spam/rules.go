@@ -0,0 +1,159 @@
+package spam
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`)
+
+func findLinks(text string) []string {
+	return linkPattern.FindAllString(text, -1)
+}
+
+func linkHost(link string) string {
+	if strings.HasPrefix(strings.ToLower(link), "www.") {
+		link = "http://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// LinkRule penalizes submissions with more than Allowed links, adding
+// Weight for each extra link.
+type LinkRule struct {
+	Allowed int
+	Weight  float64
+}
+
+func (LinkRule) Name() string { return "links" }
+
+func (r LinkRule) Check(_ context.Context, s *Submission) (float64, string) {
+	n := len(findLinks(s.Body)) + len(findLinks(s.Name))
+	if n <= r.Allowed {
+		return 0, ""
+	}
+	return float64(n-r.Allowed) * r.Weight, fmt.Sprintf("%d links", n)
+}
+
+// BlocklistRule adds Weight for every blocklisted word or phrase found in
+// the submission's body, name or email, ignoring case. Only whole words
+// match, so "cialis" doesn't flag "specialist"; phrases match across any
+// run of whitespace.
+type BlocklistRule struct {
+	Words  []string
+	Weight float64
+}
+
+func (BlocklistRule) Name() string { return "blocklist" }
+
+func (r BlocklistRule) Check(_ context.Context, s *Submission) (float64, string) {
+	text := normalizeSpace(strings.ToLower(s.Body + "\n" + s.Name + "\n" + s.Email))
+	var hits []string
+	for _, word := range r.Words {
+		if word = normalizeSpace(strings.ToLower(word)); word != "" && containsWord(text, word) {
+			hits = append(hits, word)
+		}
+	}
+	if len(hits) == 0 {
+		return 0, ""
+	}
+	return float64(len(hits)) * r.Weight, "blocklisted: " + strings.Join(hits, ", ")
+}
+
+// normalizeSpace trims s and turns each run of whitespace in it into a
+// single space.
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// containsWord reports whether word occurs in text with neither a letter
+// nor a digit right before or after it.
+func containsWord(text, word string) bool {
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(word)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(text[start:])
+		offset = start + size
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// HoneypotRule adds Weight when the hidden honeypot field was filled in.
+type HoneypotRule struct {
+	Weight float64
+}
+
+func (HoneypotRule) Name() string { return "honeypot" }
+
+func (r HoneypotRule) Check(_ context.Context, s *Submission) (float64, string) {
+	if s.Honeypot == "" {
+		return 0, ""
+	}
+	return r.Weight, "honeypot field filled in"
+}
+
+// SpeedRule catches forms submitted faster than a person could fill them
+// in, using the age of the submission's form token. A missing or invalid
+// token adds MissingWeight, a submission within Min adds Weight.
+type SpeedRule struct {
+	Tokens        *FormTokens
+	Min           time.Duration
+	Weight        float64
+	MissingWeight float64
+}
+
+func (SpeedRule) Name() string { return "speed" }
+
+func (r SpeedRule) Check(_ context.Context, s *Submission) (float64, string) {
+	age, err := r.Tokens.Age(s.FormToken)
+	if err != nil {
+		return r.MissingWeight, err.Error()
+	}
+	if age < r.Min {
+		return r.Weight, fmt.Sprintf("submitted %s after the form was shown", age.Round(100*time.Millisecond))
+	}
+	return 0, ""
+}
+
+// BayesRule scores with the naive Bayes classifier: up to Weight for
+// likely spam and down to -Weight for likely legitimate content. It has
+// no opinion until the classifier has enough training data.
+type BayesRule struct {
+	Classifier *Classifier
+	Weight     float64
+}
+
+func (BayesRule) Name() string { return "bayes" }
+
+func (r BayesRule) Check(_ context.Context, s *Submission) (float64, string) {
+	p, ok := r.Classifier.SpamProbability(s.Text())
+	if !ok {
+		return 0, ""
+	}
+	score := (2*p - 1) * r.Weight
+	if score > -0.01 && score < 0.01 {
+		return 0, ""
+	}
+	return score, fmt.Sprintf("spam probability %.2f", p)
+}
@@ -0,0 +1,12 @@
+package spam
+
+// Signals are request details spam checks use besides the content itself.
+type Signals struct {
+	IP        string
+	UserAgent string
+	// Honeypot is the value of a form field hidden from people; only bots
+	// fill it in
+	Honeypot string
+	// FormToken was issued when the form was shown, see FormTokens
+	FormToken string
+}
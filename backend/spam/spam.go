@@ -0,0 +1,129 @@
+package spam
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// Kinds of submissions
+const (
+	KindComment      = "comment"
+	KindRegistration = "registration"
+)
+
+// Labels moderators give content when training the classifier
+const (
+	LabelSpam = "spam"
+	LabelHam  = "ham"
+)
+
+// Submission is user-submitted content to score.
+type Submission struct {
+	Kind  string
+	Body  string
+	Name  string
+	Email string
+	Signals
+}
+
+// Text is the part of the submission the classifier reads. Training must
+// use the same text as checking.
+func (s *Submission) Text() string {
+	return s.Body + "\n" + s.Name
+}
+
+// RuleResult is one rule's contribution to a verdict.
+type RuleResult struct {
+	Rule   string  `json:"rule"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+// Verdict is the outcome of scoring a submission: the sum of the rule
+// scores, whether it reaches the spam threshold, and each rule's share.
+type Verdict struct {
+	Score   float64      `json:"score"`
+	Spam    bool         `json:"spam"`
+	Results []RuleResult `json:"results"`
+}
+
+// Rule scores one aspect of a submission. Positive scores suggest spam,
+// negative ones legitimate content; zero means the rule has no opinion.
+type Rule interface {
+	Name() string
+	Check(ctx context.Context, s *Submission) (score float64, reason string)
+}
+
+// Pipeline runs every rule over a submission and adds up their scores.
+type Pipeline struct {
+	rules     []Rule
+	threshold float64
+}
+
+// NewPipeline returns a pipeline flagging submissions scoring at least
+// threshold.
+func NewPipeline(threshold float64, rules ...Rule) *Pipeline {
+	return &Pipeline{rules: rules, threshold: threshold}
+}
+
+// Check scores s. Rules without an opinion are left out of the results.
+func (p *Pipeline) Check(ctx context.Context, s *Submission) Verdict {
+	v := Verdict{Results: []RuleResult{}}
+	for _, rule := range p.rules {
+		score, reason := rule.Check(ctx, s)
+		if score == 0 {
+			continue
+		}
+		v.Score += score
+		v.Results = append(v.Results, RuleResult{Rule: rule.Name(), Score: score, Reason: reason})
+	}
+	v.Spam = v.Score >= p.threshold
+	return v
+}
+
+// MaxTokenLength is the longest token Tokenize returns, in bytes.
+const MaxTokenLength = 128
+
+// Tokenize splits text into the lowercase words the classifier learns
+// from, each once. Links also yield a token for their host, which is
+// often the best spam signal.
+func Tokenize(text string) []string {
+	seen := map[string]bool{}
+	var tokens []string
+	add := func(t string) {
+		if !seen[t] {
+			seen[t] = true
+			tokens = append(tokens, t)
+		}
+	}
+
+	for _, link := range findLinks(text) {
+		if host := linkHost(link); host != "" {
+			add(hostToken(host))
+		}
+	}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if n := len([]rune(word)); n >= 2 && n <= 30 {
+			add(word)
+		}
+	}
+	return tokens
+}
+
+// hostToken is the token of a link's host. Hosts too long for a token
+// lose leading labels, keeping the domain that identifies them.
+func hostToken(host string) string {
+	const prefix = "host:"
+	for len(prefix)+len(host) > MaxTokenLength {
+		_, rest, ok := strings.Cut(host, ".")
+		if !ok {
+			host = strings.ToValidUTF8(host[len(host)-(MaxTokenLength-len(prefix)):], "")
+			break
+		}
+		host = rest
+	}
+	return prefix + host
+}
@@ -0,0 +1,95 @@
+package blocks
+
+import (
+	"testing"
+
+	"github.com/sasanzare/go-cms/blocks"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParse tests validating block documents.
+//
+// Test Cases:
+//  1. A valid document round-trips with sanitized text
+//  2. Unknown types and fields, and bad field values, are rejected with
+//     the block's position
+//  3. Empty documents, non-lists and duplicate ids are rejected
+func TestParse(t *testing.T) {
+	doc, err := blocks.Parse([]byte(`[
+		{"id": "a", "type": "heading", "data": {"text": "Hi <script>x()</script><em>there</em>", "level": 2}},
+		{"type": "paragraph", "data": {"text": "<p onclick=\"x()\">Body</p>"}}
+	]`))
+	require.NoError(t, err)
+	encoded, err := doc.JSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"id": "a", "type": "heading", "data": {"text": "Hi <em>there</em>", "level": 2}},
+		{"type": "paragraph", "data": {"text": "<p>Body</p>"}}
+	]`, string(encoded))
+
+	for raw, msg := range map[string]string{
+		`[{"type": "video", "data": {}}]`:                                                                         `unknown block type "video"`,
+		`[{"type": "heading", "data": {"text": "x", "size": 1}}]`:                                                 `invalid heading data: json: unknown field "size"`,
+		`[{"type": "paragraph", "data": {"text": "ok"}}, {"type": "heading", "data": {"text": "x", "level": 7}}]`: "block 2 (heading): level must be between 1 and 6",
+		`[{"type": "image", "data": {"url": "javascript:alert(1)"}}]`:                                             "block 1 (image): url must use http or https",
+		`[{"type": "image", "data": {"media_id": 1, "url": "https://x.example/a.png"}}]`:                          "block 1 (image): give either media_id or url, not both",
+		`[{"type": "gallery", "data": {"images": [{"alt": "x"}]}}]`:                                               "block 1 (gallery): image 1: media_id or url is required",
+		`[{"type": "code", "data": {"code": "x", "language": "go lang"}}]`:                                        "block 1 (code): invalid language",
+		`[{"type": "paragraph", "data": {"text": "<b></b>"}}]`:                                                    "block 1 (paragraph): text is required",
+		`[]`: "at least one block is required",
+		`{}`: "blocks must be a list",
+		`[{"id": "a", "type": "code", "data": {"code": "x"}}, {"id": "a", "type": "code", "data": {"code": "y"}}]`: `block 2: duplicate id "a"`,
+	} {
+		_, err := blocks.Parse([]byte(raw))
+		assert.EqualError(t, err, msg, raw)
+	}
+}
+
+// TestRender tests rendering documents to HTML and text.
+//
+// Test Cases:
+//  1. Each block type renders its HTML, escaping code
+//  2. Images take URL, size and alt text from their media item
+//  3. Images whose media item is missing are left out
+//  4. Text joins the blocks' text as paragraphs
+func TestRender(t *testing.T) {
+	doc, err := blocks.Parse([]byte(`[
+		{"type": "heading", "data": {"text": "Title", "level": 1}},
+		{"type": "paragraph", "data": {"text": "Some <strong>bold</strong> text"}},
+		{"type": "image", "data": {"media_id": 7, "caption": "Cat"}},
+		{"type": "quote", "data": {"text": "Be kind", "citation": "Someone"}},
+		{"type": "code", "data": {"code": "a < b", "language": "go"}},
+		{"type": "embed", "data": {"url": "https://video.example/v/1"}},
+		{"type": "gallery", "data": {"images": [{"media_id": 7}, {"media_id": 8}, {"url": "/img/x.png", "alt": "X"}]}}
+	]`))
+	require.NoError(t, err)
+	assert.Equal(t, []uint{7, 8}, doc.MediaIDs())
+
+	media := map[uint]models.Media{7: {ID: 7, URL: "https://cdn.example/cat.png", Alt: "A cat", Width: 640, Height: 480}}
+	assert.Equal(t, `<h1>Title</h1>
+<p>Some <strong>bold</strong> text</p>
+<figure><img src="https://cdn.example/cat.png" alt="A cat" width="640" height="480"><figcaption>Cat</figcaption></figure>
+<blockquote><p>Be kind</p><cite>Someone</cite></blockquote>
+<pre><code class="language-go">a &lt; b</code></pre>
+<figure><a href="https://video.example/v/1">https://video.example/v/1</a></figure>
+<figure><div><figure><img src="https://cdn.example/cat.png" alt="A cat" width="640" height="480"></figure><figure><img src="/img/x.png" alt="X"></figure></div></figure>
+`, doc.HTML(media))
+
+	assert.Equal(t, "Title\n\nSome bold text\n\nCat\n\nBe kind\n— Someone\n\na < b", doc.Text())
+}
+
+// TestFromHTML tests wrapping legacy HTML content in a paragraph block.
+//
+// Test Cases:
+//  1. Block markup is kept and rendered without an extra <p>
+//  2. Content without text is rejected
+func TestFromHTML(t *testing.T) {
+	doc, err := blocks.FromHTML(`<h2>Intro</h2><p>Hello<script>x()</script></p>`)
+	require.NoError(t, err)
+	assert.Equal(t, "<h2>Intro</h2><p>Hello</p>\n", doc.HTML(nil))
+
+	_, err = blocks.FromHTML("<p> </p>")
+	assert.Error(t, err)
+}
@@ -0,0 +1,238 @@
+package content
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sasanzare/go-cms/content"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const eventFields = `[
+	{"name": "title", "type": "text", "required": true, "max_length": 20},
+	{"name": "body", "type": "text"},
+	{"name": "price", "type": "number"},
+	{"name": "starts_at", "type": "date", "required": true},
+	{"name": "venue", "type": "reference", "target": "venue"},
+	{"name": "poster", "type": "media"},
+	{"name": "kind", "type": "enum", "options": ["talk", "workshop"]}
+]`
+
+func eventSchema(t *testing.T) content.Schema {
+	schema, err := content.ParseSchema([]byte(eventFields))
+	require.NoError(t, err)
+	return schema
+}
+
+// TestParseSchema tests validating content type schemas.
+//
+// Test Cases:
+//  1. A valid schema gets default labels and lists its reference targets
+//  2. Bad names, types and type-specific settings are rejected
+//  3. The title field defaults to the first text field
+func TestParseSchema(t *testing.T) {
+	schema := eventSchema(t)
+	assert.Len(t, schema, 7)
+	assert.Equal(t, "starts_at", schema[3].Label)
+	assert.Equal(t, []string{"venue"}, schema.Targets())
+
+	for raw, msg := range map[string]string{
+		`[]`:                                  "at least one field is required",
+		`{}`:                                  "fields must be a list",
+		`[{"name": "Title", "type": "text"}]`: "field 1: name must be lowercase letters, digits and underscores, starting with a letter",
+		`[{"name": "a", "type": "text"}, {"name": "a", "type": "number"}]`: `field "a": duplicate name`,
+		`[{"name": "a", "type": "blob"}]`:                                  `field "a": unknown field type "blob"`,
+		`[{"name": "a", "type": "enum"}]`:                                  `field "a": enum fields need between 1 and 200 options`,
+		`[{"name": "a", "type": "enum", "options": ["x", "x"]}]`:           `field "a": options must be unique and not empty`,
+		`[{"name": "a", "type": "reference"}]`:                             `field "a": reference fields need the target content type's slug`,
+		`[{"name": "a", "type": "number", "searchable": true}]`:            `field "a": max_length and searchable only apply to text fields`,
+	} {
+		_, err := content.ParseSchema([]byte(raw))
+		assert.EqualError(t, err, msg, raw)
+	}
+
+	title, err := schema.TitleField("")
+	require.NoError(t, err)
+	assert.Equal(t, "title", title)
+	_, err = schema.TitleField("price")
+	assert.Error(t, err)
+}
+
+// TestValidate tests checking entry data against a schema.
+//
+// Test Cases:
+//  1. Values are normalized: markup stripped, dates in UTC, IDs as integers
+//  2. Empty values count as missing, so required fields reject them
+//  3. Unknown fields and values of the wrong type are rejected
+//  4. References, media and search text are collected from the values
+func TestValidate(t *testing.T) {
+	schema := eventSchema(t)
+
+	values, err := schema.Validate([]byte(`{
+		"title": " <b>Go</b> meetup ",
+		"body": "",
+		"price": 12.5,
+		"starts_at": "2025-03-01T18:00:00+03:30",
+		"venue": 4,
+		"poster": 9,
+		"kind": null
+	}`))
+	require.NoError(t, err)
+	encoded, err := json.Marshal(values)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"title": "Go meetup", "price": 12.5, "starts_at": "2025-03-01T14:30:00Z", "venue": 4, "poster": 9}`, string(encoded))
+
+	assert.Equal(t, map[string][]uint{"venue": {4}}, values.References(schema))
+	assert.Equal(t, []uint{9}, values.MediaIDs(schema))
+	assert.Equal(t, "Go meetup", values.SearchText(schema))
+
+	for raw, msg := range map[string]string{
+		`[]`: "data must be an object",
+		`{"title": "x", "starts_at": "2025-03-01", "room": 1}`: `unknown field "room"`,
+		`{"title": "<i></i>", "starts_at": "2025-03-01"}`:      "title: a value is required",
+		`{"title": "x"}`:                                                    "starts_at: a value is required",
+		`{"title": "x", "starts_at": "March 1"}`:                            "starts_at: must be a date (YYYY-MM-DD) or RFC 3339 timestamp",
+		`{"title": "x", "starts_at": "2025-03-01", "price": "10"}`:          "price: must be a number",
+		`{"title": "x", "starts_at": "2025-03-01", "venue": 1.5}`:           "venue: must be a positive integer ID",
+		`{"title": "x", "starts_at": "2025-03-01", "kind": "party"}`:        "kind: must be one of talk, workshop",
+		`{"title": "a long title that goes on", "starts_at": "2025-03-01"}`: "title: exceeds 20 characters",
+	} {
+		_, err := schema.Validate([]byte(raw))
+		assert.EqualError(t, err, msg, raw)
+	}
+}
+
+// TestSearchText tests which fields feed full-text search.
+//
+// Test Cases:
+//  1. Every text field when none is marked searchable
+//  2. Only the marked fields otherwise
+func TestSearchText(t *testing.T) {
+	data := []byte(`{"name": "Widget", "notes": "internal"}`)
+
+	schema, err := content.ParseSchema([]byte(`[{"name": "name", "type": "text"}, {"name": "notes", "type": "text"}]`))
+	require.NoError(t, err)
+	values, err := schema.Validate(data)
+	require.NoError(t, err)
+	assert.Equal(t, "Widget\ninternal", values.SearchText(schema))
+
+	schema, err = content.ParseSchema([]byte(`[{"name": "name", "type": "text", "searchable": true}, {"name": "notes", "type": "text"}]`))
+	require.NoError(t, err)
+	assert.Equal(t, "Widget", values.SearchText(schema))
+}
+
+// TestCheckChange tests which schema changes types with entries accept.
+//
+// Test Cases:
+//  1. Adding optional fields and removing fields is allowed
+//  2. Changing a field's type or target, or adding a required field, is not
+func TestCheckChange(t *testing.T) {
+	old := eventSchema(t)
+
+	next, err := content.ParseSchema([]byte(`[{"name": "title", "type": "text", "required": true}, {"name": "tags", "type": "text"}]`))
+	require.NoError(t, err)
+	assert.NoError(t, next.CheckChange(old))
+
+	for raw, msg := range map[string]string{
+		`[{"name": "price", "type": "text"}]`:                        `field "price": type can't change while entries exist`,
+		`[{"name": "venue", "type": "reference", "target": "hall"}]`: `field "venue": target can't change while entries exist`,
+		`[{"name": "capacity", "type": "number", "required": true}]`: `field "capacity": new fields can't be required while entries exist`,
+	} {
+		next, err := content.ParseSchema([]byte(raw))
+		require.NoError(t, err)
+		assert.EqualError(t, next.CheckChange(old), msg, raw)
+	}
+}
+
+// TestPrune tests dropping stored values of removed fields.
+//
+// Test Cases:
+//  1. Keys that are no longer fields are dropped, the rest kept as stored
+//  2. Data without such keys is returned unchanged
+func TestPrune(t *testing.T) {
+	schema := eventSchema(t)
+
+	data, pruned, err := schema.Prune([]byte(`{"title": "Go meetup", "price": 12.50, "room": "B2"}`))
+	require.NoError(t, err)
+	assert.True(t, pruned)
+	assert.JSONEq(t, `{"title": "Go meetup", "price": 12.50}`, string(data))
+	_, err = schema.Validate(data)
+	assert.ErrorContains(t, err, "starts_at", "only the fields still defined are checked")
+
+	raw := []byte(`{"title": "Go meetup", "starts_at": "2025-04-01T18:00:00Z"}`)
+	data, pruned, err = schema.Prune(raw)
+	require.NoError(t, err)
+	assert.False(t, pruned)
+	assert.Equal(t, raw, data)
+}
+
+// TestFilter tests turning list filters into SQL conditions.
+//
+// Test Cases:
+//  1. Equality and "in" use JSONB containment with normalized values
+//  2. Ranges compare typed expressions
+//  3. Ranges on other field types, unknown fields and bad values are rejected
+func TestFilter(t *testing.T) {
+	schema := eventSchema(t)
+	sql := func(name, raw string) (string, []interface{}) {
+		filter, err := schema.ParseFilter(name, raw)
+		require.NoError(t, err)
+		return filter.SQL("data")
+	}
+
+	cond, args := sql("kind", "talk")
+	assert.Equal(t, "data @> ?::jsonb", cond)
+	assert.Equal(t, []interface{}{`{"kind":"talk"}`}, args)
+
+	cond, args = sql("venue", "ne:4")
+	assert.Equal(t, "NOT (data @> ?::jsonb)", cond)
+	assert.Equal(t, []interface{}{`{"venue":4}`}, args)
+
+	cond, args = sql("kind", "in:talk,workshop")
+	assert.Equal(t, "(data @> ?::jsonb OR data @> ?::jsonb)", cond)
+	assert.Equal(t, []interface{}{`{"kind":"talk"}`, `{"kind":"workshop"}`}, args)
+
+	cond, args = sql("price", "gte:10")
+	assert.Equal(t, "(data->>'price')::numeric >= ?", cond)
+	assert.Equal(t, []interface{}{10.0}, args)
+
+	cond, args = sql("starts_at", "lt:2025-04-01")
+	assert.Equal(t, "(data->>'starts_at') < ?", cond)
+	assert.Equal(t, []interface{}{"2025-04-01T00:00:00Z"}, args)
+
+	// Text that merely looks like an operator is matched as is
+	cond, args = sql("title", "note:this")
+	assert.Equal(t, "data @> ?::jsonb", cond)
+	assert.Equal(t, []interface{}{`{"title":"note:this"}`}, args)
+
+	for name, raw := range map[string]string{
+		"title": "gt:a",
+		"room":  "1",
+		"price": "cheap",
+		"kind":  "party",
+	} {
+		_, err := schema.ParseFilter(name, raw)
+		assert.Error(t, err, name)
+	}
+}
+
+// TestOrder tests sorting entries by a field.
+//
+// Test Cases:
+//  1. Ascending and descending sorts put entries without a value last
+//  2. Unknown fields are rejected
+func TestOrder(t *testing.T) {
+	schema := eventSchema(t)
+
+	order, err := schema.Order("data", "price")
+	require.NoError(t, err)
+	assert.Equal(t, "(data->>'price')::numeric ASC NULLS LAST", order)
+
+	order, err = schema.Order("data", "-starts_at")
+	require.NoError(t, err)
+	assert.Equal(t, "(data->>'starts_at') DESC NULLS LAST", order)
+
+	_, err = schema.Order("data", "room")
+	assert.Error(t, err)
+}
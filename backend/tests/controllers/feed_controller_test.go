@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/controllers"
+	"github.com/sasanzare/go-cms/i18n"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/tests/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFeedRouter stores a published post in the news category tagged go,
+// another published post and a draft, and serves their JSON feeds. It
+// returns the router and the author of the published posts and of the
+// draft.
+func newFeedRouter(t *testing.T) (*gin.Engine, *models.User, *models.User) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := testdb.Open(t, "controllers")
+	locales := i18n.Single("en")
+	posts := services.NewPostService(db, services.WithLocales(locales))
+	feeds := services.NewFeedService(db, posts, "Example", "https://cms.example.com")
+	ctx := context.Background()
+
+	var users []*models.User
+	for _, name := range []string{"writer", "drafter"} {
+		user := &models.User{Username: name, Email: name + "@example.com", Password: "hash", Role: models.UserRoleAuthor}
+		require.NoError(t, db.Create(user).Error)
+		users = append(users, user)
+	}
+	writer, drafter := users[0], users[1]
+	news := &models.Category{Name: "News", Slug: "news", Locale: "en", Status: models.CategoryStatusPublished}
+	require.NoError(t, db.Create(news).Error)
+	golang := &models.Tag{Name: "go", Slug: "go", Locale: "en"}
+	require.NoError(t, db.Create(golang).Error)
+
+	published := time.Now().Add(-time.Hour)
+	for _, post := range []*models.Post{
+		{Title: "Release notes", CategoryID: &news.ID, Tags: []models.Tag{*golang}, AuthorID: writer.ID},
+		{Title: "Office move", AuthorID: writer.ID},
+	} {
+		post.Content = "<p>" + post.Title + "</p>"
+		post.Status = models.PostStatusPublished
+		post.PublishedAt = &published
+		require.NoError(t, posts.CreatePost(ctx, post))
+	}
+	draft := &models.Post{Title: "Unfinished", Content: "<p>Soon</p>", AuthorID: drafter.ID}
+	require.NoError(t, posts.CreatePost(ctx, draft))
+
+	fc := controllers.NewFeedController(feeds, locales)
+	r := gin.New()
+	locale := middleware.Locale(locales)
+	r.GET("/feed.json", locale, fc.SiteFeed)
+	r.GET("/categories/:slug/feed.json", locale, fc.CategoryFeed)
+	r.GET("/tags/:slug/feed.json", locale, fc.TagFeed)
+	r.GET("/authors/:id/feed.json", locale, fc.AuthorFeed)
+	return r, writer, drafter
+}
+
+func getFeed(r *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func feedTitles(t *testing.T, w *httptest.ResponseRecorder) []string {
+	t.Helper()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var doc struct {
+		Items []struct {
+			Title string `json:"title"`
+		} `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	titles := []string{}
+	for _, item := range doc.Items {
+		titles = append(titles, item.Title)
+	}
+	return titles
+}
+
+// TestFeedConditionalGet tests revalidating feeds.
+//
+// Test Cases:
+//  1. Feeds carry an ETag and Last-Modified
+//  2. A matching If-None-Match answers 304, another ETag the feed
+//  3. If-Modified-Since at or after the last change answers 304, before
+//     it the feed
+//  4. If-None-Match takes precedence over If-Modified-Since
+func TestFeedConditionalGet(t *testing.T) {
+	r, _, _ := newFeedRouter(t)
+
+	w := getFeed(r, "/feed.json", nil)
+	assert.ElementsMatch(t, []string{"Release notes", "Office move"}, feedTitles(t, w))
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	modified, err := http.ParseTime(w.Header().Get("Last-Modified"))
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusNotModified, getFeed(r, "/feed.json", map[string]string{"If-None-Match": etag}).Code)
+	assert.Equal(t, http.StatusNotModified, getFeed(r, "/feed.json", map[string]string{"If-None-Match": `"other", ` + etag}).Code)
+	assert.Equal(t, http.StatusOK, getFeed(r, "/feed.json", map[string]string{"If-None-Match": `"other"`}).Code)
+
+	since := func(at time.Time) map[string]string {
+		return map[string]string{"If-Modified-Since": at.UTC().Format(http.TimeFormat)}
+	}
+	assert.Equal(t, http.StatusNotModified, getFeed(r, "/feed.json", since(modified)).Code)
+	assert.Equal(t, http.StatusNotModified, getFeed(r, "/feed.json", since(modified.Add(time.Hour))).Code)
+	assert.Equal(t, http.StatusOK, getFeed(r, "/feed.json", since(modified.Add(-time.Hour))).Code)
+	assert.Equal(t, http.StatusOK, getFeed(r, "/feed.json", map[string]string{
+		"If-None-Match":     `"other"`,
+		"If-Modified-Since": modified.UTC().Format(http.TimeFormat),
+	}).Code)
+}
+
+// TestFeedFilters tests the category, tag and author feeds.
+//
+// Test Cases:
+//  1. Category and tag feeds list the posts filed under them
+//  2. Author feeds list the author's published posts
+//  3. Unknown categories and tags are not found
+//  4. Users without published posts have no feed, like unknown users
+func TestFeedFilters(t *testing.T) {
+	r, writer, drafter := newFeedRouter(t)
+
+	assert.Equal(t, []string{"Release notes"}, feedTitles(t, getFeed(r, "/categories/news/feed.json", nil)))
+	assert.Equal(t, []string{"Release notes"}, feedTitles(t, getFeed(r, "/tags/go/feed.json", nil)))
+	assert.ElementsMatch(t, []string{"Release notes", "Office move"}, feedTitles(t, getFeed(r, "/authors/"+strconv.Itoa(int(writer.ID))+"/feed.json", nil)))
+
+	assert.Equal(t, http.StatusNotFound, getFeed(r, "/categories/sport/feed.json", nil).Code)
+	assert.Equal(t, http.StatusNotFound, getFeed(r, "/tags/rust/feed.json", nil).Code)
+	assert.Equal(t, http.StatusNotFound, getFeed(r, "/authors/"+strconv.Itoa(int(drafter.ID))+"/feed.json", nil).Code)
+	assert.Equal(t, http.StatusNotFound, getFeed(r, "/authors/999999/feed.json", nil).Code)
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/controllers"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/tests/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCreatePostValidation tests rejecting bad new posts before they reach
+// the database.
+//
+// Test Cases:
+//  1. Malformed bodies and missing titles are rejected
+//  2. Unknown content formats are rejected
+//  3. Unsupported locales are rejected
+func TestCreatePostValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// No database: every request here must fail validation first
+	pc := controllers.NewPostController(services.NewPostService(nil))
+	r := gin.New()
+	r.POST("/api/posts", pc.CreatePost)
+
+	for _, body := range []string{
+		`{`,
+		`{"content": "<p>No title</p>"}`,
+		`{"title": "Launch notes", "content_format": "rtf"}`,
+		`{"title": "Launch notes", "content": "<p>Hi</p>", "locale": "xx"}`,
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/posts", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+// TestUpdatePostCategory tests filing a post under a category and taking
+// it out again.
+//
+// Test Cases:
+//  1. category_id files the post under the category
+//  2. clear_category removes the post from its category
+//  3. clear_category can't be combined with category_id
+func TestUpdatePostCategory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testdb.Open(t, "controllers")
+	posts := services.NewPostService(db)
+	author := &models.User{Username: "writer", Email: "writer@example.com", Password: "hash", Role: models.UserRoleAuthor}
+	require.NoError(t, db.Create(author).Error)
+	news := &models.Category{Name: "News", Slug: "news", Locale: "en"}
+	require.NoError(t, db.Create(news).Error)
+	post := &models.Post{Title: "Release notes", Content: "<p>Notes</p>", AuthorID: author.ID}
+	require.NoError(t, posts.CreatePost(context.Background(), post))
+
+	pc := controllers.NewPostController(posts)
+	r := gin.New()
+	r.PUT("/api/posts/:id", func(c *gin.Context) {
+		c.Set(middleware.UserIDKey, author.ID)
+		c.Set(middleware.RoleKey, author.Role)
+	}, pc.UpdatePost)
+	update := func(body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/api/posts/"+strconv.Itoa(int(post.ID)), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	category := func() *uint {
+		var stored models.Post
+		require.NoError(t, db.First(&stored, post.ID).Error)
+		return stored.CategoryID
+	}
+
+	require.Equal(t, http.StatusOK, update(`{"category_id": `+strconv.Itoa(int(news.ID))+`}`))
+	require.NotNil(t, category())
+	assert.Equal(t, news.ID, *category())
+
+	assert.Equal(t, http.StatusBadRequest, update(`{"category_id": `+strconv.Itoa(int(news.ID))+`, "clear_category": true}`))
+	assert.NotNil(t, category())
+
+	require.Equal(t, http.StatusOK, update(`{"clear_category": true}`))
+	assert.Nil(t, category())
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sasanzare/go-cms/controllers"
+	"github.com/sasanzare/go-cms/i18n"
+	"github.com/sasanzare/go-cms/middleware"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/tests/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCreateTranslationAuthorization tests who may translate a post.
+//
+// Test Cases:
+//  1. Other authors can't translate a post, so its locales stay free
+//  2. The post's author can
+//  3. Staff can translate anyone's post
+func TestCreateTranslationAuthorization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testdb.Open(t, "controllers")
+	locales, err := i18n.New("en", []string{"en", "fa", "de"}, nil)
+	require.NoError(t, err)
+	posts := services.NewPostService(db, services.WithLocales(locales))
+	pc := controllers.NewPostController(posts)
+
+	var users []*models.User
+	for _, role := range []string{models.UserRoleAuthor, models.UserRoleAuthor, models.UserRoleEditor} {
+		user := &models.User{Username: role + strconv.Itoa(len(users)), Email: role + strconv.Itoa(len(users)) + "@example.com", Password: "hash", Role: role}
+		require.NoError(t, db.Create(user).Error)
+		users = append(users, user)
+	}
+	author, other, editor := users[0], users[1], users[2]
+	post := &models.Post{Title: "Launch notes", Content: "<p>We are launching soon.</p>", AuthorID: author.ID}
+	require.NoError(t, posts.CreatePost(context.Background(), post))
+
+	translate := func(user *models.User, locale string) int {
+		r := gin.New()
+		r.POST("/api/posts/:id/translations", func(c *gin.Context) {
+			c.Set(middleware.UserIDKey, user.ID)
+			c.Set(middleware.RoleKey, user.Role)
+		}, pc.CreateTranslation)
+		body := `{"locale": "` + locale + `", "title": "Launch notes ` + locale + `", "content": "<p>Soon.</p>"}`
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/posts/"+strconv.Itoa(int(post.ID))+"/translations", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, translate(other, "fa"))
+	assert.Equal(t, http.StatusCreated, translate(author, "fa"))
+	assert.Equal(t, http.StatusCreated, translate(editor, "de"))
+}
@@ -0,0 +1,45 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sasanzare/go-cms/events"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBusPublish tests delivering events to subscribers.
+//
+// Test Cases:
+//  1. Handlers receive only events of their type, in subscription order
+//  2. A failing or panicking handler doesn't stop the others
+//  3. Publishing on a nil bus does nothing
+func TestBusPublish(t *testing.T) {
+	bus := events.NewBus()
+	var calls []string
+
+	events.Subscribe(bus, func(ctx context.Context, e events.PostApproved) error {
+		calls = append(calls, "first")
+		return errors.New("failed")
+	})
+	events.Subscribe(bus, func(ctx context.Context, e events.PostApproved) error {
+		panic("boom")
+	})
+	events.Subscribe(bus, func(ctx context.Context, e events.PostApproved) error {
+		calls = append(calls, "third:"+e.Title)
+		return nil
+	})
+	events.Subscribe(bus, func(ctx context.Context, e events.PostRejected) error {
+		calls = append(calls, "rejected")
+		return nil
+	})
+
+	bus.Publish(context.Background(), events.PostApproved{PostID: 1, Title: "Hello"})
+	assert.Equal(t, []string{"first", "third:Hello"}, calls)
+
+	var nilBus *events.Bus
+	assert.NotPanics(t, func() {
+		nilBus.Publish(context.Background(), events.PostApproved{})
+	})
+}
@@ -0,0 +1,178 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sasanzare/go-cms/feed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// content has markup, entities and text that would end a CDATA section.
+const content = `<p>Tom &amp; Jerry say <b>"hi"</b> &lt;3</p><pre>a ]]> b</pre>`
+
+func sampleFeed() *feed.Feed {
+	published := time.Date(2024, time.August, 2, 9, 30, 0, 0, time.FixedZone("IRST", 3*3600+1800))
+	return &feed.Feed{
+		Title:       "Go CMS & friends",
+		Description: "Latest posts",
+		Link:        "https://cms.example.com/",
+		URL:         "https://cms.example.com/feed.rss?lang=fa",
+		Language:    "fa",
+		Updated:     published.Add(time.Hour),
+		Items: []feed.Item{{
+			ID:         "https://cms.example.com/posts/1",
+			Title:      "Cats <3 dogs",
+			Link:       "https://cms.example.com/posts/1",
+			Summary:    "Tom & Jerry\x00 say hi",
+			Content:    content,
+			Author:     "Sara Ahmadi",
+			Categories: []string{"News"},
+			Published:  published,
+			Updated:    published.Add(time.Hour),
+		}},
+	}
+}
+
+// TestWriteRSS tests writing RSS 2.0 feeds.
+//
+// Test Cases:
+//  1. Content, titles and summaries read back exactly as given
+//  2. Characters XML can't hold are replaced
+//  3. Dates are RFC 822 in UTC and the feed links to itself
+func TestWriteRSS(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, feed.WriteRSS(&buf, sampleFeed()))
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, xml.Header))
+	assert.NotContains(t, out, "<b>")
+
+	var doc struct {
+		Channel struct {
+			Title         string `xml:"title"`
+			Language      string `xml:"language"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Title       string `xml:"title"`
+				GUID        string `xml:"guid"`
+				PubDate     string `xml:"pubDate"`
+				Description string `xml:"description"`
+				Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+				Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "Go CMS & friends", doc.Channel.Title)
+	assert.Equal(t, "fa", doc.Channel.Language)
+	assert.Equal(t, "Fri, 02 Aug 2024 07:00:00 +0000", doc.Channel.LastBuildDate)
+	require.Len(t, doc.Channel.Items, 1)
+	item := doc.Channel.Items[0]
+	assert.Equal(t, "Cats <3 dogs", item.Title)
+	assert.Equal(t, content, item.Content)
+	assert.Equal(t, "Tom & Jerry� say hi", item.Description)
+	assert.Equal(t, "Sara Ahmadi", item.Creator)
+	assert.Equal(t, "Fri, 02 Aug 2024 06:00:00 +0000", item.PubDate)
+	assert.Contains(t, out, `<guid isPermaLink="true">https://cms.example.com/posts/1</guid>`)
+	assert.Contains(t, out, `<atom:link rel="self" type="application/rss+xml" href="https://cms.example.com/feed.rss?lang=fa"></atom:link>`)
+}
+
+// TestWriteAtom tests writing Atom feeds.
+//
+// Test Cases:
+//  1. Content is escaped HTML that reads back exactly as given
+//  2. Entries carry RFC 3339 dates, an author and categories
+//  3. Items without content have no content element
+func TestWriteAtom(t *testing.T) {
+	f := sampleFeed()
+	f.Items = append(f.Items, feed.Item{ID: "urn:2", Title: "Short", Summary: "Only a summary", Published: f.Updated})
+
+	var buf bytes.Buffer
+	require.NoError(t, feed.WriteAtom(&buf, f))
+
+	type text struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	}
+	var doc struct {
+		Lang    string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+		Updated string `xml:"updated"`
+		Entries []struct {
+			ID        string `xml:"id"`
+			Published string `xml:"published"`
+			Updated   string `xml:"updated"`
+			Author    string `xml:"author>name"`
+			Category  []struct {
+				Term string `xml:"term,attr"`
+			} `xml:"category"`
+			Summary *text `xml:"summary"`
+			Content *text `xml:"content"`
+		} `xml:"entry"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "fa", doc.Lang)
+	assert.Equal(t, "2024-08-02T07:00:00Z", doc.Updated)
+	require.Len(t, doc.Entries, 2)
+
+	entry := doc.Entries[0]
+	assert.Equal(t, "2024-08-02T06:00:00Z", entry.Published)
+	assert.Equal(t, "Sara Ahmadi", entry.Author)
+	require.Len(t, entry.Category, 1)
+	assert.Equal(t, "News", entry.Category[0].Term)
+	require.NotNil(t, entry.Content)
+	assert.Equal(t, "html", entry.Content.Type)
+	assert.Equal(t, content, entry.Content.Value)
+
+	assert.Nil(t, doc.Entries[1].Content)
+	assert.Equal(t, "Only a summary", doc.Entries[1].Summary.Value)
+	assert.Equal(t, "2024-08-02T07:00:00Z", doc.Entries[1].Updated)
+}
+
+// TestWriteJSON tests writing JSON Feed documents.
+//
+// Test Cases:
+//  1. Content reads back exactly as given
+//  2. Items without content carry their summary as content_text
+//  3. Empty feeds still list items
+func TestWriteJSON(t *testing.T) {
+	f := sampleFeed()
+	f.Items = append(f.Items, feed.Item{ID: "urn:2", Title: "Short", Summary: "Only a summary"})
+
+	var buf bytes.Buffer
+	require.NoError(t, feed.WriteJSON(&buf, f))
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "https://jsonfeed.org/version/1.1", doc["version"])
+	assert.Equal(t, "fa", doc["language"])
+	items := doc["items"].([]interface{})
+	require.Len(t, items, 2)
+	first := items[0].(map[string]interface{})
+	assert.Equal(t, content, first["content_html"])
+	assert.Equal(t, "2024-08-02T06:00:00Z", first["date_published"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "Sara Ahmadi"}}, first["authors"])
+	assert.Equal(t, "Only a summary", items[1].(map[string]interface{})["content_text"])
+
+	buf.Reset()
+	require.NoError(t, feed.WriteJSON(&buf, &feed.Feed{Title: "Empty"}))
+	assert.Contains(t, buf.String(), `"items": []`)
+}
+
+// TestWrite tests picking the writer by format.
+//
+// Test Cases:
+//  1. Every format has a media type
+//  2. Unknown formats are an error
+func TestWrite(t *testing.T) {
+	assert.Equal(t, "application/rss+xml; charset=utf-8", feed.ContentType(feed.FormatRSS))
+	assert.Equal(t, "application/atom+xml; charset=utf-8", feed.ContentType(feed.FormatAtom))
+	assert.Equal(t, "application/feed+json; charset=utf-8", feed.ContentType(feed.FormatJSON))
+
+	var buf bytes.Buffer
+	assert.EqualError(t, feed.Write(&buf, sampleFeed(), "opml"), `unknown feed format "opml"`)
+}
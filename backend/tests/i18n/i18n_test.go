@@ -0,0 +1,101 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/sasanzare/go-cms/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func siteLocales(t *testing.T) *i18n.Locales {
+	locales, err := i18n.New("en", []string{"fa", "ps", "en"}, map[string][]string{"ps": {"fa"}})
+	require.NoError(t, err)
+	return locales
+}
+
+// TestNew tests configuring a site's locales.
+//
+// Test Cases:
+//  1. The default comes first and duplicates are dropped
+//  2. Tags are normalized
+//  3. Invalid tags and fallbacks to unsupported locales are rejected
+func TestNew(t *testing.T) {
+	locales := siteLocales(t)
+	assert.Equal(t, "en", locales.Default())
+	assert.Equal(t, []string{"en", "fa", "ps"}, locales.Supported())
+
+	locales, err := i18n.New("EN_us", []string{"fa_IR"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"en-us", "fa-ir"}, locales.Supported())
+
+	_, err = i18n.New("", nil, nil)
+	assert.EqualError(t, err, `invalid default locale ""`)
+	_, err = i18n.New("en", []string{"english!"}, nil)
+	assert.EqualError(t, err, `invalid locale "english!"`)
+	_, err = i18n.New("en", []string{"fa"}, map[string][]string{"ps": {"fa"}})
+	assert.EqualError(t, err, `fallbacks given for unsupported locale "ps"`)
+	_, err = i18n.New("en", []string{"fa"}, map[string][]string{"fa": {"ar"}})
+	assert.EqualError(t, err, `unsupported fallback locale "ar" for fa`)
+}
+
+// TestNormalize tests normalizing language tags.
+//
+// Test Cases:
+//  1. Case and separators are normalized
+//  2. Malformed tags give ""
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "fa-ir", i18n.Normalize(" fa_IR "))
+	assert.Equal(t, "en", i18n.Normalize("EN"))
+	assert.Equal(t, "", i18n.Normalize("english"))
+	assert.Equal(t, "", i18n.Normalize("e"))
+	assert.Equal(t, "", i18n.Normalize("*"))
+}
+
+// TestParseAcceptLanguage tests reading Accept-Language headers.
+//
+// Test Cases:
+//  1. Tags are ordered by quality, ties keeping header order
+//  2. q=0, the wildcard and malformed weights are left out
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{"fa-IR", "fa", "en"},
+		i18n.ParseAcceptLanguage("en;q=0.5, fa-IR, fa;q=0.8"))
+	assert.Equal(t, []string{"de", "fa"},
+		i18n.ParseAcceptLanguage("de, fa, en;q=0, *;q=0.1, ar;q=x"))
+	assert.Empty(t, i18n.ParseAcceptLanguage(""))
+}
+
+// TestNegotiate tests picking the locale of a request.
+//
+// Test Cases:
+//  1. ?lang= wins over Accept-Language
+//  2. Regional tags match their base language
+//  3. Unsupported or invalid preferences fall back to the default
+func TestNegotiate(t *testing.T) {
+	locales := siteLocales(t)
+
+	assert.Equal(t, "fa", locales.Negotiate("fa", "en"))
+	assert.Equal(t, "fa", locales.Negotiate("", "de, fa-IR;q=0.9, en;q=0.8"))
+	assert.Equal(t, "ps", locales.Negotiate("english", "ps"))
+	assert.Equal(t, "en", locales.Negotiate("de", "ar, tr"))
+	assert.Equal(t, "en", locales.Negotiate("", ""))
+
+	locale, ok := locales.Match("FA_af")
+	assert.True(t, ok)
+	assert.Equal(t, "fa", locale)
+	_, ok = locales.Match("ar")
+	assert.False(t, ok)
+}
+
+// TestChain tests fallback chains.
+//
+// Test Cases:
+//  1. A locale falls back to its configured locales, then the default
+//  2. The default is not repeated
+func TestChain(t *testing.T) {
+	locales := siteLocales(t)
+
+	assert.Equal(t, []string{"ps", "fa", "en"}, locales.Chain("ps"))
+	assert.Equal(t, []string{"fa", "en"}, locales.Chain("fa"))
+	assert.Equal(t, []string{"en"}, locales.Chain("en"))
+}
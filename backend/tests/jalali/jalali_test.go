@@ -0,0 +1,152 @@
+package jalali
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sasanzare/go-cms/jalali"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConversion tests converting between the Gregorian and Jalali
+// calendars.
+//
+// Test Cases:
+//  1. Known dates, including Nowruz and the last day of leap years,
+//     convert both ways
+//  2. Every day of two centuries round-trips to a distinct valid date
+//  3. The Jalali date is that of the time's location
+func TestConversion(t *testing.T) {
+	for gregorian, want := range map[string]string{
+		"1979-02-11": "1357/11/22",
+		"2000-01-01": "1378/10/11",
+		"2023-03-20": "1401/12/29",
+		"2023-03-21": "1402/01/01",
+		"2024-03-20": "1403/01/01",
+		"2024-08-02": "1403/05/12",
+		"2025-03-20": "1403/12/30",
+		"2025-03-21": "1404/01/01",
+	} {
+		day, err := time.Parse("2006-01-02", gregorian)
+		require.NoError(t, err)
+		date := jalali.FromTime(day)
+		assert.Equal(t, want, date.String(), gregorian)
+		assert.Equal(t, day, date.Time(time.UTC), want)
+	}
+
+	day := time.Date(1925, time.January, 1, 0, 0, 0, 0, time.UTC)
+	prev := jalali.FromTime(day.AddDate(0, 0, -1))
+	for ; day.Year() < 2125; day = day.AddDate(0, 0, 1) {
+		date := jalali.FromTime(day)
+		require.Equal(t, day, date.Time(time.UTC), date.String())
+		_, err := jalali.New(date.Year, date.Month, date.Day)
+		require.NoError(t, err, date.String())
+		require.NotEqual(t, prev, date)
+		prev = date
+	}
+
+	tehran := time.FixedZone("IRST", 3*3600+1800)
+	late := time.Date(2024, time.March, 19, 22, 0, 0, 0, time.UTC)
+	assert.Equal(t, "1402/12/29", jalali.FromTime(late).String())
+	assert.Equal(t, "1403/01/01", jalali.FromTime(late.In(tehran)).String())
+}
+
+// TestLeapYears tests leap years and month lengths.
+//
+// Test Cases:
+//  1. 1399 and 1403 are leap years, 1400 to 1402 and 1404 aren't
+//  2. Esfand has 30 days in leap years only
+func TestLeapYears(t *testing.T) {
+	for _, year := range []int{1395, 1399, 1403, 1408} {
+		assert.True(t, jalali.IsLeap(year), year)
+	}
+	for _, year := range []int{1400, 1401, 1402, 1404} {
+		assert.False(t, jalali.IsLeap(year), year)
+	}
+
+	assert.Equal(t, 31, jalali.DaysIn(1402, 6))
+	assert.Equal(t, 30, jalali.DaysIn(1402, 7))
+	assert.Equal(t, 29, jalali.DaysIn(1402, 12))
+	assert.Equal(t, 30, jalali.DaysIn(1403, 12))
+}
+
+// TestParse tests reading Jalali dates.
+//
+// Test Cases:
+//  1. Separators and Persian or Arabic-Indic digits are accepted
+//  2. Malformed and nonexistent dates are rejected
+func TestParse(t *testing.T) {
+	want := jalali.Date{Year: 1403, Month: 5, Day: 12}
+	for _, raw := range []string{"1403/05/12", "1403-5-12", " 1403.05.12 ", "۱۴۰۳/۰۵/۱۲", "١٤٠٣/٠٥/١٢"} {
+		date, err := jalali.Parse(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, want, date, raw)
+	}
+
+	for raw, msg := range map[string]string{
+		"1403/05":    "date must be written year/month/day",
+		"1403/xx/12": `invalid number "xx"`,
+		"1403/13/01": "month 13 out of range",
+		"1402/12/30": "day 30 out of range for 1402/12",
+		"0/01/01":    "year 0 out of range",
+	} {
+		_, err := jalali.Parse(raw)
+		assert.EqualError(t, err, msg, raw)
+	}
+}
+
+// TestFormat tests formatting Jalali dates.
+//
+// Test Cases:
+//  1. Long dates name the month, in Persian script when asked
+//  2. Digits convert between scripts
+func TestFormat(t *testing.T) {
+	date := jalali.Date{Year: 1403, Month: 5, Day: 2}
+	assert.Equal(t, "1403/05/02", date.String())
+	assert.Equal(t, "2 Mordad 1403", date.Long(false))
+	assert.Equal(t, "۲ مرداد ۱۴۰۳", date.Long(true))
+
+	assert.Equal(t, "۱۲:۳۰", jalali.PersianDigits("12:30"))
+	assert.Equal(t, "12:30", jalali.LatinDigits("۱۲:۳۰"))
+	assert.Equal(t, "", jalali.MonthName(13, false))
+}
+
+// TestArchiveRange tests the bounds of archive periods.
+//
+// Test Cases:
+//  1. Jalali months and years start on their first day in the location
+//  2. Esfand ends at Nowruz
+//  3. Gregorian periods work alike
+//  4. Bad calendars, months and years are rejected
+func TestArchiveRange(t *testing.T) {
+	tehran := time.FixedZone("IRST", 3*3600+1800)
+
+	from, to, err := services.ArchiveRange(services.CalendarJalali, 1403, 5, tehran)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.July, 22, 0, 0, 0, 0, tehran), from)
+	assert.Equal(t, time.Date(2024, time.August, 22, 0, 0, 0, 0, tehran), to)
+
+	from, to, err = services.ArchiveRange(services.CalendarJalali, 1403, 12, tehran)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, time.February, 19, 0, 0, 0, 0, tehran), from)
+	assert.Equal(t, time.Date(2025, time.March, 21, 0, 0, 0, 0, tehran), to)
+
+	from, to, err = services.ArchiveRange(services.CalendarJalali, 1403, 0, tehran)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.March, 20, 0, 0, 0, 0, tehran), from)
+	assert.Equal(t, time.Date(2025, time.March, 21, 0, 0, 0, 0, tehran), to)
+
+	from, to, err = services.ArchiveRange(services.CalendarGregorian, 2024, 12, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.December, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), to)
+
+	_, _, err = services.ArchiveRange("hebrew", 5784, 1, time.UTC)
+	assert.EqualError(t, err, "Validation failed: calendar must be gregorian or jalali")
+	_, _, err = services.ArchiveRange(services.CalendarJalali, 1403, 13, time.UTC)
+	assert.EqualError(t, err, "Validation failed: month must be between 1 and 12")
+	_, _, err = services.ArchiveRange(services.CalendarJalali, 0, 1, time.UTC)
+	assert.EqualError(t, err, "Validation failed: year out of range")
+}
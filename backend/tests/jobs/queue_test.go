@@ -0,0 +1,263 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sasanzare/go-cms/jobs"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/tests/testdb"
+	"github.com/sasanzare/go-cms/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type greetArgs struct {
+	Name string `json:"name"`
+}
+
+func (greetArgs) Kind() string { return "test.greet" }
+
+func newJob(t *testing.T, args jobs.Args) *models.Job {
+	payload, err := json.Marshal(args)
+	require.NoError(t, err)
+	return &models.Job{Kind: args.Kind(), Payload: payload}
+}
+
+// TestBackoff tests retry delays.
+//
+// Test Cases:
+//  1. Delays double with each attempt, plus at most 20% jitter
+//  2. Delays are capped at one hour
+func TestBackoff(t *testing.T) {
+	testCases := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{0, 10 * time.Second},
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{4, 80 * time.Second},
+		{12, time.Hour},
+		{100, time.Hour},
+	}
+
+	for _, tc := range testCases {
+		for i := 0; i < 20; i++ {
+			d := jobs.Backoff(tc.attempt)
+			assert.GreaterOrEqual(t, d, tc.base, "attempt %d", tc.attempt)
+			assert.LessOrEqual(t, d, tc.base+tc.base/5, "attempt %d", tc.attempt)
+		}
+	}
+}
+
+// TestPerform tests running jobs through registered handlers.
+//
+// Test Cases:
+//  1. The payload is decoded into the handler's argument type
+//  2. Jobs without a handler fail
+//  3. Panicking handlers return an error instead of crashing the worker
+func TestPerform(t *testing.T) {
+	queue := jobs.New(nil, utils.RealClock{}, jobs.Config{})
+	ctx := context.Background()
+
+	var got string
+	jobs.Register(queue, func(ctx context.Context, args greetArgs) error {
+		if args.Name == "panic" {
+			panic("boom")
+		}
+		got = args.Name
+		return nil
+	})
+
+	require.NoError(t, queue.Perform(ctx, newJob(t, greetArgs{Name: "Sara"})))
+	assert.Equal(t, "Sara", got)
+
+	err := queue.Perform(ctx, &models.Job{Kind: "test.unknown", Payload: json.RawMessage(`{}`)})
+	assert.ErrorContains(t, err, "no handler")
+
+	err = queue.Perform(ctx, newJob(t, greetArgs{Name: "panic"}))
+	assert.ErrorContains(t, err, "boom")
+}
+
+type failArgs struct{}
+
+func (failArgs) Kind() string { return "test.fail" }
+
+func newQueue(t *testing.T, config jobs.Config) (*jobs.Queue, *utils.ManualClock, *gorm.DB) {
+	t.Helper()
+	db := testdb.Open(t, "jobs")
+	clock := utils.NewManualClock(time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC))
+	config.MaxAttempts = 3
+	return jobs.New(db, clock, config), clock, db
+}
+
+// TestUniqueJobs tests that unique keys dedupe queued jobs.
+//
+// Test Cases:
+//  1. A second job with a queued job's key is refused with ErrDuplicate
+//  2. Jobs with other keys or no key are queued
+//  3. The key is free again once its job completed
+func TestUniqueJobs(t *testing.T) {
+	queue, _, db := newQueue(t, jobs.Config{})
+	ctx := context.Background()
+	jobs.Register(queue, func(ctx context.Context, args greetArgs) error { return nil })
+
+	first, err := queue.Enqueue(ctx, greetArgs{Name: "Sara"}, jobs.Unique("greet"))
+	require.NoError(t, err)
+	_, err = queue.Enqueue(ctx, greetArgs{Name: "Reza"}, jobs.Unique("greet"))
+	assert.ErrorIs(t, err, jobs.ErrDuplicate)
+
+	_, err = queue.Enqueue(ctx, greetArgs{Name: "Reza"}, jobs.Unique("greet-other"))
+	require.NoError(t, err)
+	_, err = queue.Enqueue(ctx, greetArgs{Name: "Reza"})
+	require.NoError(t, err)
+	_, err = queue.Enqueue(ctx, greetArgs{Name: "Reza"})
+	require.NoError(t, err)
+
+	var count int64
+	require.NoError(t, db.Model(&models.Job{}).Count(&count).Error)
+	assert.Equal(t, int64(4), count)
+
+	// Jobs run in order, so the first claimed is the unique one
+	ran, err := queue.RunNext(ctx, jobs.DefaultQueue)
+	require.NoError(t, err)
+	require.True(t, ran)
+	require.ErrorIs(t, db.First(&models.Job{}, first.ID).Error, gorm.ErrRecordNotFound)
+
+	_, err = queue.Enqueue(ctx, greetArgs{Name: "Reza"}, jobs.Unique("greet"))
+	assert.NoError(t, err)
+}
+
+// TestRetryToDead tests failing jobs through their retries to the dead
+// jobs table.
+//
+// Test Cases:
+//  1. A failed attempt puts the job back, pending after a backoff, with its error
+//  2. The job isn't claimed before its backoff ends
+//  3. The last failed attempt moves the job to dead jobs with its error
+//  4. A dead job can be retried with fresh attempts
+func TestRetryToDead(t *testing.T) {
+	queue, clock, db := newQueue(t, jobs.Config{})
+	ctx := context.Background()
+	jobs.Register(queue, func(ctx context.Context, args failArgs) error {
+		return errors.New("endpoint unavailable")
+	})
+
+	enqueued, err := queue.Enqueue(ctx, failArgs{}, jobs.MaxAttempts(2), jobs.Unique("fail"))
+	require.NoError(t, err)
+
+	ran, err := queue.RunNext(ctx, jobs.DefaultQueue)
+	require.NoError(t, err)
+	require.True(t, ran)
+
+	var job models.Job
+	require.NoError(t, db.First(&job, enqueued.ID).Error)
+	assert.Equal(t, models.JobStatusPending, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "endpoint unavailable", job.LastError)
+	assert.True(t, job.RunAt.After(clock.Now()), "retry is delayed")
+	assert.Nil(t, job.LockedAt)
+
+	ran, err = queue.RunNext(ctx, jobs.DefaultQueue)
+	require.NoError(t, err)
+	assert.False(t, ran, "job is claimed during its backoff")
+
+	clock.Set(job.RunAt)
+	ran, err = queue.RunNext(ctx, jobs.DefaultQueue)
+	require.NoError(t, err)
+	require.True(t, ran)
+
+	require.ErrorIs(t, db.First(&models.Job{}, enqueued.ID).Error, gorm.ErrRecordNotFound)
+	var dead models.DeadJob
+	require.NoError(t, db.Where("job_id = ?", enqueued.ID).First(&dead).Error)
+	assert.Equal(t, "test.fail", dead.Kind)
+	assert.Equal(t, 2, dead.Attempts)
+	assert.Equal(t, "endpoint unavailable", dead.LastError)
+	assert.Equal(t, clock.Now().Unix(), dead.FailedAt.Unix())
+	require.NotNil(t, dead.UniqueKey)
+	assert.Equal(t, "fail", *dead.UniqueKey)
+
+	retried, err := queue.RetryDeadJob(ctx, dead.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, retried.Attempts)
+	assert.Equal(t, 3, retried.MaxAttempts)
+	require.ErrorIs(t, db.First(&models.DeadJob{}, dead.ID).Error, gorm.ErrRecordNotFound)
+}
+
+// TestUniqueWhileRunning tests unique jobs enqueued while their twin runs.
+//
+// Test Cases:
+//  1. A running job doesn't block a job with its key, which may be for
+//     work the running one started too early to see
+//  2. A failing job whose twin is pending is dropped instead of retried
+func TestUniqueWhileRunning(t *testing.T) {
+	queue, _, db := newQueue(t, jobs.Config{})
+	ctx := context.Background()
+	var enqueueErr error
+	jobs.Register(queue, func(ctx context.Context, args greetArgs) error {
+		_, enqueueErr = queue.Enqueue(ctx, greetArgs{Name: "again"}, jobs.Unique("greet"))
+		if args.Name == "fail" {
+			return errors.New("greeting failed")
+		}
+		return nil
+	})
+
+	_, err := queue.Enqueue(ctx, greetArgs{Name: "Sara"}, jobs.Unique("greet"))
+	require.NoError(t, err)
+	ran, err := queue.RunNext(ctx, jobs.DefaultQueue)
+	require.NoError(t, err)
+	require.True(t, ran)
+	require.NoError(t, enqueueErr)
+
+	var pending []models.Job
+	require.NoError(t, db.Find(&pending).Error)
+	require.Len(t, pending, 1)
+	assert.JSONEq(t, `{"name": "again"}`, string(pending[0].Payload))
+	require.NoError(t, db.Delete(&pending[0]).Error)
+
+	failing, err := queue.Enqueue(ctx, greetArgs{Name: "fail"}, jobs.Unique("greet"))
+	require.NoError(t, err)
+	ran, err = queue.RunNext(ctx, jobs.DefaultQueue)
+	require.NoError(t, err)
+	require.True(t, ran)
+	require.NoError(t, enqueueErr)
+
+	require.ErrorIs(t, db.First(&models.Job{}, failing.ID).Error, gorm.ErrRecordNotFound)
+	var count int64
+	require.NoError(t, db.Model(&models.Job{}).Where("status = ?", models.JobStatusPending).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+// TestHeartbeat tests that running jobs keep renewing their lock.
+//
+// Test Cases:
+//  1. A job running longer than the heartbeat interval has its lock renewed
+func TestHeartbeat(t *testing.T) {
+	queue, clock, db := newQueue(t, jobs.Config{RescueAfter: 30 * time.Millisecond})
+	ctx := context.Background()
+	var lockedAt *time.Time
+	jobs.Register(queue, func(ctx context.Context, args greetArgs) error {
+		renewed := clock.Advance(time.Hour)
+		require.Eventually(t, func() bool {
+			var job models.Job
+			if err := db.Where("kind = ?", args.Kind()).First(&job).Error; err != nil {
+				return false
+			}
+			lockedAt = job.LockedAt
+			return lockedAt != nil && !lockedAt.Before(renewed)
+		}, time.Second, 5*time.Millisecond)
+		return nil
+	})
+
+	_, err := queue.Enqueue(ctx, greetArgs{Name: "Sara"})
+	require.NoError(t, err)
+	ran, err := queue.RunNext(ctx, jobs.DefaultQueue)
+	require.NoError(t, err)
+	require.True(t, ran)
+	require.NotNil(t, lockedAt)
+}
@@ -0,0 +1,88 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"io"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sasanzare/go-cms/mail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMemoryMailer tests capturing emails in memory.
+//
+// Test Cases:
+//  1. Sent emails are recorded per recipient in order
+//  2. Links are extracted from the text body
+//  3. Err makes Send fail without recording
+func TestMemoryMailer(t *testing.T) {
+	mailer := mail.NewMemoryMailer()
+	ctx := context.Background()
+
+	require.NoError(t, mailer.Send(ctx, &mail.Email{To: "a@example.com", Subject: "First", Text: "one"}))
+	require.NoError(t, mailer.Send(ctx, &mail.Email{To: "b@example.com", Subject: "Other"}))
+	require.NoError(t, mailer.Send(ctx, &mail.Email{
+		To:      "a@example.com",
+		Subject: "Second",
+		Text:    "Open https://example.com/verify?token=abc. Or https://example.com/help, thanks",
+	}))
+
+	assert.Len(t, mailer.Emails(), 3)
+	assert.Len(t, mailer.SentTo("a@example.com"), 2)
+
+	last, ok := mailer.Last("a@example.com")
+	require.True(t, ok)
+	assert.Equal(t, "Second", last.Subject)
+	assert.Equal(t, []string{"https://example.com/verify?token=abc", "https://example.com/help"}, last.Links())
+
+	_, ok = mailer.Last("nobody@example.com")
+	assert.False(t, ok)
+
+	mailer.Err = errors.New("down")
+	assert.Error(t, mailer.Send(ctx, &mail.Email{To: "a@example.com"}))
+	mailer.Reset()
+	assert.Empty(t, mailer.Emails())
+}
+
+// TestFileMailer tests writing emails as .eml files.
+//
+// Test Cases:
+//  1. Each email becomes one .eml file
+//  2. The file is a valid message with headers and both bodies
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer := mail.NewFileMailer(filepath.Join(dir, "out"))
+
+	err := mailer.Send(context.Background(), &mail.Email{
+		From:    "cms@example.com",
+		To:      "sara@example.com",
+		Subject: "Hello",
+		Text:    "Plain body",
+		HTML:    "<p>HTML body</p>",
+	})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "out", "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+
+	msg, err := netmail.ReadMessage(f)
+	require.NoError(t, err)
+	assert.Equal(t, "sara@example.com", msg.Header.Get("To"))
+	assert.Equal(t, "Hello", msg.Header.Get("Subject"))
+	assert.Contains(t, msg.Header.Get("Content-Type"), "multipart/alternative")
+
+	body, err := io.ReadAll(msg.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "Plain body")
+	assert.Contains(t, string(body), "<p>HTML body</p>")
+}
@@ -0,0 +1,83 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sasanzare/go-cms/mail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRenderer(t *testing.T) *mail.Renderer {
+	r, err := mail.NewRenderer(mail.Site{
+		Name:         "Go CMS",
+		URL:          "https://cms.example.com",
+		SupportEmail: "help@example.com",
+	})
+	require.NoError(t, err)
+	return r
+}
+
+// TestRender tests rendering templates per locale.
+//
+// Test Cases:
+//  1. Every template renders in every locale with its sample data
+//  2. Persian emails are right to left and use Persian digits
+//  3. Data is HTML-escaped and unknown locales fall back to English
+func TestRender(t *testing.T) {
+	r := newRenderer(t)
+	assert.Equal(t, []string{"en", "fa"}, r.Locales())
+
+	for _, locale := range r.Locales() {
+		for _, name := range r.Templates() {
+			msg, err := r.Render(locale, name, r.SampleData(name))
+			require.NoError(t, err, "%s/%s", locale, name)
+			assert.NotEmpty(t, msg.Subject)
+			assert.Contains(t, msg.HTML, "Go CMS")
+			assert.NotContains(t, msg.Text, "<")
+		}
+	}
+
+	msg, err := r.Render("fa", mail.TemplatePasswordReset, r.SampleData(mail.TemplatePasswordReset))
+	require.NoError(t, err)
+	assert.Contains(t, msg.HTML, `dir="rtl"`)
+	assert.Contains(t, msg.Text, "۲۴")
+
+	msg, err = r.Render("de", mail.TemplateWelcome, map[string]interface{}{"Name": "<b>Sara</b>"})
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome to Go CMS!", msg.Subject)
+	assert.Contains(t, msg.HTML, `dir="ltr"`)
+	assert.Contains(t, msg.HTML, "&lt;b&gt;Sara&lt;/b&gt;")
+
+	_, err = r.Render("en", "missing", nil)
+	assert.Error(t, err)
+}
+
+// TestHTMLToText tests the plain-text alternative.
+//
+// Test Cases:
+//  1. Paragraphs are separated by blank lines
+//  2. Links keep their URL and list items get a dash
+//  3. Styles and the document head are dropped
+func TestHTMLToText(t *testing.T) {
+	text := mail.HTMLToText(`<html><head><title>T</title><style>p{}</style></head><body>
+		<p>Hello   <b>Sara</b>,</p>
+		<p>Click <a href="https://example.com/x">here</a> or visit <a href="https://example.com">https://example.com</a>.</p>
+		<ul><li>One</li><li>Two</li></ul>
+		<p>Bye<br>Team</p>
+	</body></html>`)
+
+	want := strings.Join([]string{
+		"Hello Sara,",
+		"",
+		"Click here (https://example.com/x) or visit https://example.com.",
+		"",
+		"- One",
+		"- Two",
+		"",
+		"Bye",
+		"Team",
+	}, "\n") + "\n"
+	assert.Equal(t, want, text)
+}
@@ -0,0 +1,85 @@
+package markup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sasanzare/go-cms/markup"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/sanitize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRenderMarkdown tests rendering Markdown posts.
+//
+// Test Cases:
+//  1. GFM tables keep their alignment through the post sanitizer
+//  2. Fenced code in a known language is highlighted with classes
+//  3. Code in an unknown language is escaped, not highlighted
+//  4. Raw HTML passes through to be sanitized afterwards
+func TestRenderMarkdown(t *testing.T) {
+	policy := sanitize.Post()
+	render := func(source string) string {
+		out, err := markup.Render(models.PostFormatMarkdown, source)
+		require.NoError(t, err)
+		return policy.Sanitize(out)
+	}
+
+	table := render("| Name | Qty |\n|:-----|----:|\n| tea | 2 |\n")
+	assert.Contains(t, table, `<th align="left">Name</th>`)
+	assert.Contains(t, table, `<td align="right">2</td>`)
+
+	code := render("```go\nfunc main() {}\n```\n")
+	assert.Contains(t, code, `<pre class="chroma">`)
+	assert.Contains(t, code, `<span class="kd">func</span>`)
+
+	plain := render("```nosuchlang\n<b>x</b>\n```\n")
+	assert.Equal(t, "<pre><code class=\"language-nosuchlang\">&lt;b&gt;x&lt;/b&gt;\n</code></pre>\n", plain)
+
+	assert.Equal(t, "<p>Hi </p>\n", render("Hi <script>alert(1)</script>"))
+}
+
+// TestRenderFormats tests the HTML and plain text formats.
+//
+// Test Cases:
+//  1. HTML is passed through unchanged
+//  2. Plain text is escaped, with paragraphs and line breaks
+//  3. Unknown formats fail
+func TestRenderFormats(t *testing.T) {
+	out, err := markup.Render(models.PostFormatHTML, "<p>x</p>")
+	require.NoError(t, err)
+	assert.Equal(t, "<p>x</p>", out)
+
+	out, err = markup.Render(models.PostFormatPlain, "AT&T\nline two\n\n\nnext <p>")
+	require.NoError(t, err)
+	assert.Equal(t, "<p>AT&amp;T<br>\nline two</p>\n<p>next &lt;p&gt;</p>\n", out)
+
+	_, err = markup.Render("rtf", "x")
+	assert.Error(t, err)
+}
+
+// TestWordCount tests counting words and reading times.
+//
+// Test Cases:
+//  1. Contractions, hyphenated words and numbers count once
+//  2. Han characters count as a word each; Persian words as words
+//  3. Reading time rounds up and is zero only for no words
+func TestWordCount(t *testing.T) {
+	assert.Equal(t, 5, markup.WordCount("Don't stop — well-known 42 times."))
+	assert.Equal(t, 3, markup.WordCount("中文 test!"))
+	assert.Equal(t, 3, markup.WordCount("سلام دنیای زیبا"))
+	assert.Equal(t, 0, markup.WordCount(" \n "))
+
+	assert.Equal(t, 0, markup.ReadingTime(0))
+	assert.Equal(t, 1, markup.ReadingTime(1))
+	assert.Equal(t, 1, markup.ReadingTime(200))
+	assert.Equal(t, 2, markup.ReadingTime(201))
+}
+
+// TestWriteHighlightCSS tests the stylesheet for highlighted code.
+func TestWriteHighlightCSS(t *testing.T) {
+	var css strings.Builder
+	require.NoError(t, markup.WriteHighlightCSS(&css))
+	assert.Contains(t, css.String(), ".chroma")
+}
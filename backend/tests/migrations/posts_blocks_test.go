@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sasanzare/go-cms/blocks"
+	"github.com/sasanzare/go-cms/migrations"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPostToBlocks tests converting posts to block content.
+//
+// Test Cases:
+//  1. HTML posts become paragraph blocks and their content their text
+//  2. Long posts are split between elements into several paragraphs
+//  3. Markdown and plain text posts keep their source
+//  4. Posts without text are left as they are
+//  5. A single element over the paragraph limit is split between its
+//     children, each part keeping its tags, and long text between words
+func TestPostToBlocks(t *testing.T) {
+	post := &models.Post{ContentFormat: models.PostFormatHTML, Content: "<h2>Intro</h2><p>Hello <b>world</b></p>"}
+	converted, err := migrations.PostToBlocks(post)
+	require.NoError(t, err)
+	assert.True(t, converted)
+	assert.Equal(t, models.PostFormatBlocks, post.ContentFormat)
+	doc, err := blocks.Parse(post.Blocks)
+	require.NoError(t, err)
+	assert.Len(t, doc, 1)
+	assert.Equal(t, doc.Text(), post.Content)
+
+	paragraph := "<p>" + strings.Repeat("word ", 1000) + "</p>"
+	long := strings.Repeat(paragraph, 10)
+	post = &models.Post{ContentFormat: models.PostFormatHTML, Content: long}
+	converted, err = migrations.PostToBlocks(post)
+	require.NoError(t, err)
+	assert.True(t, converted)
+	doc, err = blocks.Parse(post.Blocks)
+	require.NoError(t, err, "stored documents must stay valid")
+	assert.Len(t, doc, 4)
+	assert.Equal(t, long, strings.ReplaceAll(doc.HTML(nil), "\n", ""))
+
+	for _, format := range []string{models.PostFormatMarkdown, models.PostFormatPlain} {
+		post = &models.Post{ContentFormat: format, Content: "# Title\n\nSome *text*"}
+		converted, err = migrations.PostToBlocks(post)
+		require.NoError(t, err)
+		assert.False(t, converted, format)
+		assert.Equal(t, format, post.ContentFormat)
+		assert.Equal(t, "# Title\n\nSome *text*", post.Content)
+		assert.Nil(t, post.Blocks)
+	}
+
+	post = &models.Post{ContentFormat: models.PostFormatHTML, Content: "<p> </p>"}
+	converted, err = migrations.PostToBlocks(post)
+	require.NoError(t, err)
+	assert.False(t, converted)
+
+	post = &models.Post{ContentFormat: models.PostFormatHTML, Content: "<div>" + long + "</div>"}
+	converted, err = migrations.PostToBlocks(post)
+	require.NoError(t, err)
+	assert.True(t, converted)
+	doc, err = blocks.Parse(post.Blocks)
+	require.NoError(t, err, "stored documents must stay valid")
+	assert.Len(t, doc, 4)
+	for _, block := range doc {
+		html := block.Data.(*blocks.Paragraph).Text
+		assert.True(t, strings.HasPrefix(html, "<div><p>") && strings.HasSuffix(html, "</p></div>"), "parts keep the wrapper")
+	}
+	assert.Equal(t, strings.Count(long, "word"), strings.Count(post.Content, "word"))
+
+	text := strings.Repeat("word ", 10000)
+	post = &models.Post{ContentFormat: models.PostFormatHTML, Content: "<blockquote><p>" + text + "</p></blockquote>"}
+	converted, err = migrations.PostToBlocks(post)
+	require.NoError(t, err)
+	assert.True(t, converted)
+	doc, err = blocks.Parse(post.Blocks)
+	require.NoError(t, err, "stored documents must stay valid")
+	assert.Len(t, doc, 3)
+	assert.Equal(t, 10000, strings.Count(post.Content, "word"))
+}
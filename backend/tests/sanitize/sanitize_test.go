@@ -0,0 +1,78 @@
+package sanitize
+
+import (
+	"testing"
+
+	"github.com/sasanzare/go-cms/sanitize"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEmptyPolicy tests reducing HTML to escaped text with a policy
+// allowing no markup.
+//
+// Test Cases:
+//  1. Tags are dropped and their text kept
+//  2. Script and style contents are dropped
+//  3. Text is escaped rather than stripped
+func TestEmptyPolicy(t *testing.T) {
+	p := sanitize.NewPolicy()
+
+	assert.Equal(t, "Hello world", p.Sanitize("<b>Hello</b> <i>world</i>"))
+	assert.Equal(t, "ab", p.Sanitize("a<script>alert(1)</script><style>p{}</style>b"))
+	assert.Equal(t, "AT&amp;T &lt;3 (https://att.com/)", p.Sanitize("AT&amp;T &lt;3 (https://att.com/)"))
+}
+
+// TestCommentPolicy tests the markup allowed in comments.
+//
+// Test Cases:
+//  1. Basic formatting is kept and disallowed attributes are removed
+//  2. Links get rel="nofollow ugc" in place of any given rel
+//  3. Unsafe URL schemes, including obfuscated ones, are removed
+//  4. Elements outside the policy are unwrapped
+//  5. Unclosed elements are closed and stray end tags dropped
+func TestCommentPolicy(t *testing.T) {
+	p := sanitize.Comment()
+
+	assert.Equal(t, "<p><strong>Hi</strong> there</p>",
+		p.Sanitize(`<p class="x" onclick="evil()"><strong style="color:red">Hi</strong> there</p>`))
+
+	assert.Equal(t, `<a href="https://example.com/?a=1&amp;b=2" rel="nofollow ugc">link</a>`,
+		p.Sanitize(`<a href="https://example.com/?a=1&b=2" rel="me" target="_blank">link</a>`))
+
+	for _, href := range []string{
+		"javascript:alert(1)",
+		"JaVaScRiPt:alert(1)",
+		"java\tscript:alert(1)",
+		"&#106;avascript:alert(1)",
+		" data:text/html;base64,PHNjcmlwdD4=",
+		"vbscript:msgbox(1)",
+	} {
+		assert.Equal(t, `<a rel="nofollow ugc">x</a>`, p.Sanitize(`<a href="`+href+`">x</a>`), href)
+	}
+	assert.Equal(t, `<a href="/posts/1#c" rel="nofollow ugc">x</a>`, p.Sanitize(`<a href="/posts/1#c">x</a>`))
+	assert.Equal(t, `<a href="mailto:a@b.example" rel="nofollow ugc">x</a>`, p.Sanitize(`<a href="mailto:a@b.example">x</a>`))
+
+	assert.Equal(t, "Title text", p.Sanitize(`<h1>Title</h1> <img src="x" onerror="evil()">text`))
+	assert.Equal(t, "<ul><li><em>one</em></li></ul>", p.Sanitize("<ul><li><em>one</ul></div>"))
+}
+
+// TestPostPolicy tests the rich markup allowed in posts.
+//
+// Test Cases:
+//  1. Headings, images and tables are kept with their allowed attributes
+//  2. Links opening a new window get noopener and noreferrer
+//  3. Other targets are removed and links are not marked nofollow
+//  4. Iframes and their contents are removed
+func TestPostPolicy(t *testing.T) {
+	p := sanitize.Post()
+
+	assert.Equal(t, `<h2>Intro</h2><img src="https://cdn.example/a.png" alt="A"><table><tr><td colspan="2">x</td></tr></table>`,
+		p.Sanitize(`<h2 id="intro">Intro</h2><img src="https://cdn.example/a.png" alt="A" onload="x()"/><table><tr><td colspan="2">x</td></tr></table>`))
+	assert.Equal(t, `<a href="https://example.com" target="_blank" rel="noopener noreferrer">go</a>`,
+		p.Sanitize(`<a href="https://example.com" target="_blank">go</a>`))
+	assert.Equal(t, `<a href="https://example.com">go</a>`,
+		p.Sanitize(`<a href="https://example.com" target="_top">go</a>`))
+	assert.Equal(t, "<p>before after</p>",
+		p.Sanitize(`<p>before <iframe src="https://evil.example">fallback</iframe>after</p>`))
+	assert.Equal(t, "<p></p>", p.Sanitize(`<p/>`))
+}
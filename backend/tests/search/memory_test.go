@@ -0,0 +1,113 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sasanzare/go-cms/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIndex(t *testing.T) *search.MemoryIndex {
+	index := search.NewMemoryIndex()
+	docs := []search.Document{
+		{ID: 1, Title: "Getting started with PostgreSQL", Content: "Install postgres and create a database.", Language: "english", Status: "published", CategoryID: 1, TagSlugs: []string{"databases"}},
+		{ID: 2, Title: "Go concurrency patterns", Content: "Channels and goroutines in <b>practice</b>.", Language: "english", Status: "published", CategoryID: 2, TagSlugs: []string{"go"}},
+		{ID: 3, Title: "Tuning a database server", Excerpt: "PostgreSQL settings", Content: "Memory and connections.", Language: "english", Status: "draft", CategoryID: 1, TagSlugs: []string{"databases", "ops"}},
+	}
+	for _, doc := range docs {
+		require.NoError(t, index.Index(context.Background(), doc))
+	}
+	return index
+}
+
+func hitIDs(result *search.Result) []uint {
+	ids := make([]uint, len(result.Hits))
+	for i, hit := range result.Hits {
+		ids[i] = hit.ID
+	}
+	return ids
+}
+
+// TestMemoryIndexSearch tests ranking, prefix matching and highlighting.
+//
+// Test Cases:
+//  1. Title matches rank above excerpt matches
+//  2. Prefix queries match longer words
+//  3. Highlights are escaped and wrapped in <mark>
+func TestMemoryIndexSearch(t *testing.T) {
+	index := newTestIndex(t)
+	ctx := context.Background()
+
+	result, err := index.Search(ctx, search.Query{Text: "postgresql"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 3}, hitIDs(result))
+	assert.Equal(t, "Getting started with <mark>PostgreSQL</mark>", result.Hits[0].TitleHighlight)
+
+	result, err = index.Search(ctx, search.Query{Text: "goro", Prefix: true})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{2}, hitIDs(result))
+	assert.Contains(t, result.Hits[0].Snippet, "<mark>goroutines</mark>")
+	assert.NotContains(t, result.Hits[0].Snippet, "<b>")
+
+	result, err = index.Search(ctx, search.Query{Text: "goro"})
+	require.NoError(t, err)
+	assert.Empty(t, result.Hits)
+}
+
+// TestMemoryIndexFuzzy tests typo-tolerant matching.
+//
+// Test Cases:
+//  1. Misspelled words match only when Fuzzy is set
+//  2. Short words are never matched fuzzily
+func TestMemoryIndexFuzzy(t *testing.T) {
+	index := newTestIndex(t)
+	ctx := context.Background()
+
+	result, err := index.Search(ctx, search.Query{Text: "concurency"})
+	require.NoError(t, err)
+	assert.Empty(t, result.Hits)
+
+	result, err = index.Search(ctx, search.Query{Text: "concurency", Fuzzy: true})
+	require.NoError(t, err)
+	assert.True(t, result.Fuzzy)
+	assert.Equal(t, []uint{2}, hitIDs(result))
+
+	result, err = index.Search(ctx, search.Query{Text: "ga", Fuzzy: true})
+	require.NoError(t, err)
+	assert.Empty(t, result.Hits)
+}
+
+// TestMemoryIndexFiltersAndFacets tests filters, facet counts and paging.
+//
+// Test Cases:
+//  1. Facets count every match, not just the page
+//  2. Status and tag filters narrow the matches
+//  3. Deleted documents are no longer found
+func TestMemoryIndexFiltersAndFacets(t *testing.T) {
+	index := newTestIndex(t)
+	ctx := context.Background()
+
+	result, err := index.Search(ctx, search.Query{Text: "database", Prefix: true, Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, result.Hits, 1)
+	assert.Equal(t, int64(2), result.Total)
+	assert.Equal(t, int64(2), result.Facets.Categories[1])
+	assert.Equal(t, int64(2), result.Facets.Tags["databases"])
+	assert.Equal(t, int64(1), result.Facets.Tags["ops"])
+
+	result, err = index.Search(ctx, search.Query{Text: "database", Prefix: true, Status: "published"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1}, hitIDs(result))
+
+	result, err = index.Search(ctx, search.Query{Text: "database", Prefix: true, TagSlugs: []string{"ops"}})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{3}, hitIDs(result))
+
+	require.NoError(t, index.Delete(ctx, 3))
+	result, err = index.Search(ctx, search.Query{Text: "database", Prefix: true})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1}, hitIDs(result))
+	assert.Equal(t, 2, index.Len())
+}
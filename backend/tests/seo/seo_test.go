@@ -0,0 +1,165 @@
+package seo
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sasanzare/go-cms/seo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tags(list []seo.Tag, name string) []string {
+	var values []string
+	for _, tag := range list {
+		if tag.Name == name {
+			values = append(values, tag.Content)
+		}
+	}
+	return values
+}
+
+// TestBuildArticle tests the metadata of articles.
+//
+// Test Cases:
+//  1. Open Graph carries article details, locales and the image
+//  2. A post with an image gets a large Twitter card
+//  3. JSON-LD has an Article and a BreadcrumbList in order
+//  4. Descriptions are collapsed and cut; robots default to index,follow
+func TestBuildArticle(t *testing.T) {
+	published := time.Date(2024, time.August, 2, 9, 30, 0, 0, time.FixedZone("IRST", 3*3600+1800))
+	meta := seo.Build(seo.Page{
+		Type:        seo.TypeArticle,
+		Title:       "Go generics",
+		Description: "A  long\nintroduction " + strings.Repeat("word ", 50),
+		URL:         "https://cms.example.com/posts/1",
+		Image:       "https://cms.example.com/media/cover.jpg",
+		Locale:      "en-us",
+		SiteName:    "Example",
+		SiteURL:     "https://cms.example.com",
+		LogoURL:     "https://cms.example.com/logo.png",
+		Author:      "Ada Lovelace",
+		Section:     "Go",
+		Tags:        []string{"go", "generics"},
+		Published:   &published,
+		Breadcrumbs: []seo.Crumb{
+			{Name: "Example", URL: "https://cms.example.com/"},
+			{Name: "Programming", URL: "https://cms.example.com/categories/programming"},
+			{Name: "Go", URL: "https://cms.example.com/categories/go"},
+			{Name: "Go generics", URL: "https://cms.example.com/posts/1"},
+		},
+		Alternates: []seo.Alternate{
+			{Lang: "en-us", Href: "https://cms.example.com/posts/1"},
+			{Lang: "x-default", Href: "https://cms.example.com/posts/1"},
+			{Lang: "fa", Href: "https://cms.example.com/posts/2"},
+		},
+	})
+
+	assert.Equal(t, "https://cms.example.com/posts/1", meta.Canonical)
+	assert.Equal(t, seo.DefaultRobots, meta.Robots)
+	assert.LessOrEqual(t, len([]rune(meta.Description)), seo.MaxDescription+3)
+	assert.True(t, strings.HasPrefix(meta.Description, "A long introduction word"))
+
+	assert.Equal(t, []string{"article"}, tags(meta.OpenGraph, "og:type"))
+	assert.Equal(t, []string{"en_US"}, tags(meta.OpenGraph, "og:locale"))
+	assert.Equal(t, []string{"fa"}, tags(meta.OpenGraph, "og:locale:alternate"))
+	assert.Equal(t, []string{"2024-08-02T06:00:00Z"}, tags(meta.OpenGraph, "article:published_time"))
+	assert.Empty(t, tags(meta.OpenGraph, "article:modified_time"))
+	assert.Equal(t, []string{"go", "generics"}, tags(meta.OpenGraph, "article:tag"))
+	assert.Equal(t, []string{"summary_large_image"}, tags(meta.Twitter, "twitter:card"))
+	assert.Equal(t, []string{"https://cms.example.com/media/cover.jpg"}, tags(meta.Twitter, "twitter:image"))
+
+	raw, err := json.Marshal(meta.JSONLD)
+	require.NoError(t, err)
+	var ld []struct {
+		Type          string `json:"@type"`
+		Headline      string `json:"headline"`
+		DatePublished string `json:"datePublished"`
+		Author        struct {
+			Name string `json:"name"`
+		} `json:"author"`
+		Publisher struct {
+			Logo struct {
+				URL string `json:"url"`
+			} `json:"logo"`
+		} `json:"publisher"`
+		Items []struct {
+			Position int    `json:"position"`
+			Name     string `json:"name"`
+			Item     string `json:"item"`
+		} `json:"itemListElement"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &ld))
+	require.Len(t, ld, 2)
+	assert.Equal(t, "Article", ld[0].Type)
+	assert.Equal(t, "Go generics", ld[0].Headline)
+	assert.Equal(t, "2024-08-02T06:00:00Z", ld[0].DatePublished)
+	assert.Equal(t, "Ada Lovelace", ld[0].Author.Name)
+	assert.Equal(t, "https://cms.example.com/logo.png", ld[0].Publisher.Logo.URL)
+	assert.Equal(t, "BreadcrumbList", ld[1].Type)
+	require.Len(t, ld[1].Items, 4)
+	assert.Equal(t, 2, ld[1].Items[1].Position)
+	assert.Equal(t, "https://cms.example.com/categories/programming", ld[1].Items[1].Item)
+}
+
+// TestBuildWebsite tests the metadata of pages other than articles.
+//
+// Test Cases:
+//  1. Pages have no article tags nor Article JSON-LD
+//  2. Pages without images get a summary Twitter card
+//  3. Robots directives are kept
+//  4. Pages without breadcrumbs have no JSON-LD
+func TestBuildWebsite(t *testing.T) {
+	meta := seo.Build(seo.Page{
+		Type:     seo.TypeWebsite,
+		Title:    "News",
+		URL:      "https://cms.example.com/categories/news",
+		Locale:   "fa",
+		Robots:   "noindex,follow",
+		SiteName: "Example",
+	})
+
+	assert.Equal(t, []string{"website"}, tags(meta.OpenGraph, "og:type"))
+	assert.Empty(t, tags(meta.OpenGraph, "og:description"))
+	for _, tag := range meta.OpenGraph {
+		assert.False(t, strings.HasPrefix(tag.Name, "article:"), tag.Name)
+	}
+	assert.Equal(t, []string{"summary"}, tags(meta.Twitter, "twitter:card"))
+	assert.Empty(t, tags(meta.Twitter, "twitter:image"))
+	assert.Equal(t, "noindex,follow", meta.Robots)
+	assert.NotNil(t, meta.JSONLD)
+	assert.Empty(t, meta.JSONLD)
+}
+
+// TestNormalizeRobots tests checking robots directives.
+//
+// Test Cases:
+//  1. Directives are lowercased, trimmed and deduplicated
+//  2. none expands and all is dropped
+//  3. Preview limits are checked
+//  4. Unknown and contradicting directives are rejected
+func TestNormalizeRobots(t *testing.T) {
+	valid := map[string]string{
+		"":                             "",
+		" NoIndex , nofollow,noindex ": "noindex,nofollow",
+		"none":                         "noindex,nofollow",
+		"all":                          "",
+		"max-snippet:-1, max-image-preview:large": "max-snippet:-1,max-image-preview:large",
+		"noarchive,max-video-preview:30":          "noarchive,max-video-preview:30",
+	}
+	for raw, want := range valid {
+		got, err := seo.NormalizeRobots(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, want, got, raw)
+	}
+
+	for _, raw := range []string{"noidex", "index,noindex", "follow,none", "max-snippet:many", "max-image-preview:huge"} {
+		_, err := seo.NormalizeRobots(raw)
+		assert.Error(t, err, raw)
+	}
+
+	assert.True(t, seo.NoIndex("noarchive,noindex"))
+	assert.False(t, seo.NoIndex("max-image-preview:none"))
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/spam"
+	"github.com/sasanzare/go-cms/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRegisterUserSpam tests screening public signups for spam.
+//
+// Test Cases:
+//  1. A signup with the honeypot filled in is rejected before it is stored
+//  2. The rejection is a validation error
+func TestRegisterUserSpam(t *testing.T) {
+	checks := services.NewSpamService(spam.NewPipeline(1, spam.HoneypotRule{Weight: 2}), nil, nil)
+	// No database: a rejected signup must not reach it
+	auth := services.NewAuthService(nil, services.WithSpamChecks(checks))
+
+	user := &models.User{
+		Username:  "bot",
+		Email:     "bot@example.com",
+		Password:  "Secret#123",
+		FirstName: "Cheap",
+		LastName:  "Pills",
+	}
+	_, err := auth.RegisterUser(context.Background(), user, models.UserRoleUser, &spam.Signals{
+		IP:        "203.0.113.9",
+		UserAgent: "curl/8.0",
+		Honeypot:  "https://cheap-pills.example",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), utils.ValidationFailedMsg)
+	assert.Contains(t, err.Error(), "registration rejected")
+	assert.Zero(t, user.ID)
+}
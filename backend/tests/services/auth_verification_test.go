@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sasanzare/go-cms/mail"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSendVerificationEmail tests the verification email sent on signup.
+//
+// Test Cases:
+//  1. The email reaches the user in their locale
+//  2. Its link carries a token for this user and address
+//  3. The token can't be used as a login token
+func TestSendVerificationEmail(t *testing.T) {
+	utils.SetJWTSecret("test-secret")
+
+	renderer, err := mail.NewRenderer(mail.Site{Name: "Go CMS", URL: "https://cms.example.com"})
+	require.NoError(t, err)
+	mailer := mail.NewMemoryMailer()
+	email := services.NewEmailService(mailer, "cms@example.com", renderer, "en")
+	auth := services.NewAuthService(nil, services.WithEmailVerification(email, "https://cms.example.com/api/auth/verify-email"))
+
+	user := &models.User{ID: 7, Email: "sara@example.com", FirstName: "Sara", Locale: "fa"}
+	require.NoError(t, auth.SendVerificationEmail(context.Background(), user))
+
+	sent, ok := mailer.Last("sara@example.com")
+	require.True(t, ok)
+	assert.Equal(t, "cms@example.com", sent.From)
+	assert.Contains(t, sent.HTML, `dir="rtl"`)
+
+	var link string
+	for _, l := range sent.Links() {
+		if strings.Contains(l, "/verify-email") {
+			link = l
+		}
+	}
+	require.NotEmpty(t, link, "verification link in %q", sent.Text)
+
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	token := u.Query().Get("token")
+
+	claims, err := utils.ValidateActionToken(token, utils.PurposeEmailVerification)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), claims.UserID)
+	assert.Equal(t, "sara@example.com", claims.Email)
+
+	_, err = utils.ValidateToken(token)
+	assert.Error(t, err)
+}
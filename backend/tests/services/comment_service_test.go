@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sasanzare/go-cms/events"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/spam"
+	"github.com/sasanzare/go-cms/tests/testdb"
+	"github.com/sasanzare/go-cms/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commentFixture is what newCommentFixture stores: a published post open
+// to comments, a draft, the post's author, an editor and a reader.
+type commentFixture struct {
+	svc                    *services.Services
+	post, draft            *models.Post
+	author, editor, reader *models.User
+	// approvals collects the comments announced as approved
+	approvals []uint
+}
+
+// newCommentFixture wires the services with a spam pipeline flagging
+// comments that mention "casino".
+func newCommentFixture(t *testing.T) *commentFixture {
+	t.Helper()
+	db := testdb.Open(t, "services")
+	f := &commentFixture{svc: services.New(db, services.Options{
+		DigestHour: -1,
+		Spam:       services.NewSpamService(spam.NewPipeline(1, spam.BlocklistRule{Words: []string{"casino"}, Weight: 1}), nil, nil),
+	})}
+	events.Subscribe(f.svc.Events, func(_ context.Context, e events.PostCommented) error {
+		f.approvals = append(f.approvals, e.CommentID)
+		return nil
+	})
+
+	var users []*models.User
+	for _, u := range []struct{ name, role string }{
+		{"author", models.UserRoleAuthor},
+		{"editor", models.UserRoleEditor},
+		{"reader", models.UserRoleUser},
+	} {
+		user := &models.User{Username: u.name, Email: u.name + "@example.com", Password: "hash", Role: u.role}
+		require.NoError(t, db.Create(user).Error)
+		users = append(users, user)
+	}
+	f.author, f.editor, f.reader = users[0], users[1], users[2]
+
+	published := time.Now().Add(-time.Hour)
+	f.post = &models.Post{Title: "Hello", Slug: "hello", Content: "<p>Hello</p>", Status: models.PostStatusPublished, PublishedAt: &published, AuthorID: f.author.ID}
+	require.NoError(t, db.Create(f.post).Error)
+	f.draft = &models.Post{Title: "Draft", Slug: "draft", Content: "<p>Soon</p>", Status: models.PostStatusDraft, AuthorID: f.author.ID}
+	require.NoError(t, db.Create(f.draft).Error)
+	return f
+}
+
+// as returns a context signed in as user.
+func as(user *models.User) context.Context {
+	return utils.ContextWithClaims(context.Background(), &utils.Claims{UserID: user.ID, Role: user.Role})
+}
+
+// guestComment is a valid guest comment on postID.
+func guestComment(postID uint, body string) services.CommentInput {
+	return services.CommentInput{PostID: postID, Body: body, GuestName: "Guest", GuestEmail: "guest@example.com"}
+}
+
+// TestCommentThreads tests posting and listing threaded comments.
+//
+// Test Cases:
+//  1. Guests' comments wait for moderation; guests must give a name and a
+//     valid email
+//  2. Staff comments are approved right away, and so are those of users
+//     with an approved comment
+//  3. Replies nest under their approved parent in listings
+//  4. Replies to unapproved comments and replies nested too deeply are
+//     rejected
+//  5. Unpublished posts and posts closed to comments take no comments
+func TestCommentThreads(t *testing.T) {
+	f := newCommentFixture(t)
+	comments := f.svc.Comments
+	ctx := context.Background()
+
+	guest, err := comments.CreateComment(ctx, guestComment(f.post.ID, "First!"))
+	require.NoError(t, err)
+	assert.Equal(t, models.CommentStatusPending, guest.Status)
+	_, err = comments.CreateComment(ctx, services.CommentInput{PostID: f.post.ID, Body: "Nameless", GuestEmail: "guest@example.com"})
+	assert.ErrorContains(t, err, "name is required")
+	_, err = comments.CreateComment(ctx, services.CommentInput{PostID: f.post.ID, Body: "Bad email", GuestName: "Guest", GuestEmail: "guest"})
+	assert.ErrorContains(t, err, "a valid email is required")
+
+	first, err := comments.CreateComment(as(f.reader), services.CommentInput{PostID: f.post.ID, Body: "Nice post"})
+	require.NoError(t, err)
+	assert.Equal(t, models.CommentStatusPending, first.Status)
+	root, err := comments.CreateComment(as(f.editor), services.CommentInput{PostID: f.post.ID, Body: "Thanks for reading"})
+	require.NoError(t, err)
+	assert.Equal(t, models.CommentStatusApproved, root.Status)
+	_, err = comments.ModerateComment(as(f.editor), first.ID, models.CommentStatusApproved)
+	require.NoError(t, err)
+
+	reply, err := comments.CreateComment(as(f.reader), services.CommentInput{PostID: f.post.ID, ParentID: &root.ID, Body: "You're welcome"})
+	require.NoError(t, err)
+	assert.Equal(t, models.CommentStatusApproved, reply.Status, "the reader has an approved comment")
+	assert.Equal(t, 1, reply.Depth)
+	assert.Equal(t, root.ID, *reply.RootID)
+
+	_, err = comments.CreateComment(ctx, services.CommentInput{PostID: f.post.ID, ParentID: &guest.ID, Body: "Me too", GuestName: "Other", GuestEmail: "other@example.com"})
+	assert.EqualError(t, err, "parent comment not found")
+
+	parent := reply
+	for parent.Depth < services.MaxCommentDepth {
+		parent, err = comments.CreateComment(as(f.editor), services.CommentInput{PostID: f.post.ID, ParentID: &parent.ID, Body: "Deeper"})
+		require.NoError(t, err)
+	}
+	_, err = comments.CreateComment(as(f.editor), services.CommentInput{PostID: f.post.ID, ParentID: &parent.ID, Body: "Too deep"})
+	assert.ErrorContains(t, err, "nested too deeply")
+
+	threads, pagination, err := comments.ListComments(ctx, f.post.ID, 1, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, pagination.Total, "only approved top-level comments count")
+	require.Len(t, threads, 2)
+	var thread *models.Comment
+	for i := range threads {
+		if threads[i].ID == root.ID {
+			thread = &threads[i]
+		}
+	}
+	require.NotNil(t, thread)
+	require.Len(t, thread.Replies, 1)
+	assert.Equal(t, reply.ID, thread.Replies[0].ID)
+	depth := 0
+	for c := &thread.Replies[0]; len(c.Replies) > 0; c = &c.Replies[0] {
+		depth++
+	}
+	assert.Equal(t, services.MaxCommentDepth-1, depth)
+
+	_, err = comments.CreateComment(ctx, guestComment(f.draft.ID, "Early"))
+	assert.EqualError(t, err, "post not found")
+	require.NoError(t, f.svc.DB.Model(f.post).Update("comments_enabled", false).Error)
+	_, err = comments.CreateComment(ctx, guestComment(f.post.ID, "Late"))
+	assert.ErrorContains(t, err, "comments are closed")
+}
+
+// TestCommentModeration tests the moderation queue and spam screening.
+//
+// Test Cases:
+//  1. Comments flagged as spam go to the spam folder with their score and
+//     the rules that flagged them
+//  2. Staff comments skip spam screening
+//  3. The queue lists comments in one status, and counts cover them all
+//  4. The first approval is announced once; approving again after
+//     trashing is not
+//  5. Unknown statuses and comments are rejected
+func TestCommentModeration(t *testing.T) {
+	f := newCommentFixture(t)
+	comments := f.svc.Comments
+	ctx := context.Background()
+
+	flagged, err := comments.CreateComment(ctx, guestComment(f.post.ID, "Visit my casino"))
+	require.NoError(t, err)
+	assert.Equal(t, models.CommentStatusSpam, flagged.Status)
+	pending, err := comments.CreateComment(ctx, guestComment(f.post.ID, "Great read"))
+	require.NoError(t, err)
+	assert.Equal(t, models.CommentStatusPending, pending.Status)
+	staff, err := comments.CreateComment(as(f.editor), services.CommentInput{PostID: f.post.ID, Body: "Our casino review is next"})
+	require.NoError(t, err)
+	assert.Equal(t, models.CommentStatusApproved, staff.Status)
+
+	queue, _, err := comments.ModerationQueue(ctx, models.CommentStatusSpam, 0, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, queue, 1)
+	assert.Equal(t, flagged.ID, queue[0].ID)
+	assert.Equal(t, 1.0, queue[0].SpamScore)
+	require.Len(t, queue[0].SpamChecks, 1)
+	assert.Equal(t, "blocklist", queue[0].SpamChecks[0].Rule)
+
+	queue, pagination, err := comments.ModerationQueue(ctx, "", f.post.ID, 1, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, pagination.Total)
+	require.Len(t, queue, 1)
+	assert.Equal(t, pending.ID, queue[0].ID)
+	assert.Equal(t, "Hello", queue[0].PostTitle)
+	counts, err := comments.ModerationCounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{
+		models.CommentStatusPending:  1,
+		models.CommentStatusApproved: 1,
+		models.CommentStatusSpam:     1,
+		models.CommentStatusTrashed:  0,
+	}, counts)
+
+	assert.Equal(t, []uint{staff.ID}, f.approvals)
+	approved, err := comments.ModerateComment(as(f.editor), pending.ID, models.CommentStatusApproved)
+	require.NoError(t, err)
+	assert.Equal(t, models.CommentStatusApproved, approved.Status)
+	require.NotNil(t, approved.ApprovedAt)
+	require.NotNil(t, approved.ModeratedBy)
+	assert.Equal(t, f.editor.ID, *approved.ModeratedBy)
+	_, err = comments.ModerateComment(as(f.editor), pending.ID, models.CommentStatusTrashed)
+	require.NoError(t, err)
+	_, err = comments.ModerateComment(as(f.editor), pending.ID, models.CommentStatusApproved)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{staff.ID, pending.ID}, f.approvals)
+
+	_, err = comments.ModerateComment(ctx, flagged.ID, "deleted")
+	assert.ErrorContains(t, err, "unknown comment status")
+	_, _, err = comments.ModerationQueue(ctx, "deleted", 0, 1, 10)
+	assert.ErrorContains(t, err, "unknown comment status")
+	_, err = comments.ModerateComment(ctx, 999999, models.CommentStatusApproved)
+	assert.EqualError(t, err, "comment not found")
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/tests/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const eventFields = `[
+	{"name": "title", "type": "text", "required": true},
+	{"name": "body", "type": "text", "searchable": true},
+	{"name": "code", "type": "text"},
+	{"name": "price", "type": "number"},
+	{"name": "starts_at", "type": "date", "required": true},
+	{"name": "venue", "type": "reference", "target": "venue"},
+	{"name": "kind", "type": "enum", "options": ["talk", "workshop"]}
+]`
+
+// newContentTypes stores the venue and event content types.
+func newContentTypes(t *testing.T) *services.ContentService {
+	t.Helper()
+	db := testdb.Open(t, "services")
+	svc := services.NewContentService(db)
+	ctx := context.Background()
+	require.NoError(t, svc.CreateContentType(ctx, &models.ContentType{Name: "Venue", Slug: "venue", Fields: json.RawMessage(`[{"name": "name", "type": "text", "required": true}]`)}))
+	require.NoError(t, svc.CreateContentType(ctx, &models.ContentType{Name: "Event", Slug: "event", Fields: json.RawMessage(eventFields)}))
+	return svc
+}
+
+func createEntry(t *testing.T, svc *services.ContentService, slug, data string) *models.ContentEntry {
+	t.Helper()
+	entry := &models.ContentEntry{Data: json.RawMessage(data), AuthorID: 1}
+	require.NoError(t, svc.CreateEntry(context.Background(), slug, entry))
+	return entry
+}
+
+func entryIDs(entries []models.ContentEntry) []uint {
+	ids := make([]uint, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+	return ids
+}
+
+// TestContentEntries tests storing entries of runtime content types.
+//
+// Test Cases:
+//  1. Entries are validated, normalized and titled by the title field
+//  2. Updates replace data or only the status, setting the publish time once
+//  3. Deleted entries are not found
+//  4. References must point to existing entries of the target type
+//  5. Types with entries, or referenced by other types, can't be deleted
+func TestContentEntries(t *testing.T) {
+	svc := newContentTypes(t)
+	ctx := context.Background()
+
+	hall := createEntry(t, svc, "venue", `{"name": "Main hall"}`)
+	event := createEntry(t, svc, "event", `{"title": " <b>Go</b> meetup ", "starts_at": "2025-04-01"}`)
+	assert.Equal(t, "Go meetup", event.Title)
+	assert.Equal(t, models.EntryStatusDraft, event.Status)
+	assert.JSONEq(t, `{"title": "Go meetup", "starts_at": "2025-04-01T00:00:00Z"}`, string(event.Data))
+
+	err := svc.CreateEntry(ctx, "event", &models.ContentEntry{Data: json.RawMessage(`{"title": "Go meetup"}`), AuthorID: 1})
+	assert.ErrorContains(t, err, "starts_at: a value is required")
+	err = svc.CreateEntry(ctx, "event", &models.ContentEntry{Data: json.RawMessage(`{"title": "Go meetup", "starts_at": "2025-04-01", "venue": 999}`), AuthorID: 1})
+	assert.ErrorContains(t, err, "venue entry 999 not found")
+	err = svc.CreateEntry(ctx, "event", &models.ContentEntry{Data: json.RawMessage(`{"title": "Go meetup", "starts_at": "2025-04-01", "venue": ` + jsonID(event.ID) + `}`), AuthorID: 1})
+	assert.ErrorContains(t, err, "not found", "references must point to the target type")
+
+	updated, err := svc.UpdateEntry(ctx, "event", event.ID, json.RawMessage(`{"title": "Go meetup #2", "starts_at": "2025-05-01", "venue": `+jsonID(hall.ID)+`}`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Go meetup #2", updated.Title)
+	published := models.EntryStatusPublished
+	updated, err = svc.UpdateEntry(ctx, "event", event.ID, nil, &published)
+	require.NoError(t, err)
+	require.NotNil(t, updated.PublishedAt)
+	assert.Equal(t, "Go meetup #2", updated.Title)
+	publishedAt := *updated.PublishedAt
+	updated, err = svc.UpdateEntry(ctx, "event", event.ID, nil, &published)
+	require.NoError(t, err)
+	assert.True(t, publishedAt.Equal(*updated.PublishedAt))
+
+	assert.ErrorContains(t, svc.DeleteContentType(ctx, "venue"), "still has entries")
+	require.NoError(t, svc.DeleteEntry(ctx, "venue", hall.ID))
+	assert.ErrorContains(t, svc.DeleteContentType(ctx, "venue"), "referenced by event")
+
+	require.NoError(t, svc.DeleteEntry(ctx, "event", event.ID))
+	_, err = svc.GetEntry(ctx, "event", event.ID)
+	assert.EqualError(t, err, "entry not found")
+	assert.EqualError(t, svc.DeleteEntry(ctx, "event", event.ID), "entry not found")
+}
+
+func jsonID(id uint) string {
+	raw, _ := json.Marshal(id)
+	return string(raw)
+}
+
+// TestListEntries tests filtering, sorting and searching entries.
+//
+// Test Cases:
+//  1. Equality, "in" and range filters combine
+//  2. Entries sort by typed field values, those without a value last
+//  3. Search matches titles and searchable fields, best matches first
+//  4. Bad filters, sorts and empty searches are rejected
+func TestListEntries(t *testing.T) {
+	svc := newContentTypes(t)
+	ctx := context.Background()
+
+	talk := createEntry(t, svc, "event", `{"title": "Gophers at scale", "kind": "talk", "price": 9.5, "starts_at": "2025-03-01"}`)
+	workshop := createEntry(t, svc, "event", `{"title": "Testing workshop", "body": "Bring a gopher", "kind": "workshop", "price": 40, "starts_at": "2025-04-01"}`)
+	free := createEntry(t, svc, "event", `{"title": "Open evening", "kind": "talk", "starts_at": "2025-05-01"}`)
+
+	list := func(q services.EntryQuery) []uint {
+		t.Helper()
+		entries, pagination, err := svc.ListEntries(ctx, "event", q)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(entries)), pagination.Total)
+		return entryIDs(entries)
+	}
+	assert.Equal(t, []uint{talk.ID, free.ID}, list(services.EntryQuery{Filters: map[string][]string{"kind": {"talk"}}, Sort: "starts_at"}))
+	assert.Equal(t, []uint{workshop.ID}, list(services.EntryQuery{Filters: map[string][]string{"price": {"gte:10"}}}))
+	assert.Equal(t, []uint{talk.ID, workshop.ID}, list(services.EntryQuery{Filters: map[string][]string{
+		"kind":      {"in:talk,workshop"},
+		"starts_at": {"gte:2025-03-01", "lt:2025-05-01"},
+	}, Sort: "starts_at"}))
+	assert.Equal(t, []uint{workshop.ID, talk.ID, free.ID}, list(services.EntryQuery{Sort: "-price"}))
+	assert.Equal(t, []uint{talk.ID, workshop.ID, free.ID}, list(services.EntryQuery{Sort: "price"}))
+
+	entries, _, err := svc.SearchEntries(ctx, "event", "gopher", services.EntryQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{workshop.ID}, entryIDs(entries), "the simple configuration doesn't stem")
+	entries, _, err = svc.SearchEntries(ctx, "event", "gophers OR gopher", services.EntryQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{talk.ID, workshop.ID}, entryIDs(entries), "title matches rank first")
+
+	_, _, err = svc.ListEntries(ctx, "event", services.EntryQuery{Filters: map[string][]string{"room": {"1"}}})
+	assert.ErrorContains(t, err, "Validation failed")
+	_, _, err = svc.ListEntries(ctx, "event", services.EntryQuery{Sort: "room"})
+	assert.ErrorContains(t, err, "Validation failed")
+	_, _, err = svc.SearchEntries(ctx, "event", "  ", services.EntryQuery{})
+	assert.ErrorContains(t, err, "search query is empty")
+}
+
+// TestRemoveContentField tests removing a field from a type with entries.
+//
+// Test Cases:
+//  1. The removed field's values are dropped from every entry
+//  2. Entries can still be published without resending their data
+//  3. A field re-added with another type sorts and filters its new values
+func TestRemoveContentField(t *testing.T) {
+	svc := newContentTypes(t)
+	ctx := context.Background()
+	entry := createEntry(t, svc, "event", `{"title": "Go meetup", "code": "A1", "starts_at": "2025-04-01"}`)
+
+	var fields []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(eventFields), &fields))
+	withoutCode := append(fields[:2:2], fields[3:]...)
+	raw, err := json.Marshal(withoutCode)
+	require.NoError(t, err)
+	_, err = svc.UpdateContentType(ctx, "event", &models.ContentType{Name: "Event", Fields: raw})
+	require.NoError(t, err)
+
+	stored, err := svc.GetEntry(ctx, "event", entry.ID)
+	require.NoError(t, err)
+	assert.NotContains(t, string(stored.Data), "code")
+
+	published := models.EntryStatusPublished
+	updated, err := svc.UpdateEntry(ctx, "event", entry.ID, nil, &published)
+	require.NoError(t, err)
+	assert.True(t, updated.IsPublished())
+
+	raw, err = json.Marshal(append(withoutCode, map[string]interface{}{"name": "code", "type": "number"}))
+	require.NoError(t, err)
+	_, err = svc.UpdateContentType(ctx, "event", &models.ContentType{Name: "Event", Fields: raw})
+	require.NoError(t, err)
+	numbered := createEntry(t, svc, "event", `{"title": "Rust meetup", "code": 7, "starts_at": "2025-05-01"}`)
+
+	entries, _, err := svc.ListEntries(ctx, "event", services.EntryQuery{Filters: map[string][]string{"code": {"gt:1"}}, Sort: "-code"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{numbered.ID}, entryIDs(entries))
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sasanzare/go-cms/jobs"
+	"github.com/sasanzare/go-cms/mail"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/tests/testdb"
+	"github.com/sasanzare/go-cms/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newNotificationServices wires the services with a job queue delivering
+// email to the returned mailer, holding digests for digestHour.
+func newNotificationServices(t *testing.T, digestHour int) (*services.Services, *mail.MemoryMailer) {
+	t.Helper()
+	db := testdb.Open(t, "services")
+	renderer, err := mail.NewRenderer(mail.Site{Name: "Go CMS", URL: "https://cms.example.com"})
+	require.NoError(t, err)
+	mailer := mail.NewMemoryMailer()
+	return services.New(db, services.Options{
+		Queue:         jobs.New(db, utils.RealClock{}, jobs.Config{}),
+		Mailer:        mailer,
+		MailFrom:      "cms@example.com",
+		Mail:          renderer,
+		DefaultLocale: "en",
+		SiteURL:       "https://cms.example.com",
+		DigestHour:    digestHour,
+	}), mailer
+}
+
+func newNotifiedUser(t *testing.T, svc *services.Services, name string) *models.User {
+	t.Helper()
+	user := &models.User{Username: name, FirstName: name, Email: name + "@example.com", Password: "hash", Role: models.UserRoleAuthor}
+	require.NoError(t, svc.DB.Create(user).Error)
+	return user
+}
+
+// deliverEmails runs the queued email jobs.
+func deliverEmails(t *testing.T, svc *services.Services) {
+	t.Helper()
+	for {
+		ran, err := svc.Jobs.RunNext(context.Background(), services.EmailQueue)
+		require.NoError(t, err)
+		if !ran {
+			return
+		}
+	}
+}
+
+// TestNotificationPreferences tests choosing notification channels.
+//
+// Test Cases:
+//  1. Decisions on posts are emailed right away by default, conversation
+//     goes into the digest, and everything is shown in the app
+//  2. Saved preferences replace the defaults and earlier choices of their
+//     type only
+//  3. Unknown types and email modes are rejected
+func TestNotificationPreferences(t *testing.T) {
+	svc, _ := newNotificationServices(t, 8)
+	user := newNotifiedUser(t, svc, "sara")
+	ctx := context.Background()
+
+	channels := func(prefs []models.NotificationPreference) map[string]string {
+		out := map[string]string{}
+		for _, p := range prefs {
+			inApp := "app+"
+			if !p.InApp {
+				inApp = ""
+			}
+			out[p.Type] = inApp + p.Email
+		}
+		return out
+	}
+	prefs, err := svc.Notifications.Preferences(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		models.NotificationPostApproved:  "app+instant",
+		models.NotificationPostRejected:  "app+instant",
+		models.NotificationPostCommented: "app+daily",
+		models.NotificationMentioned:     "app+daily",
+	}, channels(prefs))
+
+	_, err = svc.Notifications.UpdatePreferences(ctx, user.ID, []models.NotificationPreference{
+		{Type: models.NotificationMentioned, InApp: false, Email: models.NotificationEmailInstant},
+	})
+	require.NoError(t, err)
+	prefs, err = svc.Notifications.UpdatePreferences(ctx, user.ID, []models.NotificationPreference{
+		{Type: models.NotificationMentioned, InApp: true, Email: models.NotificationEmailOff},
+		{Type: models.NotificationPostApproved, InApp: false, Email: models.NotificationEmailDaily},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		models.NotificationPostApproved:  "daily",
+		models.NotificationPostRejected:  "app+instant",
+		models.NotificationPostCommented: "app+daily",
+		models.NotificationMentioned:     "app+off",
+	}, channels(prefs))
+
+	_, err = svc.Notifications.UpdatePreferences(ctx, user.ID, []models.NotificationPreference{{Type: "liked", Email: models.NotificationEmailOff}})
+	assert.ErrorContains(t, err, "unknown notification type")
+	_, err = svc.Notifications.UpdatePreferences(ctx, user.ID, []models.NotificationPreference{{Type: models.NotificationMentioned, Email: "weekly"}})
+	assert.ErrorContains(t, err, "email must be off, instant or daily")
+}
+
+// TestNotify tests delivering notifications on the chosen channels.
+//
+// Test Cases:
+//  1. In-app notifications are listed and counted as unread until read
+//  2. Instant notifications are emailed right away
+//  3. Daily notifications are held for the digest, unless digests are off
+//  4. Notifications on no channel are not stored
+func TestNotify(t *testing.T) {
+	svc, mailer := newNotificationServices(t, 8)
+	user := newNotifiedUser(t, svc, "sara")
+	ctx := context.Background()
+	_, err := svc.Notifications.UpdatePreferences(ctx, user.ID, []models.NotificationPreference{
+		{Type: models.NotificationMentioned, InApp: false, Email: models.NotificationEmailOff},
+	})
+	require.NoError(t, err)
+
+	approved := &models.Notification{UserID: user.ID, Type: models.NotificationPostApproved, Title: "Your post was approved", Link: "/posts/1"}
+	require.NoError(t, svc.Notifications.Notify(ctx, approved))
+	commented := &models.Notification{UserID: user.ID, Type: models.NotificationPostCommented, Title: "Ali commented on your post", Link: "/posts/1#comment-2"}
+	require.NoError(t, svc.Notifications.Notify(ctx, commented))
+	mentioned := &models.Notification{UserID: user.ID, Type: models.NotificationMentioned, Title: "Ali mentioned you"}
+	require.NoError(t, svc.Notifications.Notify(ctx, mentioned))
+	assert.Zero(t, mentioned.ID)
+
+	assert.True(t, approved.InApp)
+	assert.False(t, approved.Digest)
+	assert.True(t, commented.Digest)
+	deliverEmails(t, svc)
+	sent := mailer.SentTo(user.Email)
+	require.Len(t, sent, 1)
+	assert.Equal(t, "Your post was approved", sent[0].Subject)
+	assert.Contains(t, sent[0].Links(), "https://cms.example.com/posts/1")
+
+	list, pagination, err := svc.Notifications.ListNotifications(ctx, user.ID, false, 1, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, pagination.Total)
+	require.Len(t, list, 2)
+	unread, err := svc.Notifications.UnreadCount(ctx, user.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, unread)
+	_, err = svc.Notifications.MarkRead(ctx, user.ID, approved.ID)
+	require.NoError(t, err)
+	unread, err = svc.Notifications.UnreadCount(ctx, user.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, unread)
+
+	noDigests, _ := newNotificationServices(t, -1)
+	other := newNotifiedUser(t, noDigests, "ali")
+	held := &models.Notification{UserID: other.ID, Type: models.NotificationPostCommented, Title: "Sara commented on your post"}
+	require.NoError(t, noDigests.Notifications.Notify(ctx, held))
+	assert.True(t, held.InApp)
+	assert.False(t, held.Digest)
+}
+
+// TestSendDigests tests the daily notification digest.
+//
+// Test Cases:
+//  1. Each user with held notifications gets one digest listing them
+//  2. The digest email is queued with the notifications marked, and held
+//     notifications are sent only once
+//  3. Inactive users' notifications are marked but not sent
+func TestSendDigests(t *testing.T) {
+	svc, mailer := newNotificationServices(t, 8)
+	sara := newNotifiedUser(t, svc, "sara")
+	ali := newNotifiedUser(t, svc, "ali")
+	banned := newNotifiedUser(t, svc, "spammer")
+	require.NoError(t, svc.DB.Model(banned).Update("status", models.UserStatusBanned).Error)
+	ctx := context.Background()
+
+	for _, n := range []*models.Notification{
+		{UserID: sara.ID, Type: models.NotificationPostCommented, Title: "Ali commented on your post", Link: "/posts/1#comment-2"},
+		{UserID: sara.ID, Type: models.NotificationMentioned, Title: "Reza mentioned you", Link: "/posts/3#comment-4"},
+		{UserID: ali.ID, Type: models.NotificationPostApproved, Title: "Your post was approved", Link: "/posts/5"},
+		{UserID: banned.ID, Type: models.NotificationMentioned, Title: "Sara mentioned you", Link: "/posts/1"},
+	} {
+		require.NoError(t, svc.Notifications.Notify(ctx, n))
+	}
+	deliverEmails(t, svc)
+	require.Len(t, mailer.SentTo(ali.Email), 1, "the approval was emailed right away")
+
+	sent, err := svc.Notifications.SendDigests(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	var queued int64
+	require.NoError(t, svc.DB.Model(&models.Job{}).Where("queue = ? AND status = ?", services.EmailQueue, models.JobStatusPending).Count(&queued).Error)
+	assert.EqualValues(t, 1, queued)
+	var held int64
+	require.NoError(t, svc.DB.Model(&models.Notification{}).Where("digest AND emailed_at IS NULL").Count(&held).Error)
+	assert.Zero(t, held)
+
+	deliverEmails(t, svc)
+	digests := mailer.SentTo(sara.Email)
+	require.Len(t, digests, 1)
+	assert.Contains(t, digests[0].HTML, "Ali commented on your post")
+	assert.Contains(t, digests[0].HTML, "Reza mentioned you")
+	assert.Contains(t, digests[0].Links(), "https://cms.example.com/posts/3#comment-4")
+	assert.Len(t, mailer.SentTo(ali.Email), 1)
+	assert.Empty(t, mailer.SentTo(banned.Email))
+
+	sent, err = svc.Notifications.SendDigests(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+	deliverEmails(t, svc)
+	assert.Len(t, mailer.SentTo(sara.Email), 1)
+}
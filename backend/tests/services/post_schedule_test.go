@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/tests/testdb"
+	"github.com/sasanzare/go-cms/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDraft stores an author and a draft post, with posts on a clock
+// stopped at noon.
+func newDraft(t *testing.T) (*services.PostService, *utils.ManualClock, *models.Post) {
+	t.Helper()
+	db := testdb.Open(t, "services")
+	clock := utils.NewManualClock(time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC))
+	posts := services.NewPostService(db, services.WithClock(clock))
+
+	author := &models.User{Username: "writer", FirstName: "Sara", LastName: "Writer", Email: "writer@example.com", Password: "hash", Role: models.UserRoleAuthor}
+	require.NoError(t, db.Create(author).Error)
+	post := &models.Post{Title: "Launch notes", Content: "<p>We are launching soon.</p>", AuthorID: author.ID}
+	require.NoError(t, posts.CreatePost(context.Background(), post))
+	return posts, clock, post
+}
+
+// TestSchedulePost tests publishing and unpublishing posts on schedule.
+//
+// Test Cases:
+//  1. Publish times not in the future are rejected
+//  2. An unpublish time before the publish time is rejected
+//  3. A scheduled post is published once its time comes, as of that time
+//  4. A published post is archived once its unpublish time passes
+//  5. Each status change is recorded as a status-only revision, leaving
+//     translations of the post up to date
+func TestSchedulePost(t *testing.T) {
+	ctx := context.Background()
+	posts, clock, post := newDraft(t)
+	now := clock.Now()
+
+	_, err := posts.SchedulePost(ctx, post.ID, now.Add(-time.Minute), nil)
+	assert.ErrorContains(t, err, "scheduled_at must be in the future")
+	_, err = posts.SchedulePost(ctx, post.ID, now, nil)
+	assert.ErrorContains(t, err, "scheduled_at must be in the future")
+
+	publishAt := now.Add(time.Hour)
+	early := now.Add(30 * time.Minute)
+	_, err = posts.SchedulePost(ctx, post.ID, publishAt, &early)
+	assert.ErrorContains(t, err, "unpublish_at must be after scheduled_at")
+
+	unpublishAt := now.Add(24 * time.Hour)
+	scheduled, err := posts.SchedulePost(ctx, post.ID, publishAt, &unpublishAt)
+	require.NoError(t, err)
+	assert.Equal(t, models.PostStatusScheduled, scheduled.Status)
+
+	published, err := posts.PublishDue(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, published, "not due yet")
+
+	clock.Advance(90 * time.Minute)
+	published, err = posts.PublishDue(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	got, err := posts.GetPostByID(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PostStatusPublished, got.Status)
+	require.NotNil(t, got.PublishedAt)
+	assert.True(t, publishAt.Equal(*got.PublishedAt), "published as of %v, not %v", publishAt, *got.PublishedAt)
+
+	published, err = posts.PublishDue(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, published, "published once")
+
+	unpublished, err := posts.UnpublishExpired(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, unpublished, "not expired yet")
+
+	clock.Set(unpublishAt.Add(time.Second))
+	unpublished, err = posts.UnpublishExpired(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, unpublished)
+	got, err = posts.GetPostByID(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PostStatusArchived, got.Status)
+
+	revisions, err := posts.ListRevisions(ctx, post.ID)
+	require.NoError(t, err)
+	statuses := []string{}
+	for _, revision := range revisions {
+		statuses = append(statuses, revision.Status)
+		assert.Equal(t, revision.Number > 1, revision.StatusOnly, "revision %d", revision.Number)
+		assert.Equal(t, post.AuthorID, revision.AuthorID)
+	}
+	assert.Equal(t, []string{models.PostStatusArchived, models.PostStatusPublished, models.PostStatusScheduled, models.PostStatusDraft}, statuses)
+	assert.Equal(t, got.Version, revisions[0].PostVersion)
+
+	translations, err := posts.ListTranslations(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), translations[0].LatestRevision, "status changes don't outdate translations")
+}
+
+// TestCancelSchedule tests returning scheduled posts to draft.
+//
+// Test Cases:
+//  1. Only scheduled posts can be cancelled
+//  2. A cancelled post is a draft without a publish time
+//  3. The scheduler leaves it alone once its old time passes
+func TestCancelSchedule(t *testing.T) {
+	ctx := context.Background()
+	posts, clock, post := newDraft(t)
+
+	_, err := posts.CancelSchedule(ctx, post.ID)
+	assert.ErrorContains(t, err, "post is not scheduled")
+
+	_, err = posts.SchedulePost(ctx, post.ID, clock.Now().Add(time.Hour), nil)
+	require.NoError(t, err)
+	cancelled, err := posts.CancelSchedule(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PostStatusDraft, cancelled.Status)
+	assert.Nil(t, cancelled.ScheduledAt)
+
+	clock.Advance(2 * time.Hour)
+	published, err := posts.PublishDue(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, published)
+	got, err := posts.GetPostByID(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PostStatusDraft, got.Status)
+	assert.Nil(t, got.PublishedAt)
+}
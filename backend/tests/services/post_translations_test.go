@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sasanzare/go-cms/i18n"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/tests/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// translatedPosts are the posts newTranslatedPosts stores: hello in
+// English with a published Persian and a draft German translation, and
+// English and Persian originals sharing the slug "only".
+type translatedPosts struct {
+	svc                     *services.PostService
+	hello, helloFa, helloDe *models.Post
+	onlyEn, onlyFa          *models.Post
+}
+
+func newTranslatedPosts(t *testing.T) translatedPosts {
+	t.Helper()
+	db := testdb.Open(t, "services")
+	locales, err := i18n.New("en", []string{"en", "fa", "de"}, nil)
+	require.NoError(t, err)
+	svc := services.NewPostService(db, services.WithLocales(locales))
+	ctx := context.Background()
+
+	author := &models.User{Username: "writer", FirstName: "Sara", LastName: "Writer", Email: "writer@example.com", Password: "hash", Role: models.UserRoleAuthor}
+	require.NoError(t, db.Create(author).Error)
+	create := func(title, slug, locale, status string, source *models.Post) *models.Post {
+		post := &models.Post{Title: title, Slug: slug, Locale: locale, Status: status, Content: "<p>" + title + "</p>", AuthorID: author.ID}
+		if source != nil {
+			require.NoError(t, svc.CreateTranslation(ctx, source.ID, post))
+		} else {
+			require.NoError(t, svc.CreatePost(ctx, post))
+		}
+		return post
+	}
+
+	p := translatedPosts{svc: svc}
+	p.hello = create("Hello", "hello", "en", models.PostStatusPublished, nil)
+	p.helloFa = create("Salam", "salam", "fa", models.PostStatusPublished, p.hello)
+	p.helloDe = create("Hallo", "hallo", "de", models.PostStatusDraft, p.helloFa)
+	p.onlyEn = create("Only English", "only", "en", models.PostStatusPublished, nil)
+	p.onlyFa = create("Only Persian", "only", "fa", models.PostStatusPublished, nil)
+	return p
+}
+
+// TestGetPostBySlug tests resolving slugs through locale fallback chains.
+//
+// Test Cases:
+//  1. A slug resolves to its group's variant in the earliest locale of the
+//     chain that has one
+//  2. A slug used in several locales resolves in the earliest of them
+//  3. Unpublished variants are skipped for readers, but found for staff
+//  4. Unknown slugs are not found
+func TestGetPostBySlug(t *testing.T) {
+	p := newTranslatedPosts(t)
+	ctx := context.Background()
+	require.NotNil(t, p.helloDe.SourceID)
+	assert.Equal(t, p.hello.ID, *p.helloDe.SourceID, "translations of translations join the original's group")
+
+	resolve := func(slug string, locales []string, publishedOnly bool) uint {
+		t.Helper()
+		post, err := p.svc.GetPostBySlug(ctx, slug, locales, publishedOnly)
+		require.NoError(t, err)
+		return post.ID
+	}
+	assert.Equal(t, p.hello.ID, resolve("salam", []string{"en"}, true))
+	assert.Equal(t, p.helloFa.ID, resolve("hello", []string{"fa", "en"}, true))
+	assert.Equal(t, p.helloFa.ID, resolve("hello", []string{"de", "fa", "en"}, true))
+	assert.Equal(t, p.helloDe.ID, resolve("hello", []string{"de", "fa", "en"}, false))
+	assert.Equal(t, p.onlyFa.ID, resolve("only", []string{"fa", "en"}, true))
+	assert.Equal(t, p.onlyEn.ID, resolve("only", []string{"de", "en"}, true))
+
+	_, err := p.svc.GetPostBySlug(ctx, "hallo", []string{"de", "en"}, true)
+	assert.EqualError(t, err, "post not found")
+	_, err = p.svc.GetPostBySlug(ctx, "missing", []string{"en"}, false)
+	assert.EqualError(t, err, "post not found")
+}
+
+// TestListPostsLocales tests listing one variant per translation group.
+//
+// Test Cases:
+//  1. Each group is listed once, in the earliest locale of the chain
+//  2. Variants outside the chain don't hide others
+//  3. With a status filter, variants in other statuses don't count
+func TestListPostsLocales(t *testing.T) {
+	p := newTranslatedPosts(t)
+	ctx := context.Background()
+
+	list := func(filter services.PostFilter) []uint {
+		t.Helper()
+		posts, _, err := p.svc.ListPosts(ctx, filter)
+		require.NoError(t, err)
+		ids := []uint{}
+		for _, post := range posts {
+			ids = append(ids, post.ID)
+		}
+		return ids
+	}
+	assert.ElementsMatch(t, []uint{p.helloFa.ID, p.onlyEn.ID, p.onlyFa.ID},
+		list(services.PostFilter{Status: models.PostStatusPublished, Locales: []string{"fa", "en"}}))
+	assert.ElementsMatch(t, []uint{p.hello.ID, p.onlyEn.ID},
+		list(services.PostFilter{Status: models.PostStatusPublished, Locales: []string{"en"}}))
+	assert.ElementsMatch(t, []uint{p.hello.ID, p.onlyEn.ID},
+		list(services.PostFilter{Status: models.PostStatusPublished, Locales: []string{"de", "en"}}),
+		"the German draft doesn't hide the published original")
+	assert.ElementsMatch(t, []uint{p.helloDe.ID, p.onlyEn.ID},
+		list(services.PostFilter{Locales: []string{"de", "en"}}))
+}
+
+// TestTranslationStates tests tracking missing and outdated translations.
+//
+// Test Cases:
+//  1. Translations start current with the original's latest revision
+//  2. Editing the original's text makes its translations outdated
+//  3. Translation tasks list missing and outdated translations, filtered
+//     by state
+//  4. Marking a translation up to date takes it off the tasks
+func TestTranslationStates(t *testing.T) {
+	p := newTranslatedPosts(t)
+	ctx := context.Background()
+
+	states := func(id uint) map[string]string {
+		t.Helper()
+		translations, err := p.svc.ListTranslations(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, services.TranslationSource, translations[0].State)
+		states := map[string]string{}
+		for _, tr := range translations {
+			states[tr.Locale] = tr.State
+		}
+		return states
+	}
+	assert.Equal(t, map[string]string{"en": services.TranslationSource, "fa": services.TranslationCurrent, "de": services.TranslationCurrent}, states(p.helloDe.ID))
+	assert.Equal(t, map[string]string{"en": services.TranslationMissing, "fa": services.TranslationSource, "de": services.TranslationMissing}, states(p.onlyFa.ID))
+
+	_, err := p.svc.UpdatePost(ctx, p.hello.ID, map[string]interface{}{"content": "<p>Hello again</p>"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"en": services.TranslationSource, "fa": services.TranslationOutdated, "de": services.TranslationOutdated}, states(p.hello.ID))
+
+	tasks := func(locale, state string) map[uint]string {
+		t.Helper()
+		tasks, _, err := p.svc.ListTranslationTasks(ctx, locale, state, 1, 10)
+		require.NoError(t, err)
+		states := map[uint]string{}
+		for _, task := range tasks {
+			states[task.PostID] = task.State
+		}
+		return states
+	}
+	assert.Equal(t, map[uint]string{p.hello.ID: services.TranslationOutdated, p.onlyEn.ID: services.TranslationMissing}, tasks("fa", ""))
+	assert.Equal(t, map[uint]string{p.onlyEn.ID: services.TranslationMissing}, tasks("fa", services.TranslationMissing))
+	assert.Equal(t, map[uint]string{p.hello.ID: services.TranslationOutdated}, tasks("de", services.TranslationOutdated))
+	assert.Equal(t, map[uint]string{p.onlyFa.ID: services.TranslationMissing}, tasks("en", ""))
+
+	_, err = p.svc.UpdatePost(ctx, p.helloFa.ID, map[string]interface{}{"source_revision": uint(2)})
+	require.NoError(t, err)
+	assert.Equal(t, map[uint]string{p.onlyEn.ID: services.TranslationMissing}, tasks("fa", ""))
+	_, _, err = p.svc.ListTranslationTasks(ctx, "fr", "", 1, 10)
+	assert.ErrorContains(t, err, "unsupported locale")
+}
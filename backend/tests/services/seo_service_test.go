@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sasanzare/go-cms/i18n"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/seo"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/tests/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCategoryLinks tests the URLs of categories sharing a slug across
+// locales.
+//
+// Test Cases:
+//  1. Canonical URLs and breadcrumbs name the category's locale
+//  2. hreflang alternates link each translation in its own locale
+//  3. Sitemaps list each translation under its own URL
+func TestCategoryLinks(t *testing.T) {
+	db := testdb.Open(t, "services")
+	locales, err := i18n.New("en", []string{"en", "fa"}, nil)
+	require.NoError(t, err)
+	taxonomy := services.NewTaxonomyService(db, locales)
+	ctx := context.Background()
+
+	news := &models.Category{Name: "News", Slug: "news", Status: models.CategoryStatusPublished}
+	require.NoError(t, taxonomy.CreateCategory(ctx, news))
+	fa := &models.Category{Name: "Akhbar", Slug: "news", Locale: "fa", Status: models.CategoryStatusPublished}
+	require.NoError(t, taxonomy.CreateCategoryTranslation(ctx, news.ID, fa))
+
+	const site = "https://cms.example.com"
+	meta, err := services.NewSEOService(db, "Example", site, "", locales).CategoryMeta(ctx, fa.ID)
+	require.NoError(t, err)
+	assert.Equal(t, site+"/categories/news?lang=fa", meta.Canonical)
+	assert.ElementsMatch(t, []seo.Alternate{
+		{Lang: "en", Href: site + "/categories/news?lang=en"},
+		{Lang: "x-default", Href: site + "/categories/news?lang=en"},
+		{Lang: "fa", Href: site + "/categories/news?lang=fa"},
+	}, meta.Alternates)
+
+	doc, err := services.NewSitemapService(db, site, locales).Shard(ctx, "categories", 1)
+	require.NoError(t, err)
+	assert.Contains(t, string(doc.Body), "<loc>"+site+"/categories/news?lang=en</loc>")
+	assert.Contains(t, string(doc.Body), "<loc>"+site+"/categories/news?lang=fa</loc>")
+}
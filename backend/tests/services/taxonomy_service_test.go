@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sasanzare/go-cms/i18n"
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/tests/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTaxonomyService(t *testing.T) *services.TaxonomyService {
+	t.Helper()
+	locales, err := i18n.New("en", []string{"en", "fa", "de"}, map[string][]string{"de": {"en"}})
+	require.NoError(t, err)
+	return services.NewTaxonomyService(testdb.Open(t, "services"), locales)
+}
+
+func translationStates(translations []services.TermTranslation) map[string]string {
+	states := map[string]string{}
+	for _, tr := range translations {
+		states[tr.Locale] = tr.State
+	}
+	return states
+}
+
+// TestCategoryTranslations tests translating categories.
+//
+// Test Cases:
+//  1. Translations join the original's group, even when made from another
+//     translation, once per supported locale
+//  2. Slugs are unique per locale only
+//  3. Readers see each published group once, in their preferred locale or
+//     its fallback
+//  4. Slugs resolve to the group's variant in the preferred locale
+//  5. Translations are outdated once the original changes, until updated
+//  6. Translation tasks list missing and outdated translations
+func TestCategoryTranslations(t *testing.T) {
+	svc := newTaxonomyService(t)
+	ctx := context.Background()
+
+	news := &models.Category{Name: "News", Slug: "news", Status: models.CategoryStatusPublished}
+	require.NoError(t, svc.CreateCategory(ctx, news))
+	assert.Equal(t, "en", news.Locale)
+	assert.NotNil(t, news.PublishedAt)
+	sport := &models.Category{Name: "Sport", Slug: "sport", Status: models.CategoryStatusPublished}
+	require.NoError(t, svc.CreateCategory(ctx, sport))
+	drafts := &models.Category{Name: "Drafts", Slug: "drafts"}
+	require.NoError(t, svc.CreateCategory(ctx, drafts))
+
+	fa := &models.Category{Name: "Akhbar", Slug: "akhbar", Locale: "fa", Status: models.CategoryStatusPublished}
+	require.NoError(t, svc.CreateCategoryTranslation(ctx, news.ID, fa))
+	require.NotNil(t, fa.SourceID)
+	assert.Equal(t, news.ID, *fa.SourceID)
+	de := &models.Category{Name: "Nachrichten", Slug: "news", Locale: "de", Status: models.CategoryStatusPublished}
+	require.NoError(t, svc.CreateCategoryTranslation(ctx, fa.ID, de), "the slug is free in another locale")
+	assert.Equal(t, news.ID, *de.SourceID)
+
+	err := svc.CreateCategoryTranslation(ctx, news.ID, &models.Category{Name: "Khabar", Slug: "khabar", Locale: "fa"})
+	assert.ErrorContains(t, err, "already has a fa translation")
+	err = svc.CreateCategoryTranslation(ctx, news.ID, &models.Category{Name: "Nouvelles", Locale: "fr"})
+	assert.ErrorContains(t, err, "unsupported locale")
+	err = svc.CreateCategory(ctx, &models.Category{Name: "News again", Slug: "news"})
+	assert.ErrorContains(t, err, "slug is already used")
+	err = svc.CreateCategoryTranslation(ctx, 999, &models.Category{Name: "Missing", Locale: "fa"})
+	assert.EqualError(t, err, "category not found")
+
+	categories, err := svc.ListCategories(ctx, []string{"fa", "en"}, true)
+	require.NoError(t, err)
+	names := []string{}
+	for _, category := range categories {
+		names = append(names, category.Name)
+	}
+	assert.Equal(t, []string{"Akhbar", "Sport"}, names)
+
+	category, err := svc.GetCategoryBySlug(ctx, "news", []string{"de", "en"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, de.ID, category.ID)
+	category, err = svc.GetCategoryBySlug(ctx, "akhbar", []string{"en"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, news.ID, category.ID, "the group's English variant is preferred")
+	_, err = svc.GetCategoryBySlug(ctx, "drafts", []string{"en"}, true)
+	assert.EqualError(t, err, "category not found")
+
+	translations, err := svc.ListCategoryTranslations(ctx, de.ID)
+	require.NoError(t, err)
+	assert.Equal(t, services.TranslationSource, translations[0].State)
+	assert.Equal(t, map[string]string{"en": services.TranslationSource, "fa": services.TranslationCurrent, "de": services.TranslationCurrent}, translationStates(translations))
+
+	_, err = svc.UpdateCategory(ctx, news.ID, map[string]interface{}{"description": "What happened"})
+	require.NoError(t, err)
+	_, err = svc.UpdateCategory(ctx, de.ID, map[string]interface{}{"description": "Was geschah"})
+	require.NoError(t, err)
+	translations, err = svc.ListCategoryTranslations(ctx, news.ID)
+	require.NoError(t, err)
+	assert.Equal(t, services.TranslationOutdated, translationStates(translations)["fa"])
+	assert.Equal(t, services.TranslationCurrent, translationStates(translations)["de"])
+
+	tasks, pagination, err := svc.ListCategoryTranslationTasks(ctx, "fa", "", 1, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, pagination.Total)
+	states := map[uint]string{}
+	for _, task := range tasks {
+		states[task.ID] = task.State
+	}
+	assert.Equal(t, map[uint]string{news.ID: services.TranslationOutdated, sport.ID: services.TranslationMissing, drafts.ID: services.TranslationMissing}, states)
+	tasks, _, err = svc.ListCategoryTranslationTasks(ctx, "fa", services.TranslationOutdated, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, fa.ID, *tasks[0].TranslationID)
+	_, _, err = svc.ListCategoryTranslationTasks(ctx, "fa", "done", 1, 10)
+	assert.ErrorContains(t, err, "state must be missing or outdated")
+}
+
+// TestTagTranslations tests translating tags.
+//
+// Test Cases:
+//  1. Names and slugs are unique per locale only
+//  2. Names are stored as plain text and can't be left empty
+//  3. Readers see each active group once, in their preferred locale
+//  4. Missing translations are listed as tasks
+func TestTagTranslations(t *testing.T) {
+	svc := newTaxonomyService(t)
+	ctx := context.Background()
+
+	golang := &models.Tag{Name: "go"}
+	require.NoError(t, svc.CreateTag(ctx, golang))
+	assert.Equal(t, "go", golang.Slug)
+	assert.Equal(t, models.TagStatusActive, golang.Status)
+	old := &models.Tag{Name: "old", Status: models.TagStatusArchived}
+	require.NoError(t, svc.CreateTag(ctx, old))
+
+	err := svc.CreateTag(ctx, &models.Tag{Name: "go", Slug: "golang"})
+	assert.ErrorContains(t, err, "name is already used")
+	fa := &models.Tag{Name: "go", Locale: "fa"}
+	require.NoError(t, svc.CreateTagTranslation(ctx, golang.ID, fa))
+	_, err = svc.UpdateTag(ctx, old.ID, map[string]interface{}{"slug": "go"})
+	assert.ErrorContains(t, err, "slug is already used")
+	renamed, err := svc.UpdateTag(ctx, old.ID, map[string]interface{}{"name": "<b>old &amp; gold</b>"})
+	require.NoError(t, err)
+	assert.Equal(t, "old & gold", renamed.Name)
+	_, err = svc.UpdateTag(ctx, old.ID, map[string]interface{}{"name": "<i></i>"})
+	assert.ErrorContains(t, err, "name is required")
+
+	tags, err := svc.ListTags(ctx, []string{"fa", "en"}, true)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	assert.Equal(t, fa.ID, tags[0].ID)
+
+	tag, err := svc.GetTagBySlug(ctx, "go", []string{"de", "en"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, golang.ID, tag.ID)
+
+	tasks, _, err := svc.ListTagTranslationTasks(ctx, "de", services.TranslationMissing, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1, "archived tags need no translation")
+	assert.Equal(t, golang.ID, tasks[0].ID)
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sasanzare/go-cms/models"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewVersionConflict tests describing edits based on an old version.
+//
+// Test Cases:
+//  1. The conflict names the expected and current versions
+//  2. ChangedFields lists the revisioned fields that differ, sorted
+//  3. Changes diffs the base revision's text against the current text
+//  4. Without a base revision there is no diff
+//  5. A status-only change lists no fields
+func TestNewVersionConflict(t *testing.T) {
+	base := &models.PostRevision{
+		PostID:      7,
+		Number:      2,
+		PostVersion: 3,
+		Title:       "Launch notes",
+		Content:     "line one\nline two",
+		Excerpt:     "Soon",
+	}
+	post := &models.Post{
+		ID:      7,
+		Version: 5,
+		Title:   "Launch notes, updated",
+		Content: "line one\nline three",
+		Excerpt: "Soon",
+	}
+
+	conflict := services.NewVersionConflict(post, 3, base)
+	assert.Equal(t, uint(7), conflict.PostID)
+	assert.Equal(t, uint(3), conflict.ExpectedVersion)
+	assert.Equal(t, uint(5), conflict.CurrentVersion)
+	assert.Same(t, post, conflict.Current)
+	assert.Equal(t, []string{"content", "title"}, conflict.ChangedFields)
+	assert.Contains(t, conflict.Error(), "version 5 is current, not 3")
+
+	require.NotNil(t, conflict.Changes)
+	assert.Equal(t, uint(7), conflict.Changes.PostID)
+	assert.Equal(t, uint(2), conflict.Changes.From)
+	assert.Zero(t, conflict.Changes.To)
+	assert.Equal(t, services.DiffModeLine, conflict.Changes.Mode)
+	assert.Contains(t, conflict.Changes.Content, utils.DiffOp{Type: utils.DiffDelete, Text: "line two"})
+	assert.Contains(t, conflict.Changes.Content, utils.DiffOp{Type: utils.DiffInsert, Text: "line three"})
+
+	conflict = services.NewVersionConflict(post, 3, nil)
+	assert.Nil(t, conflict.Changes)
+	assert.NotNil(t, conflict.ChangedFields)
+	assert.Empty(t, conflict.ChangedFields)
+
+	unchanged := models.NewPostRevision(post, 4, 1)
+	conflict = services.NewVersionConflict(post, 4, unchanged)
+	assert.Empty(t, conflict.ChangedFields)
+	require.NotNil(t, conflict.Changes)
+}
+
+// TestUpdatePostIfMatch tests optimistic locking of post edits.
+//
+// Test Cases:
+//  1. An edit based on the current version applies
+//  2. An edit based on an older version fails with the conflict, listing
+//     and diffing what changed since
+//  3. The failed edit isn't applied
+func TestUpdatePostIfMatch(t *testing.T) {
+	ctx := context.Background()
+	posts, _, post := newDraft(t)
+	require.Equal(t, uint(1), post.Version)
+
+	updated, err := posts.UpdatePostIfMatch(ctx, post.ID, 1, map[string]interface{}{"title": "Launch notes, take two"})
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), updated.Version)
+
+	_, err = posts.UpdatePostIfMatch(ctx, post.ID, 1, map[string]interface{}{"excerpt": "Stale edit"})
+	var conflict *services.VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, uint(1), conflict.ExpectedVersion)
+	assert.Equal(t, uint(2), conflict.CurrentVersion)
+	assert.Equal(t, []string{"title"}, conflict.ChangedFields)
+	require.NotNil(t, conflict.Changes)
+	assert.Contains(t, conflict.Changes.Title, utils.DiffOp{Type: utils.DiffInsert, Text: "notes, take two"})
+
+	got, err := posts.GetPostByID(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Empty(t, got.Excerpt)
+	assert.Equal(t, uint(2), got.Version)
+}
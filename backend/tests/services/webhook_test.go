@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sasanzare/go-cms/events"
+	"github.com/sasanzare/go-cms/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWebhookDelivery tests posting events to webhook endpoints.
+//
+// Test Cases:
+//  1. Events published on the bus are posted to every endpoint
+//  2. Bodies carry the event and its data, signed with the secret
+//  3. Endpoints answering other than 2xx fail the delivery, to be retried
+func TestWebhookDelivery(t *testing.T) {
+	type delivery struct {
+		event     string
+		signature string
+		body      []byte
+	}
+	received := make(chan delivery, 4)
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- delivery{r.Header.Get(services.WebhookEventHeader), r.Header.Get(services.WebhookSignatureHeader), body}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	webhooks := services.NewWebhookService([]string{server.URL + "/a", server.URL + "/b"}, "s3cret", time.Second)
+	bus := events.NewBus()
+	webhooks.Subscribe(bus)
+	bus.Publish(context.Background(), events.PostApproved{PostID: 7, AuthorID: 2, ActorID: 1, Title: "Hello"})
+
+	require.Len(t, received, 2)
+	for i := 0; i < 2; i++ {
+		d := <-received
+		assert.Equal(t, "post.approved", d.event)
+		assert.Equal(t, services.SignWebhook("s3cret", d.body), d.signature)
+
+		var payload services.WebhookPayload
+		require.NoError(t, json.Unmarshal(d.body, &payload))
+		assert.Equal(t, "post.approved", payload.Event)
+		assert.JSONEq(t, `{"PostID":7,"AuthorID":2,"ActorID":1,"Title":"Hello"}`, string(payload.Data))
+	}
+
+	status = http.StatusBadGateway
+	err := webhooks.Deliver(context.Background(), services.DeliverWebhookArgs{URL: server.URL, Event: "post.approved", Data: json.RawMessage(`{}`)})
+	assert.ErrorContains(t, err, "502")
+}
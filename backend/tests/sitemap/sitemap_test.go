@@ -0,0 +1,92 @@
+package sitemap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/sasanzare/go-cms/services"
+	"github.com/sasanzare/go-cms/sitemap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWriteURLSet tests writing sitemaps.
+//
+// Test Cases:
+//  1. URLs are escaped and lastmod is W3C datetime in UTC
+//  2. Images and hreflang alternates use their namespaces
+//  3. Pages without images or translations have neither
+func TestWriteURLSet(t *testing.T) {
+	updated := time.Date(2024, time.August, 2, 9, 30, 0, 0, time.FixedZone("IRST", 3*3600+1800))
+	var buf bytes.Buffer
+	err := sitemap.WriteURLSet(&buf, []sitemap.URL{
+		{
+			Loc:     "https://cms.example.com/posts/1?a=1&b=2",
+			LastMod: updated,
+			Images:  []string{"https://cms.example.com/media/cat.jpg"},
+			Alternates: []sitemap.Alternate{
+				{Lang: "en", Href: "https://cms.example.com/posts/1"},
+				{Lang: "x-default", Href: "https://cms.example.com/posts/1"},
+				{Lang: "fa", Href: "https://cms.example.com/posts/2"},
+			},
+		},
+		{Loc: "https://cms.example.com/categories/news"},
+	})
+	require.NoError(t, err)
+	out := buf.String()
+
+	assert.Contains(t, out, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"`)
+	assert.Contains(t, out, `<loc>https://cms.example.com/posts/1?a=1&amp;b=2</loc>`)
+	assert.Contains(t, out, `<lastmod>2024-08-02T06:00:00Z</lastmod>`)
+	assert.Contains(t, out, `<xhtml:link rel="alternate" hreflang="fa" href="https://cms.example.com/posts/2"></xhtml:link>`)
+	assert.Contains(t, out, `<image:image><image:loc>https://cms.example.com/media/cat.jpg</image:loc></image:image>`)
+
+	var doc struct {
+		URLs []struct {
+			Loc   string `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 loc"`
+			Links []struct {
+				HrefLang string `xml:"hreflang,attr"`
+			} `xml:"http://www.w3.org/1999/xhtml link"`
+			Images []string `xml:"http://www.google.com/schemas/sitemap-image/1.1 image>loc"`
+		} `xml:"url"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	require.Len(t, doc.URLs, 2)
+	assert.Equal(t, "https://cms.example.com/posts/1?a=1&b=2", doc.URLs[0].Loc)
+	assert.Len(t, doc.URLs[0].Links, 3)
+	assert.Equal(t, []string{"https://cms.example.com/media/cat.jpg"}, doc.URLs[0].Images)
+	assert.Empty(t, doc.URLs[1].Links)
+	assert.Empty(t, doc.URLs[1].Images)
+	assert.NotContains(t, out, "<lastmod></lastmod>")
+}
+
+// TestWriteIndex tests writing sitemap indexes.
+//
+// Test Cases:
+//  1. Each sitemap is listed with its lastmod
+//  2. Shard paths number sections from 1
+func TestWriteIndex(t *testing.T) {
+	var buf bytes.Buffer
+	err := sitemap.WriteIndex(&buf, []sitemap.Sitemap{
+		{Loc: "https://cms.example.com" + services.SitemapPath("posts", 1), LastMod: time.Date(2024, time.August, 2, 0, 0, 0, 0, time.UTC)},
+		{Loc: "https://cms.example.com" + services.SitemapPath("categories", 1)},
+	})
+	require.NoError(t, err)
+
+	var doc struct {
+		XMLName  xml.Name
+		Sitemaps []struct {
+			Loc     string `xml:"loc"`
+			LastMod string `xml:"lastmod"`
+		} `xml:"sitemap"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "sitemapindex", doc.XMLName.Local)
+	require.Len(t, doc.Sitemaps, 2)
+	assert.Equal(t, "https://cms.example.com/sitemaps/posts-1.xml", doc.Sitemaps[0].Loc)
+	assert.Equal(t, "2024-08-02T00:00:00Z", doc.Sitemaps[0].LastMod)
+	assert.Equal(t, "https://cms.example.com/sitemaps/categories-1.xml", doc.Sitemaps[1].Loc)
+	assert.Empty(t, doc.Sitemaps[1].LastMod)
+}
@@ -0,0 +1,166 @@
+package spam
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sasanzare/go-cms/spam"
+	"github.com/sasanzare/go-cms/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPipeline tests scoring submissions with the heuristic rules.
+//
+// Test Cases:
+//  1. A plain comment scores zero and has no rule results
+//  2. Extra links, blocklisted words and a filled honeypot add up per rule
+//  3. A submission is spam once its score reaches the threshold
+func TestPipeline(t *testing.T) {
+	ctx := context.Background()
+	pipeline := spam.NewPipeline(1,
+		spam.LinkRule{Allowed: 1, Weight: 0.5},
+		spam.BlocklistRule{Words: []string{"casino", "Cheap Pills"}, Weight: 1},
+		spam.HoneypotRule{Weight: 2},
+	)
+
+	v := pipeline.Check(ctx, &spam.Submission{Body: "Nice post, thanks!"})
+	assert.Zero(t, v.Score)
+	assert.False(t, v.Spam)
+	assert.Empty(t, v.Results)
+
+	v = pipeline.Check(ctx, &spam.Submission{Body: "See https://a.example and www.b.example"})
+	assert.Equal(t, 0.5, v.Score)
+	assert.False(t, v.Spam)
+	require.Len(t, v.Results, 1)
+	assert.Equal(t, spam.RuleResult{Rule: "links", Score: 0.5, Reason: "2 links"}, v.Results[0])
+
+	v = pipeline.Check(ctx, &spam.Submission{Body: "Best CASINO and cheap pills", Signals: spam.Signals{Honeypot: "x"}})
+	assert.Equal(t, 4.0, v.Score)
+	assert.True(t, v.Spam)
+	require.Len(t, v.Results, 2)
+	assert.Equal(t, "blocklisted: casino, cheap pills", v.Results[0].Reason)
+	assert.Equal(t, "honeypot", v.Results[1].Rule)
+}
+
+// TestBlocklistRule tests matching blocklisted words and phrases.
+//
+// Test Cases:
+//  1. Words match whole, ignoring case, in the body, name or email
+//  2. Words inside longer words don't match
+//  3. Phrases match across line breaks and repeated spaces
+func TestBlocklistRule(t *testing.T) {
+	ctx := context.Background()
+	rule := spam.BlocklistRule{Words: []string{"cialis", "cheap pills", "viagra"}, Weight: 1}
+
+	score, reason := rule.Check(ctx, &spam.Submission{Body: "Buy CIALIS now!", Email: "viagra@example.com"})
+	assert.Equal(t, 2.0, score)
+	assert.Equal(t, "blocklisted: cialis, viagra", reason)
+
+	score, _ = rule.Check(ctx, &spam.Submission{Body: "Ask a specialist about cheap pillsbury dough", Name: "Viagrafan"})
+	assert.Zero(t, score)
+
+	score, reason = rule.Check(ctx, &spam.Submission{Body: "Cheap\n  pills, here"})
+	assert.Equal(t, 1.0, score)
+	assert.Equal(t, "blocklisted: cheap pills", reason)
+}
+
+// TestFormTokens tests the tokens behind the submission speed check.
+//
+// Test Cases:
+//  1. A token's age follows the clock
+//  2. Missing, tampered and expired tokens are rejected
+//  3. SpeedRule penalizes fast and tokenless submissions
+func TestFormTokens(t *testing.T) {
+	clock := utils.NewManualClock(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	tokens := spam.NewFormTokens([]byte("secret"), clock)
+
+	token := tokens.Issue()
+	clock.Advance(5 * time.Second)
+	age, err := tokens.Age(token)
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, age)
+
+	_, err = tokens.Age("")
+	assert.EqualError(t, err, "no form token")
+	_, err = spam.NewFormTokens([]byte("other"), clock).Age(token)
+	assert.EqualError(t, err, "invalid form token")
+	clock.Advance(25 * time.Hour)
+	_, err = tokens.Age(token)
+	assert.EqualError(t, err, "expired form token")
+
+	rule := spam.SpeedRule{Tokens: tokens, Min: 3 * time.Second, Weight: 1, MissingWeight: 0.5}
+	fast := tokens.Issue()
+	clock.Advance(time.Second)
+	score, _ := rule.Check(context.Background(), &spam.Submission{Signals: spam.Signals{FormToken: fast}})
+	assert.Equal(t, 1.0, score)
+	clock.Advance(time.Minute)
+	score, _ = rule.Check(context.Background(), &spam.Submission{Signals: spam.Signals{FormToken: fast}})
+	assert.Zero(t, score)
+	score, reason := rule.Check(context.Background(), &spam.Submission{})
+	assert.Equal(t, 0.5, score)
+	assert.Equal(t, "no form token", reason)
+}
+
+// TestClassifier tests training the naive Bayes classifier.
+//
+// Test Cases:
+//  1. It has no opinion before seeing enough examples of both classes
+//  2. After training, spammy text scores high and legitimate text low
+//  3. Unlearning reverses learning, and a reloaded classifier agrees
+func TestClassifier(t *testing.T) {
+	ctx := context.Background()
+	store := spam.NewMemoryStore()
+	c := spam.NewClassifier(store)
+
+	_, ok := c.SpamProbability("anything")
+	assert.False(t, ok)
+
+	for i := 0; i < 6; i++ {
+		require.NoError(t, c.Learn(ctx, fmt.Sprintf("buy cheap watches at https://deals%d.example now", i), true))
+		require.NoError(t, c.Learn(ctx, fmt.Sprintf("great article about go generics, part %d", i), false))
+	}
+
+	p, ok := c.SpamProbability("cheap watches here")
+	require.True(t, ok)
+	assert.Greater(t, p, 0.9)
+	p, _ = c.SpamProbability("I enjoyed this article about generics")
+	assert.Less(t, p, 0.1)
+
+	extra := "cheap article"
+	before, _ := c.SpamProbability(extra)
+	require.NoError(t, c.Learn(ctx, extra, true))
+	require.NoError(t, c.Unlearn(ctx, extra, true))
+	after, _ := c.SpamProbability(extra)
+	assert.InDelta(t, before, after, 1e-9)
+
+	reloaded := spam.NewClassifier(store)
+	require.NoError(t, reloaded.Load(ctx))
+	p, _ = reloaded.SpamProbability(extra)
+	assert.InDelta(t, before, p, 1e-9)
+}
+
+// TestTokenize tests splitting text into classifier tokens.
+//
+// Test Cases:
+//  1. Words are lowercased and deduplicated, and short words dropped
+//  2. Links add a token for their host
+//  3. Long hosts keep their trailing labels within the token limit
+func TestTokenize(t *testing.T) {
+	tokens := spam.Tokenize("Visit www.Shop.example — visit NOW a")
+	assert.Equal(t, []string{"host:shop.example", "visit", "www", "shop", "example", "now"}, tokens)
+
+	label := strings.Repeat("a", 60)
+	tokens = spam.Tokenize("https://" + strings.Repeat(label+".", 4) + "cheap-pills.example/buy")
+	require.NotEmpty(t, tokens)
+	assert.Equal(t, "host:"+label+".cheap-pills.example", tokens[0])
+	for _, token := range tokens {
+		assert.LessOrEqual(t, len(token), spam.MaxTokenLength, token)
+	}
+
+	tokens = spam.Tokenize("https://" + strings.Repeat("b", 200) + ".example")
+	assert.Equal(t, "host:example", tokens[0])
+}
@@ -0,0 +1,105 @@
+// Package testdb gives tests a migrated PostgreSQL database. Set
+// TEST_DATABASE_URL to a database tests may wipe; tests using Open are
+// skipped without it.
+//
+// Each test package gets a schema of its own, since packages run in
+// parallel, and every Open empties its tables.
+package testdb
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sasanzare/go-cms/migrations"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	mu  sync.Mutex
+	dbs = map[string]*gorm.DB{}
+)
+
+// Open returns the database of schema test_<name>, migrated and emptied.
+func Open(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	db, ok := dbs[name]
+	if !ok {
+		var err error
+		db, err = open(dsn, "test_"+name)
+		require.NoError(t, err)
+		dbs[name] = db
+	}
+	reset(t, db)
+	return db
+}
+
+func open(dsn, schema string) (*gorm.DB, error) {
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		return nil, err
+	}
+	// Extensions live in public, where every schema finds them
+	err = admin.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA public`).Error
+	if err == nil {
+		err = admin.Exec(fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %q`, schema)).Error
+	}
+	if sqlDB, closeErr := admin.DB(); closeErr == nil {
+		_ = sqlDB.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(postgres.Open(withSearchPath(dsn, schema)), config)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrations.InitAutoMigrations(db, "en"); err != nil {
+		return nil, fmt.Errorf("migrate %s: %w", schema, err)
+	}
+	return db, nil
+}
+
+// withSearchPath adds search_path to a URL or key=value DSN.
+func withSearchPath(dsn, schema string) string {
+	searchPath := schema + ",public"
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		q := u.Query()
+		q.Set("search_path", searchPath)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + searchPath
+}
+
+// reset empties every table but the migration records.
+func reset(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	var tables []string
+	err := db.Raw(`SELECT tablename FROM pg_tables
+		WHERE schemaname = current_schema() AND tablename <> 'migration_records'`).
+		Scan(&tables).Error
+	require.NoError(t, err)
+	if len(tables) == 0 {
+		return
+	}
+	for i, table := range tables {
+		tables[i] = fmt.Sprintf("%q", table)
+	}
+	require.NoError(t, db.Exec("TRUNCATE "+strings.Join(tables, ", ")+" RESTART IDENTITY CASCADE").Error)
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sasanzare/go-cms/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestManualClock tests that a manual clock only moves when told to.
+//
+// Test Cases:
+//  1. Now returns the start time until the clock is moved
+//  2. Advance and Set move the clock
+//  3. Concurrent advances are not lost
+func TestManualClock(t *testing.T) {
+	start := time.Date(2025, 3, 21, 9, 0, 0, 0, time.UTC)
+	clock := utils.NewManualClock(start)
+
+	assert.Equal(t, start, clock.Now())
+	assert.Equal(t, start.Add(time.Hour), clock.Advance(time.Hour))
+	assert.Equal(t, start.Add(time.Hour), clock.Now())
+
+	clock.Set(start)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clock.Advance(time.Minute)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, start.Add(10*time.Minute), clock.Now())
+}
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sasanzare/go-cms/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rebuild reconstructs the old and new texts from a diff.
+func rebuild(ops []utils.DiffOp) (string, string) {
+	var a, b strings.Builder
+	for _, op := range ops {
+		if op.Type != utils.DiffInsert {
+			a.WriteString(op.Text)
+		}
+		if op.Type != utils.DiffDelete {
+			b.WriteString(op.Text)
+		}
+	}
+	return a.String(), b.String()
+}
+
+// TestDiffLines tests line-level diffs.
+//
+// Test Cases:
+//  1. Unchanged lines are kept as a single equal op
+//  2. A replaced line becomes a delete followed by an insert
+//  3. Both texts can be rebuilt from the ops
+func TestDiffLines(t *testing.T) {
+	a := "first\nsecond\nthird\n"
+	b := "first\nchanged\nthird\nfourth\n"
+
+	ops := utils.DiffLines(a, b)
+	assert.Equal(t, []utils.DiffOp{
+		{Type: utils.DiffEqual, Text: "first\n"},
+		{Type: utils.DiffDelete, Text: "second\n"},
+		{Type: utils.DiffInsert, Text: "changed\n"},
+		{Type: utils.DiffEqual, Text: "third\n"},
+		{Type: utils.DiffInsert, Text: "fourth\n"},
+	}, ops)
+
+	gotA, gotB := rebuild(ops)
+	assert.Equal(t, a, gotA)
+	assert.Equal(t, b, gotB)
+
+	assert.Empty(t, utils.DiffLines("", ""))
+	assert.Equal(t, []utils.DiffOp{{Type: utils.DiffEqual, Text: a}}, utils.DiffLines(a, a))
+}
+
+// TestDiffWords tests word-level diffs.
+//
+// Test Cases:
+//  1. Only the changed word is reported
+//  2. Whitespace is preserved so both texts can be rebuilt
+func TestDiffWords(t *testing.T) {
+	a := "The quick brown fox"
+	b := "The quick  red fox jumps"
+
+	ops := utils.DiffWords(a, b)
+	gotA, gotB := rebuild(ops)
+	assert.Equal(t, a, gotA)
+	assert.Equal(t, b, gotB)
+
+	var deleted, inserted []string
+	for _, op := range ops {
+		switch op.Type {
+		case utils.DiffDelete:
+			deleted = append(deleted, op.Text)
+		case utils.DiffInsert:
+			inserted = append(inserted, op.Text)
+		}
+	}
+	assert.Equal(t, "brown", strings.TrimSpace(strings.Join(deleted, "")))
+	assert.Contains(t, strings.Join(inserted, ""), "red")
+	assert.Contains(t, strings.Join(inserted, ""), "jumps")
+}
+
+// lcsLength is the length of the longest common subsequence of a and b.
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// TestDiffMinimal tests that diffs keep as much text as possible.
+//
+// Test Cases:
+//  1. Random edits rebuild both texts
+//  2. The unchanged lines are a longest common subsequence of both texts
+func TestDiffMinimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a'+rng.Intn(4))) + "\n"
+		}
+		return lines
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := randomLines(), randomLines()
+		ops := utils.DiffLines(strings.Join(a, ""), strings.Join(b, ""))
+		gotA, gotB := rebuild(ops)
+		require.Equal(t, strings.Join(a, ""), gotA)
+		require.Equal(t, strings.Join(b, ""), gotB)
+
+		kept := 0
+		for _, op := range ops {
+			if op.Type == utils.DiffEqual {
+				kept += strings.Count(op.Text, "\n")
+			}
+		}
+		require.Equal(t, lcsLength(a, b), kept, "%q -> %q", a, b)
+	}
+}
+
+// TestDiffLarge tests diffing large revisions.
+//
+// Test Cases:
+//  1. A small edit to a long text is reported as just that edit
+//  2. Unrelated long texts are diffed quickly and still rebuild both
+func TestDiffLarge(t *testing.T) {
+	a := strings.Repeat("lorem ipsum dolor sit amet ", 40000)
+	b := "Intro " + strings.Replace(a, "dolor", "color", 1) + " outro"
+	ops := utils.DiffWords(a, b)
+	gotA, gotB := rebuild(ops)
+	assert.Equal(t, a, gotA)
+	assert.Equal(t, b, gotB)
+	var deleted, inserted strings.Builder
+	for _, op := range ops {
+		switch op.Type {
+		case utils.DiffDelete:
+			deleted.WriteString(op.Text)
+		case utils.DiffInsert:
+			inserted.WriteString(op.Text)
+		}
+	}
+	assert.Equal(t, "dolor ", deleted.String())
+	assert.Equal(t, "Intro color  outro", inserted.String())
+
+	start := time.Now()
+	a = strings.Repeat("alpha beta gamma ", 50000)
+	b = strings.Repeat("delta epsilon zeta ", 50000)
+	gotA, gotB = rebuild(utils.DiffWords(a, b))
+	assert.Equal(t, a, gotA)
+	assert.Equal(t, b, gotB)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/sasanzare/go-cms/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestETagListContains tests matching If-None-Match and If-Match headers.
+//
+// Test Cases:
+//  1. Single and listed tags match, with or without a weak marker
+//  2. "*" matches anything
+//  3. Other tags and empty headers don't match
+func TestETagListContains(t *testing.T) {
+	etag := `"post-7-v3"`
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`"post-7-v3"`, true},
+		{`W/"post-7-v3"`, true},
+		{`"post-7-v2", W/"post-7-v3"`, true},
+		{`*`, true},
+		{`"post-7-v2"`, false},
+		{`"post-7-v3-jalali-long"`, false},
+		{`"post-8-v3"`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, utils.ETagListContains(tt.header, etag), tt.header)
+	}
+}
+
+// TestIfMatchVersion tests reading expected versions from If-Match.
+//
+// Test Cases:
+//  1. Absent headers and "*" are unconditional
+//  2. Weak tags count like strong ones, as for ETagListContains
+//  3. Of several listed versions the newest counts; other resources' tags
+//     are ignored
+//  4. Representation suffixes after the version are ignored
+//  5. Headers listing no version of the resource are rejected
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		header      string
+		version     uint
+		conditional bool
+		err         bool
+	}{
+		{``, 0, false, false},
+		{` `, 0, false, false},
+		{`*`, 0, false, false},
+		{`"post-1-v2", *`, 0, false, false},
+		{`"post-7-v3"`, 3, true, false},
+		{`W/"post-7-v3"`, 3, true, false},
+		{`"post-7-v2", W/"post-7-v4"`, 4, true, false},
+		{`"post-8-v9", "post-7-v3"`, 3, true, false},
+		{`"post-7-v3-jalali-long"`, 3, true, false},
+		{`W/"post-7-v3-gregorian-date", "post-7-v2"`, 3, true, false},
+		{`"post-7-v-3"`, 0, false, true},
+		{`"post-8-v3"`, 0, false, true},
+		{`"post-7-v0"`, 0, false, true},
+		{`"post-7-vx"`, 0, false, true},
+		{`post-7-v3`, 0, false, true},
+		{`"post-77-v3"`, 0, false, true},
+	}
+	for _, tt := range tests {
+		version, conditional, err := utils.IfMatchVersion(tt.header, "post-7-v")
+		assert.Equal(t, tt.version, version, tt.header)
+		assert.Equal(t, tt.conditional, conditional, tt.header)
+		if tt.err {
+			assert.ErrorIs(t, err, utils.ErrIfMatchMismatch, tt.header)
+		} else {
+			assert.NoError(t, err, tt.header)
+		}
+	}
+}
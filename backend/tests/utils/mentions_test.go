@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/sasanzare/go-cms/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExtractMentions tests finding @username mentions in text.
+//
+// Test Cases:
+//  1. Mentions are returned once, in order of appearance
+//  2. Email addresses and too-short names are not mentions
+//  3. Punctuation around a mention is ignored
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"none", "no mentions here", nil},
+		{"several", "@sara and @reza, thanks @sara!", []string{"sara", "reza"}},
+		{"email", "write to sara@example.com", nil},
+		{"short", "hi @ab", nil},
+		{"punctuation", "(@mina) said: @omid.", []string{"mina", "omid"}},
+		{"non-ascii neighbours", "سلام @sara", []string{"sara"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, utils.ExtractMentions(tt.text))
+		})
+	}
+}
+
+// TestExcerpt tests shortening text for previews.
+//
+// Test Cases:
+//  1. Short text is unchanged
+//  2. Long text is cut at a word boundary with an ellipsis
+func TestExcerpt(t *testing.T) {
+	assert.Equal(t, "short", utils.Excerpt("short", 10))
+	assert.Equal(t, "the quick…", utils.Excerpt("the quick brown fox", 12))
+	assert.Equal(t, "سلام…", utils.Excerpt("سلام دنیای زیبا", 8))
+}
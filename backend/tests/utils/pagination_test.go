@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/sasanzare/go-cms/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNormalizePage tests clamping of page numbers and page sizes.
+//
+// Test Cases:
+//  1. Unset values fall back to page 1 and the default size
+//  2. Oversized pages are capped at MaxPageSize
+//  3. Valid values are kept
+func TestNormalizePage(t *testing.T) {
+	testCases := []struct {
+		page, pageSize         int
+		wantPage, wantPageSize int
+	}{
+		{0, 0, 1, utils.DefaultPageSize},                 // Defaults
+		{-3, -1, 1, utils.DefaultPageSize},               // Negative values
+		{5, 10, 5, 10},                                   // Unchanged
+		{2, utils.MaxPageSize + 1, 2, utils.MaxPageSize}, // Capped size
+	}
+
+	for _, tc := range testCases {
+		page, pageSize := utils.NormalizePage(tc.page, tc.pageSize)
+		assert.Equal(t, tc.wantPage, page)
+		assert.Equal(t, tc.wantPageSize, pageSize)
+	}
+}
+
+// TestTotalPages tests page count calculation including partial last pages.
+func TestTotalPages(t *testing.T) {
+	assert.Equal(t, 0, utils.TotalPages(0, 20))
+	assert.Equal(t, 1, utils.TotalPages(20, 20))
+	assert.Equal(t, 2, utils.TotalPages(21, 20))
+	assert.Equal(t, 0, utils.TotalPages(10, 0))
+}
+
+// TestCursorRoundTrip tests that cursors decode to the value they encode
+// and that tampered cursors are rejected.
+//
+// Test Cases:
+//  1. Encode then decode returns the original value
+//  2. Malformed base64 returns ErrInvalidCursor
+//  3. Valid base64 with invalid JSON returns ErrInvalidCursor
+func TestCursorRoundTrip(t *testing.T) {
+	type cursor struct {
+		Value string `json:"v"`
+		ID    uint   `json:"id"`
+	}
+
+	encoded, err := utils.EncodeCursor(cursor{Value: "2024-01-02T03:04:05Z", ID: 42})
+	assert.NoError(t, err)
+	assert.NotContains(t, encoded, "=") // URL safe, unpadded
+
+	var decoded cursor
+	assert.NoError(t, utils.DecodeCursor(encoded, &decoded))
+	assert.Equal(t, cursor{Value: "2024-01-02T03:04:05Z", ID: 42}, decoded)
+
+	assert.ErrorIs(t, utils.DecodeCursor("not base64!", &decoded), utils.ErrInvalidCursor)
+	assert.ErrorIs(t, utils.DecodeCursor("bm90IGpzb24", &decoded), utils.ErrInvalidCursor)
+}
+
+// TestNewPagination tests describing a page of an offset-paginated listing.
+//
+// Test Cases:
+//  1. Pages before the last have more
+//  2. The last page, and pages past it, have no more
+//  3. Empty listings have no pages
+func TestNewPagination(t *testing.T) {
+	p := utils.NewPagination(1, 20, 41)
+	assert.Equal(t, utils.Pagination{Page: 1, PageSize: 20, Total: 41, TotalPages: 3, HasMore: true}, *p)
+	assert.False(t, utils.NewPagination(3, 20, 41).HasMore)
+	assert.False(t, utils.NewPagination(4, 20, 41).HasMore)
+	assert.Equal(t, 0, utils.NewPagination(1, 20, 0).TotalPages)
+	assert.False(t, utils.NewPagination(1, 20, 0).HasMore)
+}
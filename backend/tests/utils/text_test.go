@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/sasanzare/go-cms/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSanitizeText tests reducing untrusted input to plain text.
+//
+// Test Cases:
+//  1. Tags are removed and entities decoded
+//  2. Script and style contents are dropped
+//  3. Control characters are removed and blank lines collapsed
+//  4. Plain text with angle brackets, ampersands and quotes survives
+//  5. Emails and URLs are kept intact
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"tags", `<p>Nice <b>post</b> &amp; thanks</p>`, "Nice post & thanks"},
+		{"script", `Hi<script>alert("x")</script><style>p{}</style> there`, "Hi there"},
+		{"control", "line one\x00\r\n\r\n\r\n\r\nline two\x07", "line one\n\nline two"},
+		{"plain", `I'd say 2 < 3 and "quotes" are fine`, `I'd say 2 < 3 and "quotes" are fine`},
+		{"whitespace", "  \n padded \n ", "padded"},
+		{"ampersand", "AT&T (US)", "AT&T (US)"},
+		{"email", "user@domain.com", "user@domain.com"},
+		{"url", "https://example.com/a/b?x=1&y=2", "https://example.com/a/b?x=1&y=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, utils.SanitizeText(tt.input))
+		})
+	}
+}
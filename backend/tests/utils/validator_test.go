@@ -71,35 +71,3 @@ func TestValidatePassword(t *testing.T) {
 		assert.Equal(t, tc.expected, utils.ValidatePassword(tc.password), tc.password)
 	}
 }
-
-// TestSanitizeInput tests the SanitizeInput function with various input scenarios.
-// It verifies proper whitespace trimming and removal of potentially harmful characters.
-//
-// Test Cases:
-//   1. Input with surrounding whitespace
-//   2. Clean input (no changes needed)
-//   3. Whitespace-only input
-//   4. Input with HTML tags
-//   5. Input with special HTML characters
-//   6. Valid email input
-//
-// Security Note:
-//   - Tests basic XSS protection
-//   - Verifies preservation of safe content
-func TestSanitizeInput(t *testing.T) {
-	testCases := []struct {
-		input    string
-		expected string
-	}{
-		{"  clean  ", "clean"},                     // Should trim whitespace
-		{"no spaces", "no spaces"},                 // No change needed
-		{"   ", ""},                                // Whitespace-only should become empty
-		{"<script>alert('xss')</script>", "scriptalertxssscript"}, // Should remove HTML tags
-		{"user@domain.com", "user@domain.com"},     // Valid email should remain unchanged
-		{"a&b<c>d", "abcd"},                       // Should remove special HTML characters
-	}
-
-	for _, tc := range testCases {
-		assert.Equal(t, tc.expected, utils.SanitizeInput(tc.input), tc.input)
-	}
-}
\ No newline at end of file
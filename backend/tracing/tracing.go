@@ -0,0 +1,174 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/sasanzare/go-cms"
+
+// Exporter names accepted by Config.Exporter
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterNone   = "none"
+)
+
+// Config describes how spans are sampled and exported.
+type Config struct {
+	ServiceName string
+	// Exporter is otlp, stdout, file or none. When empty, otlp is used if
+	// OTLPEndpoint is set and tracing is off otherwise.
+	Exporter string
+	// OTLPEndpoint is the collector base URL, e.g. http://localhost:4318.
+	OTLPEndpoint string
+	// FilePath is where the file exporter appends JSON spans.
+	FilePath string
+	// SampleRatio is the fraction of new traces recorded (0..1). Incoming
+	// sampled parents are always honoured.
+	SampleRatio float64
+}
+
+// ShutdownFunc flushes pending spans and releases exporter resources.
+type ShutdownFunc func(context.Context) error
+
+// Init installs the global tracer provider and the W3C trace-context and
+// baggage propagators.
+//
+// Parameters:
+//   - ctx: context used while creating the exporter
+//   - cfg: tracing configuration
+//
+// Returns:
+//   - ShutdownFunc: must be called on exit to flush buffered spans
+//   - error: exporter creation error if any
+func Init(ctx context.Context, cfg Config) (ShutdownFunc, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporter, closer, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
+
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, io.Closer, error) {
+	kind := cfg.Exporter
+	if kind == "" {
+		kind = ExporterNone
+		if cfg.OTLPEndpoint != "" {
+			kind = ExporterOTLP
+		}
+	}
+
+	switch kind {
+	case ExporterNone:
+		return nil, nil, nil
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{}
+		if cfg.OTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+		}
+		exp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		slog.InfoContext(ctx, "tracing enabled", slog.String("exporter", kind), slog.String("endpoint", cfg.OTLPEndpoint))
+		return exp, nil, nil
+	case ExporterFile:
+		f, err := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		slog.InfoContext(ctx, "tracing enabled", slog.String("exporter", kind), slog.String("path", cfg.FilePath))
+		return exp, f, nil
+	case ExporterStdout:
+		exp, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		slog.InfoContext(ctx, "tracing enabled", slog.String("exporter", kind))
+		return exp, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown trace exporter %q", kind)
+	}
+}
+
+// Tracer returns the application tracer from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start begins a child span of the span in ctx.
+//
+// Example:
+//
+//	ctx, span := tracing.Start(ctx, "PostService.CreatePost")
+//	defer span.End()
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End finishes span, marking it failed if *errp holds an error. It is meant
+// to be deferred with a pointer to the caller's named error result.
+//
+// Example:
+//
+//	ctx, span := tracing.Start(ctx, "PostService.UpdatePost")
+//	defer tracing.End(span, &err)
+func End(span trace.Span, errp *error) {
+	if errp != nil && *errp != nil {
+		span.RecordError(*errp)
+		span.SetStatus(codes.Error, (*errp).Error())
+	}
+	span.End()
+}
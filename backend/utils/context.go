@@ -0,0 +1,21 @@
+package utils
+
+import "context"
+
+type claimsKey struct{}
+
+// ContextWithClaims returns a copy of ctx carrying the authenticated user's
+// claims so services can identify the acting user.
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims stored by ContextWithClaims.
+//
+// Returns:
+//   - *Claims: the authenticated user's claims
+//   - bool: false for anonymous requests
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok && claims != nil
+}
@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Diff operation types
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// DiffOp is one run of unchanged, inserted or deleted text. Concatenating
+// the Text of equal and delete ops yields the old text; equal and insert
+// ops yield the new text.
+type DiffOp struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+var wordPattern = regexp.MustCompile(`\s+|[^\s]+`)
+
+// DiffLines compares a and b line by line.
+func DiffLines(a, b string) []DiffOp {
+	return diffTokens(splitLines(a), splitLines(b))
+}
+
+// DiffWords compares a and b word by word, keeping whitespace as tokens so
+// the output reconstructs both texts exactly.
+func DiffWords(a, b string) []DiffOp {
+	return diffTokens(wordPattern.FindAllString(a, -1), wordPattern.FindAllString(b, -1))
+}
+
+// splitLines splits s after each newline, keeping the newlines.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// maxDiffEdits bounds the edit distance the diff searches for between two
+// texts; beyond it the differing span is reported as replaced. Together
+// with linear memory this keeps the cost of diffing large, unrelated
+// revisions proportional to their size.
+const maxDiffEdits = 2000
+
+// diffTokens computes a minimal diff with Myers' algorithm in linear
+// space: the common prefix and suffix are trimmed, then each remaining
+// span is split at the middle of its shortest edit path and the halves
+// diffed in turn.
+func diffTokens(a, b []string) []DiffOp {
+	return appendDiff(nil, a, b)
+}
+
+func appendDiff(ops []DiffOp, a, b []string) []DiffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ops = appendOp(ops, DiffEqual, a[:prefix]...)
+
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if x, y, ok := bisect(ma, mb); ok {
+		ops = appendDiff(ops, ma[:x], mb[:y])
+		ops = appendDiff(ops, ma[x:], mb[y:])
+	} else {
+		ops = appendOp(ops, DiffDelete, ma...)
+		ops = appendOp(ops, DiffInsert, mb...)
+	}
+
+	return appendOp(ops, DiffEqual, a[len(a)-suffix:]...)
+}
+
+// bisect finds where the shortest edit path from a to b crosses its
+// middle, searching from both ends at once. It fails when either is empty,
+// when they have nothing in common, or when the path is longer than
+// maxDiffEdits.
+func bisect(a, b []string) (x, y int, ok bool) {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+	maxD := min((n+m+1)/2, maxDiffEdits)
+	offset := maxD + 1
+	// forward[offset+k] is the furthest x reached on diagonal k = x-y from
+	// the start; backward likewise from the end
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	delta := n - m
+	// With an odd delta the paths first overlap in a forward step
+	front := delta%2 != 0
+	var kfStart, kfEnd, kbStart, kbEnd int
+	for d := 0; d < maxD; d++ {
+		for k := -d + kfStart; k <= d-kfEnd; k += 2 {
+			i := offset + k
+			var xf int
+			if k == -d || (k != d && forward[i-1] < forward[i+1]) {
+				xf = forward[i+1]
+			} else {
+				xf = forward[i-1] + 1
+			}
+			yf := xf - k
+			for xf < n && yf < m && a[xf] == b[yf] {
+				xf++
+				yf++
+			}
+			forward[i] = xf
+			switch {
+			case xf > n:
+				kfEnd += 2
+			case yf > m:
+				kfStart += 2
+			case front:
+				if j := offset + delta - k; j >= 0 && j < len(backward) && backward[j] != -1 && xf >= n-backward[j] {
+					return xf, yf, true
+				}
+			}
+		}
+		for k := -d + kbStart; k <= d-kbEnd; k += 2 {
+			i := offset + k
+			var xb int
+			if k == -d || (k != d && backward[i-1] < backward[i+1]) {
+				xb = backward[i+1]
+			} else {
+				xb = backward[i-1] + 1
+			}
+			yb := xb - k
+			for xb < n && yb < m && a[n-xb-1] == b[m-yb-1] {
+				xb++
+				yb++
+			}
+			backward[i] = xb
+			switch {
+			case xb > n:
+				kbEnd += 2
+			case yb > m:
+				kbStart += 2
+			case !front:
+				if j := offset + delta - k; j >= 0 && j < len(forward) && forward[j] != -1 {
+					xf := forward[j]
+					if xf >= n-xb {
+						return xf, offset + xf - j, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// appendOp adds tokens to ops, merging them into the last op when it has
+// the same type.
+func appendOp(ops []DiffOp, typ string, tokens ...string) []DiffOp {
+	if len(tokens) == 0 {
+		return ops
+	}
+	text := strings.Join(tokens, "")
+	if n := len(ops); n > 0 && ops[n-1].Type == typ {
+		ops[n-1].Text += text
+		return ops
+	}
+	return append(ops, DiffOp{Type: typ, Text: text})
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrIfMatchMismatch is returned by IfMatchVersion for headers listing no
+// version of the resource.
+var ErrIfMatchMismatch = errors.New("If-Match does not match this resource")
+
+// ETagListContains reports whether a comma-separated If-None-Match or
+// If-Match header lists etag or "*". Weak validators compare equal to
+// their strong form, since compressing proxies weaken ETags on the way.
+func ETagListContains(header, etag string) bool {
+	for _, tag := range etagList(header) {
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// IfMatchVersion reads the version an If-Match header expects from ETags
+// of the form "<prefix><version>", such as "post-7-v3" with prefix
+// post-7-v. A suffix after a dash naming another representation of the
+// version, as in "post-7-v3-jalali-long", is ignored. The header is read
+// like ETagListContains does. It returns conditional=false when the
+// header is absent or lists "*", which match any version.
+//
+// Tags of other resources are ignored. Of several listed versions the
+// newest counts: versions only grow, so the client has seen no newer one.
+func IfMatchVersion(header, prefix string) (version uint, conditional bool, err error) {
+	tags := etagList(header)
+	if len(tags) == 0 {
+		return 0, false, nil
+	}
+	for _, tag := range tags {
+		if tag == "*" {
+			return 0, false, nil
+		}
+		raw, ok := strings.CutPrefix(tag, `"`+prefix)
+		raw, ok2 := strings.CutSuffix(raw, `"`)
+		if !ok || !ok2 {
+			continue
+		}
+		raw, _, _ = strings.Cut(raw, "-")
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err == nil && n > 0 && uint(n) > version {
+			version = uint(n)
+		}
+	}
+	if version == 0 {
+		return 0, false, ErrIfMatchMismatch
+	}
+	return version, true, nil
+}
+
+// etagList splits an If-Match or If-None-Match header into its tags,
+// without weak markers.
+func etagList(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
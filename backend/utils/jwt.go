@@ -71,6 +71,64 @@ func ValidateToken(tokenString string) (*Claims, error) {
 	return nil, errors.New("invalid token")
 }
 
+// Purposes of action tokens
+const (
+	PurposeEmailVerification = "email_verification"
+)
+
+// ActionClaims authorize a single kind of action, such as verifying an
+// email address, for one user.
+type ActionClaims struct {
+	UserID  uint   `json:"user_id"`
+	Email   string `json:"email"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// GenerateActionToken creates a token allowing purpose for the user until
+// ttl passes. Action tokens are signed with a key derived from the purpose,
+// so they can't be used as login tokens or for another purpose.
+//
+// Example:
+//   token, err := GenerateActionToken(42, "sara@example.com", PurposeEmailVerification, 48*time.Hour)
+func GenerateActionToken(userID uint, email, purpose string, ttl time.Duration) (string, error) {
+	claims := &ActionClaims{
+		UserID:  userID,
+		Email:   email,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(actionKey(purpose))
+}
+
+// ValidateActionToken checks a token created by GenerateActionToken for
+// purpose and returns its claims.
+func ValidateActionToken(tokenString, purpose string) (*ActionClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &ActionClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return actionKey(purpose), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*ActionClaims)
+	if !ok || !token.Valid || claims.Purpose != purpose {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+func actionKey(purpose string) []byte {
+	return append(append([]byte{}, jwtSecret...), ":"+purpose...)
+}
+
 // GetJWTSecret returns the current JWT secret key as a string.
 // This is primarily for debugging or logging purposes (avoid exposing secrets in production).
 //
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// mentionPattern matches @username where username follows the account
+// rules (3-50 letters or digits) and the @ doesn't continue a word, which
+// skips email addresses.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9]{3,50})\b`)
+
+// ExtractMentions returns the usernames mentioned in text, in order of first
+// appearance and without duplicates.
+func ExtractMentions(text string) []string {
+	var usernames []string
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			usernames = append(usernames, m[1])
+		}
+	}
+	return usernames
+}
+
+// Excerpt shortens text to at most max runes, cutting at a word boundary
+// and adding an ellipsis when anything was removed.
+func Excerpt(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)[:max]
+	cut := len(runes)
+	for i := len(runes) - 1; i >= max/2; i-- {
+		if runes[i] == ' ' || runes[i] == '\n' {
+			cut = i
+			break
+		}
+	}
+	return string(runes[:cut]) + "…"
+}
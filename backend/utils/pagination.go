@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ErrInvalidCursor is returned when a pagination cursor can't be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Pagination describes one page of a list response and is sent as the
+// "meta" field of JSONResponse.
+//
+// Fields:
+//   - Page: 1-based page number (offset pagination only)
+//   - PageSize: maximum number of items per page
+//   - Total: number of items matching the filters across all pages
+//   - TotalPages: number of pages at the current page size
+//   - NextCursor: opaque cursor for the following page, empty on the last page
+//   - HasMore: whether another page follows
+type Pagination struct {
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"page_size"`
+	Total      int64  `json:"total"`
+	TotalPages int    `json:"total_pages"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// NormalizePage clamps page and page size to sane values.
+//
+// Parameters:
+//   - page: requested 1-based page number
+//   - pageSize: requested page size
+//
+// Returns:
+//   - int: page, at least 1
+//   - int: page size between 1 and MaxPageSize, DefaultPageSize if unset
+func NormalizePage(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	return page, pageSize
+}
+
+// TotalPages returns how many pages of pageSize are needed for total items.
+func TotalPages(total int64, pageSize int) int {
+	if pageSize < 1 || total <= 0 {
+		return 0
+	}
+	return int((total + int64(pageSize) - 1) / int64(pageSize))
+}
+
+// NewPagination describes page of an offset-paginated listing of total
+// items, pageSize per page.
+func NewPagination(page, pageSize int, total int64) *Pagination {
+	totalPages := TotalPages(total, pageSize)
+	return &Pagination{
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: totalPages,
+		HasMore:    page < totalPages,
+	}
+}
+
+// EncodeCursor serializes v into an opaque, URL-safe cursor string.
+//
+// Example:
+//   cursor, err := EncodeCursor(struct{ ID uint }{42})
+func EncodeCursor(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor parses a cursor produced by EncodeCursor into v.
+//
+// Returns:
+//   - error: ErrInvalidCursor if the cursor is malformed
+func DecodeCursor(cursor string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
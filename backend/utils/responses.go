@@ -15,11 +15,13 @@ const (
 //   - Success: indicates if the request was successful
 //   - Message: optional success message
 //   - Data: optional payload data
+//   - Meta: optional metadata such as pagination details
 //   - Error: optional error message
 type JSONResponse struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
+	Meta    interface{} `json:"meta,omitempty"`
 	Error   string      `json:"error,omitempty"`
 }
 
@@ -55,6 +57,26 @@ func SendSuccessMessage(c *gin.Context, message string) {
 	})
 }
 
+// SendPaginated sends a successful JSON response with a page of data and
+// its pagination metadata.
+//
+// Parameters:
+//   - c: Gin context
+//   - message: optional success message
+//   - data: the current page of items
+//   - meta: pagination metadata describing the page
+//
+// Example:
+//   SendPaginated(c, "", posts, pagination)
+func SendPaginated(c *gin.Context, message string, data interface{}, meta *Pagination) {
+	c.JSON(http.StatusOK, JSONResponse{
+		Success: true,
+		Message: message,
+		Data:    data,
+		Meta:    meta,
+	})
+}
+
 // SendError sends an error JSON response with specified status code.
 //
 // Parameters:
@@ -71,6 +93,25 @@ func SendError(c *gin.Context, statusCode int, errorMessage string) {
 	})
 }
 
+// SendErrorWithData sends an error response that carries a payload, such
+// as the current state of a resource the request conflicted with.
+//
+// Parameters:
+//   - c: Gin context
+//   - statusCode: HTTP status code
+//   - errorMessage: error message to include
+//   - data: payload describing the error
+//
+// Example:
+//   SendErrorWithData(c, http.StatusPreconditionFailed, "Post was modified", conflict)
+func SendErrorWithData(c *gin.Context, statusCode int, errorMessage string, data interface{}) {
+	c.JSON(statusCode, JSONResponse{
+		Success: false,
+		Error:   errorMessage,
+		Data:    data,
+	})
+}
+
 // SendValidationError sends a validation error response with details.
 //
 // Parameters:
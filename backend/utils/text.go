@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+var extraBlankLines = regexp.MustCompile(`\n{3,}`)
+
+// SanitizeText turns untrusted input into plain text: HTML tags are
+// dropped (with the contents of script and style elements), entities are
+// decoded, control characters other than newlines and tabs are removed,
+// and runs of blank lines are collapsed. The result must still be escaped
+// when rendered as HTML.
+//
+// Example:
+//
+//	SanitizeText("<b>Hi</b> &amp; <script>x()</script>bye") // "Hi & bye"
+func SanitizeText(input string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(input))
+	skip := 0
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			text := strings.ReplaceAll(b.String(), "\r\n", "\n")
+			text = strings.Map(func(r rune) rune {
+				if r == '\n' || r == '\t' {
+					return r
+				}
+				if r == '\r' {
+					return '\n'
+				}
+				if unicode.IsControl(r) {
+					return -1
+				}
+				return r
+			}, text)
+			return strings.TrimSpace(extraBlankLines.ReplaceAllString(text, "\n\n"))
+		case html.StartTagToken:
+			if name, _ := z.TagName(); isRawTextTag(name) {
+				skip++
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); isRawTextTag(name) && skip > 0 {
+				skip--
+			}
+		case html.TextToken:
+			if skip == 0 {
+				b.WriteString(html.UnescapeString(string(z.Raw())))
+			}
+		}
+	}
+}
+
+func isRawTextTag(name []byte) bool {
+	switch string(name) {
+	case "script", "style":
+		return true
+	}
+	return false
+}
@@ -2,7 +2,6 @@ package utils
 
 import (
 	"regexp"
-	"strings"
 )
 
 // ValidateEmail checks if an email address has a valid format.
@@ -52,25 +51,3 @@ func ValidatePassword(password string) bool {
 	hasSpecial := regexp.MustCompile(`[!@#$%^&*]`).MatchString(password)
 	return hasLetter && hasNumber && hasUpper && hasSpecial
 }
-
-// SanitizeInput cleans and sanitizes user input by:
-//   - Trimming whitespace
-//   - Removing potentially harmful characters (<>"'&()/)
-//
-// Parameters:
-//   - input: The string to sanitize (string)
-//
-// Returns:
-//   - string: The sanitized version of the input
-//
-// Security Note:
-//   - This provides basic protection against XSS but shouldn't be
-//     the only security measure
-//
-// Example:
-//   clean := SanitizeInput("  <script>alert()</script>  ") // returns "scriptalertscript"
-func SanitizeInput(input string) string {
-    cleaned := strings.TrimSpace(input)
-    cleaned = regexp.MustCompile(`[<>"'&()/]`).ReplaceAllString(cleaned, "")
-    return cleaned
-}
\ No newline at end of file